@@ -33,6 +33,18 @@ func (r PolicyReport) HitRate() float64 {
 	return float64(r.Hits) / float64(total)
 }
 
+// Interval returns the range the policy's true hit rate lies in with
+// IntervalLevel confidence, given the requests it measured. Two policies
+// whose intervals overlap heavily are within noise of each other, whatever
+// their HitRate says; Advice.Confidence puts a number on exactly how much.
+//
+// Under ShadowSampleRate the interval is computed from the sampled counts,
+// and is as wide as a sample that size deserves: at a rate of 0.05 it is
+// roughly four times as wide as the full traffic would make it.
+func (r PolicyReport) Interval() (low, high float64) {
+	return wilson(PolicyStats{Hits: r.Hits, Misses: r.Misses})
+}
+
 // Advice is what the cache has learned about which policy suits the traffic it
 // has seen.
 //
@@ -51,11 +63,28 @@ type Advice struct {
 	Epochs int64
 	// Active is the policy serving requests.
 	Active PolicyType
+	// Switches is how many times the cache has changed its active policy
+	// since it was built. ObserveOnly suppresses only the switches the bandit
+	// selects, so in that mode it counts pins alone.
+	Switches int64
+	// Damping is what oscillation damping has done to the switch cooldown.
+	// It is zero unless Settings.Damping is set.
+	Damping DampingStatus
+	// Pin is the pin in force, if any. While it is, Best is still measured
+	// and reported, but the cache serves the pinned policy whatever it is.
+	Pin PinStatus
 	// Best is the policy with the highest measured hit rate.
 	Best PolicyType
 	// Improvement is how many percentage points Best beats Active by. It is
 	// zero when they are the same policy.
 	Improvement float64
+	// Confidence is the probability that Best's true hit rate beats Active's,
+	// given the requests each has measured in its current role - the one
+	// number that says whether the gap between their intervals is real. It is what
+	// Settings.SwitchConfidence is compared against, and the number to read
+	// before acting on Improvement: a large improvement at a confidence near
+	// one half is noise. It is zero when Best is Active.
+	Confidence float64
 	// Sampled reports whether the measurements come from a sampled substream,
 	// in which case the rates are estimates.
 	Sampled bool
@@ -80,20 +109,41 @@ func (a Advice) String() string {
 	} else {
 		fmt.Fprintf(&b, "On this traffic %s beats %s by %.2f points of hit rate, over %d epochs.\n",
 			a.Best, a.Active, a.Improvement*100, a.Epochs)
+		// Rounded, a near-certainty would print as a certainty, which the
+		// evidence never amounts to.
+		chance := fmt.Sprintf("a %.1f%%", a.Confidence*100)
+		if a.Confidence > 0.999 {
+			chance = "over a 99.9%"
+		}
+		fmt.Fprintf(&b, "The evidence gives %s %s chance of truly beating %s.\n",
+			a.Best, chance, a.Active)
 	}
 
 	if a.Sampled {
 		fmt.Fprintf(&b, "Rates are estimated from %.1f%% of the keyspace.\n", a.SampleRate*100)
 	}
+	if a.Pin.Pinned() {
+		source := "an operator"
+		if a.Pin.Fleet {
+			source = "the fleet"
+		}
+		fmt.Fprintf(&b, "Pinned to %s by %s: the bandit is not consulted.\n", a.Pin.Policy, source)
+	}
+	if a.Damping.Level > 0 {
+		fmt.Fprintf(&b, "Flapping damped: switches at most every %d epochs.\n", a.Damping.CooldownEpochs)
+	}
 
-	fmt.Fprintf(&b, "\n%-10s %9s %12s %12s\n", "policy", "hit rate", "hits", "misses")
+	fmt.Fprintf(&b, "\n%-10s %9s %17s %12s %12s\n", "policy", "hit rate",
+		fmt.Sprintf("%.0f%% interval", IntervalLevel*100), "hits", "misses")
 	for _, r := range a.Reports {
 		marker := " "
 		if r.Active {
 			marker = "*"
 		}
-		fmt.Fprintf(&b, "%s%-9s %8.2f%% %12d %12d\n",
-			marker, r.Policy, r.HitRate()*100, r.Hits, r.Misses)
+		low, high := r.Interval()
+		fmt.Fprintf(&b, "%s%-9s %8.2f%% %17s %12d %12d\n",
+			marker, r.Policy, r.HitRate()*100,
+			fmt.Sprintf("%.2f-%.2f%%", low*100, high*100), r.Hits, r.Misses)
 	}
 	b.WriteString("\n* currently active\n")
 
@@ -121,6 +171,9 @@ func (c *AdaptiveCache[K, V]) Advice() Advice {
 	advice := Advice{
 		Epochs:     c.reportingEpochs,
 		Active:     c.activePolicy,
+		Switches:   c.switches,
+		Damping:    c.dampingStatusLocked(),
+		Pin:        c.pinStatusLocked(),
 		Best:       c.activePolicy,
 		Sampled:    c.sampler.sampling,
 		SampleRate: c.sampler.rate,
@@ -159,6 +212,9 @@ func (c *AdaptiveCache[K, V]) Advice() Advice {
 	for _, r := range advice.Reports {
 		if r.Active {
 			advice.Improvement = best.HitRate() - r.HitRate()
+			if !best.Active {
+				advice.Confidence = probabilityBeats(c.tenureStats[best.Policy], c.tenureStats[r.Policy])
+			}
 
 			break
 		}
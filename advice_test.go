@@ -291,6 +291,31 @@ func TestAdvice_DoesNotRecommendRevertingACorrectSwitch(t *testing.T) {
 		"the active policy is the best one, so there is nothing being left on the table")
 }
 
+// TestAdvice_CountsSwitches guards the one number a scraper cannot work out
+// from ActivePolicy: a switch and a switch back between two scrapes look like
+// no change at all.
+func TestAdvice_CountsSwitches(t *testing.T) {
+	lru := newMockPolicy[string, int](LRU, 100)
+	lfu := newMockPolicy[string, int](LFU, 100)
+	bandit := &mockBandit{next: LFU}
+
+	ac, err := NewAdaptiveCache(
+		[]Policy[string, int]{lru, lfu}, bandit,
+		&Settings{EpochDuration: 24 * time.Hour, EvictPartialCapacityFilling: true},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ac.Close() })
+
+	ac.runEpoch()
+	ac.runEpoch()
+	bandit.next = LRU
+	ac.runEpoch()
+
+	advice := ac.Advice()
+	assert.Equal(t, LRU, advice.Active)
+	assert.Equal(t, int64(2), advice.Switches, "an epoch that keeps the policy is not a switch")
+}
+
 // TestAdvice_EpochsCountsOnlyMeasuredEpochs guards a counter that reported
 // elapsed ticks as evidence. The capacity gate can skip measurement for
 // thousands of ticks, and reporting those as epochs behind a recommendation
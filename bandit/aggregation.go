@@ -0,0 +1,162 @@
+package bandit
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// Aggregation selects how replicas' evidence is combined into the fleet's.
+type Aggregation uint8
+
+const (
+	// AggregationSum adds every replica's counts together, so a replica
+	// counts in proportion to its traffic. It is the default, it is what a
+	// single cache serving the fleet's traffic would have measured, and it is
+	// the only aggregation that needs nothing but a summed window.
+	AggregationSum Aggregation = iota + 1
+
+	// AggregationCapped sums like AggregationSum, but limits each replica's
+	// evidence for an arm to MaxReplicaWeight times the median replica's.
+	// Replicas within that factor of each other pool exactly as they would
+	// summed; one carrying a hundred times the traffic counts as a few.
+	AggregationCapped
+
+	// AggregationEqual weights every replica's hit rate equally, whatever its
+	// traffic. The fleet's rate for an arm is the mean of its replicas' rates.
+	AggregationEqual
+
+	// AggregationTrimmedMean is AggregationEqual after discarding the
+	// replicas with the highest and lowest rates for each arm - Trim of them
+	// at each end - so a few replicas seeing an unusual workload cannot move
+	// the fleet's decision at all.
+	AggregationTrimmedMean
+)
+
+// String names the aggregation as Snapshot reports it.
+func (a Aggregation) String() string {
+	switch a {
+	case AggregationSum:
+		return "sum"
+	case AggregationCapped:
+		return "capped"
+	case AggregationEqual:
+		return "equal"
+	case AggregationTrimmedMean:
+		return "trimmed-mean"
+	default:
+		return "unknown"
+	}
+}
+
+// combine pools per-replica evidence into one posterior per arm by the given
+// aggregation.
+//
+// Every aggregation but the sum changes how much a replica's evidence counts,
+// never how much evidence there is in total for the replicas it keeps. The
+// posterior's width is meant to reflect what the fleet has actually measured,
+// and capEvidence, applied afterwards, is what bounds it.
+func combine(
+	nodes map[string]map[ascache.PolicyType]weighted,
+	how Aggregation,
+	trim, maxWeight float64,
+) map[ascache.PolicyType]weighted {
+	if how == AggregationSum {
+		return sumNodes(nodes)
+	}
+
+	pooled := make(map[ascache.PolicyType]weighted)
+	for policy, replicas := range byArm(nodes) {
+		switch how {
+		case AggregationCapped:
+			pooled[policy] = capped(replicas, maxWeight)
+		case AggregationEqual:
+			pooled[policy] = meanRate(replicas)
+		case AggregationTrimmedMean:
+			// Rounding down keeps a small fleet whole: trimming a tenth of
+			// three replicas trims none of them rather than all but one.
+			k := int(trim * float64(len(replicas)))
+			pooled[policy] = meanRate(replicas[k : len(replicas)-k])
+		}
+	}
+
+	return pooled
+}
+
+// replicaArm is one replica's evidence for one arm.
+type replicaArm struct {
+	node string
+	arm  weighted
+}
+
+// byArm regroups per-replica evidence by arm, each arm's replicas ordered by
+// hit rate and then node ID, so trimming and summing do not depend on map
+// order. Replicas with no evidence for an arm are left out of it.
+func byArm(nodes map[string]map[ascache.PolicyType]weighted) map[ascache.PolicyType][]replicaArm {
+	arms := make(map[ascache.PolicyType][]replicaArm)
+	for node, evidence := range nodes {
+		for policy, arm := range evidence {
+			if arm.total() <= 0 {
+				continue
+			}
+			arms[policy] = append(arms[policy], replicaArm{node: node, arm: arm})
+		}
+	}
+
+	for _, replicas := range arms {
+		slices.SortFunc(replicas, func(a, b replicaArm) int {
+			if c := cmp.Compare(a.arm.hitRate(), b.arm.hitRate()); c != 0 {
+				return c
+			}
+
+			return strings.Compare(a.node, b.node)
+		})
+	}
+
+	return arms
+}
+
+// capped sums an arm's replicas after scaling each down to at most maxWeight
+// times the median replica's evidence, preserving each replica's rate.
+func capped(replicas []replicaArm, maxWeight float64) weighted {
+	totals := make([]float64, len(replicas))
+	for i, replica := range replicas {
+		totals[i] = replica.arm.total()
+	}
+	slices.Sort(totals)
+
+	median := totals[len(totals)/2]
+	if len(totals)%2 == 0 {
+		median = (totals[len(totals)/2-1] + median) / 2
+	}
+	limit := maxWeight * median
+
+	var sum weighted
+	for _, replica := range replicas {
+		scale := math.Min(1, limit/replica.arm.total())
+		sum.Hits += replica.arm.Hits * scale
+		sum.Misses += replica.arm.Misses * scale
+	}
+
+	return sum
+}
+
+// meanRate gives an arm the unweighted mean of its replicas' hit rates, over
+// the evidence those replicas hold between them.
+func meanRate(replicas []replicaArm) weighted {
+	if len(replicas) == 0 {
+		return weighted{}
+	}
+
+	var rates, total float64
+	for _, replica := range replicas {
+		rates += replica.arm.hitRate()
+		total += replica.arm.total()
+	}
+	rate := rates / float64(len(replicas))
+
+	return weighted{Hits: rate * total, Misses: (1 - rate) * total}
+}
@@ -0,0 +1,79 @@
+package bandit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// skewedFleet is four replicas agreeing on a 50% hit rate and one with a
+// hundred times their traffic measuring 90%.
+func skewedFleet() map[string]map[ascache.PolicyType]weighted {
+	nodes := map[string]map[ascache.PolicyType]weighted{
+		"heavy": {ascache.LRU: {Hits: 90_000, Misses: 10_000}},
+	}
+	for _, node := range []string{"a", "b", "c", "d"} {
+		nodes[node] = map[ascache.PolicyType]weighted{ascache.LRU: {Hits: 500, Misses: 500}}
+	}
+
+	return nodes
+}
+
+func TestCombine_SumIsTrafficWeighted(t *testing.T) {
+	pooled := combine(skewedFleet(), AggregationSum, DefaultTrim, DefaultMaxReplicaWeight)
+
+	assert.InDelta(t, 92_000.0/104_000, pooled[ascache.LRU].hitRate(), 1e-9)
+	assert.InDelta(t, 104_000.0, pooled[ascache.LRU].total(), 1e-9)
+}
+
+func TestCombine_CappedLimitsAReplicaToMultiplesOfTheMedian(t *testing.T) {
+	pooled := combine(skewedFleet(), AggregationCapped, DefaultTrim, 4)
+
+	// The heavy replica counts as four median replicas, at its own rate.
+	want := weighted{Hits: 2000 + 3600, Misses: 2000 + 400}
+	assert.InDelta(t, want.Hits, pooled[ascache.LRU].Hits, 1e-9)
+	assert.InDelta(t, want.Misses, pooled[ascache.LRU].Misses, 1e-9)
+}
+
+func TestCombine_EqualAveragesRatesOverAllTheEvidence(t *testing.T) {
+	pooled := combine(skewedFleet(), AggregationEqual, DefaultTrim, DefaultMaxReplicaWeight)
+
+	assert.InDelta(t, (4*0.5+0.9)/5, pooled[ascache.LRU].hitRate(), 1e-9)
+	assert.InDelta(t, 104_000.0, pooled[ascache.LRU].total(), 1e-9,
+		"reweighting must not change how much the fleet has measured")
+}
+
+func TestCombine_TrimmedMeanDropsTheExtremes(t *testing.T) {
+	nodes := skewedFleet()
+	nodes["low"] = map[ascache.PolicyType]weighted{ascache.LRU: {Hits: 100, Misses: 900}}
+
+	pooled := combine(nodes, AggregationTrimmedMean, 1.0/6, DefaultMaxReplicaWeight)
+
+	assert.InDelta(t, 0.5, pooled[ascache.LRU].hitRate(), 1e-9, "only the four agreeing replicas are left")
+	assert.InDelta(t, 4000.0, pooled[ascache.LRU].total(), 1e-9)
+}
+
+func TestCombine_TrimmingNeverEmptiesASmallFleet(t *testing.T) {
+	nodes := map[string]map[ascache.PolicyType]weighted{
+		"a": {ascache.LRU: {Hits: 10, Misses: 90}},
+		"b": {ascache.LRU: {Hits: 90, Misses: 10}},
+	}
+
+	pooled := combine(nodes, AggregationTrimmedMean, DefaultTrim, DefaultMaxReplicaWeight)
+
+	assert.InDelta(t, 0.5, pooled[ascache.LRU].hitRate(), 1e-9)
+}
+
+func TestCombine_SkipsReplicasWithoutEvidenceForAnArm(t *testing.T) {
+	nodes := map[string]map[ascache.PolicyType]weighted{
+		"a": {ascache.LRU: {Hits: 80, Misses: 20}, ascache.TinyLFU: {}},
+		"b": {ascache.LRU: {Hits: 60, Misses: 40}},
+	}
+
+	pooled := combine(nodes, AggregationEqual, DefaultTrim, DefaultMaxReplicaWeight)
+
+	assert.InDelta(t, 0.7, pooled[ascache.LRU].hitRate(), 1e-9)
+	assert.NotContains(t, pooled, ascache.TinyLFU)
+}
@@ -0,0 +1,237 @@
+package bandit
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// Canary stages a fleet decision: a new policy is applied first by a fixed
+// fraction of replicas, and the rest of the fleet follows only if it measures
+// no worse there than the current policy does everywhere else.
+//
+// Under ModeLeader a decision otherwise reaches every replica in the next
+// bucket, so a switch that looked right on shadow evidence and turns out wrong
+// at full size - shadows run on miniatures, and a miniature can flatter - is
+// wrong on the whole fleet at once. A canary spends a few buckets of a few
+// replicas to find out.
+//
+// The comparison is made on active-role counts only: canary replicas measure
+// the candidate at full capacity and the rest measure the current policy the
+// same way, so neither side carries a shadow's bias.
+type Canary struct {
+	// Fraction is the share of replicas that apply a new decision first.
+	// Zero, the default, disables canarying; otherwise it must be in (0,1).
+	//
+	// Membership is a hash of the node ID and the candidate, so it needs no
+	// coordination, and a different slice of the fleet takes the risk for
+	// each candidate.
+	Fraction float64
+
+	// Buckets is how many buckets the canary runs before the leader judges
+	// it. Defaults to DefaultCanaryBuckets.
+	Buckets int
+
+	// MaxRegression is how far, as a hit rate, the canary may trail the
+	// control before the decision is rolled back. Defaults to
+	// DefaultCanaryMaxRegression.
+	MaxRegression float64
+}
+
+// Defaults applied to a zero-valued Canary field once Fraction is set.
+const (
+	// DefaultCanaryBuckets runs a canary for five buckets, five seconds at
+	// the suggested one-second coordination epoch.
+	DefaultCanaryBuckets = 5
+	// DefaultCanaryMaxRegression rolls a decision back when the canary trails
+	// the control by more than a point.
+	DefaultCanaryMaxRegression = 0.01
+)
+
+// Canary phases, as CanaryStatus.Phase reports them.
+const (
+	canaryRunning    = "canary"
+	canaryPromoted   = "promoted"
+	canaryRolledBack = "rolled-back"
+	canaryAbandoned  = "abandoned"
+)
+
+// CanaryStatus is the state of the latest staged rollout this replica has
+// seen.
+type CanaryStatus struct {
+	// Phase is "canary" while the rollout runs, then "promoted" or
+	// "rolled-back" - or "abandoned" if this replica lost the store and fell
+	// back to deciding locally before the verdict.
+	Phase string `json:"phase"`
+	// Candidate is the decision being rolled out, Baseline the policy the
+	// fleet ran before it.
+	Candidate string `json:"candidate"`
+	Baseline  string `json:"baseline"`
+	// Member reports whether this replica is one of the canaries.
+	Member bool `json:"member"`
+	// Since is the bucket this replica joined the rollout in, and Until the
+	// bucket from which a verdict is due.
+	Since int64 `json:"since"`
+	Until int64 `json:"until"`
+	// CanaryHitRate and ControlHitRate are the active-role rates the verdict
+	// was reached on. Only the replica that judged the canary has them.
+	CanaryHitRate  float64 `json:"canary_hit_rate,omitempty"`
+	ControlHitRate float64 `json:"control_hit_rate,omitempty"`
+}
+
+// rollout is a staged decision in progress on this replica.
+type rollout struct {
+	candidate ascache.PolicyType
+	baseline  ascache.PolicyType
+	since     Bucket
+	until     Bucket
+	member    bool
+}
+
+// enabled reports whether decisions are staged at all.
+func (c Canary) enabled() bool { return c.Fraction > 0 }
+
+// inCanary reports whether node applies candidate ahead of the fleet.
+func inCanary(node string, candidate ascache.PolicyType, fraction float64) bool {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(node))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(EncodePolicy(candidate)))
+
+	return float64(h.Sum64()) < fraction*math.MaxUint64
+}
+
+// stageLocked folds a fleet decision into the rollout in progress, if any,
+// and returns what this replica should run.
+//
+// Replicas follow the rollout from the decisions alone. The leader publishes
+// the candidate for as long as the canary runs, and then publishes its
+// verdict: the candidate again to promote it, or the baseline to roll it
+// back. A replica that first saw the candidate a bucket late reaches the
+// end of its canary a bucket late too; seeing the leader move on to a third
+// policy in the meantime tells it the candidate was promoted.
+func (d *Distributed) stageLocked(bucket Bucket, decision ascache.PolicyType) ascache.PolicyType {
+	current := ascache.PolicyType(d.selection.Load())
+
+	if r := d.rollout; r != nil {
+		switch {
+		case decision == r.candidate && bucket < r.until:
+			if r.member {
+				return r.candidate
+			}
+
+			return r.baseline
+
+		case decision == r.candidate:
+			d.endRolloutLocked(canaryPromoted)
+			return decision
+
+		case decision == r.baseline:
+			d.endRolloutLocked(canaryRolledBack)
+			return decision
+
+		default:
+			d.endRolloutLocked(canaryPromoted)
+			current = r.candidate
+		}
+	}
+
+	if decision == current || current == ascache.Undefined {
+		// Nothing to stage: the fleet is not changing its mind, or this is
+		// its first decision and there is no policy to protect.
+		return decision
+	}
+
+	r := &rollout{
+		candidate: decision,
+		baseline:  current,
+		since:     bucket,
+		until:     bucket + Bucket(d.cfg.Canary.Buckets) + 1,
+		member:    inCanary(d.cfg.NodeID, decision, d.cfg.Canary.Fraction),
+	}
+	d.rollout = r
+	d.state.canary = &CanaryStatus{
+		Phase:     canaryRunning,
+		Candidate: r.candidate.String(),
+		Baseline:  r.baseline.String(),
+		Member:    r.member,
+		Since:     int64(r.since),
+		Until:     int64(r.until),
+	}
+
+	if r.member {
+		return r.candidate
+	}
+
+	return r.baseline
+}
+
+// endRolloutLocked closes the rollout in progress with a verdict.
+func (d *Distributed) endRolloutLocked(phase string) {
+	d.rollout = nil
+	if d.state.canary == nil {
+		return
+	}
+
+	d.state.canary.Phase = phase
+	switch phase {
+	case canaryPromoted:
+		d.state.promotions++
+	case canaryRolledBack:
+		d.state.rollbacks++
+	}
+}
+
+// judgeCanary decides whether a canary that has run its course is promoted,
+// returning the candidate, or rolled back, returning the baseline.
+//
+// The canary's measurements land one bucket after the bucket they were made
+// in, when the replica next syncs, so the window read is the Buckets buckets
+// that follow the rollout's first. With nothing measured on either side there
+// is nothing to object to, and the decision stands exactly as it would have
+// without a canary.
+func (d *Distributed) judgeCanary(ctx context.Context, namespace string, r rollout) (ascache.PolicyType, bool) {
+	window, err := d.cfg.Store.Window(ctx, namespace, r.since+1, r.until-1)
+	if err != nil {
+		d.recordFailure(err)
+		return ascache.Undefined, false
+	}
+
+	var canary, control weighted
+	for _, bucket := range window {
+		for key, stats := range bucket.Arms {
+			if key.Role != RoleActive {
+				continue
+			}
+
+			var side *weighted
+			switch key.Policy {
+			case r.candidate:
+				side = &canary
+			case r.baseline:
+				side = &control
+			default:
+				continue
+			}
+			side.Hits += float64(stats.Hits)
+			side.Misses += float64(stats.Misses)
+		}
+	}
+
+	verdict := r.candidate
+	if canary.total() > 0 && control.total() > 0 &&
+		canary.hitRate() < control.hitRate()-d.cfg.Canary.MaxRegression {
+		verdict = r.baseline
+	}
+
+	d.mu.Lock()
+	if status := d.state.canary; status != nil {
+		status.CanaryHitRate = canary.hitRate()
+		status.ControlHitRate = control.hitRate()
+	}
+	d.mu.Unlock()
+
+	return verdict, true
+}
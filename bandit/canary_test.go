@@ -0,0 +1,149 @@
+package bandit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// runStaged drives a canaried fleet that starts on LRU, each replica
+// reporting TinyLFU at shadowRate while it shadows and at activeRate while it
+// serves, and LRU at a steady 50% either way. It returns the replicas and the
+// most replicas seen running TinyLFU at once before the first verdict.
+func runStaged(t *testing.T, shadowRate, activeRate float64, rounds int) ([]*replica, int) {
+	t.Helper()
+
+	store, clock := newFleetStore(t)
+	replicas := fleet(t, 10, store, clock, func(cfg *Config) {
+		cfg.Canary = Canary{Fraction: 0.3, Buckets: 3}
+		// Shadow evidence this lopsided would otherwise settle the draw for
+		// good before the canary had anything to say.
+		cfg.MaxEvidence = 50
+	})
+	for _, r := range replicas {
+		r.bandit.selection.Store(uint64(ascache.LRU))
+	}
+
+	widest := 0
+	for range rounds {
+		running := 0
+		for _, r := range replicas {
+			active := r.bandit.SelectPolicy()
+			tiny := shadowRate
+			if active == ascache.TinyLFU {
+				tiny = activeRate
+				running++
+			}
+			r.active = active
+			r.report(t, 1000, map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: tiny})
+			r.bandit.sync()
+		}
+
+		verdicts := int64(0)
+		for _, r := range replicas {
+			snapshot := r.bandit.Snapshot()
+			verdicts += snapshot.Promotions + snapshot.Rollbacks
+		}
+		if verdicts == 0 {
+			widest = max(widest, running)
+		}
+
+		clock.advance(testEpoch)
+	}
+
+	return replicas, widest
+}
+
+func TestCanary_RollsBackADecisionThatRegressesAtFullSize(t *testing.T) {
+	// TinyLFU looks far better in a shadow than it is once it serves. Six
+	// buckets is one with nothing to read, one to draw it, three of canary
+	// and one for the verdict.
+	replicas, widest := runStaged(t, 0.9, 0.3, 6)
+
+	assert.LessOrEqual(t, widest, 3, "only the canaries may run the candidate before a verdict")
+
+	judged := false
+	for _, r := range replicas {
+		snapshot := r.bandit.Snapshot()
+		assert.Equal(t, ascache.LRU, r.bandit.SelectPolicy(), snapshot.NodeID)
+		assert.Equal(t, int64(1), snapshot.Rollbacks, snapshot.NodeID)
+		require.NotNil(t, snapshot.Canary, snapshot.NodeID)
+		assert.Equal(t, "rolled-back", snapshot.Canary.Phase, snapshot.NodeID)
+
+		if c := snapshot.Canary; c.ControlHitRate != 0 {
+			judged = true
+			assert.InDelta(t, 0.5, c.ControlHitRate, 0.01)
+			assert.InDelta(t, 0.3, c.CanaryHitRate, 0.01)
+		}
+	}
+	assert.True(t, judged, "the judging leader reports what it compared")
+}
+
+func TestCanary_PromotesADecisionThatHolds(t *testing.T) {
+	replicas, widest := runStaged(t, 0.9, 0.9, 12)
+
+	assert.LessOrEqual(t, widest, 3)
+	for _, r := range replicas {
+		snapshot := r.bandit.Snapshot()
+		assert.Equal(t, ascache.TinyLFU, r.bandit.SelectPolicy(), snapshot.NodeID)
+		require.NotNil(t, snapshot.Canary, snapshot.NodeID)
+		assert.Equal(t, "promoted", snapshot.Canary.Phase, snapshot.NodeID)
+		assert.Contains(t, snapshot.String(), "rollout of TinyLFU over LRU: promoted")
+	}
+}
+
+func TestCanary_FollowsTheRolloutFromDecisionsAlone(t *testing.T) {
+	store, clock := newFleetStore(t)
+	replicas := fleet(t, 10, store, clock, func(cfg *Config) {
+		cfg.Canary = Canary{Fraction: 0.3, Buckets: 2}
+	})
+
+	var member, control *Distributed
+	for _, r := range replicas {
+		r.report(t, 100, map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6, ascache.LFU: 0.55})
+		if inCanary(r.bandit.cfg.NodeID, ascache.TinyLFU, 0.3) {
+			member = r.bandit
+		} else {
+			control = r.bandit
+		}
+	}
+	require.NotNil(t, member)
+	require.NotNil(t, control)
+
+	apply := func(bucket Bucket, decision ascache.PolicyType) {
+		member.applyResult(bucket, decision, true)
+		control.applyResult(bucket, decision, true)
+	}
+
+	apply(10, ascache.LRU)
+	assert.Equal(t, ascache.LRU, member.SelectPolicy(), "a first decision is applied outright")
+	assert.Nil(t, member.Snapshot().Canary)
+
+	apply(11, ascache.TinyLFU)
+	assert.Equal(t, ascache.TinyLFU, member.SelectPolicy())
+	assert.Equal(t, ascache.LRU, control.SelectPolicy())
+	assert.Equal(t, "canary", control.Snapshot().Canary.Phase)
+
+	apply(13, ascache.TinyLFU)
+	assert.Equal(t, ascache.LRU, control.SelectPolicy(), "the canary runs until its verdict is due")
+
+	apply(14, ascache.LRU)
+	assert.Equal(t, ascache.LRU, member.SelectPolicy(), "publishing the baseline rolls the canary back")
+	assert.Equal(t, "rolled-back", member.Snapshot().Canary.Phase)
+	assert.Equal(t, int64(1), member.Snapshot().Rollbacks)
+
+	// A replica that missed a verdict learns it from the leader moving on to
+	// a third policy: the candidate must have been promoted, and is now the
+	// baseline the next rollout is staged over.
+	apply(20, ascache.TinyLFU)
+	control.applyResult(30, ascache.LFU, true)
+
+	snapshot := control.Snapshot()
+	assert.Equal(t, int64(1), snapshot.Promotions)
+	require.NotNil(t, snapshot.Canary)
+	assert.Equal(t, ascache.LFU.String(), snapshot.Canary.Candidate)
+	assert.Equal(t, ascache.TinyLFU.String(), snapshot.Canary.Baseline)
+}
@@ -0,0 +1,50 @@
+package bandit
+
+import (
+	"strconv"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// EncodePolicy renders a PolicyType for a store shared between processes.
+//
+// A built-in policy is written as its number, which is what stores have always
+// written and what every release agrees on; a built-in's name is free to change
+// and a renamed one would otherwise split a fleet's counters in two. A policy
+// type allocated at run time - by ascache.RegisterPolicyType or
+// ascache.Variant - is the other way round: its number is allocated in the
+// process that registered it and means nothing to any other, while its name -
+// Clock, TTL/30s - is what the caller chose and is the same everywhere. So it
+// is written by name.
+//
+// The two forms never collide. A registered name starts with a letter, and a
+// number never does.
+func EncodePolicy(policy ascache.PolicyType) string {
+	if policy.Builtin() {
+		return strconv.FormatUint(uint64(policy), 10)
+	}
+
+	return policy.String()
+}
+
+// DecodePolicy reverses EncodePolicy. It reports false for text that names no
+// policy known to this process: a number outside the built-ins, or a name this
+// process has not registered - which a replica running different arms in the
+// same namespace could legitimately have written.
+func DecodePolicy(text string) (ascache.PolicyType, bool) {
+	if number, err := strconv.ParseUint(text, 10, 64); err == nil {
+		policy := ascache.PolicyType(number)
+		if !policy.Builtin() || uint64(policy) != number {
+			return ascache.Undefined, false
+		}
+
+		return policy, true
+	}
+
+	policy, ok := ascache.ParsePolicyType(text)
+	if !ok || policy.Builtin() {
+		return ascache.Undefined, false
+	}
+
+	return policy, true
+}
@@ -0,0 +1,54 @@
+package bandit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+func TestEncodePolicy_BuiltinsStayNumeric(t *testing.T) {
+	// Stores written before variants existed hold numbers; a release that
+	// wrote names instead would split every fleet mid-rollout.
+	assert.Equal(t, "1", EncodePolicy(ascache.LRU))
+	assert.Equal(t, "7", EncodePolicy(ascache.TinyLFU))
+}
+
+func TestEncodePolicy_VariantsRoundTripByName(t *testing.T) {
+	variant, err := ascache.Variant(ascache.LRUK, "codec-k3")
+	require.NoError(t, err)
+
+	encoded := EncodePolicy(variant)
+	assert.Equal(t, "LRUK/codec-k3", encoded)
+
+	decoded, ok := DecodePolicy(encoded)
+	require.True(t, ok)
+	assert.Equal(t, variant, decoded)
+}
+
+func TestDecodePolicy_RejectsWhatThisProcessCannotName(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"99",
+		"65536",
+		"-1",
+		"LRU",
+		"TTL/never-registered",
+	} {
+		_, ok := DecodePolicy(text)
+		assert.False(t, ok, "%q", text)
+	}
+}
+
+var testRegistered = ascache.RegisterPolicyType("CodecTestClock")
+
+func TestEncodePolicy_RegisteredTypesRoundTripByName(t *testing.T) {
+	encoded := EncodePolicy(testRegistered)
+	assert.Equal(t, "CodecTestClock", encoded)
+
+	decoded, ok := DecodePolicy(encoded)
+	require.True(t, ok)
+	assert.Equal(t, testRegistered, decoded)
+}
@@ -2,6 +2,7 @@ package bandit
 
 import (
 	"fmt"
+	"log/slog"
 	"math/rand/v2"
 	"os"
 	"time"
@@ -115,6 +116,44 @@ type Config struct {
 	// EvidenceAll.
 	Evidence EvidenceMode
 
+	// Contributors reads the window replica by replica rather than summed,
+	// so Snapshot can say how much of the evidence each replica supplied and
+	// flag any measuring rates unlike the rest. It requires a Store that
+	// implements ContributorStore.
+	//
+	// It is off by default because the read it makes grows with the fleet:
+	// a summed window costs the same at a thousand replicas as at two, and
+	// this one costs a thousand times as much.
+	Contributors bool
+
+	// Aggregation selects how replicas' evidence combines into the fleet's.
+	// Defaults to AggregationSum, where a replica counts in proportion to its
+	// traffic. The others read the window replica by replica, as Contributors
+	// does, and so need a Store that implements ContributorStore.
+	//
+	// Summing is right when every replica sees the same workload, which is
+	// what a load balancer is for. When they do not - one replica pinned to a
+	// tenant, or taking a hundred times the traffic of the rest - the sum is
+	// that replica's decision with everyone else's name on it, and the other
+	// aggregations keep it to a replica's worth.
+	Aggregation Aggregation
+
+	// Trim is the fraction of replicas, at each end, that
+	// AggregationTrimmedMean discards per arm. Defaults to DefaultTrim, and
+	// must be in [0,0.5).
+	Trim float64
+
+	// MaxReplicaWeight is how many times the median replica's evidence for an
+	// arm any one replica may count for under AggregationCapped. Defaults to
+	// DefaultMaxReplicaWeight, and must be at least 1.
+	MaxReplicaWeight float64
+
+	// Canary stages each change of fleet policy under ModeLeader, applying it
+	// on a fraction of replicas first and promoting or rolling it back on
+	// what they measure. Disabled while Canary.Fraction is zero; rejected
+	// under ModeSharedPosterior, where there is no fleet decision to stage.
+	Canary Canary
+
 	// FallbackAfter is how long the store may go unreachable before this
 	// replica stops waiting for the fleet and decides locally. Defaults to
 	// three coordination epochs.
@@ -142,6 +181,18 @@ type Config struct {
 	// synchronise the jitter it exists to break up.
 	Seed uint64
 
+	// Logger receives what the coordination goroutine would otherwise only
+	// leave in Snapshot: entering and leaving fallback at Warn, each failed
+	// round trip at Debug, a fleet decision refused for a policy this cache
+	// does not hold at Warn, and the regime fingerprint each time this replica
+	// starts coordinating under one at Info. An outage is therefore two Warn
+	// records however long it lasts. Every record carries the namespace and
+	// node. Defaults to discarding everything.
+	//
+	// It is only ever written to from the coordination goroutine, never from
+	// a call the cache makes, and never while Distributed holds its own lock.
+	Logger *slog.Logger
+
 	// Now is the clock used for staleness and jitter, a seam for tests.
 	// Defaults to time.Now. It is never used to derive a bucket - that is the
 	// store's job precisely so a replica's clock cannot matter.
@@ -163,6 +214,13 @@ const (
 	DefaultLocalDiscount = 0.7
 	// DefaultJitter spreads syncs over a tenth of the coordination epoch.
 	DefaultJitter = 0.1
+	// DefaultTrim discards the top and bottom tenth of replicas by rate, so
+	// a fleet of ten ignores its most and least flattering replica.
+	DefaultTrim = 0.1
+	// DefaultMaxReplicaWeight lets a replica count for at most four median
+	// replicas: uneven enough for ordinary load-balancer skew to pool as a
+	// plain sum, too little for one replica to outvote a fleet.
+	DefaultMaxReplicaWeight = 4.0
 	// DefaultMaxEvidence caps an arm's posterior at a hundred thousand
 	// weighted observations, which puts its standard deviation at roughly a
 	// sixth of a percentage point: enough certainty to separate arms that
@@ -191,6 +249,12 @@ func (c *Config) validate() error {
 	if c.Jitter < 0 || c.Jitter >= 0.5 {
 		return fmt.Errorf("%w: got %v", ErrInvalidJitter, c.Jitter)
 	}
+	if c.Trim < 0 || c.Trim >= 0.5 {
+		return fmt.Errorf("%w: got %v", ErrInvalidTrim, c.Trim)
+	}
+	if c.MaxReplicaWeight != 0 && c.MaxReplicaWeight < 1 {
+		return fmt.Errorf("%w: got %v", ErrInvalidReplicaWeight, c.MaxReplicaWeight)
+	}
 
 	// Both enums number from one, so their zero value names no mode at all
 	// rather than happening to name the default. That has to be resolved here,
@@ -204,10 +268,25 @@ func (c *Config) validate() error {
 	if c.Evidence == 0 {
 		c.Evidence = EvidenceAll
 	}
+	if c.Aggregation == 0 {
+		c.Aggregation = AggregationSum
+	}
 
 	if c.Mode == ModeLeader && c.Evidence == EvidenceShadowOnly {
 		return ErrShadowOnlyUnderLeader
 	}
+	if c.Canary.enabled() && c.Mode != ModeLeader {
+		return ErrCanaryUnderSharedPosterior
+	}
+	if c.Canary.Fraction < 0 || c.Canary.Fraction >= 1 || c.Canary.Buckets < 0 || c.Canary.MaxRegression < 0 {
+		return fmt.Errorf("%w: got %+v", ErrInvalidCanary, c.Canary)
+	}
+	if _, ok := c.Store.(ContributorStore); c.Contributors && !ok {
+		return fmt.Errorf("%w: %T", ErrContributorsUnsupported, c.Store)
+	}
+	if _, ok := c.Store.(ContributorStore); c.Aggregation != AggregationSum && !ok {
+		return fmt.Errorf("%w: %s over %T", ErrAggregationUnsupported, c.Aggregation, c.Store)
+	}
 
 	if c.Window == 0 {
 		c.Window = DefaultWindow
@@ -218,6 +297,18 @@ func (c *Config) validate() error {
 	if c.Jitter == 0 {
 		c.Jitter = DefaultJitter
 	}
+	if c.Canary.enabled() && c.Canary.Buckets == 0 {
+		c.Canary.Buckets = DefaultCanaryBuckets
+	}
+	if c.Canary.enabled() && c.Canary.MaxRegression == 0 {
+		c.Canary.MaxRegression = DefaultCanaryMaxRegression
+	}
+	if c.Trim == 0 {
+		c.Trim = DefaultTrim
+	}
+	if c.MaxReplicaWeight == 0 {
+		c.MaxReplicaWeight = DefaultMaxReplicaWeight
+	}
 	if c.LocalDiscount <= 0 || c.LocalDiscount > 1 {
 		c.LocalDiscount = DefaultLocalDiscount
 	}
@@ -241,6 +332,9 @@ func (c *Config) validate() error {
 	if c.NodeID == "" {
 		c.NodeID = defaultNodeID(c.Seed)
 	}
+	if c.Logger == nil {
+		c.Logger = slog.New(slog.DiscardHandler)
+	}
 
 	return nil
 }
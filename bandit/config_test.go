@@ -77,6 +77,57 @@ func TestConfig_RejectsWhatCannotBeDefaulted(t *testing.T) {
 			},
 			wantErr: ErrShadowOnlyUnderLeader,
 		},
+		{
+			name: "contributors over a store that sums every replica",
+			mutate: func(c *Config) {
+				c.Store = struct{ Store }{NewMemStore()}
+				c.Contributors = true
+			},
+			wantErr: ErrContributorsUnsupported,
+		},
+		{
+			name: "per-replica aggregation over a store that sums every replica",
+			mutate: func(c *Config) {
+				c.Store = struct{ Store }{NewMemStore()}
+				c.Aggregation = AggregationTrimmedMean
+			},
+			wantErr: ErrAggregationUnsupported,
+		},
+		{
+			name:    "trimming half the replicas",
+			mutate:  func(c *Config) { c.Trim = 0.5 },
+			wantErr: ErrInvalidTrim,
+		},
+		{
+			name:    "negative trim",
+			mutate:  func(c *Config) { c.Trim = -0.1 },
+			wantErr: ErrInvalidTrim,
+		},
+		{
+			name:    "replica weight below the median",
+			mutate:  func(c *Config) { c.MaxReplicaWeight = 0.5 },
+			wantErr: ErrInvalidReplicaWeight,
+		},
+		{
+			name:    "a canary of the whole fleet",
+			mutate:  func(c *Config) { c.Canary.Fraction = 1 },
+			wantErr: ErrInvalidCanary,
+		},
+		{
+			name: "a canary of negative length",
+			mutate: func(c *Config) {
+				c.Canary = Canary{Fraction: 0.1, Buckets: -1}
+			},
+			wantErr: ErrInvalidCanary,
+		},
+		{
+			name: "a canary without a fleet decision",
+			mutate: func(c *Config) {
+				c.Mode = ModeSharedPosterior
+				c.Canary.Fraction = 0.1
+			},
+			wantErr: ErrCanaryUnderSharedPosterior,
+		},
 	}
 
 	for _, tt := range tests {
@@ -141,6 +192,9 @@ func TestConfig_FillsInDefaults(t *testing.T) {
 	assert.InDelta(t, DefaultJitter, cfg.Jitter, 1e-9)
 	assert.InDelta(t, DefaultLocalDiscount, cfg.LocalDiscount, 1e-9)
 	assert.InDelta(t, DefaultMaxEvidence, cfg.MaxEvidence, 1e-9)
+	assert.Equal(t, AggregationSum, cfg.Aggregation)
+	assert.InDelta(t, DefaultTrim, cfg.Trim, 1e-9)
+	assert.InDelta(t, DefaultMaxReplicaWeight, cfg.MaxReplicaWeight, 1e-9)
 	assert.Equal(t, 3*time.Second, cfg.FallbackAfter)
 	assert.Equal(t, time.Second, cfg.SyncTimeout)
 	assert.NotEmpty(t, cfg.NodeID)
@@ -0,0 +1,190 @@
+package bandit
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"strings"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// ContributorStore is a Store that also keeps each replica's counts apart, so
+// a window can be read replica by replica rather than only as the fleet's
+// sum.
+//
+// A summed window cannot say who it came from. One replica carrying nine
+// tenths of the traffic looks the same as ten carrying a tenth each, and a
+// replica measuring a hit rate nothing like the rest - a bad shard, a cache
+// configured by hand, a client hammering one key - disappears into the
+// average it is distorting. Keeping the counts per replica as well is what
+// lets Config.Contributors show both.
+//
+// It is optional, and so is reading it: a per-replica window grows with the
+// fleet where a summed one does not, so a bandit reads it only when
+// Config.Contributors asks. Every store in this repository implements it.
+type ContributorStore interface {
+	Store
+
+	// NodeWindow reads the counts for buckets first through last inclusive,
+	// per replica. A bucket is omitted exactly when Window would omit it, and
+	// summing a bucket's replicas gives exactly what Window reports for it.
+	NodeWindow(ctx context.Context, namespace string, first, last Bucket) ([]NodeWindowCounts, error)
+}
+
+// NodeWindowCounts is one bucket's measurements, per replica.
+type NodeWindowCounts struct {
+	Bucket Bucket
+	// Nodes holds each replica's counts, keyed by node ID and then by policy
+	// and role.
+	Nodes map[string]map[ArmKey]ascache.PolicyStats
+}
+
+// Contributor is one replica's part in the evidence behind the last decision.
+type Contributor struct {
+	NodeID string `json:"node_id"`
+	// Share is the fraction of the window's weighted observations, across
+	// every arm, that came from this replica.
+	Share float64 `json:"share"`
+	// Arms is this replica's own evidence, weighted as the fleet's is, best
+	// hit rate first.
+	Arms []ArmEvidence `json:"arms"`
+	// Outliers names the arms this replica measures at a hit rate more than
+	// five points from the rest of the fleet's, by more than chance explains.
+	// Empty for a replica in line with everyone else.
+	Outliers []string `json:"outliers,omitempty"`
+}
+
+// An arm's rate on one replica is flagged as an outlier when it is both far
+// from the rest of the fleet's and further than chance explains.
+//
+// Both tests are needed. The gap alone would flag a replica whose few dozen
+// requests landed unluckily; the significance alone would flag every replica
+// of a large fleet, because with millions of requests behind it a tenth of a
+// point is significant and still means nothing. Five points is a difference
+// no two replicas behind one load balancer should show, and four standard
+// errors is well clear of what a fleet of hundreds throws up by chance.
+const (
+	outlierGap = 0.05
+	outlierZ   = 4.0
+)
+
+// aggregateNodes pools a per-replica window the way aggregate pools a summed
+// one, keeping each replica's evidence apart.
+func aggregateNodes(
+	window []NodeWindowCounts,
+	newest Bucket,
+	decay float64,
+	mode EvidenceMode,
+) map[string]map[ascache.PolicyType]weighted {
+	nodes := make(map[string]map[ascache.PolicyType]weighted)
+
+	for _, bucket := range window {
+		weight, ok := bucketWeight(bucket.Bucket, newest, decay)
+		if !ok {
+			continue
+		}
+
+		for node, arms := range bucket.Nodes {
+			pooled := nodes[node]
+			if pooled == nil {
+				pooled = make(map[ascache.PolicyType]weighted)
+				nodes[node] = pooled
+			}
+			addWeighted(pooled, arms, weight, mode)
+		}
+	}
+
+	return nodes
+}
+
+// sumNodes adds every replica's evidence together, which is what aggregate
+// would have made of the same window summed.
+func sumNodes(nodes map[string]map[ascache.PolicyType]weighted) map[ascache.PolicyType]weighted {
+	pooled := make(map[ascache.PolicyType]weighted)
+	for _, arms := range nodes {
+		for policy, arm := range arms {
+			sum := pooled[policy]
+			sum.Hits += arm.Hits
+			sum.Misses += arm.Misses
+			pooled[policy] = sum
+		}
+	}
+
+	return pooled
+}
+
+// contributions describes each replica's part in pooled, the sum of nodes,
+// largest share first.
+//
+// An outlier is judged against the rest of the fleet, not against pooled: a
+// replica carrying most of the traffic is most of the pooled rate, and
+// measured against itself it could never stand out.
+func contributions(
+	nodes map[string]map[ascache.PolicyType]weighted,
+	pooled map[ascache.PolicyType]weighted,
+) []Contributor {
+	var total float64
+	for _, arm := range pooled {
+		total += arm.total()
+	}
+
+	contributors := make([]Contributor, 0, len(nodes))
+	for node, arms := range nodes {
+		contributor := Contributor{NodeID: node, Arms: make([]ArmEvidence, 0, len(arms))}
+
+		var own float64
+		for policy, arm := range arms {
+			own += arm.total()
+			contributor.Arms = append(contributor.Arms, ArmEvidence{
+				Policy:  policy.String(),
+				Hits:    arm.Hits,
+				Misses:  arm.Misses,
+				HitRate: arm.hitRate(),
+			})
+
+			fleet := pooled[policy]
+			rest := weighted{Hits: fleet.Hits - arm.Hits, Misses: fleet.Misses - arm.Misses}
+			if diverges(arm, rest) {
+				contributor.Outliers = append(contributor.Outliers, policy.String())
+			}
+		}
+		if total > 0 {
+			contributor.Share = own / total
+		}
+
+		sortEvidence(contributor.Arms)
+		slices.Sort(contributor.Outliers)
+		contributors = append(contributors, contributor)
+	}
+
+	slices.SortFunc(contributors, func(a, b Contributor) int {
+		if c := cmp.Compare(b.Share, a.Share); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.NodeID, b.NodeID)
+	})
+
+	return contributors
+}
+
+// diverges reports whether one replica's evidence for an arm is an outlier
+// against the rest of the fleet's, by a two-proportion z-test on the pooled
+// rate. With nobody else measuring the arm there is nothing to diverge from.
+func diverges(own, rest weighted) bool {
+	if own.total() == 0 || rest.total() <= 0 {
+		return false
+	}
+
+	gap := math.Abs(own.hitRate() - rest.hitRate())
+	if gap <= outlierGap {
+		return false
+	}
+
+	rate := (own.Hits + rest.Hits) / (own.total() + rest.total())
+	stderr := math.Sqrt(rate * (1 - rate) * (1/own.total() + 1/rest.total()))
+
+	return gap > outlierZ*stderr
+}
@@ -0,0 +1,64 @@
+package bandit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+func TestContributions_SharesSumToOneLargestFirst(t *testing.T) {
+	nodes := map[string]map[ascache.PolicyType]weighted{
+		"a": {ascache.LRU: {Hits: 30, Misses: 30}},
+		"b": {ascache.LRU: {Hits: 90, Misses: 90}, ascache.TinyLFU: {Hits: 30, Misses: 30}},
+	}
+
+	contributors := contributions(nodes, sumNodes(nodes))
+
+	require.Len(t, contributors, 2)
+	assert.Equal(t, "b", contributors[0].NodeID)
+	assert.InDelta(t, 0.8, contributors[0].Share, 1e-9)
+	assert.InDelta(t, 0.2, contributors[1].Share, 1e-9)
+	assert.Empty(t, contributors[0].Outliers)
+	assert.Empty(t, contributors[1].Outliers)
+}
+
+func TestContributions_JudgesAReplicaAgainstTheRest(t *testing.T) {
+	// b carries most of the traffic, so the pooled rate is mostly its own. It
+	// is still the one out of line with a and c, and they with it only as a
+	// pair, which one replica alone cannot outweigh.
+	nodes := map[string]map[ascache.PolicyType]weighted{
+		"a": {ascache.LRU: {Hits: 500, Misses: 500}},
+		"b": {ascache.LRU: {Hits: 8000, Misses: 2000}},
+		"c": {ascache.LRU: {Hits: 500, Misses: 500}},
+	}
+
+	contributors := contributions(nodes, sumNodes(nodes))
+
+	outliers := make(map[string][]string)
+	for _, contributor := range contributors {
+		outliers[contributor.NodeID] = contributor.Outliers
+	}
+	assert.Equal(t, []string{ascache.LRU.String()}, outliers["b"])
+}
+
+func TestDiverges_NeedsBothAGapAndSignificance(t *testing.T) {
+	tests := []struct {
+		name      string
+		own, rest weighted
+		want      bool
+	}{
+		{"large gap, plenty of evidence", weighted{Hits: 900, Misses: 100}, weighted{Hits: 5000, Misses: 5000}, true},
+		{"large gap, a handful of requests", weighted{Hits: 9, Misses: 1}, weighted{Hits: 5000, Misses: 5000}, false},
+		{"significant but small gap", weighted{Hits: 5.2e6, Misses: 4.8e6}, weighted{Hits: 5e7, Misses: 5e7}, false},
+		{"nobody else measures the arm", weighted{Hits: 900, Misses: 100}, weighted{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, diverges(tt.own, tt.rest))
+		})
+	}
+}
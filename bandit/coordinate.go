@@ -8,6 +8,7 @@ package bandit
 
 import (
 	"context"
+	"log/slog"
 	"time"
 
 	ascache "github.com/sshaplygin/as-cache"
@@ -20,13 +21,22 @@ func (d *Distributed) coordinate() {
 	timer := time.NewTimer(d.nextInterval())
 	defer timer.Stop()
 
+	var pushed <-chan PushedDecision
 	for {
 		select {
 		case <-d.ctx.Done():
 			return
 		case <-timer.C:
 			d.sync()
+			pushed = d.subscribe()
 			timer.Reset(d.nextInterval())
+		case decision, ok := <-pushed:
+			if !ok {
+				d.dropSubscription()
+				pushed = nil
+				continue
+			}
+			d.applyPushed(decision)
 		}
 	}
 }
@@ -55,6 +65,7 @@ func (d *Distributed) sync() {
 		// there is nothing to publish or to key by.
 		return
 	}
+	d.logRegime(req.Namespace)
 
 	ctx, cancel := context.WithTimeout(d.ctx, d.cfg.SyncTimeout)
 	defer cancel()
@@ -82,6 +93,8 @@ func (d *Distributed) sync() {
 	}
 
 	d.applyResult(result.Bucket, decision, decided)
+	d.announceRegime(ctx, req.Namespace)
+	d.readPin(ctx)
 }
 
 // drain takes everything buffered since the last sync and builds the request.
@@ -131,15 +144,40 @@ func (d *Distributed) readPooled(
 	newest := bucket - 1
 	first := newest - Bucket(d.cfg.Window) + 1
 
-	window, err := d.cfg.Store.Window(ctx, namespace, first, newest)
-	if err != nil {
-		d.recordFailure(err)
-		return nil, false
+	var (
+		pooled       map[ascache.PolicyType]weighted
+		contributors []Contributor
+	)
+	perNode := d.cfg.Contributors || d.cfg.Aggregation != AggregationSum
+	if store, ok := d.cfg.Store.(ContributorStore); ok && perNode {
+		// One read serves both: the per-replica window sums to the fleet's,
+		// so there is no second round trip for the summed one.
+		window, err := store.NodeWindow(ctx, namespace, first, newest)
+		if err != nil {
+			d.recordFailure(err)
+			return nil, false
+		}
+
+		nodes := aggregateNodes(window, newest, d.cfg.Decay, d.cfg.Evidence)
+		pooled = combine(nodes, d.cfg.Aggregation, d.cfg.Trim, d.cfg.MaxReplicaWeight)
+		if d.cfg.Contributors {
+			// Shares and outliers describe what the replicas measured, so
+			// they are taken against the plain sum whatever the aggregation
+			// then made of it.
+			contributors = contributions(nodes, sumNodes(nodes))
+		}
+	} else {
+		window, err := d.cfg.Store.Window(ctx, namespace, first, newest)
+		if err != nil {
+			d.recordFailure(err)
+			return nil, false
+		}
+
+		pooled = aggregate(window, newest, d.cfg.Decay, d.cfg.Evidence)
 	}
 
-	pooled := aggregate(window, newest, d.cfg.Decay, d.cfg.Evidence)
 	capEvidence(pooled, d.cfg.MaxEvidence)
-	d.recordFleet(pooled)
+	d.recordFleet(pooled, contributors)
 
 	return pooled, true
 }
@@ -154,17 +192,35 @@ func (d *Distributed) leadBucket(
 ) (ascache.PolicyType, bool) {
 	d.mu.Lock()
 	d.state.leaderships++
+	staged := d.rollout
 	d.mu.Unlock()
 
-	pooled, ok := d.readPooled(ctx, namespace, bucket)
-	if !ok {
-		return ascache.Undefined, false
-	}
+	var choice ascache.PolicyType
+	switch {
+	case staged != nil && bucket < staged.until:
+		// The canary is still running. Drawing now would judge it on
+		// evidence that is mostly the control's, so the leader holds the
+		// candidate until the verdict is due.
+		choice = staged.candidate
+
+	case staged != nil:
+		verdict, ok := d.judgeCanary(ctx, namespace, *staged)
+		if !ok {
+			return ascache.Undefined, false
+		}
+		choice = verdict
 
-	choice := draw(d.rng, pooled)
-	if choice == ascache.Undefined {
-		// Nothing in the window: the fleet has published no evidence yet.
-		return ascache.Undefined, false
+	default:
+		pooled, ok := d.readPooled(ctx, namespace, bucket)
+		if !ok {
+			return ascache.Undefined, false
+		}
+
+		choice = draw(d.rng, pooled)
+		if choice == ascache.Undefined {
+			// Nothing in the window: the fleet has published no evidence yet.
+			return ascache.Undefined, false
+		}
 	}
 
 	// The decision in force is what the store reports, not what was drawn: if
@@ -198,35 +254,79 @@ func (d *Distributed) drawShared(
 }
 
 // applyResult folds a successful sync into the selection and the observable
-// state.
+// state, and logs what changed once mu is released.
 func (d *Distributed) applyResult(bucket Bucket, decision ascache.PolicyType, decided bool) {
+	recovered, refused := d.foldResult(bucket, decision, decided)
+
+	if recovered {
+		d.log.Warn("bandit: store reachable again, following the fleet")
+	}
+	if refused {
+		d.logRefused(bucket, decision)
+	}
+}
+
+// foldResult is applyResult under mu. It reports whether the sync ended a
+// fallback, and whether it refused the fleet's decision.
+func (d *Distributed) foldResult(bucket Bucket, decision ascache.PolicyType, decided bool) (recovered, refused bool) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	recovered = d.state.fallback
 	d.state.lastSync = d.cfg.Now()
 	d.state.lastBucket = bucket
 	d.state.lastErr = nil
 	d.state.syncs++
 	d.state.fallback = false
 
-	switch {
-	case !decided:
-		// No decision this round - the leader has not published yet, or the
-		// window was empty. Keeping the previous selection is the mode's one
-		// coordination epoch of built-in staleness, and it is bounded: the
-		// decision will be there on the next sync.
+	// No decision this round means the leader has not published yet, or the
+	// window was empty. Keeping the previous selection is the mode's one
+	// coordination epoch of built-in staleness - unless the store pushes
+	// decisions - and it is bounded: the decision will be there on the next
+	// sync.
+	if decided {
+		_, refused = d.applyDecisionLocked(bucket, decision)
+	}
 
-	case !d.knownArmLocked(decision):
+	return recovered, refused
+}
+
+// applyDecisionLocked applies the fleet's decision for a bucket, and reports
+// whether it did, or refused it. A decision already applied - pushed ahead of
+// the sync that would have read it, or read back by the leader that
+// published it - is neither applied nor counted twice.
+func (d *Distributed) applyDecisionLocked(bucket Bucket, decision ascache.PolicyType) (applied, refused bool) {
+	if d.state.applied == (appliedDecision{bucket: bucket, policy: decision}) {
+		return false, false
+	}
+
+	if !d.knownArmLocked(decision) {
 		// Something is publishing decisions for a policy this cache does not
 		// have. The fingerprint in the namespace is supposed to make that
 		// impossible, so this is a real misconfiguration rather than a race:
 		// refuse it, count it, and keep serving.
 		d.state.rejected++
+		return false, true
+	}
 
-	default:
-		d.state.decisions++
-		d.selection.Store(uint64(decision))
+	d.state.applied = appliedDecision{bucket: bucket, policy: decision}
+	d.state.decisions++
+	if d.cfg.Canary.enabled() {
+		decision = d.stageLocked(bucket, decision)
 	}
+	d.selection.Store(uint64(decision))
+
+	return true, false
+}
+
+// logRefused logs a fleet decision applyDecisionLocked refused. It is a
+// misconfiguration rather than weather, so it goes out at Warn on every
+// occurrence.
+func (d *Distributed) logRefused(bucket Bucket, decision ascache.PolicyType) {
+	d.log.Warn("bandit: refused a fleet decision for a policy this cache does not hold",
+		slog.String("policy", decision.String()),
+		slog.Int64("bucket", int64(bucket)),
+	)
 }
 
 func (d *Distributed) knownArmLocked(policy ascache.PolicyType) bool {
@@ -235,18 +335,39 @@ func (d *Distributed) knownArmLocked(policy ascache.PolicyType) bool {
 	return ok
 }
 
-// recordFleet stores the pooled posterior for Snapshot to report.
-func (d *Distributed) recordFleet(pooled map[ascache.PolicyType]weighted) {
+// recordFleet stores the pooled posterior, and who contributed to it, for
+// Snapshot to report.
+func (d *Distributed) recordFleet(pooled map[ascache.PolicyType]weighted, contributors []Contributor) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	d.state.fleet = pooled
+	d.state.contributors = contributors
 }
 
 // recordFailure notes a failed round trip and, once the store has been
 // unreachable for longer than FallbackAfter, hands selection to the local
-// bandit.
+// bandit. It logs both once mu is released: the failure itself at Debug,
+// since an outage fails every round trip, and the fallback it starts at Warn.
 func (d *Distributed) recordFailure(err error) {
+	entered, choice := d.noteFailure(err)
+
+	if d.ctx.Err() != nil {
+		// Close cancelled the round trip; the store did nothing wrong.
+		return
+	}
+	d.log.Debug("bandit: store round trip failed", slog.Any("error", err))
+	if entered {
+		d.log.Warn("bandit: store unreachable, selecting locally",
+			slog.Duration("after", d.cfg.FallbackAfter),
+			slog.String("policy", choice.String()),
+		)
+	}
+}
+
+// noteFailure is recordFailure under mu. It reports whether this failure
+// started a fallback, and the policy the local bandit selected if so.
+func (d *Distributed) noteFailure(err error) (entered bool, choice ascache.PolicyType) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -258,14 +379,42 @@ func (d *Distributed) recordFailure(err error) {
 	stale := d.state.lastSync.IsZero() ||
 		d.cfg.Now().Sub(d.state.lastSync) > d.cfg.FallbackAfter
 	if !stale {
-		return
+		return false, ascache.Undefined
 	}
 
+	entered = !d.state.fallback
 	d.state.fallback = true
+	if d.rollout != nil {
+		// Local selection is about to overrule the fleet, so this replica is
+		// no longer part of the rollout whatever its verdict.
+		d.endRolloutLocked(canaryAbandoned)
+	}
 
-	choice := d.local.SelectPolicy()
+	choice = d.local.SelectPolicy()
 	if choice == ascache.Undefined || !d.knownArmLocked(choice) {
-		return
+		return entered, ascache.Undefined
 	}
 	d.selection.Store(uint64(choice))
+
+	return entered, choice
+}
+
+// logRegime logs the regime this replica coordinates under, the first time it
+// syncs under namespace. A fingerprint that differs between replicas meant to
+// pool is the one misconfiguration nothing else reports, and the regime beside
+// it says what differs.
+func (d *Distributed) logRegime(namespace string) {
+	if namespace == d.regime {
+		return
+	}
+	d.regime = namespace
+
+	d.mu.Lock()
+	regime := d.shape.String()
+	d.mu.Unlock()
+
+	d.log.Info("bandit: coordinating under regime",
+		slog.String("regime", regime),
+		slog.String("scoped_namespace", namespace),
+	)
 }
@@ -2,6 +2,7 @@ package bandit
 
 import (
 	"context"
+	"log/slog"
 	"math/rand/v2"
 	"sync"
 	"sync/atomic"
@@ -11,8 +12,9 @@ import (
 )
 
 var (
-	_ ascache.Bandit      = (*Distributed)(nil)
-	_ ascache.EpochBandit = (*Distributed)(nil)
+	_ ascache.Bandit        = (*Distributed)(nil)
+	_ ascache.EpochBandit   = (*Distributed)(nil)
+	_ ascache.PinningBandit = (*Distributed)(nil)
 )
 
 // Distributed pools every replica's measurements through a shared store, so a
@@ -57,12 +59,27 @@ type Distributed struct {
 	// SelectPolicy must never wait on the goroutine, which may be
 	// mid-round-trip.
 	selection atomic.Uint64
+	// pinned is the fleet-wide pin as last read from a PinStore, held the way
+	// selection is and for the same reason.
+	pinned atomic.Uint64
 
 	// rng is confined to the coordination goroutine: jitter and, under
 	// ModeSharedPosterior, this replica's own draw. It is deliberately not
 	// shared with local, which has its own.
 	rng *rand.Rand
 
+	// push is the subscription to pushed decisions, and noPush records that
+	// the store said it does not publish them. Both are confined to the
+	// coordination goroutine, as rng is.
+	push   *subscription
+	noPush bool
+
+	// log is Config.Logger with the namespace and node attached. regime is
+	// the fingerprinted namespace it last logged a regime for, and is
+	// confined to the coordination goroutine as rng is.
+	log    *slog.Logger
+	regime string
+
 	mu sync.Mutex
 	// pending accumulates what the cache has reported since the last sync.
 	pending map[ArmKey]ascache.PolicyStats
@@ -77,8 +94,11 @@ type Distributed struct {
 	// arms is the set of policies this cache actually has. A decision naming
 	// anything else is refused: it means something with a different build or
 	// configuration is publishing into this namespace.
-	arms  map[ascache.PolicyType]struct{}
-	state state
+	arms map[ascache.PolicyType]struct{}
+	// rollout is the staged decision this replica is following, when
+	// Config.Canary is set and the fleet is changing policy.
+	rollout *rollout
+	state   state
 
 	// ctx is cancelled by Close, and every round trip derives its context from
 	// it. Without that a Close arriving while a sync is in flight would wait
@@ -103,6 +123,29 @@ type state struct {
 	rejected     int64
 	fallback     bool
 	fleet        map[ascache.PolicyType]weighted
+	// announced is the namespace this replica last announced its regime
+	// under, and siblings what the store said about the other regimes there.
+	announced string
+	siblings  []RegimeCount
+	// contributors is the per-replica breakdown of fleet, when
+	// Config.Contributors asks for one.
+	contributors []Contributor
+	// canary is the latest rollout this replica has seen, and promotions and
+	// rollbacks count the verdicts it has applied.
+	canary     *CanaryStatus
+	promotions int64
+	rollbacks  int64
+	// applied is the last decision applied, so one arriving twice - pushed
+	// and then synced - counts once. subscribed reports a live push feed and
+	// pushed counts the decisions it delivered first.
+	applied    appliedDecision
+	subscribed bool
+	pushed     int64
+}
+
+type appliedDecision struct {
+	bucket Bucket
+	policy ascache.PolicyType
 }
 
 // NewDistributed starts a distributed bandit and its coordination goroutine.
@@ -135,10 +178,12 @@ func newDistributed(cfg Config) (*Distributed, error) {
 		arms:    make(map[ascache.PolicyType]struct{}),
 		//nolint:gosec // deliberate: a seeded, reproducible source, not a secret
 		rng:    rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x2545f4914f6cdd1d)),
+		log:    cfg.Logger.With(slog.String("namespace", cfg.Namespace), slog.String("node", cfg.NodeID)),
 		ctx:    ctx,
 		cancel: cancel,
 	}
 	d.selection.Store(uint64(ascache.Undefined))
+	d.pinned.Store(uint64(ascache.Undefined))
 
 	return d, nil
 }
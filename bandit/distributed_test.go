@@ -550,6 +550,131 @@ func TestDistributed_ReplicasWithDifferentShapesDoNotPool(t *testing.T) {
 		replicas[1].bandit.Snapshot().Namespace)
 }
 
+// TestDistributed_ReportsSiblingRegimes checks that a fleet split by a
+// mismatched deployment says so, from both sides of the split.
+func TestDistributed_ReportsSiblingRegimes(t *testing.T) {
+	store, clock := newFleetStore(t)
+	replicas := fleet(t, 3, store, clock, nil)
+	rates := map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6}
+
+	resized := replicas[2]
+	round := func() {
+		replicas[0].report(t, 100, rates)
+		replicas[1].report(t, 100, rates)
+		resized.bandit.RecordEpoch(ascache.EpochReport{
+			Active: ascache.LRU,
+			Stats: []ascache.ShadowStats{
+				{Policy: ascache.LRU, Hits: 50, Misses: 50},
+				{Policy: ascache.TinyLFU, Hits: 60, Misses: 40},
+			},
+			Capacity:   2000,
+			SampleRate: 1,
+		})
+		for _, r := range replicas {
+			r.bandit.sync()
+		}
+		clock.advance(testEpoch)
+	}
+
+	round()
+	assert.Empty(t, replicas[0].bandit.Snapshot().SiblingRegimes,
+		"the first replica to announce has nobody to compare with yet")
+
+	snapshot := resized.bandit.Snapshot()
+	require.Equal(t, []RegimeCount{{Regime: replicas[0].bandit.Snapshot().Regime, Replicas: 2}},
+		snapshot.SiblingRegimes)
+	assert.Contains(t, snapshot.String(), "2 replicas in sibling regimes")
+
+	// The others hear about it at their next announcement.
+	for range announceEvery {
+		round()
+	}
+	assert.Equal(t, []RegimeCount{{Regime: snapshot.Regime, Replicas: 1}},
+		replicas[0].bandit.Snapshot().SiblingRegimes)
+}
+
+func TestDistributed_ReportsContributorsAndOutliers(t *testing.T) {
+	store, clock := newFleetStore(t)
+	replicas := fleet(t, 10, store, clock, func(cfg *Config) { cfg.Contributors = true })
+	rates := map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6}
+
+	// One replica of ten measures TinyLFU far better than everyone else.
+	odd := replicas[9]
+	for range 4 {
+		for _, r := range replicas[:9] {
+			r.report(t, 1000, rates)
+		}
+		odd.report(t, 1000, map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.9})
+		for _, r := range replicas {
+			r.bandit.sync()
+		}
+		clock.advance(testEpoch)
+	}
+
+	var snapshot Snapshot
+	for _, r := range replicas {
+		if s := r.bandit.Snapshot(); len(s.Contributors) > 0 {
+			snapshot = s
+		}
+	}
+	require.Len(t, snapshot.Contributors, 10, "the leader reports every replica it pooled")
+
+	for _, contributor := range snapshot.Contributors {
+		assert.InDelta(t, 0.1, contributor.Share, 1e-9, contributor.NodeID)
+		if contributor.NodeID == odd.bandit.cfg.NodeID {
+			assert.Equal(t, []string{ascache.TinyLFU.String()}, contributor.Outliers)
+		} else {
+			assert.Empty(t, contributor.Outliers, contributor.NodeID)
+		}
+	}
+	assert.Contains(t, snapshot.String(), "OUTLIER on "+ascache.TinyLFU.String())
+}
+
+func TestDistributed_ContributorsAreOffByDefault(t *testing.T) {
+	store, clock := newFleetStore(t)
+	replicas := fleet(t, 2, store, clock, nil)
+
+	for range 3 {
+		for _, r := range replicas {
+			r.report(t, 100, map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6})
+			r.bandit.sync()
+		}
+		clock.advance(testEpoch)
+	}
+
+	for _, r := range replicas {
+		assert.Empty(t, r.bandit.Snapshot().Contributors)
+	}
+}
+
+func TestDistributed_EqualAggregationKeepsOneReplicaFromDecidingForAll(t *testing.T) {
+	run := func(how Aggregation) ascache.PolicyType {
+		store, clock := newFleetStore(t)
+		replicas := fleet(t, 5, store, clock, func(cfg *Config) { cfg.Aggregation = how })
+
+		// The first replica takes a hundred times anyone else's traffic, and
+		// its workload favours the arm the rest of the fleet does not.
+		for range 8 {
+			replicas[0].report(t, 20_000, map[ascache.PolicyType]float64{ascache.LRU: 0.8, ascache.TinyLFU: 0.5})
+			for _, r := range replicas[1:] {
+				r.report(t, 200, map[ascache.PolicyType]float64{ascache.LRU: 0.4, ascache.TinyLFU: 0.7})
+			}
+			for _, r := range replicas {
+				r.bandit.sync()
+			}
+			clock.advance(testEpoch)
+		}
+		for _, r := range replicas {
+			r.bandit.sync()
+		}
+
+		return replicas[1].bandit.SelectPolicy()
+	}
+
+	assert.Equal(t, ascache.LRU, run(AggregationSum), "summed, the busiest replica decides")
+	assert.Equal(t, ascache.TinyLFU, run(AggregationEqual), "weighted equally, the fleet decides")
+}
+
 // ---------------------------------------------------------------------------
 // Lifecycle
 // ---------------------------------------------------------------------------
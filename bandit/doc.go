@@ -30,8 +30,14 @@
 //	defer b.Close()
 //
 // The store is an interface, not a client: [MemStore] runs a whole fleet in
-// one process for tests, and github.com/sshaplygin/as-cache/bandit/redis backs
-// it with Valkey or Redis.
+// one process for tests, github.com/sshaplygin/as-cache/bandit/redis backs
+// it with Valkey or Redis, github.com/sshaplygin/as-cache/bandit/sqlstore with
+// Postgres or SQLite, [github.com/sshaplygin/as-cache/bandit/filestore]
+// with a directory the fleet shares, and
+// github.com/sshaplygin/as-cache/bandit/remote with a coordinator service the
+// replicas reach by URL. github.com/sshaplygin/as-cache/bandit/gossip needs
+// none of them: replicas exchange counts with each other, and draw under
+// [ModeSharedPosterior].
 //
 // # What crosses the wire
 //
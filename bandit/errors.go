@@ -31,6 +31,37 @@ var ErrInvalidJitter = errors.New("bandit: jitter must be in [0,0.5)")
 // is required rather than defaulted.
 var ErrEmptyNamespace = errors.New("bandit: namespace must not be empty")
 
+// ErrContributorsUnsupported is returned by NewDistributed when
+// Config.Contributors is set over a Store that does not implement
+// ContributorStore. Quietly reporting no contributors would look exactly like
+// a fleet nobody is contributing to.
+var ErrContributorsUnsupported = errors.New("bandit: Contributors needs a store that implements ContributorStore")
+
+// ErrAggregationUnsupported is returned by NewDistributed when
+// Config.Aggregation is anything but AggregationSum over a Store that does
+// not implement ContributorStore. A summed window has already forgotten which
+// replica supplied what, so there is nothing left to reweight.
+var ErrAggregationUnsupported = errors.New("bandit: Aggregation needs a store that implements ContributorStore")
+
+// ErrInvalidTrim is returned by NewDistributed when Config.Trim falls outside
+// [0,0.5). Trimming half the replicas from each end would leave none.
+var ErrInvalidTrim = errors.New("bandit: trim must be in [0,0.5)")
+
+// ErrInvalidReplicaWeight is returned by NewDistributed when
+// Config.MaxReplicaWeight is below 1, which would cap the median replica
+// itself.
+var ErrInvalidReplicaWeight = errors.New("bandit: max replica weight must be at least 1")
+
+// ErrInvalidCanary is returned by NewDistributed when Config.Canary.Fraction
+// falls outside [0,1) or its Buckets or MaxRegression is negative. A canary of
+// the whole fleet is no canary at all.
+var ErrInvalidCanary = errors.New("bandit: canary fraction must be in [0,1), buckets and max regression not negative")
+
+// ErrCanaryUnderSharedPosterior is returned by NewDistributed when
+// Config.Canary is set under ModeSharedPosterior. Every replica there draws
+// its own selection, so there is no fleet decision to stage.
+var ErrCanaryUnderSharedPosterior = errors.New("bandit: Canary can only be used with ModeLeader")
+
 // ErrShadowOnlyUnderLeader is returned by NewDistributed for the combination
 // of ModeLeader and EvidenceShadowOnly.
 //
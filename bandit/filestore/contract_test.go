@@ -0,0 +1,26 @@
+//go:build unix
+
+package filestore_test
+
+import (
+	"testing"
+
+	"github.com/sshaplygin/as-cache/bandit"
+	"github.com/sshaplygin/as-cache/bandit/filestore"
+	"github.com/sshaplygin/as-cache/bandit/storetest"
+)
+
+func TestStore_Contract(t *testing.T) {
+	// One directory for the whole suite, as a fleet shares one: the checks
+	// keep apart by namespace, not by directory.
+	dir := t.TempDir()
+
+	storetest.Run(t, func() bandit.Store {
+		store, err := filestore.New(filestore.Options{Dir: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return store
+	})
+}
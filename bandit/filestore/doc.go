@@ -0,0 +1,53 @@
+// Package filestore backs a distributed bandit with a directory every replica
+// can see - a shared volume, an NFS export - for fleets that have a file
+// system in common and no Valkey or Redis to spare.
+//
+//	store, err := filestore.New(filestore.Options{Dir: "/mnt/shared/as-cache"})
+//	if err != nil {
+//	    return err
+//	}
+//	defer store.Close()
+//
+//	b, err := bandit.NewDistributed(bandit.Config{
+//	    Store:             store,
+//	    Namespace:         "sessions",
+//	    CoordinationEpoch: 5 * time.Second,
+//	})
+//
+// # Layout
+//
+// Each namespace is a directory of small text files: two append-only counts
+// files per bucket, to which every replica appends a line per arm - one
+// summed, one carrying the replica's node ID for bandit.ContributorStore - and
+// a leader file and a decision file per bucket, each written whole and
+// renamed into place. Every line and every file carries its own expiry, so a file
+// that outlives its TTL is ignored on read and deleted by whichever replica
+// next sweeps the namespace - about once per coordination epoch fleet-wide,
+// not once per replica.
+//
+// Beside the namespace directories sit a directory of regime announcements per
+// namespace, for bandit.RegimeStore, and a pin file per pinned namespace, for
+// bandit.PinStore. A pin has no expiry: it stands until it is lifted.
+//
+// Every other call holds an advisory lock on the namespace's lock file,
+// flock(2), for as long as it reads or writes. The lock is what makes the append, the
+// leadership claim and the write-once decision atomic across replicas, so the
+// directory must be on a file system that honours it across machines: a local
+// disk shared by processes on one host, or NFS on Linux, which maps flock onto
+// NFS byte-range locks. A file system that silently ignores locks will lose
+// leadership races and the occasional count.
+//
+// # Clock
+//
+// No replica's clock is consulted. Each call writes to the namespace's clock
+// file and reads back its modification time, which the file system - the NFS
+// server, for an export - stamps, so every replica derives the same bucket
+// from the same clock exactly as they would from a Valkey server's. The
+// coordination epoch should comfortably exceed the file system's timestamp
+// granularity, and it should be seconds rather than milliseconds anyway: every
+// call is several file operations under a lock shared by the whole fleet.
+//
+// # Platforms
+//
+// Unix only. On other platforms New returns ErrUnsupported.
+package filestore
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package filestore
+
+import "os"
+
+const locksSupported = false
+
+func tryLock(*os.File) (bool, error) { return false, ErrUnsupported }
+
+func unlock(*os.File) error { return ErrUnsupported }
@@ -0,0 +1,86 @@
+package filestore
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+// NodeWindow reads the per-node counts files for buckets first through last.
+//
+// Each bucket's counts are appended twice under the same lock, once to the
+// summed file Window reads and once, with the node on every line, to a file
+// of their own. Keeping the two apart costs an append per sync and leaves
+// the summed file exactly as a replica from before per-node counts reads it,
+// so a fleet can be upgraded one replica at a time.
+func (s *Store) NodeWindow(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.NodeWindowCounts, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if first > last {
+		return nil, nil
+	}
+
+	dir := s.namespaceDir(namespace)
+
+	var window []bandit.NodeWindowCounts
+	err := s.withLock(ctx, dir, false, func(now time.Time) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			bucket, ok := parseFileName(entry.Name(), nodesPrefix)
+			if !ok || bucket < first || bucket > last {
+				continue
+			}
+
+			nodes := make(map[string]map[bandit.ArmKey]ascache.PolicyStats)
+			expires, err := scanCounts(filepath.Join(dir, entry.Name()),
+				func(node string, key bandit.ArmKey, hits, misses int64) {
+					arms := nodes[node]
+					if arms == nil {
+						arms = make(map[bandit.ArmKey]ascache.PolicyStats)
+						nodes[node] = arms
+					}
+					addStats(arms, key, hits, misses)
+				})
+			if err != nil {
+				return err
+			}
+			if expired(now, expires) || len(nodes) == 0 {
+				continue
+			}
+
+			window = append(window, bandit.NodeWindowCounts{Bucket: bucket, Nodes: nodes})
+		}
+
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("filestore: node window: %w", err)
+	}
+
+	slices.SortFunc(window, func(a, b bandit.NodeWindowCounts) int {
+		return cmp.Compare(a.Bucket, b.Bucket)
+	})
+
+	return window, nil
+}
@@ -0,0 +1,78 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+var _ bandit.PinStore = (*Store)(nil)
+
+// pinPrefix names a namespace's pin file. The namespace is escaped as a path
+// segment after it.
+const pinPrefix = "pin-"
+
+// SetPin pins the namespace to policy, or lifts its pin when policy is
+// ascache.Undefined.
+//
+// A pin is one small file beside the namespace directories, written whole and
+// renamed into place, so it needs no lock: a reader sees the old pin or the
+// new one, and of two operators pinning at once the later rename wins. It
+// carries no expiry and no sweep deletes it; it stands until it is lifted.
+func (s *Store) SetPin(ctx context.Context, namespace string, policy ascache.PolicyType) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var err error
+	if policy == ascache.Undefined {
+		err = os.Remove(filepath.Join(s.dir, pinName(namespace)))
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	} else {
+		err = writeRecord(s.dir, pinName(namespace), time.UnixMilli(0), bandit.EncodePolicy(policy))
+	}
+	if err != nil {
+		return fmt.Errorf("filestore: set pin: %w", err)
+	}
+
+	return nil
+}
+
+// Pin returns the namespace's pin, or ascache.Undefined. A pin naming a policy
+// this process does not know is reported as no pin, which is what it is for
+// a replica without that arm.
+func (s *Store) Pin(ctx context.Context, namespace string) (ascache.PolicyType, error) {
+	if err := ctx.Err(); err != nil {
+		return ascache.Undefined, err
+	}
+
+	_, text, ok, err := readRecord(filepath.Join(s.dir, pinName(namespace)))
+	if err != nil {
+		return ascache.Undefined, fmt.Errorf("filestore: pin: %w", err)
+	}
+	if !ok {
+		return ascache.Undefined, nil
+	}
+
+	policy, ok := bandit.DecodePolicy(text)
+	if !ok {
+		return ascache.Undefined, nil
+	}
+
+	return policy, nil
+}
+
+// pinName returns the name of the namespace's pin file.
+func pinName(namespace string) string {
+	return pinPrefix + url.PathEscape(namespace)
+}
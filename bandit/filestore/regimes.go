@@ -0,0 +1,80 @@
+package filestore
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+var _ bandit.RegimeStore = (*Store)(nil)
+
+// nodePrefix names a node's regime announcement. The node ID is escaped as a
+// path segment after it.
+const nodePrefix = "node-"
+
+// Regimes writes the node's announcement and lists the live ones.
+//
+// Announcements for a namespace live in a directory of their own, one small
+// file per node written whole and renamed into place, beside the namespace
+// directories rather than in any of them: the namespace they are announced
+// under is the configured one, which no replica syncs under. Expired files
+// are deleted as they are read, so the directory holds at most one file per
+// node that announced within the TTL.
+func (s *Store) Regimes(ctx context.Context, req bandit.RegimeRequest) ([]bandit.RegimeCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var regimes []bandit.RegimeCount
+
+	dir := filepath.Join(s.dir, "regimes-"+url.PathEscape(req.Namespace))
+	err := s.withLock(ctx, dir, true, func(now time.Time) error {
+		err := writeRecord(dir, nodePrefix+url.PathEscape(req.NodeID), now.Add(req.TTL), req.Regime)
+		if err != nil {
+			return err
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		replicas := make(map[string]int)
+		for _, entry := range entries {
+			if !strings.HasPrefix(entry.Name(), nodePrefix) {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			expires, regime, ok, err := readRecord(path)
+			if err != nil {
+				return err
+			}
+			if !ok || expired(now, expires) {
+				_ = os.Remove(path)
+				continue
+			}
+			replicas[regime]++
+		}
+
+		for regime, n := range replicas {
+			regimes = append(regimes, bandit.RegimeCount{Regime: regime, Replicas: n})
+		}
+		slices.SortFunc(regimes, func(a, b bandit.RegimeCount) int { return cmp.Compare(a.Regime, b.Regime) })
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filestore: regimes: %w", err)
+	}
+
+	return regimes, nil
+}
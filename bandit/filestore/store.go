@@ -0,0 +1,675 @@
+package filestore
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+var _ bandit.ContributorStore = (*Store)(nil)
+
+// ErrEmptyDir is returned by New when Options.Dir is empty.
+var ErrEmptyDir = errors.New("filestore: directory must not be empty")
+
+// ErrUnsupported is returned by New on a platform without flock(2).
+var ErrUnsupported = errors.New("filestore: advisory file locks are not supported on this platform")
+
+// Names inside a namespace's directory. Buckets are appended to the prefixes
+// in decimal.
+const (
+	lockName       = "lock"
+	clockName      = "clock"
+	sweptName      = "swept"
+	countsPrefix   = "counts-"
+	nodesPrefix    = "nodes-"
+	leaderPrefix   = "leader-"
+	decisionPrefix = "decision-"
+	tempPrefix     = ".tmp-"
+)
+
+// nodeField begins the node ID on a line of a per-node counts file.
+const nodeField = "node="
+
+// The lock is polled rather than waited on, so a call gives up when its
+// context does: a blocking flock cannot be interrupted.
+const (
+	minLockBackoff = time.Millisecond
+	maxLockBackoff = 50 * time.Millisecond
+)
+
+// Options configures a Store.
+type Options struct {
+	// Dir is the directory the fleet shares. Required, and created if it does
+	// not exist. Every replica in a fleet must be given the same one.
+	Dir string
+}
+
+// Store implements bandit.Store over a shared directory.
+type Store struct {
+	dir string
+
+	mu sync.Mutex
+	// now, when set, replaces the file system's clock. See SetClock.
+	now func() time.Time
+}
+
+// New returns a Store over opts.Dir. It holds no open files between calls, so
+// there is nothing for Close to release.
+func New(opts Options) (*Store, error) {
+	if !locksSupported {
+		return nil, ErrUnsupported
+	}
+	if opts.Dir == "" {
+		return nil, ErrEmptyDir
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("filestore: %w", err)
+	}
+
+	return &Store{dir: opts.Dir}, nil
+}
+
+// SetClock replaces the file system's clock with now, or restores it when now
+// is nil. It exists for tests, which need to move bucket boundaries without
+// waiting for them; a fleet sharing a directory must use the file system's
+// clock, or its replicas are back to trusting their own.
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+// Sync appends one replica's counts to the current bucket's file, claims the
+// bucket if asked and if it is unclaimed, and reports any decision published
+// for it.
+func (s *Store) Sync(ctx context.Context, req bandit.SyncRequest) (bandit.SyncResult, error) {
+	if err := ctx.Err(); err != nil {
+		return bandit.SyncResult{}, err
+	}
+
+	var result bandit.SyncResult
+
+	dir := s.namespaceDir(req.Namespace)
+	err := s.withLock(ctx, dir, true, func(now time.Time) error {
+		bucket := bucketAt(now, req.EpochMillis)
+		result.Bucket = bucket
+
+		expires := now.Add(req.CounterTTL)
+		err := appendCounts(filepath.Join(dir, fileName(countsPrefix, bucket)), expires, "", req.Counts)
+		if err != nil {
+			return err
+		}
+		err = appendCounts(filepath.Join(dir, fileName(nodesPrefix, bucket)), expires, req.NodeID, req.Counts)
+		if err != nil {
+			return err
+		}
+
+		if req.Lead {
+			result.Leader, err = claim(dir, fileName(leaderPrefix, bucket), now, req.NodeID, req.LeaderTTL)
+			if err != nil {
+				return err
+			}
+		}
+
+		result.Decision, result.HasDecision, err = readDecision(dir, bucket, now)
+		if err != nil {
+			return err
+		}
+
+		return sweep(dir, now, req.EpochMillis)
+	})
+	if err != nil {
+		return bandit.SyncResult{}, fmt.Errorf("filestore: sync: %w", err)
+	}
+
+	return result, nil
+}
+
+// Window reads the counts files for buckets first through last. Buckets that
+// were never written, or whose every line has expired, are omitted rather
+// than reported as zero.
+func (s *Store) Window(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.WindowCounts, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if first > last {
+		return nil, nil
+	}
+
+	dir := s.namespaceDir(namespace)
+
+	var window []bandit.WindowCounts
+	err := s.withLock(ctx, dir, false, func(now time.Time) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		// The directory is listed rather than the span walked: the span is
+		// caller-supplied, and a wide one would cost a stat per bucket that
+		// was never written.
+		for _, entry := range entries {
+			bucket, ok := parseFileName(entry.Name(), countsPrefix)
+			if !ok || bucket < first || bucket > last {
+				continue
+			}
+
+			arms, expires, err := readCounts(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return err
+			}
+			if expired(now, expires) || len(arms) == 0 {
+				continue
+			}
+
+			window = append(window, bandit.WindowCounts{Bucket: bucket, Arms: arms})
+		}
+
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		// Nobody has synced into this namespace yet.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("filestore: window: %w", err)
+	}
+
+	slices.SortFunc(window, func(a, b bandit.WindowCounts) int {
+		return cmp.Compare(a.Bucket, b.Bucket)
+	})
+
+	return window, nil
+}
+
+// Decide writes the decision file for a bucket unless a live one is already
+// there, and returns whichever decision is in force.
+func (s *Store) Decide(
+	ctx context.Context,
+	namespace string,
+	bucket bandit.Bucket,
+	policy ascache.PolicyType,
+	ttl time.Duration,
+) (ascache.PolicyType, error) {
+	if err := ctx.Err(); err != nil {
+		return ascache.Undefined, err
+	}
+
+	decided := policy
+
+	dir := s.namespaceDir(namespace)
+	err := s.withLock(ctx, dir, true, func(now time.Time) error {
+		existing, ok, err := readDecision(dir, bucket, now)
+		if err != nil {
+			return err
+		}
+		if ok {
+			decided = existing
+
+			return nil
+		}
+
+		return writeRecord(dir, fileName(decisionPrefix, bucket), now.Add(ttl), bandit.EncodePolicy(policy))
+	})
+	if err != nil {
+		return ascache.Undefined, fmt.Errorf("filestore: decide: %w", err)
+	}
+
+	return decided, nil
+}
+
+// Close releases the store. It holds nothing between calls, and leaves the
+// directory in place for the rest of the fleet.
+func (s *Store) Close() error { return nil }
+
+// namespaceDir is the directory holding one namespace. The namespace is
+// escaped as a single path segment, so no namespace can reach outside Dir or
+// into another's directory.
+func (s *Store) namespaceDir(namespace string) string {
+	return filepath.Join(s.dir, "ns-"+url.PathEscape(namespace))
+}
+
+// processLocks serialises Stores in one process that share a namespace. flock
+// already excludes them on a local disk, but on NFS Linux implements it with
+// byte-range locks, which belong to the process and would let two Stores in it
+// through together.
+var (
+	processLocksMu sync.Mutex
+	processLocks   = make(map[string]chan struct{})
+)
+
+func processLock(path string) chan struct{} {
+	processLocksMu.Lock()
+	defer processLocksMu.Unlock()
+
+	lock, ok := processLocks[path]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		processLocks[path] = lock
+	}
+
+	return lock
+}
+
+// withLock runs fn holding the namespace's lock, passing it the time by the
+// store's clock. create makes the namespace directory if it is missing; a
+// read of a namespace nobody has written fails with fs.ErrNotExist instead of
+// leaving an empty directory behind.
+func (s *Store) withLock(ctx context.Context, dir string, create bool, fn func(now time.Time) error) error {
+	if create {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	} else if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, lockName)
+
+	local := processLock(path)
+	select {
+	case local <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-local }()
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	backoff := minLockBackoff
+	for {
+		locked, err := tryLock(file)
+		if err != nil {
+			return fmt.Errorf("lock %s: %w", path, err)
+		}
+		if locked {
+			break
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+
+			return ctx.Err()
+		}
+		backoff = min(2*backoff, maxLockBackoff)
+	}
+	defer func() { _ = unlock(file) }()
+
+	now, err := s.clock(dir)
+	if err != nil {
+		return err
+	}
+
+	return fn(now)
+}
+
+// clock reads the time from the file system: it rewrites the namespace's
+// clock file and returns the modification time the file system gave it. On
+// NFS that is the server's clock, the one thing every replica shares.
+func (s *Store) clock(dir string) (time.Time, error) {
+	s.mu.Lock()
+	now := s.now
+	s.mu.Unlock()
+
+	if now != nil {
+		return now(), nil
+	}
+
+	path := filepath.Join(dir, clockName)
+	if err := os.WriteFile(path, []byte{'\n'}, 0o644); err != nil {
+		return time.Time{}, fmt.Errorf("clock: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("clock: %w", err)
+	}
+
+	return info.ModTime(), nil
+}
+
+// bucketAt divides the store's clock into coordination epochs, exactly as
+// every other store does, so a fleet can move between stores without its
+// buckets shifting.
+func bucketAt(now time.Time, epochMillis int64) bandit.Bucket {
+	if epochMillis <= 0 {
+		epochMillis = 1
+	}
+
+	return bandit.Bucket(now.UnixMilli() / epochMillis)
+}
+
+// expired reports whether an expiry, in Unix milliseconds, has passed.
+func expired(now time.Time, expiresMillis int64) bool {
+	return now.UnixMilli() > expiresMillis
+}
+
+func fileName(prefix string, bucket bandit.Bucket) string {
+	return prefix + strconv.FormatInt(int64(bucket), 10)
+}
+
+func parseFileName(name, prefix string) (bandit.Bucket, bool) {
+	digits, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return 0, false
+	}
+
+	bucket, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return bandit.Bucket(bucket), true
+}
+
+// appendCounts appends one line per arm to a bucket's counts file:
+//
+//	<expires> <policy> <role> <hits> <misses>
+//
+// or, given a node, to its per-node counts file:
+//
+//	<expires> node=<node> <policy> <role> <hits> <misses>
+//
+// The node is escaped as a path segment, so it holds no whitespace to split
+// on. Each line carries its own expiry, and the file lives as long as its
+// latest line, so a bucket outlives its last contribution rather than its
+// first. Zero deltas are dropped: they would read back as evidence of a zero
+// hit rate rather than as an absence of evidence.
+func appendCounts(path string, expires time.Time, node string, counts []bandit.ArmCounts) error {
+	var line []byte
+	for _, count := range counts {
+		if count.Hits == 0 && count.Misses == 0 {
+			continue
+		}
+
+		line = strconv.AppendInt(line, expires.UnixMilli(), 10)
+		line = append(line, ' ')
+		if node != "" {
+			line = append(line, nodeField...)
+			line = append(line, url.PathEscape(node)...)
+			line = append(line, ' ')
+		}
+		line = append(line, bandit.EncodePolicy(count.Policy)...)
+		line = append(line, ' ')
+		line = append(line, count.Role.String()...)
+		line = append(line, ' ')
+		line = strconv.AppendInt(line, count.Hits, 10)
+		line = append(line, ' ')
+		line = strconv.AppendInt(line, count.Misses, 10)
+		line = append(line, '\n')
+	}
+	if len(line) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(line); err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
+
+// readCounts sums a counts file per arm and returns the latest expiry in it.
+func readCounts(path string) (map[bandit.ArmKey]ascache.PolicyStats, int64, error) {
+	arms := make(map[bandit.ArmKey]ascache.PolicyStats)
+	latest, err := scanCounts(path, func(_ string, key bandit.ArmKey, hits, misses int64) {
+		addStats(arms, key, hits, misses)
+	})
+
+	return arms, latest, err
+}
+
+// scanCounts calls fn for every line of a counts or per-node counts file and
+// returns the latest expiry in it, passing an empty node for a line without
+// one. Lines it cannot read - torn by a writer that died mid-append, or
+// naming a policy this process has not registered - are skipped, as a Valkey
+// store skips fields it does not recognise; their expiry still counts, so a
+// file is never swept while a line in it is live.
+func scanCounts(path string, fn func(node string, key bandit.ArmKey, hits, misses int64)) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	var latest int64
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		var node string
+		if len(fields) == 6 {
+			escaped, ok := strings.CutPrefix(fields[1], nodeField)
+			if !ok {
+				continue
+			}
+			if node, err = url.PathUnescape(escaped); err != nil {
+				continue
+			}
+			fields = append(fields[:1], fields[2:]...)
+		}
+		if len(fields) != 5 {
+			continue
+		}
+
+		expires, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		hits, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		misses, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
+		}
+		latest = max(latest, expires)
+
+		policy, ok := bandit.DecodePolicy(fields[1])
+		if !ok {
+			continue
+		}
+		role, ok := parseRole(fields[2])
+		if !ok {
+			continue
+		}
+
+		fn(node, bandit.ArmKey{Policy: policy, Role: role}, hits, misses)
+	}
+
+	return latest, scanner.Err()
+}
+
+func addStats(arms map[bandit.ArmKey]ascache.PolicyStats, key bandit.ArmKey, hits, misses int64) {
+	stats := arms[key]
+	stats.Hits += hits
+	stats.Misses += misses
+	arms[key] = stats
+}
+
+func parseRole(text string) (bandit.Role, bool) {
+	switch text {
+	case bandit.RoleActive.String():
+		return bandit.RoleActive, true
+	case bandit.RoleShadow.String():
+		return bandit.RoleShadow, true
+	default:
+		return 0, false
+	}
+}
+
+// writeRecord writes a one-line file, "<expires> <value>", through a
+// temporary file and a rename, so a reader never sees half of one.
+func writeRecord(dir, name string, expires time.Time, value string) error {
+	temp, err := os.CreateTemp(dir, tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+
+	_, err = temp.WriteString(strconv.FormatInt(expires.UnixMilli(), 10) + " " + value + "\n")
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), filepath.Join(dir, name))
+	}
+	if err != nil {
+		_ = os.Remove(temp.Name())
+	}
+
+	return err
+}
+
+// readRecord reads a file written by writeRecord. A file that is missing or
+// unreadable is reported as absent: either way there is nothing in force.
+func readRecord(path string) (expires int64, value string, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, "", false, nil
+		}
+
+		return 0, "", false, err
+	}
+
+	head, value, found := strings.Cut(strings.TrimSuffix(string(data), "\n"), " ")
+	if !found {
+		return 0, "", false, nil
+	}
+
+	expires, err = strconv.ParseInt(head, 10, 64)
+	if err != nil {
+		return 0, "", false, nil
+	}
+
+	return expires, value, true, nil
+}
+
+// claim takes a bucket's leadership unless a live claim is already there.
+func claim(dir, name string, now time.Time, nodeID string, ttl time.Duration) (bool, error) {
+	expires, _, ok, err := readRecord(filepath.Join(dir, name))
+	if err != nil {
+		return false, err
+	}
+	if ok && !expired(now, expires) {
+		return false, nil
+	}
+
+	if err := writeRecord(dir, name, now.Add(ttl), nodeID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// readDecision returns the live decision for a bucket, if there is one.
+//
+// Unlike a stray counter, a decision this process cannot name is an error: the
+// fleet has agreed on a policy and this replica is unable to follow it.
+func readDecision(dir string, bucket bandit.Bucket, now time.Time) (ascache.PolicyType, bool, error) {
+	expires, text, ok, err := readRecord(filepath.Join(dir, fileName(decisionPrefix, bucket)))
+	if err != nil || !ok || expired(now, expires) {
+		return ascache.Undefined, false, err
+	}
+
+	policy, ok := bandit.DecodePolicy(text)
+	if !ok {
+		return ascache.Undefined, false, fmt.Errorf("decision %q is not a policy known to this process", text)
+	}
+
+	return policy, true, nil
+}
+
+// sweep deletes every expired file in a namespace, at most once per epoch
+// across the whole fleet: the time of the last sweep is itself kept in the
+// directory. Without it a fleet that stopped running would leave its files
+// behind, where a Valkey store would have let them expire.
+func sweep(dir string, now time.Time, epochMillis int64) error {
+	sweptPath := filepath.Join(dir, sweptName)
+	if data, err := os.ReadFile(sweptPath); err == nil {
+		last, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err == nil && now.UnixMilli()-last < max(epochMillis, 1) {
+			return nil
+		}
+	}
+
+	if err := os.WriteFile(sweptPath, []byte(strconv.FormatInt(now.UnixMilli(), 10)+"\n"), 0o644); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+
+		var stale bool
+		switch {
+		case strings.HasPrefix(name, tempPrefix):
+			// Every writer holds the lock, so a temporary file seen under it
+			// belongs to one that died before renaming it.
+			stale = true
+		case strings.HasPrefix(name, countsPrefix), strings.HasPrefix(name, nodesPrefix):
+			expires, err := scanCounts(path, func(string, bandit.ArmKey, int64, int64) {})
+			if err != nil {
+				return err
+			}
+			stale = expired(now, expires)
+		case strings.HasPrefix(name, leaderPrefix), strings.HasPrefix(name, decisionPrefix):
+			expires, _, ok, err := readRecord(path)
+			if err != nil {
+				return err
+			}
+			stale = !ok || expired(now, expires)
+		}
+
+		if stale {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
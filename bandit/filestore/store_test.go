@@ -0,0 +1,256 @@
+//go:build unix
+
+package filestore_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+	"github.com/sshaplygin/as-cache/bandit/filestore"
+)
+
+// testClock is a clock a test drives by hand, so bucket boundaries are exact
+// and nothing has to sleep.
+type testClock struct {
+	mu sync.Mutex
+	at time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{at: time.Date(2026, 7, 26, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.at
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.at = c.at.Add(d)
+}
+
+const testEpoch = time.Second
+
+func newStore(t *testing.T, dir string, clock *testClock) *filestore.Store {
+	t.Helper()
+
+	store, err := filestore.New(filestore.Options{Dir: dir})
+	require.NoError(t, err)
+	if clock != nil {
+		store.SetClock(clock.now)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+func testSyncRequest(namespace, node string, counts ...bandit.ArmCounts) bandit.SyncRequest {
+	return bandit.SyncRequest{
+		Namespace:   namespace,
+		NodeID:      node,
+		Counts:      counts,
+		EpochMillis: testEpoch.Milliseconds(),
+		CounterTTL:  12 * testEpoch,
+		LeaderTTL:   2 * testEpoch,
+	}
+}
+
+func shadow(policy ascache.PolicyType, hits, misses int64) bandit.ArmCounts {
+	return bandit.ArmCounts{Policy: policy, Role: bandit.RoleShadow, Hits: hits, Misses: misses}
+}
+
+var lruShadow = bandit.ArmKey{Policy: ascache.LRU, Role: bandit.RoleShadow}
+
+func TestNew_RequiresADirectory(t *testing.T) {
+	_, err := filestore.New(filestore.Options{})
+	assert.ErrorIs(t, err, filestore.ErrEmptyDir)
+}
+
+// TestStore_BucketComesFromTheFileSystemClock checks that with no clock set,
+// the bucket is the file system's time, which on a local disk is close to
+// this machine's.
+func TestStore_BucketComesFromTheFileSystemClock(t *testing.T) {
+	store := newStore(t, t.TempDir(), nil)
+
+	before := time.Now().Add(-time.Minute).UnixMilli() / testEpoch.Milliseconds()
+	result, err := store.Sync(t.Context(), testSyncRequest("ns", "a"))
+	require.NoError(t, err)
+	after := time.Now().Add(time.Minute).UnixMilli() / testEpoch.Milliseconds()
+
+	assert.GreaterOrEqual(t, int64(result.Bucket), before)
+	assert.LessOrEqual(t, int64(result.Bucket), after)
+}
+
+// TestStore_SumsCountsAcrossReplicas uses one Store per replica, as a fleet
+// would, with the directory as the only thing they share.
+func TestStore_SumsCountsAcrossReplicas(t *testing.T) {
+	dir := t.TempDir()
+	clock := newTestClock()
+	a := newStore(t, dir, clock)
+	b := newStore(t, dir, clock)
+
+	result, err := a.Sync(t.Context(), testSyncRequest("ns", "a", shadow(ascache.LRU, 10, 5)))
+	require.NoError(t, err)
+	_, err = b.Sync(t.Context(), testSyncRequest("ns", "b", shadow(ascache.LRU, 20, 15)))
+	require.NoError(t, err)
+
+	window, err := a.Window(t.Context(), "ns", result.Bucket, result.Bucket)
+	require.NoError(t, err)
+	require.Len(t, window, 1)
+
+	assert.Equal(t, ascache.PolicyStats{Hits: 30, Misses: 20}, window[0].Arms[lruShadow])
+}
+
+func TestStore_NamespacesDoNotPool(t *testing.T) {
+	store := newStore(t, t.TempDir(), newTestClock())
+
+	result, err := store.Sync(t.Context(), testSyncRequest("one", "a", shadow(ascache.LRU, 10, 0)))
+	require.NoError(t, err)
+	_, err = store.Sync(t.Context(), testSyncRequest("two", "b", shadow(ascache.LRU, 999, 0)))
+	require.NoError(t, err)
+	// A namespace that is not a valid file name is still only itself.
+	_, err = store.Sync(t.Context(), testSyncRequest("../one", "c", shadow(ascache.LRU, 999, 0)))
+	require.NoError(t, err)
+
+	window, err := store.Window(t.Context(), "one", result.Bucket, result.Bucket)
+	require.NoError(t, err)
+	require.Len(t, window, 1)
+
+	assert.Equal(t, int64(10), window[0].Arms[lruShadow].Hits)
+}
+
+func TestStore_OneLeaderPerBucket(t *testing.T) {
+	dir := t.TempDir()
+	clock := newTestClock()
+
+	leaders := 0
+	for _, node := range []string{"a", "b", "c", "d", "e"} {
+		req := testSyncRequest("ns", node)
+		req.Lead = true
+		result, err := newStore(t, dir, clock).Sync(t.Context(), req)
+		require.NoError(t, err)
+		if result.Leader {
+			leaders++
+		}
+	}
+	assert.Equal(t, 1, leaders, "leadership of a bucket is claimed once")
+
+	clock.advance(testEpoch)
+	req := testSyncRequest("ns", "f")
+	req.Lead = true
+	result, err := newStore(t, dir, clock).Sync(t.Context(), req)
+	require.NoError(t, err)
+	assert.True(t, result.Leader, "the next bucket is up for grabs again")
+}
+
+// TestStore_SweepDeletesExpiredFiles checks a fleet that stops leaves nothing
+// behind: the next sync after everything has expired removes it.
+func TestStore_SweepDeletesExpiredFiles(t *testing.T) {
+	dir := t.TempDir()
+	clock := newTestClock()
+	store := newStore(t, dir, clock)
+
+	req := testSyncRequest("ns", "a", shadow(ascache.LRU, 10, 0))
+	req.Lead = true
+	result, err := store.Sync(t.Context(), req)
+	require.NoError(t, err)
+	_, err = store.Decide(t.Context(), "ns", result.Bucket, ascache.LRU, testEpoch)
+	require.NoError(t, err)
+
+	clock.advance(time.Minute)
+	_, err = store.Sync(t.Context(), testSyncRequest("ns", "a"))
+	require.NoError(t, err)
+
+	namespaces, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, namespaces, 1)
+
+	entries, err := os.ReadDir(filepath.Join(dir, namespaces[0].Name()))
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotRegexp(t, `^(counts|leader|decision)-`, entry.Name(), "expired files are swept")
+	}
+}
+
+// TestStore_SkipsTornLines checks a line left half-written by a replica that
+// died mid-append costs that replica's counts and nobody else's.
+func TestStore_SkipsTornLines(t *testing.T) {
+	dir := t.TempDir()
+	store := newStore(t, dir, newTestClock())
+
+	result, err := store.Sync(t.Context(), testSyncRequest("ns", "a", shadow(ascache.LRU, 10, 0)))
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "ns-ns", "counts-"+strconv.FormatInt(int64(result.Bucket), 10))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = file.WriteString("17853 0 sha")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	_, err = store.Sync(t.Context(), testSyncRequest("ns", "b", shadow(ascache.LRU, 5, 0)))
+	require.NoError(t, err)
+	_, err = store.Sync(t.Context(), testSyncRequest("ns", "c", shadow(ascache.LRU, 1, 0)))
+	require.NoError(t, err)
+
+	window, err := store.Window(t.Context(), "ns", result.Bucket, result.Bucket)
+	require.NoError(t, err)
+	require.Len(t, window, 1)
+	assert.Equal(t, int64(11), window[0].Arms[lruShadow].Hits, "only the line the torn one ran into is lost")
+}
+
+func TestStore_ConcurrentReplicas(t *testing.T) {
+	dir := t.TempDir()
+
+	const replicas = 8
+	const rounds = 20
+
+	var wg sync.WaitGroup
+	for node := range replicas {
+		store := newStore(t, dir, nil)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				req := testSyncRequest("ns", string(rune('a'+node)), shadow(ascache.LRU, 1, 1))
+				req.CounterTTL = time.Hour
+				req.EpochMillis = time.Hour.Milliseconds()
+				req.Lead = true
+				if _, err := store.Sync(t.Context(), req); err != nil {
+					assert.NoError(t, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	store := newStore(t, dir, nil)
+	req := testSyncRequest("ns", "z")
+	req.EpochMillis = time.Hour.Milliseconds()
+	result, err := store.Sync(t.Context(), req)
+	require.NoError(t, err)
+
+	window, err := store.Window(t.Context(), "ns", result.Bucket-1, result.Bucket)
+	require.NoError(t, err)
+
+	var hits int64
+	for _, counts := range window {
+		hits += counts.Arms[lruShadow].Hits
+	}
+	assert.Equal(t, int64(replicas*rounds), hits, "no append is lost under contention")
+}
@@ -3,6 +3,7 @@ package bandit
 import (
 	"fmt"
 	"hash/fnv"
+	"sort"
 	"strings"
 
 	ascache "github.com/sshaplygin/as-cache"
@@ -29,8 +30,8 @@ type regime struct {
 }
 
 // String renders the regime in the form that gets hashed. It is legible on
-// purpose: when a fleet mysteriously splits into two namespaces, this is the
-// string worth logging.
+// purpose: it is what a RegimeStore records and what Snapshot lists under
+// SiblingRegimes, so a fleet split into two namespaces says which two.
 func (r regime) String() string {
 	names := make([]string, 0, len(r.arms))
 	for _, arm := range r.arms {
@@ -54,14 +55,20 @@ func (r regime) fingerprint() string {
 	return fmt.Sprintf("%012x", h.Sum64()&0xffffffffffff)
 }
 
-// regimeOf reads the measurement regime out of an epoch report. The arms are
-// already sorted by PolicyType - EpochReport documents that ordering - so the
-// fingerprint does not depend on anything the cache could vary between runs.
+// regimeOf reads the measurement regime out of an epoch report.
+//
+// EpochReport orders its arms by PolicyType, which for built-ins is fixed but
+// for registered types and variants is the order this process happened to
+// register them in. Two replicas building the same arms in a different order
+// would fingerprint apart, so the arms are put in an order of their own:
+// built-ins by number, which keeps every fingerprint from before registration
+// existed, then everything else by name.
 func regimeOf(report ascache.EpochReport) regime {
 	arms := make([]ascache.PolicyType, 0, len(report.Stats))
 	for _, stats := range report.Stats {
 		arms = append(arms, stats.Policy)
 	}
+	sort.Slice(arms, func(i, j int) bool { return armBefore(arms[i], arms[j]) })
 
 	return regime{
 		arms:       arms,
@@ -80,3 +87,16 @@ func (r regime) equal(other regime) bool {
 func scopedNamespace(namespace string, r regime) string {
 	return namespace + ":" + r.fingerprint()
 }
+
+// armBefore is the order arms are fingerprinted in: built-ins first by number,
+// then everything else by name.
+func armBefore(a, b ascache.PolicyType) bool {
+	if a.Builtin() != b.Builtin() {
+		return a.Builtin()
+	}
+	if a.Builtin() {
+		return a < b
+	}
+
+	return a.String() < b.String()
+}
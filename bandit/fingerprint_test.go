@@ -105,3 +105,25 @@ func TestScopedNamespace_KeepsTheCallersNameReadable(t *testing.T) {
 
 	assert.Contains(t, scopedNamespace("sessions", r), "sessions:")
 }
+
+func TestRegime_VariantsFingerprintByNameNotRegistrationOrder(t *testing.T) {
+	// Variant numbers are allocated per process, so two replicas that built
+	// the same arms in a different order hold different numbers for them.
+	// The fingerprint must not see the difference.
+	short, err := ascache.Variant(ascache.TTL, "fp-30s")
+	assert.NoError(t, err)
+	long, err := ascache.Variant(ascache.TTL, "fp-5m")
+	assert.NoError(t, err)
+
+	left := regimeOf(report(1000, 1, ascache.LRU, short, long))
+	right := regimeOf(report(1000, 1, long, ascache.LRU, short))
+
+	assert.Equal(t, "arms=LRU,TTL/fp-30s,TTL/fp-5m;cap=1000;rate=1.0000", left.String())
+	assert.True(t, left.equal(right))
+}
+
+func TestRegime_RegisteredTypesFingerprintByName(t *testing.T) {
+	r := regimeOf(report(1000, 1, testRegistered, ascache.TinyLFU, ascache.LRU))
+
+	assert.Equal(t, "arms=LRU,TinyLFU,CodecTestClock;cap=1000;rate=1.0000", r.String())
+}
@@ -0,0 +1,79 @@
+// Package gossip pools a fleet's evidence peer to peer, with no store for the
+// replicas to share.
+//
+// Each node keeps the whole fleet's recent counts in memory and exchanges
+// them with a few peers at random every Options.Interval, so a count
+// published on one node reaches every other in a handful of rounds. It
+// implements bandit.Store, so a bandit over it is the ordinary
+// bandit.Distributed - same window, same decay, same draw - run in
+// bandit.ModeSharedPosterior:
+//
+//	transport, err := gossip.NewTCP(gossip.TCPOptions{Listen: ":7946", Advertise: podIP + ":7946"})
+//	if err != nil {
+//	    return err
+//	}
+//	defer transport.Close()
+//
+//	store, err := gossip.New(gossip.Options{Transport: transport, Seeds: []string{"cache-0.cache:7946"}})
+//	if err != nil {
+//	    return err
+//	}
+//	defer store.Close()
+//
+//	b, err := bandit.NewDistributed(bandit.Config{
+//	    Store:             store,
+//	    Namespace:         "sessions",
+//	    CoordinationEpoch: 5 * time.Second,
+//	    Mode:              bandit.ModeSharedPosterior,
+//	})
+//
+// # What is exchanged
+//
+// Per-replica running totals of hits and misses per arm per bucket - not the
+// decayed, weighted evidence the bandit draws from. A running total that
+// only one replica adds to can be merged by taking the larger of two copies,
+// which makes hearing the same thing twice, late, or by two routes harmless;
+// a decayed sum cannot be merged that way without counting someone twice.
+// Every node decays the same totals the same way when it reads its window, so
+// the fleet still draws from one posterior.
+//
+// Membership travels with the counts. A node starts from Options.Seeds,
+// learns the peers its peers know, and forgets a learned peer after three
+// failed exchanges in a row. Seeds are never forgotten.
+//
+// # What it does not do
+//
+// There is no leader. Electing one per bucket needs the agreement that a
+// store provides and gossip does not, so Sync refuses to claim leadership and
+// Decide always fails, with ErrLeaderless: a bandit left in ModeLeader over
+// this store reports that error and decides locally rather than waiting on a
+// decision nobody will publish. For the same reason it does not pass the
+// leadership checks in bandit/storetest.
+//
+// Buckets come from each node's own clock, so the fleet's clocks must agree
+// to well within one coordination epoch - NTP does. A skewed node's counts
+// land a bucket early or late, which shifts their weight slightly and does
+// not lose them.
+//
+// # Regimes
+//
+// Replicas pool only within a namespace, and the namespace a bandit syncs
+// under carries its cache's regime fingerprint, exactly as it does for every
+// other store. Two caches with different arms or capacities gossip with each
+// other and relay each other's counts, but never read them.
+//
+// Regime announcements travel with the counts, so Snapshot on any node lists
+// the regimes the rest of the fleet is running once they have had a few
+// rounds to spread.
+//
+// # Cost
+//
+// Each exchange carries the sender's whole live state, about a hundred bytes
+// per arm per replica per bucket, so the traffic grows with the square of the
+// fleet. That suits tens of replicas, and a coordinator or a store past that.
+//
+// # Testing
+//
+// Loopback connects nodes in one process, with no sockets, and SetDown
+// partitions one off.
+package gossip
@@ -0,0 +1,632 @@
+package gossip
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+var (
+	_ bandit.RegimeStore      = (*Store)(nil)
+	_ bandit.ContributorStore = (*Store)(nil)
+)
+
+// ErrNilTransport is returned by New when Options.Transport is nil.
+var ErrNilTransport = errors.New("gossip: transport must not be nil")
+
+// ErrLeaderless is returned by Sync when asked to claim leadership, and by
+// Decide. Electing one leader per bucket takes agreement, which is exactly
+// what a fleet gossiping without a store does not have, so the store supports
+// bandit.ModeSharedPosterior only.
+//
+// It is an error rather than a claim that never succeeds because the latter
+// would leave a ModeLeader fleet waiting on decisions nobody publishes, with
+// nothing wrong in sight. As an error it surfaces in Snapshot.LastError and
+// the replica falls back to deciding locally.
+var ErrLeaderless = errors.New("gossip: no leader election; use bandit.ModeSharedPosterior")
+
+const (
+	// DefaultInterval is how often a node gossips when Options.Interval is
+	// zero. A second spreads a count through a fleet of a hundred in a few
+	// seconds at a fanout of two, well inside one window of ten epochs.
+	DefaultInterval = time.Second
+
+	// DefaultFanout is how many peers a node exchanges with per round when
+	// Options.Fanout is zero.
+	DefaultFanout = 2
+
+	// maxPeers bounds how many peers a node remembers. Membership spreads by
+	// gossip too, so without a bound one confused node could fill every
+	// other's list.
+	maxPeers = 1024
+
+	// maxFailures is how many exchanges in a row a learned peer may fail
+	// before it is forgotten. Seeds are never forgotten: they are how a node
+	// that has lost every other peer finds its way back.
+	maxFailures = 3
+)
+
+// Options configures a Store.
+type Options struct {
+	// Transport carries exchanges to and from peers. Required.
+	Transport Transport
+
+	// Seeds are peers to start from. A node learns the rest of the fleet from
+	// them, and they from it, so every node needs at least one seed that is
+	// up when it starts - except the first.
+	Seeds []string
+
+	// Interval is how often the node gossips. Defaults to DefaultInterval.
+	Interval time.Duration
+
+	// Fanout is how many peers the node exchanges with per round. Defaults
+	// to DefaultFanout.
+	Fanout int
+}
+
+// Store is a bandit.Store that keeps the fleet's counts in every node and
+// spreads them by gossip.
+type Store struct {
+	transport Transport
+	interval  time.Duration
+	fanout    int
+
+	mu      sync.Mutex
+	now     func() time.Time
+	origins map[originKey]*originEntry
+	regimes map[regimeKey]*regimeEntry
+	peers   map[string]*peer
+	closed  bool
+	rng     *rand.Rand
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// originKey identifies one replica's counts for one bucket. Only that replica
+// ever adds to them, which is what makes merging by maximum correct.
+type originKey struct {
+	namespace string
+	bucket    bandit.Bucket
+	node      string
+}
+
+// armName identifies an arm as the wire names it. Arms are kept by name, not
+// decoded, so a node relays counts for policies it has not registered itself
+// rather than dropping them on the way to nodes that have.
+type armName struct {
+	policy string
+	role   string
+}
+
+type originEntry struct {
+	arms    map[armName]ascache.PolicyStats
+	expires time.Time
+}
+
+// regimeKey identifies one replica's regime announcement. Only that replica
+// ever announces under it, so the copy that expires last is the newest.
+type regimeKey struct {
+	namespace string
+	node      string
+}
+
+type regimeEntry struct {
+	regime  string
+	expires time.Time
+}
+
+type peer struct {
+	seed     bool
+	failures int
+}
+
+// New starts a node on opts.Transport and gossips until Close.
+func New(opts Options) (*Store, error) {
+	if opts.Transport == nil {
+		return nil, ErrNilTransport
+	}
+
+	s := &Store{
+		transport: opts.Transport,
+		interval:  opts.Interval,
+		fanout:    opts.Fanout,
+		now:       time.Now,
+		origins:   make(map[originKey]*originEntry),
+		regimes:   make(map[regimeKey]*regimeEntry),
+		peers:     make(map[string]*peer),
+		//nolint:gosec // peer choice, not a secret
+		rng:  rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		stop: make(chan struct{}),
+	}
+	if s.interval <= 0 {
+		s.interval = DefaultInterval
+	}
+	if s.fanout <= 0 {
+		s.fanout = DefaultFanout
+	}
+	for _, seed := range opts.Seeds {
+		if seed != opts.Transport.Addr() {
+			s.peers[seed] = &peer{seed: true}
+		}
+	}
+
+	if err := opts.Transport.Listen(s.handle); err != nil {
+		return nil, fmt.Errorf("gossip: listen: %w", err)
+	}
+
+	s.wg.Add(1)
+	go s.loop()
+
+	return s, nil
+}
+
+// SetClock replaces the node's clock, which every bucket and expiry is taken
+// from. It exists for tests.
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+// Sync adds a replica's counts to its own entry for the current bucket.
+//
+// The bucket comes from this node's clock: there is no store to ask. Buckets
+// on two nodes agree as long as their clocks agree to well within an epoch,
+// and a count that lands one bucket early or late on a skewed node still
+// lands in the window, which is what the leader - here, every node - reads.
+func (s *Store) Sync(ctx context.Context, req bandit.SyncRequest) (bandit.SyncResult, error) {
+	if err := ctx.Err(); err != nil {
+		return bandit.SyncResult{}, err
+	}
+	if req.Lead {
+		return bandit.SyncResult{}, ErrLeaderless
+	}
+	if req.EpochMillis <= 0 {
+		return bandit.SyncResult{}, fmt.Errorf("gossip: sync: epoch of %dms", req.EpochMillis)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	bucket := bucketAt(now, req.EpochMillis)
+
+	key := originKey{namespace: req.Namespace, bucket: bucket, node: req.NodeID}
+	for _, counts := range req.Counts {
+		if counts.Hits == 0 && counts.Misses == 0 {
+			continue
+		}
+
+		entry := s.origins[key]
+		if entry == nil {
+			entry = &originEntry{arms: make(map[armName]ascache.PolicyStats)}
+			s.origins[key] = entry
+		}
+
+		name := armName{policy: bandit.EncodePolicy(counts.Policy), role: counts.Role.String()}
+		stats := entry.arms[name]
+		stats.Hits += counts.Hits
+		stats.Misses += counts.Misses
+		entry.arms[name] = stats
+		entry.expires = now.Add(req.CounterTTL)
+	}
+
+	s.expireLocked(now)
+
+	return bandit.SyncResult{Bucket: bucket}, nil
+}
+
+// Window sums every replica's entries this node has heard of for buckets
+// first through last inclusive.
+func (s *Store) Window(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.WindowCounts, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	buckets := make(map[bandit.Bucket]map[bandit.ArmKey]ascache.PolicyStats)
+
+	for key, entry := range s.origins {
+		if key.namespace != namespace || key.bucket < first || key.bucket > last || !now.Before(entry.expires) {
+			continue
+		}
+
+		arms := buckets[key.bucket]
+		if arms == nil {
+			arms = make(map[bandit.ArmKey]ascache.PolicyStats)
+			buckets[key.bucket] = arms
+		}
+		addArms(arms, entry.arms)
+	}
+
+	window := make([]bandit.WindowCounts, 0, len(buckets))
+	for bucket, arms := range buckets {
+		window = append(window, bandit.WindowCounts{Bucket: bucket, Arms: arms})
+	}
+	slices.SortFunc(window, func(a, b bandit.WindowCounts) int { return cmp.Compare(a.Bucket, b.Bucket) })
+
+	return window, nil
+}
+
+// NodeWindow lists every replica's entries this node has heard of for buckets
+// first through last inclusive, replica by replica. Entries are kept per
+// origin anyway, so this costs no more than Window.
+func (s *Store) NodeWindow(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.NodeWindowCounts, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	buckets := make(map[bandit.Bucket]map[string]map[bandit.ArmKey]ascache.PolicyStats)
+
+	for key, entry := range s.origins {
+		if key.namespace != namespace || key.bucket < first || key.bucket > last || !now.Before(entry.expires) {
+			continue
+		}
+
+		nodes := buckets[key.bucket]
+		if nodes == nil {
+			nodes = make(map[string]map[bandit.ArmKey]ascache.PolicyStats)
+			buckets[key.bucket] = nodes
+		}
+
+		arms := make(map[bandit.ArmKey]ascache.PolicyStats, len(entry.arms))
+		addArms(arms, entry.arms)
+		nodes[key.node] = arms
+	}
+
+	window := make([]bandit.NodeWindowCounts, 0, len(buckets))
+	for bucket, nodes := range buckets {
+		window = append(window, bandit.NodeWindowCounts{Bucket: bucket, Nodes: nodes})
+	}
+	slices.SortFunc(window, func(a, b bandit.NodeWindowCounts) int { return cmp.Compare(a.Bucket, b.Bucket) })
+
+	return window, nil
+}
+
+// addArms decodes an origin's arms into arms, skipping any this process
+// cannot name; they are still relayed, just not counted here.
+func addArms(arms map[bandit.ArmKey]ascache.PolicyStats, named map[armName]ascache.PolicyStats) {
+	for name, counts := range named {
+		policy, ok := bandit.DecodePolicy(name.policy)
+		if !ok {
+			continue
+		}
+		role, ok := parseRole(name.role)
+		if !ok {
+			continue
+		}
+
+		armKey := bandit.ArmKey{Policy: policy, Role: role}
+		stats := arms[armKey]
+		stats.Hits += counts.Hits
+		stats.Misses += counts.Misses
+		arms[armKey] = stats
+	}
+}
+
+// Regimes records a replica's regime announcement on this node, to be
+// gossiped with the counts, and lists the live announcements this node has
+// heard of under the namespace - which, a few rounds after a replica
+// announces, is every node's.
+func (s *Store) Regimes(ctx context.Context, req bandit.RegimeRequest) ([]bandit.RegimeCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.regimes[regimeKey{namespace: req.Namespace, node: req.NodeID}] = &regimeEntry{
+		regime:  req.Regime,
+		expires: now.Add(req.TTL),
+	}
+	s.expireLocked(now)
+
+	replicas := make(map[string]int)
+	for key, entry := range s.regimes {
+		if key.namespace == req.Namespace {
+			replicas[entry.regime]++
+		}
+	}
+
+	regimes := make([]bandit.RegimeCount, 0, len(replicas))
+	for regime, n := range replicas {
+		regimes = append(regimes, bandit.RegimeCount{Regime: regime, Replicas: n})
+	}
+	slices.SortFunc(regimes, func(a, b bandit.RegimeCount) int { return cmp.Compare(a.Regime, b.Regime) })
+
+	return regimes, nil
+}
+
+// Decide always fails with ErrLeaderless. See ErrLeaderless.
+func (s *Store) Decide(
+	context.Context,
+	string,
+	bandit.Bucket,
+	ascache.PolicyType,
+	time.Duration,
+) (ascache.PolicyType, error) {
+	return ascache.Undefined, ErrLeaderless
+}
+
+// Close stops gossiping. The node answers no further exchanges, which its
+// peers see as it going away. The transport belongs to the caller and is
+// left open.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	s.wg.Wait()
+
+	return nil
+}
+
+func (s *Store) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.round()
+		}
+	}
+}
+
+// round exchanges state with up to fanout peers chosen at random. Each
+// exchange is push-pull: this node's state goes out in the request and the
+// peer's comes back in the reply, so one round trip brings both up to date.
+func (s *Store) round() {
+	for _, addr := range s.pickPeers() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+		err := s.exchange(ctx, addr)
+		cancel()
+
+		s.mu.Lock()
+		if p, ok := s.peers[addr]; ok {
+			if err == nil {
+				p.failures = 0
+			} else if p.failures++; !p.seed && p.failures >= maxFailures {
+				delete(s.peers, addr)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *Store) exchange(ctx context.Context, addr string) error {
+	request, err := s.message()
+	if err != nil {
+		return err
+	}
+
+	reply, err := s.transport.Exchange(ctx, addr, request)
+	if err != nil {
+		return err
+	}
+
+	return s.merge(reply)
+}
+
+func (s *Store) pickPeers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addrs := make([]string, 0, len(s.peers))
+	for addr := range s.peers {
+		addrs = append(addrs, addr)
+	}
+	slices.Sort(addrs)
+	s.rng.Shuffle(len(addrs), func(i, j int) { addrs[i], addrs[j] = addrs[j], addrs[i] })
+
+	return addrs[:min(s.fanout, len(addrs))]
+}
+
+// handle answers a peer's exchange with this node's state, then merges the
+// peer's. The reply is built first so it carries only what this node knew:
+// echoing the peer's own state back would double the bytes and tell it
+// nothing.
+func (s *Store) handle(request []byte) ([]byte, error) {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return nil, errors.New("gossip: node closed")
+	}
+
+	reply, err := s.message()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.merge(request); err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// message encodes this node's live state and the peers it knows.
+func (s *Store) message() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expireLocked(now)
+
+	msg := message{
+		From:    s.transport.Addr(),
+		Peers:   make([]string, 0, len(s.peers)),
+		Entries: make([]wireEntry, 0, len(s.origins)),
+	}
+	for addr := range s.peers {
+		msg.Peers = append(msg.Peers, addr)
+	}
+
+	for key, entry := range s.origins {
+		wire := wireEntry{
+			Namespace: key.namespace,
+			Bucket:    int64(key.bucket),
+			Node:      key.node,
+			TTLMillis: entry.expires.Sub(now).Milliseconds(),
+			Arms:      make([]wireArm, 0, len(entry.arms)),
+		}
+		for name, stats := range entry.arms {
+			wire.Arms = append(wire.Arms, wireArm{
+				Policy: name.policy,
+				Role:   name.role,
+				Hits:   stats.Hits,
+				Misses: stats.Misses,
+			})
+		}
+		msg.Entries = append(msg.Entries, wire)
+	}
+
+	for key, entry := range s.regimes {
+		msg.Regimes = append(msg.Regimes, wireRegime{
+			Namespace: key.namespace,
+			Node:      key.node,
+			Regime:    entry.regime,
+			TTLMillis: entry.expires.Sub(now).Milliseconds(),
+		})
+	}
+
+	return json.Marshal(msg)
+}
+
+// merge folds a peer's state into this node's.
+//
+// Every count is a running total that only its own replica adds to, so two
+// copies of it differ only in how recent they are, and the larger is the
+// newer. Taking the maximum makes a merge idempotent and order-independent:
+// hearing the same state twice, or by two routes, or out of order, changes
+// nothing. Expiries travel as time remaining rather than as instants, so no
+// two nodes' clocks are compared.
+func (s *Store) merge(payload []byte) error {
+	var msg message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return fmt.Errorf("gossip: malformed message: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	self := s.transport.Addr()
+	for _, addr := range append(msg.Peers, msg.From) {
+		if addr == "" || addr == self || len(s.peers) >= maxPeers {
+			continue
+		}
+		if _, ok := s.peers[addr]; !ok {
+			s.peers[addr] = &peer{}
+		}
+	}
+
+	for _, wire := range msg.Entries {
+		if wire.TTLMillis <= 0 {
+			continue
+		}
+
+		key := originKey{namespace: wire.Namespace, bucket: bandit.Bucket(wire.Bucket), node: wire.Node}
+		entry := s.origins[key]
+		if entry == nil {
+			entry = &originEntry{arms: make(map[armName]ascache.PolicyStats, len(wire.Arms))}
+			s.origins[key] = entry
+		}
+
+		if expires := now.Add(time.Duration(wire.TTLMillis) * time.Millisecond); expires.After(entry.expires) {
+			entry.expires = expires
+		}
+
+		for _, arm := range wire.Arms {
+			name := armName{policy: arm.Policy, role: arm.Role}
+			stats := entry.arms[name]
+			stats.Hits = max(stats.Hits, arm.Hits)
+			stats.Misses = max(stats.Misses, arm.Misses)
+			entry.arms[name] = stats
+		}
+	}
+
+	// An announcement is replaced rather than merged: a replica redeployed
+	// into another regime announces it with a fresh TTL, which outlasts
+	// every copy of its old one still going round.
+	for _, wire := range msg.Regimes {
+		if wire.TTLMillis <= 0 {
+			continue
+		}
+
+		key := regimeKey{namespace: wire.Namespace, node: wire.Node}
+		expires := now.Add(time.Duration(wire.TTLMillis) * time.Millisecond)
+		if entry := s.regimes[key]; entry == nil || expires.After(entry.expires) {
+			s.regimes[key] = &regimeEntry{regime: wire.Regime, expires: expires}
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) expireLocked(now time.Time) {
+	for key, entry := range s.origins {
+		if !now.Before(entry.expires) {
+			delete(s.origins, key)
+		}
+	}
+	for key, entry := range s.regimes {
+		if !now.Before(entry.expires) {
+			delete(s.regimes, key)
+		}
+	}
+}
+
+func bucketAt(now time.Time, epochMillis int64) bandit.Bucket {
+	return bandit.Bucket(now.UnixMilli() / epochMillis)
+}
+
+func parseRole(text string) (bandit.Role, bool) {
+	switch text {
+	case bandit.RoleActive.String():
+		return bandit.RoleActive, true
+	case bandit.RoleShadow.String():
+		return bandit.RoleShadow, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,374 @@
+package gossip
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+// testClock is a clock a test drives by hand, so expiry is exact and nothing
+// has to sleep.
+type testClock struct {
+	mu sync.Mutex
+	at time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{at: time.Date(2026, 8, 2, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.at
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.at = c.at.Add(d)
+}
+
+const testEpoch = time.Second
+
+// newNode starts a node on network that never gossips by itself: the test
+// runs its rounds, so convergence is a loop rather than a race.
+func newNode(t *testing.T, network *Loopback, clock *testClock, addr string, seeds ...string) *Store {
+	t.Helper()
+
+	store, err := New(Options{Transport: network.Transport(addr), Seeds: seeds, Interval: time.Hour})
+	require.NoError(t, err)
+	store.SetClock(clock.now)
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+func syncRequest(node string, counts ...bandit.ArmCounts) bandit.SyncRequest {
+	return bandit.SyncRequest{
+		Namespace:   "ns",
+		NodeID:      node,
+		Counts:      counts,
+		EpochMillis: testEpoch.Milliseconds(),
+		CounterTTL:  12 * testEpoch,
+	}
+}
+
+func shadow(policy ascache.PolicyType, hits, misses int64) bandit.ArmCounts {
+	return bandit.ArmCounts{Policy: policy, Role: bandit.RoleShadow, Hits: hits, Misses: misses}
+}
+
+var lruShadow = bandit.ArmKey{Policy: ascache.LRU, Role: bandit.RoleShadow}
+
+func lruIn(t *testing.T, s *Store, bucket bandit.Bucket) ascache.PolicyStats {
+	t.Helper()
+
+	window, err := s.Window(t.Context(), "ns", bucket, bucket)
+	require.NoError(t, err)
+	if len(window) == 0 {
+		return ascache.PolicyStats{}
+	}
+
+	return window[0].Arms[lruShadow]
+}
+
+func TestNew_RequiresATransport(t *testing.T) {
+	_, err := New(Options{})
+	require.ErrorIs(t, err, ErrNilTransport)
+}
+
+// TestStore_ConvergesAcrossTheFleet checks that counts published on every node
+// of a chain - c knows only b, b knows only a - reach every other node, and
+// that membership spreads with them.
+func TestStore_ConvergesAcrossTheFleet(t *testing.T) {
+	network := NewLoopback()
+	clock := newTestClock()
+	nodes := []*Store{
+		newNode(t, network, clock, "a"),
+		newNode(t, network, clock, "b", "a"),
+		newNode(t, network, clock, "c", "b"),
+	}
+
+	var bucket bandit.Bucket
+	for i, node := range nodes {
+		result, err := node.Sync(t.Context(), syncRequest(string(rune('a'+i)), shadow(ascache.LRU, int64(i+1), 1)))
+		require.NoError(t, err)
+		bucket = result.Bucket
+	}
+
+	want := ascache.PolicyStats{Hits: 6, Misses: 3}
+	for range 10 {
+		for _, node := range nodes {
+			node.round()
+		}
+	}
+
+	for i, node := range nodes {
+		assert.Equal(t, want, lruIn(t, node, bucket), "node %d", i)
+	}
+	assert.Contains(t, nodes[0].peers, "c", "a learned of c without being told")
+}
+
+// TestStore_KeepsCountsPerNode checks that a node reports what it has heard
+// replica by replica, relayed counts included.
+func TestStore_KeepsCountsPerNode(t *testing.T) {
+	network := NewLoopback()
+	clock := newTestClock()
+	a := newNode(t, network, clock, "a")
+	b := newNode(t, network, clock, "b", "a")
+
+	result, err := a.Sync(t.Context(), syncRequest("a", shadow(ascache.LRU, 9, 1)))
+	require.NoError(t, err)
+	_, err = b.Sync(t.Context(), syncRequest("b", shadow(ascache.LRU, 1, 9)))
+	require.NoError(t, err)
+	b.round()
+
+	window, err := a.NodeWindow(t.Context(), "ns", result.Bucket, result.Bucket)
+	require.NoError(t, err)
+	require.Len(t, window, 1)
+
+	key := bandit.ArmKey{Policy: ascache.LRU, Role: bandit.RoleShadow}
+	assert.Equal(t, ascache.PolicyStats{Hits: 9, Misses: 1}, window[0].Nodes["a"][key])
+	assert.Equal(t, ascache.PolicyStats{Hits: 1, Misses: 9}, window[0].Nodes["b"][key])
+}
+
+func TestStore_MergeIsIdempotent(t *testing.T) {
+	network := NewLoopback()
+	clock := newTestClock()
+	a := newNode(t, network, clock, "a")
+	b := newNode(t, network, clock, "b")
+
+	result, err := a.Sync(t.Context(), syncRequest("a", shadow(ascache.LRU, 5, 5)))
+	require.NoError(t, err)
+
+	msg, err := a.message()
+	require.NoError(t, err)
+	require.NoError(t, b.merge(msg))
+	require.NoError(t, b.merge(msg))
+	assert.Equal(t, ascache.PolicyStats{Hits: 5, Misses: 5}, lruIn(t, b, result.Bucket))
+
+	// A later, larger total from the same replica replaces the earlier one
+	// rather than adding to it; an older copy arriving late changes nothing.
+	_, err = a.Sync(t.Context(), syncRequest("a", shadow(ascache.LRU, 1, 0)))
+	require.NoError(t, err)
+	newer, err := a.message()
+	require.NoError(t, err)
+	require.NoError(t, b.merge(newer))
+	require.NoError(t, b.merge(msg))
+	assert.Equal(t, ascache.PolicyStats{Hits: 6, Misses: 5}, lruIn(t, b, result.Bucket))
+}
+
+// TestStore_RelaysPoliciesItCannotName checks that a node passes on counts
+// for an arm it has not registered, so they reach the nodes that have.
+func TestStore_RelaysPoliciesItCannotName(t *testing.T) {
+	network := NewLoopback()
+	clock := newTestClock()
+	b := newNode(t, network, clock, "b")
+
+	payload, err := json.Marshal(message{From: "a", Entries: []wireEntry{{
+		Namespace: "ns",
+		Bucket:    1,
+		Node:      "a",
+		TTLMillis: 60_000,
+		Arms:      []wireArm{{Policy: "RegisteredElsewhere", Role: "shadow", Hits: 3, Misses: 1}},
+	}}})
+	require.NoError(t, err)
+	require.NoError(t, b.merge(payload))
+
+	window, err := b.Window(t.Context(), "ns", 1, 1)
+	require.NoError(t, err)
+	require.Len(t, window, 1)
+	assert.Empty(t, window[0].Arms, "b cannot name it, so does not count it")
+
+	relayed, err := b.message()
+	require.NoError(t, err)
+	assert.True(t, bytes.Contains(relayed, []byte("RegisteredElsewhere")), "but does pass it on")
+}
+
+func TestStore_IsLeaderless(t *testing.T) {
+	a := newNode(t, NewLoopback(), newTestClock(), "a")
+
+	req := syncRequest("a")
+	req.Lead = true
+	_, err := a.Sync(t.Context(), req)
+	require.ErrorIs(t, err, ErrLeaderless)
+
+	_, err = a.Decide(t.Context(), "ns", 1, ascache.LRU, time.Minute)
+	require.ErrorIs(t, err, ErrLeaderless)
+}
+
+func TestStore_CountsExpire(t *testing.T) {
+	network := NewLoopback()
+	clock := newTestClock()
+	a := newNode(t, network, clock, "a")
+	b := newNode(t, network, clock, "b", "a")
+
+	result, err := a.Sync(t.Context(), syncRequest("a", shadow(ascache.LRU, 1, 1)))
+	require.NoError(t, err)
+	b.round()
+	require.NotZero(t, lruIn(t, b, result.Bucket))
+
+	clock.advance(13 * testEpoch)
+	assert.Zero(t, lruIn(t, a, result.Bucket))
+	assert.Zero(t, lruIn(t, b, result.Bucket))
+
+	b.round()
+	assert.Empty(t, a.origins, "nothing expired is gossiped back")
+}
+
+func TestStore_SpreadsRegimeAnnouncements(t *testing.T) {
+	network := NewLoopback()
+	clock := newTestClock()
+	a := newNode(t, network, clock, "a")
+	b := newNode(t, network, clock, "b", "a")
+
+	announce := func(s *Store, node, regime string) []bandit.RegimeCount {
+		t.Helper()
+
+		regimes, err := s.Regimes(t.Context(), bandit.RegimeRequest{
+			Namespace: "sessions",
+			NodeID:    node,
+			Regime:    regime,
+			TTL:       10 * testEpoch,
+		})
+		require.NoError(t, err)
+
+		return regimes
+	}
+
+	announce(a, "a", "cap=1000")
+	b.round()
+	assert.Equal(t, []bandit.RegimeCount{{Regime: "cap=1000", Replicas: 1}, {Regime: "cap=2000", Replicas: 1}},
+		announce(b, "b", "cap=2000"))
+
+	// a redeploys with b's capacity. Its new announcement outlasts the old
+	// one, so b takes it even though it has heard the old one already.
+	clock.advance(testEpoch)
+	announce(a, "a", "cap=2000")
+	b.round()
+	assert.Equal(t, []bandit.RegimeCount{{Regime: "cap=2000", Replicas: 2}}, announce(b, "b", "cap=2000"))
+
+	clock.advance(11 * testEpoch)
+	assert.Equal(t, []bandit.RegimeCount{{Regime: "cap=2000", Replicas: 1}}, announce(b, "b", "cap=2000"),
+		"a stopped announcing, so it drops out")
+}
+
+func TestStore_ForgetsLearnedPeersButNotSeeds(t *testing.T) {
+	network := NewLoopback()
+	clock := newTestClock()
+	a := newNode(t, network, clock, "a", "seed")
+	newNode(t, network, clock, "seed")
+	learned := newNode(t, network, clock, "learned", "seed")
+
+	learned.round()
+	a.round()
+	require.Contains(t, a.peers, "learned", "seed told a about learned")
+
+	network.SetDown("seed", true)
+	network.SetDown("learned", true)
+	for range maxFailures {
+		a.round()
+	}
+
+	assert.Contains(t, a.peers, "seed")
+	assert.NotContains(t, a.peers, "learned")
+}
+
+func TestTCP_Exchanges(t *testing.T) {
+	transport, err := NewTCP(TCPOptions{Listen: "127.0.0.1:0"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = transport.Close() })
+
+	require.NoError(t, transport.Listen(func(request []byte) ([]byte, error) {
+		return append([]byte("echo "), request...), nil
+	}))
+
+	reply, err := transport.Exchange(t.Context(), transport.Addr(), []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo hello", string(reply))
+
+	// A frame claiming to be larger than the limit is refused before it is
+	// read, and the sender sees the connection close without a reply.
+	conn, err := net.Dial("tcp", transport.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	_, err = conn.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	require.NoError(t, err)
+	_, err = readFrame(conn)
+	assert.Error(t, err)
+}
+
+// TestDistributed_PoolsWithinARegimeOnly runs three bandits over three
+// gossiping nodes. Two share a regime and one has a different capacity: the
+// first two pool, and the third relays their counts without ever reading them.
+func TestDistributed_PoolsWithinARegimeOnly(t *testing.T) {
+	network := NewLoopback()
+	start := func(addr string, seeds ...string) *bandit.Distributed {
+		store, err := New(Options{Transport: network.Transport(addr), Seeds: seeds, Interval: 5 * time.Millisecond})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = store.Close() })
+
+		d, err := bandit.NewDistributed(bandit.Config{
+			Store:             store,
+			Namespace:         "sessions",
+			NodeID:            addr,
+			CoordinationEpoch: 20 * time.Millisecond,
+			Mode:              bandit.ModeSharedPosterior,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = d.Close() })
+
+		return d
+	}
+
+	hitting := start("hitting")
+	missing := start("missing", "hitting")
+	resized := start("resized", "missing")
+
+	report := func(d *bandit.Distributed, capacity int, hits int64) {
+		d.RecordEpoch(ascache.EpochReport{
+			Active: ascache.LRU,
+			Stats: []ascache.ShadowStats{
+				{Policy: ascache.LRU, Hits: hits, Misses: 100},
+				{Policy: ascache.TinyLFU, Hits: hits, Misses: 100},
+			},
+			Capacity:   capacity,
+			SampleRate: 1,
+		})
+	}
+	heardHits := func(d *bandit.Distributed) (fleet int, hits float64) {
+		snapshot := d.Snapshot()
+		for _, arm := range snapshot.Fleet {
+			hits += arm.Hits
+		}
+
+		return len(snapshot.Fleet), hits
+	}
+
+	// Only "hitting" reports any hits, so a hit anywhere else came from it.
+	require.Eventually(t, func() bool {
+		report(hitting, 1000, 100)
+		report(missing, 1000, 0)
+		report(resized, 2000, 0)
+
+		_, hits := heardHits(missing)
+		fleet, _ := heardHits(resized)
+
+		return hits > 0 && fleet > 0
+	}, 10*time.Second, 5*time.Millisecond)
+
+	_, hits := heardHits(resized)
+	assert.Zero(t, hits, "a cache of another capacity must not pool with these")
+	assert.NotEqual(t, hitting.Snapshot().Namespace, resized.Snapshot().Namespace)
+}
@@ -0,0 +1,278 @@
+package gossip
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Handler answers one exchange: it is given a peer's message and returns this
+// node's reply. An error sends no reply, which the peer sees as a failed
+// exchange.
+type Handler func(request []byte) ([]byte, error)
+
+// Transport carries exchanges between nodes. An exchange is one message each
+// way - a request and its reply - which is all push-pull gossip needs.
+//
+// Implementations must be safe for concurrent use. The store supplies the
+// handler once, in New, and never closes the transport: it belongs to the
+// caller, like every other store's connection.
+type Transport interface {
+	// Addr is the address peers reach this node at. It is what the node
+	// advertises, so it must be routable from them - not a wildcard.
+	Addr() string
+
+	// Listen starts delivering incoming exchanges to handle, and returns once
+	// it is doing so.
+	Listen(handle Handler) error
+
+	// Exchange sends request to the node at peer and returns its reply.
+	Exchange(ctx context.Context, peer string, request []byte) ([]byte, error)
+}
+
+// ErrUnreachable is returned by a Loopback transport's Exchange when the peer
+// is not on the network, or has been taken down with SetDown.
+var ErrUnreachable = errors.New("gossip: peer unreachable")
+
+// ErrMessageTooLarge is returned when a message exceeds MaxMessageSize.
+var ErrMessageTooLarge = errors.New("gossip: message too large")
+
+// MaxMessageSize bounds one message on the TCP transport. A message is a
+// node's whole live state, which for a fleet of tens of replicas with a
+// dozen buckets of six arms is tens of kilobytes; the bound is there so a
+// stray connection cannot make a node allocate without limit.
+const MaxMessageSize = 16 << 20
+
+// TCPOptions configures NewTCP.
+type TCPOptions struct {
+	// Listen is the address to accept exchanges on, such as ":7946".
+	Listen string
+
+	// Advertise is the address peers are told to reach this node at.
+	// Defaults to the address the listener bound, which is only right when
+	// Listen names a routable host.
+	Advertise string
+
+	// Timeout bounds one exchange from either side. Defaults to five seconds.
+	Timeout time.Duration
+}
+
+// TCP is a Transport over TCP: one connection per exchange, each message a
+// four-byte big-endian length and the bytes.
+//
+// Gossip runs once per Options.Interval per peer, so the cost of a connection
+// per exchange is small, and it leaves nothing open to go stale between
+// rounds. It is unauthenticated and unencrypted: run it on a network only the
+// fleet can reach.
+type TCP struct {
+	listener  net.Listener
+	advertise string
+	timeout   time.Duration
+
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+var _ Transport = (*TCP)(nil)
+
+// NewTCP binds opts.Listen. Exchanges are not accepted until Listen is
+// called.
+func NewTCP(opts TCPOptions) (*TCP, error) {
+	listener, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("gossip: %w", err)
+	}
+
+	t := &TCP{listener: listener, advertise: opts.Advertise, timeout: opts.Timeout}
+	if t.advertise == "" {
+		t.advertise = listener.Addr().String()
+	}
+	if t.timeout <= 0 {
+		t.timeout = 5 * time.Second
+	}
+
+	return t, nil
+}
+
+// Addr returns the advertised address.
+func (t *TCP) Addr() string { return t.advertise }
+
+// Listen accepts exchanges in the background until Close.
+func (t *TCP) Listen(handle Handler) error {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		for {
+			conn, err := t.listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				continue
+			}
+
+			t.wg.Add(1)
+			go func() {
+				defer t.wg.Done()
+				t.serve(conn, handle)
+			}()
+		}
+	}()
+
+	return nil
+}
+
+func (t *TCP) serve(conn net.Conn, handle Handler) {
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.SetDeadline(time.Now().Add(t.timeout))
+
+	request, err := readFrame(conn)
+	if err != nil {
+		return
+	}
+
+	reply, err := handle(request)
+	if err != nil {
+		return
+	}
+
+	_ = writeFrame(conn, reply)
+}
+
+// Exchange dials peer, sends request and reads the reply.
+func (t *TCP) Exchange(ctx context.Context, peer string, request []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", peer)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err := writeFrame(conn, request); err != nil {
+		return nil, err
+	}
+
+	return readFrame(conn)
+}
+
+// Close stops accepting exchanges and waits for those in flight.
+func (t *TCP) Close() error {
+	var err error
+	t.closeOnce.Do(func() {
+		err = t.listener.Close()
+		t.wg.Wait()
+	})
+
+	return err
+}
+
+func writeFrame(w io.Writer, payload []byte) error {
+	if len(payload) > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+
+	_, err := w.Write(frame)
+
+	return err
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxMessageSize {
+		return nil, ErrMessageTooLarge
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// Loopback is an in-process network of transports, for tests and for
+// simulating a fleet in one process the way bandit.MemStore does for a
+// central store. Exchanges are direct function calls.
+type Loopback struct {
+	mu       sync.Mutex
+	handlers map[string]Handler
+	down     map[string]bool
+}
+
+// NewLoopback returns an empty network.
+func NewLoopback() *Loopback {
+	return &Loopback{
+		handlers: make(map[string]Handler),
+		down:     make(map[string]bool),
+	}
+}
+
+// Transport returns the transport for the node at addr.
+func (l *Loopback) Transport(addr string) Transport {
+	return &loopbackTransport{network: l, addr: addr}
+}
+
+// SetDown takes the node at addr off the network, or puts it back: while it
+// is down, exchanges to and from it fail with ErrUnreachable.
+func (l *Loopback) SetDown(addr string, down bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.down[addr] = down
+}
+
+type loopbackTransport struct {
+	network *Loopback
+	addr    string
+}
+
+func (t *loopbackTransport) Addr() string { return t.addr }
+
+func (t *loopbackTransport) Listen(handle Handler) error {
+	t.network.mu.Lock()
+	defer t.network.mu.Unlock()
+
+	t.network.handlers[t.addr] = handle
+
+	return nil
+}
+
+func (t *loopbackTransport) Exchange(ctx context.Context, peer string, request []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	t.network.mu.Lock()
+	handle, ok := t.network.handlers[peer]
+	down := t.network.down[peer] || t.network.down[t.addr]
+	t.network.mu.Unlock()
+
+	if !ok || down {
+		return nil, fmt.Errorf("%w: %s", ErrUnreachable, peer)
+	}
+
+	return handle(request)
+}
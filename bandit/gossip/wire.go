@@ -0,0 +1,38 @@
+package gossip
+
+// message is one side of an exchange: everything the sender knows that has
+// not expired, and the peers it knows, so membership spreads the same way the
+// counts do.
+type message struct {
+	From    string       `json:"from"`
+	Peers   []string     `json:"peers,omitempty"`
+	Entries []wireEntry  `json:"entries"`
+	Regimes []wireRegime `json:"regimes,omitempty"`
+}
+
+// wireEntry is one replica's counts for one bucket. Policies are named as
+// bandit.EncodePolicy names them and roles by name, as every store persists
+// them.
+type wireEntry struct {
+	Namespace string    `json:"ns"`
+	Bucket    int64     `json:"bucket"`
+	Node      string    `json:"node"`
+	TTLMillis int64     `json:"ttl_ms"`
+	Arms      []wireArm `json:"arms"`
+}
+
+type wireArm struct {
+	Policy string `json:"policy"`
+	Role   string `json:"role"`
+	Hits   int64  `json:"hits"`
+	Misses int64  `json:"misses"`
+}
+
+// wireRegime is one replica's regime announcement under a namespace as
+// configured.
+type wireRegime struct {
+	Namespace string `json:"ns"`
+	Node      string `json:"node"`
+	Regime    string `json:"regime"`
+	TTLMillis int64  `json:"ttl_ms"`
+}
@@ -0,0 +1,69 @@
+package bandit
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+func TestDistributed_LogsOutagesRefusalsAndRegimes(t *testing.T) {
+	store, clock := newFleetStore(t)
+	var logged bytes.Buffer
+	subject := fleet(t, 1, store, clock, func(cfg *Config) {
+		cfg.Logger = slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	})[0]
+	rates := map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6}
+
+	subject.report(t, 100, rates)
+	subject.bandit.sync()
+	subject.report(t, 100, rates)
+	subject.bandit.sync()
+	assert.Equal(t, 1, bytes.Count(logged.Bytes(), []byte("coordinating under regime")),
+		"once per regime, not per sync")
+	assert.Contains(t, logged.String(), `regime="arms=LRU,TinyLFU;cap=1000;rate=1.0000"`)
+	assert.Contains(t, logged.String(), "namespace=test node=a")
+
+	logged.Reset()
+	store.Fail(errors.New("connection refused"))
+	clock.advance(4 * testEpoch)
+	subject.report(t, 100, rates)
+	subject.bandit.sync()
+	assert.Contains(t, logged.String(), `level=DEBUG msg="bandit: store round trip failed" namespace=test node=a error="connection refused"`)
+	assert.Contains(t, logged.String(), `level=WARN msg="bandit: store unreachable, selecting locally"`)
+
+	logged.Reset()
+	for range 3 {
+		subject.report(t, 100, rates)
+		subject.bandit.sync()
+	}
+	assert.Equal(t, 3, bytes.Count(logged.Bytes(), []byte("store round trip failed")))
+	assert.NotContains(t, logged.String(), "level=WARN", "an outage warns as it starts and ends, not on every sync")
+
+	logged.Reset()
+	store.Fail(nil)
+	subject.report(t, 100, rates)
+	subject.bandit.sync()
+	assert.Contains(t, logged.String(), `level=WARN msg="bandit: store reachable again, following the fleet"`)
+
+	logged.Reset()
+	subject.bandit.applyPushed(PushedDecision{Bucket: Bucket(subject.bandit.Snapshot().LastBucket + 1), Policy: ascache.ARC})
+	assert.Contains(t, logged.String(), `level=WARN msg="bandit: refused a fleet decision for a policy this cache does not hold"`)
+	assert.Contains(t, logged.String(), "policy=ARC")
+}
+
+func TestDistributed_LoggerDefaultsToDiscarding(t *testing.T) {
+	store, clock := newFleetStore(t)
+	store.Fail(errors.New("down"))
+	subject := fleet(t, 1, store, clock, func(cfg *Config) { cfg.FallbackAfter = time.Hour })[0]
+
+	subject.report(t, 100, map[ascache.PolicyType]float64{ascache.LRU: 0.5})
+	require.NotPanics(t, subject.bandit.sync)
+	assert.NotNil(t, subject.bandit.cfg.Logger)
+}
@@ -2,13 +2,18 @@ package bandit
 
 import (
 	"context"
+	"maps"
 	"sync"
 	"time"
 
 	ascache "github.com/sshaplygin/as-cache"
 )
 
-var _ Store = (*MemStore)(nil)
+var (
+	_ RegimeStore      = (*MemStore)(nil)
+	_ ContributorStore = (*MemStore)(nil)
+	_ PinStore         = (*MemStore)(nil)
+)
 
 // MemStore is a Store held in memory, shared by every replica in one process.
 //
@@ -30,6 +35,11 @@ type MemStore struct {
 	counts    map[bucketKey]*bucketEntry
 	leaders   map[bucketKey]entry[string]
 	decisions map[bucketKey]entry[ascache.PolicyType]
+	// regimes holds each node's announced regime per configured namespace.
+	regimes map[string]map[string]entry[string]
+	// pins holds the fleet-wide pin per configured namespace. Pins do not
+	// expire.
+	pins map[string]ascache.PolicyType
 
 	// failure, when non-nil, is returned by every call. It exists so a test
 	// can take the store away mid-run and watch replicas fall back.
@@ -47,7 +57,9 @@ type entry[T any] struct {
 }
 
 type bucketEntry struct {
-	arms    map[ArmKey]ascache.PolicyStats
+	arms map[ArmKey]ascache.PolicyStats
+	// nodes holds the same counts per replica, for NodeWindow.
+	nodes   map[string]map[ArmKey]ascache.PolicyStats
 	expires time.Time
 }
 
@@ -58,6 +70,8 @@ func NewMemStore() *MemStore {
 		counts:    make(map[bucketKey]*bucketEntry),
 		leaders:   make(map[bucketKey]entry[string]),
 		decisions: make(map[bucketKey]entry[ascache.PolicyType]),
+		regimes:   make(map[string]map[string]entry[string]),
+		pins:      make(map[string]ascache.PolicyType),
 	}
 }
 
@@ -103,19 +117,26 @@ func (s *MemStore) Sync(ctx context.Context, req SyncRequest) (SyncResult, error
 	if len(req.Counts) > 0 {
 		state, ok := s.counts[key]
 		if !ok {
-			state = &bucketEntry{arms: make(map[ArmKey]ascache.PolicyStats)}
+			state = &bucketEntry{
+				arms:  make(map[ArmKey]ascache.PolicyStats),
+				nodes: make(map[string]map[ArmKey]ascache.PolicyStats),
+			}
 			s.counts[key] = state
 		}
 		// The TTL is refreshed by every writer, so a bucket outlives its last
 		// contribution rather than its first.
 		state.expires = now.Add(req.CounterTTL)
 
+		node := state.nodes[req.NodeID]
+		if node == nil {
+			node = make(map[ArmKey]ascache.PolicyStats, len(req.Counts))
+			state.nodes[req.NodeID] = node
+		}
+
 		for _, count := range req.Counts {
 			armKey := ArmKey{Policy: count.Policy, Role: count.Role}
-			stats := state.arms[armKey]
-			stats.Hits += count.Hits
-			stats.Misses += count.Misses
-			state.arms[armKey] = stats
+			addCounts(state.arms, armKey, count)
+			addCounts(node, armKey, count)
 		}
 	}
 
@@ -172,6 +193,39 @@ func (s *MemStore) Window(ctx context.Context, namespace string, first, last Buc
 	return window, nil
 }
 
+// NodeWindow returns the buckets in [first, last] that hold counts, per
+// replica, omitting the same buckets Window omits.
+func (s *MemStore) NodeWindow(ctx context.Context, namespace string, first, last Bucket) ([]NodeWindowCounts, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return nil, s.failure
+	}
+
+	s.expireLocked(s.now())
+
+	window := make([]NodeWindowCounts, 0, min(max(0, int(last-first)+1), 64))
+	for bucket := first; bucket <= last; bucket++ {
+		state, ok := s.counts[bucketKey{namespace: namespace, bucket: bucket}]
+		if !ok {
+			continue
+		}
+
+		nodes := make(map[string]map[ArmKey]ascache.PolicyStats, len(state.nodes))
+		for node, arms := range state.nodes {
+			nodes[node] = maps.Clone(arms)
+		}
+		window = append(window, NodeWindowCounts{Bucket: bucket, Nodes: nodes})
+	}
+
+	return window, nil
+}
+
 // Decide publishes a decision for a bucket, leaving any existing one in place,
 // and returns whichever decision is in force.
 func (s *MemStore) Decide(
@@ -207,6 +261,77 @@ func (s *MemStore) Decide(
 	return policy, nil
 }
 
+// Regimes records a node's regime and lists the live ones under the
+// namespace.
+func (s *MemStore) Regimes(ctx context.Context, req RegimeRequest) ([]RegimeCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return nil, s.failure
+	}
+
+	now := s.now()
+	s.expireLocked(now)
+
+	nodes, ok := s.regimes[req.Namespace]
+	if !ok {
+		nodes = make(map[string]entry[string])
+		s.regimes[req.Namespace] = nodes
+	}
+	nodes[req.NodeID] = entry[string]{value: req.Regime, expires: now.Add(req.TTL)}
+
+	replicas := make(map[string]int)
+	for _, announced := range nodes {
+		replicas[announced.value]++
+	}
+
+	return countRegimes(replicas), nil
+}
+
+// SetPin pins the namespace to policy, or lifts its pin when policy is
+// ascache.Undefined.
+func (s *MemStore) SetPin(ctx context.Context, namespace string, policy ascache.PolicyType) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	if policy == ascache.Undefined {
+		delete(s.pins, namespace)
+	} else {
+		s.pins[namespace] = policy
+	}
+
+	return nil
+}
+
+// Pin returns the namespace's pin, or ascache.Undefined.
+func (s *MemStore) Pin(ctx context.Context, namespace string) (ascache.PolicyType, error) {
+	if err := ctx.Err(); err != nil {
+		return ascache.Undefined, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return ascache.Undefined, s.failure
+	}
+
+	return s.pins[namespace], nil
+}
+
 // Close discards everything the store holds.
 func (s *MemStore) Close() error {
 	s.mu.Lock()
@@ -215,10 +340,19 @@ func (s *MemStore) Close() error {
 	clear(s.counts)
 	clear(s.leaders)
 	clear(s.decisions)
+	clear(s.regimes)
+	clear(s.pins)
 
 	return nil
 }
 
+func addCounts(arms map[ArmKey]ascache.PolicyStats, key ArmKey, count ArmCounts) {
+	stats := arms[key]
+	stats.Hits += count.Hits
+	stats.Misses += count.Misses
+	arms[key] = stats
+}
+
 // expireLocked drops everything past its TTL. A real store does this itself;
 // doing it here keeps a long-running simulation from growing without bound and
 // keeps the two implementations behaving the same when a leader reads back
@@ -239,6 +373,16 @@ func (s *MemStore) expireLocked(now time.Time) {
 			delete(s.decisions, key)
 		}
 	}
+	for namespace, nodes := range s.regimes {
+		for node, announced := range nodes {
+			if now.After(announced.expires) {
+				delete(nodes, node)
+			}
+		}
+		if len(nodes) == 0 {
+			delete(s.regimes, namespace)
+		}
+	}
 }
 
 // bucketAt divides the store's clock into coordination epochs. Every replica
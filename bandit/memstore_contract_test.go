@@ -0,0 +1,12 @@
+package bandit_test
+
+import (
+	"testing"
+
+	"github.com/sshaplygin/as-cache/bandit"
+	"github.com/sshaplygin/as-cache/bandit/storetest"
+)
+
+func TestMemStore_Contract(t *testing.T) {
+	storetest.Run(t, func() bandit.Store { return bandit.NewMemStore() })
+}
@@ -0,0 +1,119 @@
+package bandit
+
+import (
+	"context"
+	"errors"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// PinStore is a Store that can also hold a fleet-wide pin: one policy every
+// replica under a namespace serves, whatever the bandit selects, until the pin
+// is lifted.
+//
+// A pin set on one cache with AdaptiveCache.Pin holds that cache and no other,
+// which during an incident means finding and pinning every replica - and
+// missing the ones that restart halfway through. A pin in the store is set
+// once, is read by every replica on its next sync, and is still there for a
+// replica that starts after it was set.
+//
+// It is optional. Distributed.Pin reports ErrPinUnsupported over a store that
+// does not implement it, and a replica over such a store is never pinned by
+// the fleet.
+type PinStore interface {
+	Store
+
+	// SetPin pins every replica under namespace to policy, replacing any pin
+	// already set, or lifts the pin when policy is ascache.Undefined. A pin
+	// does not expire: lifting it is an operator's decision, and one that
+	// lapsed on its own would hand an incident back to the bandit
+	// unannounced.
+	//
+	// The namespace is the one the fleet configured, not the fingerprinted
+	// one Sync is given, so a pin holds every regime sharing the name, and
+	// what it records must live apart from everything Sync, Window and
+	// Decide keep.
+	SetPin(ctx context.Context, namespace string, policy ascache.PolicyType) error
+
+	// Pin returns the policy pinned under namespace, or ascache.Undefined
+	// when there is none.
+	Pin(ctx context.Context, namespace string) (ascache.PolicyType, error)
+}
+
+// ErrPinUnsupported is returned by Distributed.Pin and Distributed.Unpin when
+// the store does not implement PinStore.
+var ErrPinUnsupported = errors.New("bandit: a fleet-wide pin needs a store that implements PinStore")
+
+// Pin pins every replica coordinating under Config.Namespace to policy: each
+// one serves it from its next sync, and ignores what the bandit selects until
+// Unpin. This replica is pinned at once, without waiting for a sync.
+//
+// Replicas go on measuring and pooling while pinned, so the fleet's evidence
+// is current the moment the pin is lifted. A replica whose cache does not
+// hold policy is not pinned, and selects as before; a pin set on the cache
+// itself, with AdaptiveCache.Pin, takes precedence over this one. A cache in
+// ObserveOnly mode follows the pin as it would AdaptiveCache.Pin: observing
+// stops it acting on the bandit, not on an operator.
+//
+// It returns ErrPinUnsupported when the store does not implement PinStore.
+func (d *Distributed) Pin(ctx context.Context, policy ascache.PolicyType) error {
+	return d.setPin(ctx, policy)
+}
+
+// Unpin lifts the fleet-wide pin. Every replica hands selection back to the
+// bandit from its next sync, this one at once.
+//
+// It returns ErrPinUnsupported when the store does not implement PinStore.
+func (d *Distributed) Unpin(ctx context.Context) error {
+	return d.setPin(ctx, ascache.Undefined)
+}
+
+func (d *Distributed) setPin(ctx context.Context, policy ascache.PolicyType) error {
+	store, ok := d.cfg.Store.(PinStore)
+	if !ok {
+		return ErrPinUnsupported
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.SyncTimeout)
+	defer cancel()
+
+	if err := store.SetPin(ctx, d.cfg.Namespace, policy); err != nil {
+		return err
+	}
+	d.pinned.Store(uint64(policy))
+
+	return nil
+}
+
+// Pinned reports the fleet-wide pin, as last read from the store, when this
+// replica's cache holds the pinned policy. It implements
+// [ascache.PinningBandit], and like SelectPolicy it never waits on the store.
+func (d *Distributed) Pinned() (ascache.PolicyType, bool) {
+	// Only a PolicyType is ever stored here, as with selection.
+	policy := ascache.PolicyType(d.pinned.Load())
+	if policy == ascache.Undefined {
+		return ascache.Undefined, false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return policy, d.knownArmLocked(policy)
+}
+
+// readPin reads the fleet-wide pin, on every sync. A failure leaves the pin
+// as it was, for the same reason a failed regime announcement does: a replica
+// that cannot read the pin this round is better off holding the last answer
+// than dropping a pin mid-incident.
+func (d *Distributed) readPin(ctx context.Context) {
+	store, ok := d.cfg.Store.(PinStore)
+	if !ok {
+		return
+	}
+
+	policy, err := store.Pin(ctx, d.cfg.Namespace)
+	if err != nil {
+		return
+	}
+	d.pinned.Store(uint64(policy))
+}
@@ -0,0 +1,67 @@
+package bandit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+func TestDistributed_PinReachesTheWholeFleet(t *testing.T) {
+	store, clock := newFleetStore(t)
+	replicas := fleet(t, 2, store, clock, nil)
+	rates := map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6}
+
+	round := func() {
+		for _, r := range replicas {
+			r.report(t, 100, rates)
+			r.bandit.sync()
+		}
+		clock.advance(testEpoch)
+	}
+	round()
+
+	require.NoError(t, replicas[0].bandit.Pin(t.Context(), ascache.TinyLFU))
+	policy, ok := replicas[0].bandit.Pinned()
+	assert.True(t, ok, "the replica that set the pin is pinned at once")
+	assert.Equal(t, ascache.TinyLFU, policy)
+	_, ok = replicas[1].bandit.Pinned()
+	assert.False(t, ok, "the rest hear of it at their next sync")
+
+	round()
+	policy, ok = replicas[1].bandit.Pinned()
+	assert.True(t, ok)
+	assert.Equal(t, ascache.TinyLFU, policy)
+	assert.Equal(t, "TinyLFU", replicas[1].bandit.Snapshot().Pinned)
+	assert.Contains(t, replicas[1].bandit.Snapshot().String(), "PINNED to TinyLFU")
+
+	require.NoError(t, replicas[1].bandit.Unpin(t.Context()))
+	round()
+	for _, r := range replicas {
+		_, ok := r.bandit.Pinned()
+		assert.False(t, ok)
+		assert.Empty(t, r.bandit.Snapshot().Pinned)
+	}
+}
+
+func TestDistributed_PinIsIgnoredByAReplicaWithoutTheArm(t *testing.T) {
+	store, clock := newFleetStore(t)
+	replicas := fleet(t, 1, store, clock, nil)
+	replicas[0].report(t, 100, map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6})
+
+	require.NoError(t, replicas[0].bandit.Pin(t.Context(), ascache.ARC))
+	_, ok := replicas[0].bandit.Pinned()
+	assert.False(t, ok)
+}
+
+func TestDistributed_PinNeedsAPinStore(t *testing.T) {
+	store, clock := newFleetStore(t)
+	// Embedding the interface rather than the MemStore hides every optional
+	// method.
+	replicas := fleet(t, 1, struct{ Store }{store}, clock, nil)
+
+	assert.ErrorIs(t, replicas[0].bandit.Pin(t.Context(), ascache.LRU), ErrPinUnsupported)
+	assert.ErrorIs(t, replicas[0].bandit.Unpin(t.Context()), ErrPinUnsupported)
+}
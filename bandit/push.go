@@ -0,0 +1,136 @@
+package bandit
+
+import (
+	"context"
+	"errors"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// PushStore is a Store that can also deliver decisions to followers the moment
+// a leader publishes them.
+//
+// Under ModeLeader a follower learns the bucket's decision from its own next
+// sync, so one that syncs before the leader has decided keeps the previous
+// policy for the rest of the coordination epoch. A push closes that gap. It is
+// only ever a shortcut: a follower that misses one - it was reconnecting, or
+// the store dropped the message - reads the same decision from Sync a bucket
+// later, as it always has, and Decide remains the one source of truth.
+//
+// It is optional, and a store that implements it may still be configured not
+// to publish, in which case Decisions reports ErrNoPush and the replica does
+// not ask again.
+type PushStore interface {
+	Store
+
+	// Decisions delivers every decision published under namespace from now
+	// on, until ctx is done, and then closes the channel. Delivery is best
+	// effort: decisions may be missed, and never arrive out of bucket order
+	// from any one leader.
+	Decisions(ctx context.Context, namespace string) (<-chan PushedDecision, error)
+}
+
+// PushedDecision is one decision as a leader published it.
+type PushedDecision struct {
+	Bucket Bucket
+	Policy ascache.PolicyType
+}
+
+// ErrNoPush is returned by PushStore.Decisions when the store is not
+// publishing decisions at all.
+var ErrNoPush = errors.New("bandit: store is not configured to push decisions")
+
+// subscription is the push feed for one namespace.
+type subscription struct {
+	namespace string
+	decisions <-chan PushedDecision
+	cancel    context.CancelFunc
+}
+
+// subscribe keeps this replica subscribed to pushed decisions for the
+// namespace it is syncing under, and returns the feed to wait on - nil when
+// there is none, which a select simply never chooses.
+//
+// It runs on the coordination goroutine after every sync, so a replica whose
+// namespace changed moves its subscription with it, and one whose
+// subscription failed tries again a coordination epoch later.
+func (d *Distributed) subscribe() <-chan PushedDecision {
+	store, ok := d.cfg.Store.(PushStore)
+	if !ok || d.cfg.Mode != ModeLeader {
+		return nil
+	}
+
+	d.mu.Lock()
+	namespace := d.namespace
+	d.mu.Unlock()
+
+	sub := d.push
+	if sub != nil && sub.namespace == namespace {
+		return sub.decisions
+	}
+	if sub != nil {
+		sub.cancel()
+		d.push = nil
+	}
+	if namespace == "" || d.noPush {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	decisions, err := store.Decisions(ctx, namespace)
+	if err != nil {
+		cancel()
+		d.noPush = errors.Is(err, ErrNoPush)
+
+		return nil
+	}
+
+	d.push = &subscription{namespace: namespace, decisions: decisions, cancel: cancel}
+
+	d.mu.Lock()
+	d.state.subscribed = true
+	d.mu.Unlock()
+
+	return decisions
+}
+
+// dropSubscription forgets a feed whose channel has closed, so the next
+// subscribe opens a new one.
+func (d *Distributed) dropSubscription() {
+	if d.push != nil {
+		d.push.cancel()
+		d.push = nil
+	}
+
+	d.mu.Lock()
+	d.state.subscribed = false
+	d.mu.Unlock()
+}
+
+// applyPushed applies a decision delivered ahead of this replica's own sync.
+// A push for a bucket older than the one this replica last synced in is a
+// decision the fleet has already moved on from, and one for a policy this
+// cache does not have is refused exactly as a synced one would be.
+func (d *Distributed) applyPushed(pushed PushedDecision) {
+	if d.foldPushed(pushed) {
+		d.logRefused(pushed.Bucket, pushed.Policy)
+	}
+}
+
+// foldPushed is applyPushed under mu. It reports whether it refused the
+// decision.
+func (d *Distributed) foldPushed(pushed PushedDecision) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.state.fallback || pushed.Bucket < d.state.lastBucket {
+		return false
+	}
+
+	applied, refused := d.applyDecisionLocked(pushed.Bucket, pushed.Policy)
+	if applied {
+		d.state.pushed++
+	}
+
+	return refused
+}
@@ -0,0 +1,170 @@
+package bandit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// pushStore is a MemStore that pushes every new decision to its subscribers,
+// or, with disabled set, reports that it does not.
+type pushStore struct {
+	*MemStore
+
+	disabled bool
+
+	mu    sync.Mutex
+	subs  map[string][]chan PushedDecision
+	calls int
+}
+
+func newPushStore(t *testing.T) (*pushStore, *testClock) {
+	t.Helper()
+
+	store, clock := newFleetStore(t)
+
+	return &pushStore{MemStore: store, subs: make(map[string][]chan PushedDecision)}, clock
+}
+
+func (s *pushStore) Decide(
+	ctx context.Context,
+	namespace string,
+	bucket Bucket,
+	policy ascache.PolicyType,
+	ttl time.Duration,
+) (ascache.PolicyType, error) {
+	inForce, err := s.MemStore.Decide(ctx, namespace, bucket, policy, ttl)
+	if err != nil || inForce != policy {
+		return inForce, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sub := range s.subs[namespace] {
+		select {
+		case sub <- PushedDecision{Bucket: bucket, Policy: policy}:
+		default:
+		}
+	}
+
+	return inForce, nil
+}
+
+func (s *pushStore) Decisions(ctx context.Context, namespace string) (<-chan PushedDecision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.disabled {
+		return nil, ErrNoPush
+	}
+
+	sub := make(chan PushedDecision, 8)
+	s.subs[namespace] = append(s.subs[namespace], sub)
+
+	go func() {
+		<-ctx.Done()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for i, other := range s.subs[namespace] {
+			if other == sub {
+				s.subs[namespace] = append(s.subs[namespace][:i], s.subs[namespace][i+1:]...)
+				break
+			}
+		}
+		close(sub)
+	}()
+
+	return sub, nil
+}
+
+func TestDistributed_AppliesAPushedDecisionBeforeItsNextSync(t *testing.T) {
+	store, clock := newPushStore(t)
+	follower := fleet(t, 1, store, clock, nil)[0]
+	follower.report(t, 100, map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6})
+
+	// Another replica has claimed the bucket and not decided yet, so the
+	// follower's sync finds no decision.
+	namespace := follower.bandit.namespace
+	claimed, err := store.Sync(t.Context(), SyncRequest{
+		Namespace:   namespace,
+		NodeID:      "leader",
+		EpochMillis: testEpoch.Milliseconds(),
+		LeaderTTL:   time.Minute,
+		Lead:        true,
+	})
+	require.NoError(t, err)
+	require.True(t, claimed.Leader)
+
+	follower.bandit.sync()
+	assert.Equal(t, ascache.Undefined, follower.bandit.SelectPolicy())
+
+	feed := follower.bandit.subscribe()
+	require.NotNil(t, feed)
+
+	_, err = store.Decide(t.Context(), namespace, claimed.Bucket, ascache.TinyLFU, time.Minute)
+	require.NoError(t, err)
+
+	select {
+	case decision := <-feed:
+		follower.bandit.applyPushed(decision)
+	case <-time.After(time.Second):
+		t.Fatal("no decision was pushed")
+	}
+	assert.Equal(t, ascache.TinyLFU, follower.bandit.SelectPolicy())
+
+	// The sync that would have delivered it reads it again, and it counts once.
+	follower.report(t, 100, map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6})
+	follower.bandit.sync()
+
+	snapshot := follower.bandit.Snapshot()
+	assert.True(t, snapshot.Subscribed)
+	assert.Equal(t, int64(1), snapshot.Pushed)
+	assert.Equal(t, int64(1), snapshot.Decisions)
+	assert.Contains(t, snapshot.String(), "1 pushed")
+}
+
+func TestDistributed_IgnoresAPushForABucketItHasPassed(t *testing.T) {
+	store, clock := newPushStore(t)
+	follower := fleet(t, 1, store, clock, nil)[0]
+	follower.report(t, 100, map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6})
+	follower.bandit.sync()
+
+	follower.bandit.applyPushed(PushedDecision{Bucket: follower.bandit.state.lastBucket - 1, Policy: ascache.TinyLFU})
+
+	assert.NotEqual(t, ascache.TinyLFU, follower.bandit.SelectPolicy())
+	assert.Zero(t, follower.bandit.Snapshot().Pushed)
+}
+
+func TestDistributed_StopsAskingAStoreThatDoesNotPush(t *testing.T) {
+	store, clock := newPushStore(t)
+	store.disabled = true
+	replica := fleet(t, 1, store, clock, nil)[0]
+	replica.report(t, 100, map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6})
+
+	for range 3 {
+		replica.bandit.sync()
+		assert.Nil(t, replica.bandit.subscribe())
+	}
+
+	assert.Equal(t, 1, store.calls)
+	assert.False(t, replica.bandit.Snapshot().Subscribed)
+}
+
+func TestDistributed_SharedPosteriorDoesNotSubscribe(t *testing.T) {
+	store, clock := newPushStore(t)
+	replica := fleet(t, 1, store, clock, func(cfg *Config) { cfg.Mode = ModeSharedPosterior })[0]
+	replica.report(t, 100, map[ascache.PolicyType]float64{ascache.LRU: 0.5, ascache.TinyLFU: 0.6})
+
+	assert.Nil(t, replica.bandit.subscribe())
+	assert.Zero(t, store.calls)
+}
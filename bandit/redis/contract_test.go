@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+	"github.com/sshaplygin/as-cache/bandit/storetest"
+)
+
+func TestStore_Contract(t *testing.T) {
+	storetest.Run(t, func() bandit.Store {
+		store, _, server := newStore(t)
+		if server == nil {
+			// A real server's clock cannot be driven, so the suite skips the
+			// rules about time; TestStore_CountersExpire waits one out.
+			return store
+		}
+
+		return &clockedStore{Store: store, server: server}
+	})
+}
+
+// clockedStore drives miniredis from the suite's clock. The scripts read TIME,
+// which miniredis answers from SetTime, and keys expire as FastForward moves
+// them on, so both have to follow the clock before every call.
+type clockedStore struct {
+	*Store
+	server *miniredis.Miniredis
+
+	mu   sync.Mutex
+	now  func() time.Time
+	last time.Time
+}
+
+func (s *clockedStore) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+	s.last = now()
+	s.server.SetTime(s.last)
+}
+
+func (s *clockedStore) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.now == nil {
+		return
+	}
+
+	now := s.now()
+	if elapsed := now.Sub(s.last); elapsed > 0 {
+		s.server.FastForward(elapsed)
+	}
+	s.last = now
+	s.server.SetTime(now)
+}
+
+func (s *clockedStore) Sync(ctx context.Context, req bandit.SyncRequest) (bandit.SyncResult, error) {
+	s.tick()
+
+	return s.Store.Sync(ctx, req)
+}
+
+func (s *clockedStore) Window(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.WindowCounts, error) {
+	s.tick()
+
+	return s.Store.Window(ctx, namespace, first, last)
+}
+
+func (s *clockedStore) Decide(
+	ctx context.Context,
+	namespace string,
+	bucket bandit.Bucket,
+	policy ascache.PolicyType,
+	ttl time.Duration,
+) (ascache.PolicyType, error) {
+	s.tick()
+
+	return s.Store.Decide(ctx, namespace, bucket, policy, ttl)
+}
+
+func (s *clockedStore) Regimes(ctx context.Context, req bandit.RegimeRequest) ([]bandit.RegimeCount, error) {
+	s.tick()
+
+	return s.Store.Regimes(ctx, req)
+}
+
+func (s *clockedStore) NodeWindow(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.NodeWindowCounts, error) {
+	s.tick()
+
+	return s.Store.NodeWindow(ctx, namespace, first, last)
+}
@@ -33,6 +33,18 @@
 // key slot, which is not much - but it is the number to check before running a
 // coordination epoch faster than a second.
 //
+// # Pushing decisions
+//
+// A follower normally learns a bucket's decision from its next sync, so one
+// that synced just before its leader decided runs the previous policy for
+// the rest of the coordination epoch. With Options.PushDecisions set, the
+// script that publishes a decision also PUBLISHes it on a channel per
+// namespace, and bandit.Distributed subscribes to it and applies it the
+// moment it arrives. It costs one message per bucket and a subscribed
+// connection per replica. Pub/sub delivers at most once, and that is enough:
+// the decision key is still the source of truth, and a replica that missed a
+// push reads it from Sync as before.
+//
 // # Requirements
 //
 // Redis 7.0 or Valkey 7.2 and above. Buckets are derived from the server's
@@ -35,14 +35,67 @@ func (s *Store) countsKey(namespace string, bucket bandit.Bucket) string {
 	return s.keyBase(namespace) + ":c:" + strconv.FormatInt(int64(bucket), 10)
 }
 
+// nodesKey holds one bucket's per-node counters as a hash, each field a node
+// ID, a colon and a countField. It must match the expression the Lua builds.
+func (s *Store) nodesKey(namespace string, bucket bandit.Bucket) string {
+	return s.keyBase(namespace) + ":n:" + strconv.FormatInt(int64(bucket), 10)
+}
+
+// pushChannel is the pub/sub channel a namespace's decisions are pushed on.
+// Channels are not keys and Cluster broadcasts them to every node, so the
+// hash tag here only keeps the name in step with the keys.
+func (s *Store) pushChannel(namespace string) string {
+	return s.keyBase(namespace) + ":push"
+}
+
+// parsePush reads a pushed decision, "<bucket>:<policy>". A message this
+// process cannot read - another version's format, or a policy it does not
+// have - is skipped: the decision is still there for Sync to find.
+func parsePush(payload string) (bandit.PushedDecision, bool) {
+	bucket, policy, ok := strings.Cut(payload, ":")
+	if !ok {
+		return bandit.PushedDecision{}, false
+	}
+
+	n, err := strconv.ParseInt(bucket, 10, 64)
+	if err != nil {
+		return bandit.PushedDecision{}, false
+	}
+
+	decoded, ok := bandit.DecodePolicy(policy)
+	if !ok {
+		return bandit.PushedDecision{}, false
+	}
+
+	return bandit.PushedDecision{Bucket: bandit.Bucket(n), Policy: decoded}, true
+}
+
+// regimeKeys are the sorted set of announcement expiries and the hash of
+// announced regimes for a namespace as configured. No replica syncs under
+// that namespace - they sync under it with a fingerprint appended - so these
+// share a slot with nothing else, and the suffix keeps them clear of the
+// counter and decision keys regardless.
+func (s *Store) regimeKeys(namespace string) (expiries, regimes string) {
+	base := s.keyBase(namespace) + ":r:"
+
+	return base + "z", base + "h"
+}
+
+// pinKey holds the fleet-wide pin for a namespace as configured, apart from
+// the counter and decision keys for the same reason the regime keys are.
+func (s *Store) pinKey(namespace string) string {
+	return s.keyBase(namespace) + ":pin"
+}
+
 // countField names one counter within a bucket's hash.
 //
-// The policy is written as its numeric PolicyType rather than its name. Names
-// come from stringer and are a presentation detail: renaming one would
-// silently split a fleet's counters in two, with every replica still reporting
-// and none of them agreeing, and nothing in the data would show why. The role
-// is "a" for active or "s" for shadow, and the suffix is "h" for hits or "m"
-// for misses.
+// The policy is written by bandit.EncodePolicy: a built-in as its number, a
+// registered type or variant by name. A built-in's name is a presentation
+// detail, and renaming one would silently split a fleet's counters in two,
+// with every replica still reporting and none of them agreeing; a registered
+// type's number is allocated per process and means nothing to any other.
+// Neither form contains a colon. The role is "a" for active or "s" for
+// shadow, and the suffix is "h" for hits or "m" for misses.
 func countField(policy ascache.PolicyType, role bandit.Role, hits bool) string {
 	kind := "m"
 	if hits {
@@ -54,20 +107,37 @@ func countField(policy ascache.PolicyType, role bandit.Role, hits bool) string {
 		roleTag = "a"
 	}
 
-	return strconv.FormatUint(uint64(policy), 10) + ":" + roleTag + ":" + kind
+	return bandit.EncodePolicy(policy) + ":" + roleTag + ":" + kind
+}
+
+// parseNodeField splits a per-node field into the node ID and the countField
+// after it. The count field is the last three colon-separated parts and holds
+// no colon of its own, so a node ID may contain anything, colons included.
+func parseNodeField(field string) (node, count string, ok bool) {
+	end := len(field)
+	for range 3 {
+		end = strings.LastIndexByte(field[:end], ':')
+		if end < 0 {
+			return "", "", false
+		}
+	}
+
+	return field[:end], field[end+1:], true
 }
 
 // parseCountField reverses countField. An unrecognised field is reported as
 // not ok rather than as an error: the store may be shared, and a stray field
 // written by something else must not stop a fleet reading its own counters.
+// That includes a name this process never registered, which can only have
+// come from a replica running different arms.
 func parseCountField(field string) (policy ascache.PolicyType, role bandit.Role, hits, ok bool) {
 	parts := strings.Split(field, ":")
 	if len(parts) != 3 {
 		return 0, 0, false, false
 	}
 
-	number, err := strconv.ParseUint(parts[0], 10, 64)
-	if err != nil {
+	policy, ok = bandit.DecodePolicy(parts[0])
+	if !ok {
 		return 0, 0, false, false
 	}
 
@@ -89,5 +159,5 @@ func parseCountField(field string) (policy ascache.PolicyType, role bandit.Role,
 		return 0, 0, false, false
 	}
 
-	return ascache.PolicyType(number), role, hits, true
+	return policy, role, hits, true
 }
@@ -14,6 +14,10 @@ import goredis "github.com/redis/go-redis/v9"
 // window nobody reads while dragging the fleet's view of its traffic with it.
 // Asking the server means there is one clock and no agreement to reach.
 //
+// Every count is written twice: to the bucket's summed hash, which Window
+// reads, and to its per-node hash, which NodeWindow reads, under the field
+// name prefixed with the node ID.
+//
 //	KEYS[1]    anchor key, present so Cluster routes the script to the slot
 //	           the computed keys live in
 //	ARGV[1]    key base, including the hash tag: "asc:{namespace}"
@@ -33,12 +37,16 @@ local bucket = math.floor(nowMs / epoch)
 
 if #ARGV >= 7 then
 	local countsKey = base .. ':c:' .. bucket
+	local nodesKey = base .. ':n:' .. bucket
+	local node = ARGV[5] .. ':'
 	for i = 7, #ARGV, 2 do
 		redis.call('HINCRBY', countsKey, ARGV[i], ARGV[i + 1])
+		redis.call('HINCRBY', nodesKey, node .. ARGV[i], ARGV[i + 1])
 	end
 	-- Refreshed by every writer, so a bucket outlives its last contribution
 	-- rather than its first.
 	redis.call('PEXPIRE', countsKey, ARGV[3])
+	redis.call('PEXPIRE', nodesKey, ARGV[3])
 end
 
 local leader = 0
@@ -72,12 +80,53 @@ return {bucket, leader, decision}
 //	ARGV[2] bucket
 //	ARGV[3] policy
 //	ARGV[4] decision TTL in milliseconds
+//	ARGV[5] channel to push the decision on
+//	ARGV[6] "1" to push it
+//
+// Only the call that publishes the decision pushes it, so followers hear of
+// each decision once, and never of a draw that lost the race to publish.
 var decideScript = goredis.NewScript(`
 local key = ARGV[1] .. ':d:' .. ARGV[2]
 
 if redis.call('SET', key, ARGV[3], 'NX', 'PX', ARGV[4]) then
+	if ARGV[6] == '1' then
+		redis.call('PUBLISH', ARGV[5], ARGV[2] .. ':' .. ARGV[3])
+	end
 	return ARGV[3]
 end
 
 return redis.call('GET', key)
 `)
+
+// regimesScript records one replica's regime announcement and returns every
+// live one under the namespace, as alternating node and regime.
+//
+// The expiries are kept in a sorted set beside the hash of regimes, rather
+// than as a TTL per key, so one call can both drop the expired announcements
+// and read the rest. Both keys are refreshed by every announcement, so they
+// outlive the last replica to announce and no longer.
+//
+//	KEYS[1] sorted set of node ids scored by expiry in milliseconds
+//	KEYS[2] hash of node id to regime
+//	ARGV[1] node id
+//	ARGV[2] regime
+//	ARGV[3] announcement TTL in milliseconds
+var regimesScript = goredis.NewScript(`
+local t = redis.call('TIME')
+local nowMs = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local ttl = tonumber(ARGV[3])
+
+redis.call('ZADD', KEYS[1], nowMs + ttl, ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+
+local gone = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', '(' .. nowMs)
+for _, node in ipairs(gone) do
+	redis.call('HDEL', KEYS[2], node)
+end
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', '(' .. nowMs)
+
+redis.call('PEXPIRE', KEYS[1], ttl)
+redis.call('PEXPIRE', KEYS[2], ttl)
+
+return redis.call('HGETALL', KEYS[2])
+`)
@@ -1,9 +1,11 @@
 package redis
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"time"
 
@@ -13,7 +15,12 @@ import (
 	"github.com/sshaplygin/as-cache/bandit"
 )
 
-var _ bandit.Store = (*Store)(nil)
+var (
+	_ bandit.RegimeStore      = (*Store)(nil)
+	_ bandit.ContributorStore = (*Store)(nil)
+	_ bandit.PushStore        = (*Store)(nil)
+	_ bandit.PinStore         = (*Store)(nil)
+)
 
 // ErrNilClient is returned by New when Options.Client is nil.
 var ErrNilClient = errors.New("redis: client must not be nil")
@@ -31,12 +38,21 @@ type Options struct {
 	// KeyPrefix begins every key this store writes. Defaults to
 	// DefaultKeyPrefix.
 	KeyPrefix string
+
+	// PushDecisions publishes every new decision on a pub/sub channel as it
+	// is made, so followers subscribed through Decisions apply it at once
+	// instead of at their next sync. It costs one PUBLISH per bucket and one
+	// subscribed connection per replica; a replica that misses a message
+	// still reads the decision from its next sync, so nothing depends on
+	// delivery.
+	PushDecisions bool
 }
 
 // Store implements bandit.Store over Valkey or Redis.
 type Store struct {
 	client goredis.UniversalClient
 	prefix string
+	push   bool
 }
 
 // New returns a Store over the given client. It does not take ownership of the
@@ -51,7 +67,7 @@ func New(opts Options) (*Store, error) {
 		prefix = DefaultKeyPrefix
 	}
 
-	return &Store{client: opts.Client, prefix: prefix}, nil
+	return &Store{client: opts.Client, prefix: prefix, push: opts.PushDecisions}, nil
 }
 
 // Sync publishes one replica's counts, claims the bucket if asked and if it is
@@ -192,6 +208,70 @@ func (s *Store) Window(
 	return window, nil
 }
 
+// NodeWindow reads the per-node counts for a range of buckets, pipelined into
+// one round trip, omitting the buckets Window omits.
+func (s *Store) NodeWindow(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.NodeWindowCounts, error) {
+	if first > last {
+		return nil, nil
+	}
+
+	buckets := make([]bandit.Bucket, 0, last-first+1)
+	commands := make([]*goredis.MapStringStringCmd, 0, last-first+1)
+
+	pipe := s.client.Pipeline()
+	for bucket := first; bucket <= last; bucket++ {
+		buckets = append(buckets, bucket)
+		commands = append(commands, pipe.HGetAll(ctx, s.nodesKey(namespace, bucket)))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, goredis.Nil) {
+		return nil, fmt.Errorf("redis: node window: %w", err)
+	}
+
+	window := make([]bandit.NodeWindowCounts, 0, len(commands))
+	for i, cmd := range commands {
+		fields, err := cmd.Result()
+		if err != nil {
+			if errors.Is(err, goredis.Nil) {
+				continue
+			}
+
+			return nil, fmt.Errorf("redis: node window: bucket %d: %w", buckets[i], err)
+		}
+
+		// Grouped by node first, then parsed as a bucket hash each.
+		grouped := make(map[string]map[string]string)
+		for field, value := range fields {
+			node, count, ok := parseNodeField(field)
+			if !ok {
+				continue
+			}
+			if grouped[node] == nil {
+				grouped[node] = make(map[string]string)
+			}
+			grouped[node][count] = value
+		}
+
+		nodes := make(map[string]map[bandit.ArmKey]ascache.PolicyStats, len(grouped))
+		for node, counts := range grouped {
+			if arms := parseArms(counts); len(arms) > 0 {
+				nodes[node] = arms
+			}
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+
+		window = append(window, bandit.NodeWindowCounts{Bucket: buckets[i], Nodes: nodes})
+	}
+
+	return window, nil
+}
+
 // parseArms turns a bucket's hash into per-arm counts, skipping anything it
 // does not recognise.
 func parseArms(fields map[string]string) map[bandit.ArmKey]ascache.PolicyStats {
@@ -230,12 +310,19 @@ func (s *Store) Decide(
 	policy ascache.PolicyType,
 	ttl time.Duration,
 ) (ascache.PolicyType, error) {
+	push := "0"
+	if s.push {
+		push = "1"
+	}
+
 	raw, err := decideScript.Run(ctx, s.client,
 		[]string{s.anchorKey(namespace)},
 		s.keyBase(namespace),
 		strconv.FormatInt(int64(bucket), 10),
-		strconv.FormatUint(uint64(policy), 10),
+		bandit.EncodePolicy(policy),
 		strconv.FormatInt(millis(ttl), 10),
+		s.pushChannel(namespace),
+		push,
 	).Result()
 	if err != nil {
 		return ascache.Undefined, fmt.Errorf("redis: decide: %w", err)
@@ -249,17 +336,144 @@ func (s *Store) Decide(
 	return parsePolicy(text)
 }
 
+// Decisions subscribes to the decisions published under namespace, when the
+// store was built with Options.PushDecisions; otherwise it reports
+// bandit.ErrNoPush. The subscription holds a connection of its own for as long
+// as ctx lives, and go-redis re-establishes it if the server drops it, so
+// pushes made while it was down are missed and read from Sync instead.
+func (s *Store) Decisions(ctx context.Context, namespace string) (<-chan bandit.PushedDecision, error) {
+	if !s.push {
+		return nil, bandit.ErrNoPush
+	}
+
+	sub := s.client.Subscribe(ctx, s.pushChannel(namespace))
+	// Waiting for the confirmation is what turns a server that is down into
+	// an error here rather than into a feed that never delivers anything.
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, fmt.Errorf("redis: subscribe: %w", err)
+	}
+
+	decisions := make(chan bandit.PushedDecision, 1)
+	go func() {
+		defer close(decisions)
+		defer func() { _ = sub.Close() }()
+
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				decision, ok := parsePush(message.Payload)
+				if !ok {
+					continue
+				}
+
+				select {
+				case decisions <- decision:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return decisions, nil
+}
+
+// Regimes records a replica's regime announcement and lists the live ones
+// under the namespace, in one round trip. Expiry is judged by the server's
+// clock, as buckets are.
+func (s *Store) Regimes(ctx context.Context, req bandit.RegimeRequest) ([]bandit.RegimeCount, error) {
+	expiries, regimes := s.regimeKeys(req.Namespace)
+	raw, err := regimesScript.Run(ctx, s.client,
+		[]string{expiries, regimes},
+		req.NodeID,
+		req.Regime,
+		strconv.FormatInt(millis(req.TTL), 10),
+	).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: regimes: %w", err)
+	}
+
+	values, ok := raw.([]any)
+	if !ok || len(values)%2 != 0 {
+		return nil, fmt.Errorf("redis: regimes: unexpected reply %v", raw)
+	}
+
+	replicas := make(map[string]int, len(values)/2)
+	for i := 1; i < len(values); i += 2 {
+		regime, ok := values[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("redis: regimes: regime is %T, want string", values[i])
+		}
+		replicas[regime]++
+	}
+
+	counts := make([]bandit.RegimeCount, 0, len(replicas))
+	for regime, n := range replicas {
+		counts = append(counts, bandit.RegimeCount{Regime: regime, Replicas: n})
+	}
+	slices.SortFunc(counts, func(a, b bandit.RegimeCount) int { return cmp.Compare(a.Regime, b.Regime) })
+
+	return counts, nil
+}
+
+// SetPin pins the namespace to policy, or lifts its pin when policy is
+// ascache.Undefined. The pin key is the one key this store writes without a
+// TTL: a pin stands until it is lifted.
+func (s *Store) SetPin(ctx context.Context, namespace string, policy ascache.PolicyType) error {
+	var err error
+	if policy == ascache.Undefined {
+		err = s.client.Del(ctx, s.pinKey(namespace)).Err()
+	} else {
+		err = s.client.Set(ctx, s.pinKey(namespace), bandit.EncodePolicy(policy), 0).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("redis: set pin: %w", err)
+	}
+
+	return nil
+}
+
+// Pin returns the namespace's pin, or ascache.Undefined. A pin naming a policy
+// this process does not know is reported as no pin, which is what it is for
+// a replica without that arm.
+func (s *Store) Pin(ctx context.Context, namespace string) (ascache.PolicyType, error) {
+	text, err := s.client.Get(ctx, s.pinKey(namespace)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return ascache.Undefined, nil
+	}
+	if err != nil {
+		return ascache.Undefined, fmt.Errorf("redis: pin: %w", err)
+	}
+
+	policy, ok := bandit.DecodePolicy(text)
+	if !ok {
+		return ascache.Undefined, nil
+	}
+
+	return policy, nil
+}
+
 // Close releases the store. The client belongs to the caller and is left open.
 func (s *Store) Close() error { return nil }
 
-// parsePolicy reads the numeric PolicyType a decision is stored as.
+// parsePolicy reads a decision, stored as bandit.EncodePolicy wrote it.
+//
+// Unlike a stray counter, a decision this process cannot name is an error: the
+// fleet has agreed on a policy and this replica is unable to follow it.
 func parsePolicy(text string) (ascache.PolicyType, error) {
-	number, err := strconv.ParseUint(text, 10, 64)
-	if err != nil {
-		return ascache.Undefined, fmt.Errorf("redis: decision %q is not a policy: %w", text, err)
+	policy, ok := bandit.DecodePolicy(text)
+	if !ok {
+		return ascache.Undefined, fmt.Errorf("redis: decision %q is not a policy known to this process", text)
 	}
 
-	return ascache.PolicyType(number), nil
+	return policy, nil
 }
 
 // millis rounds a TTL up to at least one millisecond. PEXPIRE with a
@@ -227,6 +227,130 @@ func TestStore_SyncReadsBackAPublishedDecision(t *testing.T) {
 	assert.Equal(t, ascache.TwoQueue, again.Decision)
 }
 
+// pushingStore is a second Store over newStore's client and keys, built to
+// push decisions.
+func pushingStore(t *testing.T) (*Store, *Store) {
+	t.Helper()
+
+	store, client, _ := newStore(t)
+	pushing, err := New(Options{Client: client, KeyPrefix: store.prefix, PushDecisions: true})
+	require.NoError(t, err)
+
+	return store, pushing
+}
+
+func TestStore_PushesEachNewDecisionOnce(t *testing.T) {
+	_, store := pushingStore(t)
+	variant, err := ascache.Variant(ascache.TTL, "30s")
+	require.NoError(t, err)
+
+	decisions, err := store.Decisions(t.Context(), "ns")
+	require.NoError(t, err)
+
+	_, err = store.Decide(t.Context(), "ns", 42, ascache.TinyLFU, time.Minute)
+	require.NoError(t, err)
+	// Losing the race to publish pushes nothing.
+	_, err = store.Decide(t.Context(), "ns", 42, ascache.LRU, time.Minute)
+	require.NoError(t, err)
+	_, err = store.Decide(t.Context(), "other", 42, ascache.LRU, time.Minute)
+	require.NoError(t, err)
+	_, err = store.Decide(t.Context(), "ns", 43, variant, time.Minute)
+	require.NoError(t, err)
+
+	var got []bandit.PushedDecision
+	for range 2 {
+		select {
+		case decision := <-decisions:
+			got = append(got, decision)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("got %d pushes, want 2", len(got))
+		}
+	}
+	assert.Equal(t, []bandit.PushedDecision{
+		{Bucket: 42, Policy: ascache.TinyLFU},
+		{Bucket: 43, Policy: variant},
+	}, got)
+
+	select {
+	case decision := <-decisions:
+		t.Fatalf("unexpected push %+v", decision)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStore_DecisionsCloseWithTheirContext(t *testing.T) {
+	_, store := pushingStore(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	decisions, err := store.Decisions(ctx, "ns")
+	require.NoError(t, err)
+
+	cancel()
+	select {
+	case _, ok := <-decisions:
+		assert.False(t, ok)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the feed outlived its context")
+	}
+}
+
+func TestStore_DoesNotPushUnlessAsked(t *testing.T) {
+	store, pushing := pushingStore(t)
+
+	_, err := store.Decisions(t.Context(), "ns")
+	require.ErrorIs(t, err, bandit.ErrNoPush)
+
+	// A store that does not push publishes nothing for one that listens.
+	decisions, err := pushing.Decisions(t.Context(), "ns")
+	require.NoError(t, err)
+	_, err = store.Decide(t.Context(), "ns", 42, ascache.TinyLFU, time.Minute)
+	require.NoError(t, err)
+
+	select {
+	case decision := <-decisions:
+		t.Fatalf("unexpected push %+v", decision)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestParsePush_SkipsWhatItCannotRead(t *testing.T) {
+	for _, payload := range []string{"", "42", "x:LRU", "42:NoSuchPolicy"} {
+		_, ok := parsePush(payload)
+		assert.False(t, ok, payload)
+	}
+}
+
+func TestStore_VariantsAreStoredByName(t *testing.T) {
+	store, client, _ := newStore(t)
+
+	short, err := ascache.Variant(ascache.TTL, "30s")
+	require.NoError(t, err)
+	long, err := ascache.Variant(ascache.TTL, "5m")
+	require.NoError(t, err)
+
+	result, err := store.Sync(t.Context(), syncRequest("ns", "a",
+		shadow(short, 3, 1), shadow(long, 1, 3)))
+	require.NoError(t, err)
+
+	// The numbers are this process's; another replica registering the same
+	// variants in another order holds different ones. Only names may leave.
+	fields, err := client.HKeys(t.Context(),
+		store.countsKey("ns", result.Bucket)).Result()
+	require.NoError(t, err)
+	assert.Contains(t, fields, "TTL/30s:s:h")
+	assert.Contains(t, fields, "TTL/5m:s:m")
+
+	window, err := store.Window(t.Context(), "ns", result.Bucket, result.Bucket)
+	require.NoError(t, err)
+	require.Len(t, window, 1)
+	assert.Equal(t, int64(3), window[0].Arms[bandit.ArmKey{Policy: short, Role: bandit.RoleShadow}].Hits)
+	assert.Equal(t, int64(3), window[0].Arms[bandit.ArmKey{Policy: long, Role: bandit.RoleShadow}].Misses)
+
+	decided, err := store.Decide(t.Context(), "ns", result.Bucket, long, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, long, decided)
+}
+
 func TestStore_WindowOmitsBucketsThatHoldNothing(t *testing.T) {
 	store, _, _ := newStore(t)
 
@@ -382,10 +506,18 @@ func TestStore_RespectsContextCancellation(t *testing.T) {
 	assert.ErrorIs(t, err, context.Canceled)
 }
 
+// testRegistered stands in for a third-party arm. Registration is permanent,
+// so it happens once, at package level.
+var testRegistered = ascache.RegisterPolicyType("RedisTestClock")
+
 func TestParseCountField_RoundTrips(t *testing.T) {
+	variant, err := ascache.Variant(ascache.TTL, "30s")
+	require.NoError(t, err)
+
 	policies := []ascache.PolicyType{
 		ascache.LRU, ascache.LFU, ascache.TwoQueue,
 		ascache.ARC, ascache.Random, ascache.TTL, ascache.TinyLFU,
+		ascache.LRUK, ascache.LRFU, variant, testRegistered,
 	}
 
 	for _, policy := range policies {
@@ -404,8 +536,23 @@ func TestParseCountField_RoundTrips(t *testing.T) {
 }
 
 func TestParseCountField_RejectsJunk(t *testing.T) {
-	for _, field := range []string{"", "1", "1:s", "1:s:h:x", "x:s:h", "1:z:h", "1:s:z"} {
+	for _, field := range []string{
+		"", "1", "1:s", "1:s:h:x", "x:s:h", "1:z:h", "1:s:z",
+		"99:s:h", "TTL/unregistered:s:h",
+	} {
 		_, _, _, ok := parseCountField(field)
 		assert.False(t, ok, "field %q should not have parsed", field)
 	}
 }
+
+func TestParseNodeField_KeepsColonsInTheNodeID(t *testing.T) {
+	field := "host:6379:" + countField(ascache.LRU, bandit.RoleShadow, true)
+
+	node, count, ok := parseNodeField(field)
+	require.True(t, ok)
+	assert.Equal(t, "host:6379", node)
+	assert.Equal(t, countField(ascache.LRU, bandit.RoleShadow, true), count)
+
+	_, _, ok = parseNodeField("1:s")
+	assert.False(t, ok)
+}
@@ -0,0 +1,127 @@
+package bandit
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+)
+
+// RegimeStore is a Store that can also say which measurement regimes are
+// syncing under a fleet's namespace.
+//
+// Replicas only pool with replicas in the same regime - same arms, same
+// capacity, same sample rate - and they enforce that by syncing under a
+// namespace that carries the regime's fingerprint. That is what keeps a
+// mismatched cache from polluting the fleet's evidence, and it is silent by
+// design: half a fleet deployed with a different capacity is simply two
+// fleets, each pooling half the evidence, and nothing in either one's counts
+// says so. Announcing the regime under the namespace as configured, before
+// fingerprinting, is what lets Snapshot say so instead.
+//
+// It is optional. A store that does not implement it works exactly as
+// before, and Snapshot reports no sibling regimes. Every store in this
+// repository implements it.
+type RegimeStore interface {
+	Store
+
+	// Regimes records that req.NodeID is running req.Regime under
+	// req.Namespace until req.TTL runs out, replacing whatever that node
+	// announced before, and returns every regime with a live announcement
+	// under req.Namespace - this one included - with the number of replicas
+	// announcing each, in regime order.
+	//
+	// The namespace is the one the fleet configured, not the fingerprinted
+	// one Sync is given, so what it records must live apart from everything
+	// Sync, Window and Decide keep.
+	Regimes(ctx context.Context, req RegimeRequest) ([]RegimeCount, error)
+}
+
+// RegimeRequest is one replica's announcement of the regime it is running.
+type RegimeRequest struct {
+	// Namespace is Config.Namespace, without the regime fingerprint.
+	Namespace string
+	NodeID    string
+	// Regime is the regime in the form Snapshot.Regime reports it.
+	Regime string
+	// TTL is how long the announcement stands unless renewed.
+	TTL time.Duration
+}
+
+// RegimeCount is one regime and how many replicas are announcing it.
+type RegimeCount struct {
+	Regime   string `json:"regime"`
+	Replicas int    `json:"replicas"`
+}
+
+// countRegimes turns replicas per regime into the list Regimes returns.
+func countRegimes(replicas map[string]int) []RegimeCount {
+	regimes := make([]RegimeCount, 0, len(replicas))
+	for regime, n := range replicas {
+		regimes = append(regimes, RegimeCount{Regime: regime, Replicas: n})
+	}
+	slices.SortFunc(regimes, func(a, b RegimeCount) int { return cmp.Compare(a.Regime, b.Regime) })
+
+	return regimes
+}
+
+// announceEvery is how many syncs pass between regime announcements. The
+// list is a diagnostic, so it need not be fresh to the epoch, and announcing
+// on every tenth sync keeps it to a tenth of a round trip per epoch.
+const announceEvery = 10
+
+// regimeTTL is how long an announcement stands: long enough to survive two
+// missed announcements, so a replica with a slow store does not blink out of
+// the list, and short enough that one redeployed into a new regime leaves its
+// old one within a few minutes at a one-second epoch.
+func (c *Config) regimeTTL() time.Duration {
+	return 3 * announceEvery * c.CoordinationEpoch
+}
+
+// announceRegime tells the store which regime this replica is running and
+// keeps what it says about the rest of the fleet for Snapshot. It runs on the
+// first sync, every announceEvery syncs after, and on the first sync after
+// the regime changes.
+//
+// A failure is kept out of the sync's own accounting: a store that cannot
+// list regimes can still coordinate, and falling back over a diagnostic would
+// be the tail wagging the dog. The previous list stands until the next
+// announcement succeeds.
+func (d *Distributed) announceRegime(ctx context.Context, namespace string) {
+	store, ok := d.cfg.Store.(RegimeStore)
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	due := d.state.announced != namespace || d.state.syncs%announceEvery == 1
+	current := d.shape.String()
+	d.mu.Unlock()
+
+	if !due {
+		return
+	}
+
+	regimes, err := store.Regimes(ctx, RegimeRequest{
+		Namespace: d.cfg.Namespace,
+		NodeID:    d.cfg.NodeID,
+		Regime:    current,
+		TTL:       d.cfg.regimeTTL(),
+	})
+	if err != nil {
+		return
+	}
+
+	siblings := make([]RegimeCount, 0, len(regimes))
+	for _, r := range regimes {
+		if r.Regime != current {
+			siblings = append(siblings, r)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.state.announced = namespace
+	d.state.siblings = siblings
+}
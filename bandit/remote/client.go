@@ -0,0 +1,318 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+var (
+	_ bandit.RegimeStore      = (*Client)(nil)
+	_ bandit.ContributorStore = (*Client)(nil)
+	_ bandit.PinStore         = (*Client)(nil)
+)
+
+// ErrEmptyURL is returned by New when Options.URL is empty.
+var ErrEmptyURL = errors.New("remote: URL must not be empty")
+
+// ErrInvalidURL is returned by New when Options.URL is not an absolute http
+// or https URL.
+var ErrInvalidURL = errors.New("remote: URL must be an absolute http or https URL")
+
+// Options configures a Client.
+type Options struct {
+	// URL is the coordinator's base URL, such as https://coordinator:8080.
+	URL string
+
+	// Token, when set, is sent as a bearer token on every call, and must
+	// match the token the coordinator was started with.
+	Token string
+
+	// HTTPClient makes the calls. Defaults to a client of the store's own,
+	// with no timeout: every call already carries the deadline Distributed
+	// gives it, Config.SyncTimeout, and a second, shorter one here would only
+	// turn a slow coordinator into a fallback sooner than the bandit asked
+	// for.
+	HTTPClient *http.Client
+}
+
+// Client is a bandit.Store that forwards every call to a coordinator.
+type Client struct {
+	base  string
+	token string
+
+	http  *http.Client
+	owned bool
+}
+
+// New returns a client for the coordinator at opts.URL. It does not contact
+// the coordinator: a replica that starts while the coordinator is down should
+// start, and decide locally until it is back.
+func New(opts Options) (*Client, error) {
+	if opts.URL == "" {
+		return nil, ErrEmptyURL
+	}
+
+	base, err := url.Parse(opts.URL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidURL, opts.URL)
+	}
+
+	c := &Client{
+		base:  strings.TrimSuffix(base.String(), "/"),
+		token: opts.Token,
+		http:  opts.HTTPClient,
+	}
+	if c.http == nil {
+		c.http = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+		c.owned = true
+	}
+
+	return c, nil
+}
+
+// Sync publishes one replica's counts through the coordinator.
+func (c *Client) Sync(ctx context.Context, req bandit.SyncRequest) (bandit.SyncResult, error) {
+	body := syncRequest{
+		Namespace:    req.Namespace,
+		NodeID:       req.NodeID,
+		Counts:       make([]wireCounts, 0, len(req.Counts)),
+		EpochMillis:  req.EpochMillis,
+		CounterTTLMS: req.CounterTTL.Milliseconds(),
+		LeaderTTLMS:  req.LeaderTTL.Milliseconds(),
+		Lead:         req.Lead,
+	}
+	for _, counts := range req.Counts {
+		body.Counts = append(body.Counts, wireCounts{
+			Policy: bandit.EncodePolicy(counts.Policy),
+			Role:   counts.Role.String(),
+			Hits:   counts.Hits,
+			Misses: counts.Misses,
+		})
+	}
+
+	var reply syncReply
+	if err := c.call(ctx, syncPath, body, &reply); err != nil {
+		return bandit.SyncResult{}, fmt.Errorf("remote: sync: %w", err)
+	}
+
+	result := bandit.SyncResult{Bucket: bandit.Bucket(reply.Bucket), Leader: reply.Leader}
+	if reply.Decision != "" {
+		policy, err := parsePolicy(reply.Decision)
+		if err != nil {
+			return bandit.SyncResult{}, fmt.Errorf("remote: sync: %w", err)
+		}
+		result.Decision, result.HasDecision = policy, true
+	}
+
+	return result, nil
+}
+
+// Window reads the fleet's counts for buckets first through last inclusive.
+// Arms this process cannot name are skipped, as every store skips them.
+func (c *Client) Window(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.WindowCounts, error) {
+	var reply windowReply
+	err := c.call(ctx, windowPath, windowRequest{Namespace: namespace, First: int64(first), Last: int64(last)}, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("remote: window: %w", err)
+	}
+
+	window := make([]bandit.WindowCounts, 0, len(reply.Buckets))
+	for _, bucket := range reply.Buckets {
+		window = append(window, bandit.WindowCounts{Bucket: bandit.Bucket(bucket.Bucket), Arms: fromWire(bucket.Arms)})
+	}
+
+	return window, nil
+}
+
+// NodeWindow reads the fleet's counts per replica for buckets first through
+// last inclusive. A coordinator whose backend does not keep them answers with
+// an error.
+func (c *Client) NodeWindow(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.NodeWindowCounts, error) {
+	var reply nodeWindowReply
+	err := c.call(ctx, nodeWindowPath, windowRequest{Namespace: namespace, First: int64(first), Last: int64(last)}, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("remote: node window: %w", err)
+	}
+
+	window := make([]bandit.NodeWindowCounts, 0, len(reply.Buckets))
+	for _, bucket := range reply.Buckets {
+		nodes := make(map[string]map[bandit.ArmKey]ascache.PolicyStats, len(bucket.Nodes))
+		for _, node := range bucket.Nodes {
+			nodes[node.NodeID] = fromWire(node.Arms)
+		}
+
+		window = append(window, bandit.NodeWindowCounts{Bucket: bandit.Bucket(bucket.Bucket), Nodes: nodes})
+	}
+
+	return window, nil
+}
+
+// Decide publishes the policy the fleet should run for a bucket through the
+// coordinator and returns the decision actually in force for it.
+func (c *Client) Decide(
+	ctx context.Context,
+	namespace string,
+	bucket bandit.Bucket,
+	policy ascache.PolicyType,
+	ttl time.Duration,
+) (ascache.PolicyType, error) {
+	body := decideRequest{
+		Namespace: namespace,
+		Bucket:    int64(bucket),
+		Policy:    bandit.EncodePolicy(policy),
+		TTLMS:     ttl.Milliseconds(),
+	}
+
+	var reply decideReply
+	if err := c.call(ctx, decidePath, body, &reply); err != nil {
+		return ascache.Undefined, fmt.Errorf("remote: decide: %w", err)
+	}
+
+	decided, err := parsePolicy(reply.Policy)
+	if err != nil {
+		return ascache.Undefined, fmt.Errorf("remote: decide: %w", err)
+	}
+
+	return decided, nil
+}
+
+// Regimes announces a replica's regime through the coordinator. A coordinator
+// whose backend does not record regimes answers with an error, which the
+// bandit takes as there being nothing to report.
+func (c *Client) Regimes(ctx context.Context, req bandit.RegimeRequest) ([]bandit.RegimeCount, error) {
+	body := regimesRequest{
+		Namespace: req.Namespace,
+		NodeID:    req.NodeID,
+		Regime:    req.Regime,
+		TTLMS:     req.TTL.Milliseconds(),
+	}
+
+	var reply regimesReply
+	if err := c.call(ctx, regimesPath, body, &reply); err != nil {
+		return nil, fmt.Errorf("remote: regimes: %w", err)
+	}
+
+	return reply.Regimes, nil
+}
+
+// SetPin pins the namespace to policy through the coordinator, or lifts its
+// pin when policy is ascache.Undefined. A coordinator whose backend does not
+// hold pins answers with an error.
+func (c *Client) SetPin(ctx context.Context, namespace string, policy ascache.PolicyType) error {
+	body := pinRequest{Namespace: namespace}
+	if policy != ascache.Undefined {
+		body.Policy = bandit.EncodePolicy(policy)
+	}
+
+	var reply struct{}
+	if err := c.call(ctx, setPinPath, body, &reply); err != nil {
+		return fmt.Errorf("remote: set pin: %w", err)
+	}
+
+	return nil
+}
+
+// Pin returns the namespace's pin, or ascache.Undefined. A pin naming a policy
+// this process does not know is reported as no pin, which is what it is for
+// a replica without that arm.
+func (c *Client) Pin(ctx context.Context, namespace string) (ascache.PolicyType, error) {
+	var reply pinReply
+	if err := c.call(ctx, pinPath, pinRequest{Namespace: namespace}, &reply); err != nil {
+		return ascache.Undefined, fmt.Errorf("remote: pin: %w", err)
+	}
+	if reply.Policy == "" {
+		return ascache.Undefined, nil
+	}
+
+	policy, ok := bandit.DecodePolicy(reply.Policy)
+	if !ok {
+		return ascache.Undefined, nil
+	}
+
+	return policy, nil
+}
+
+// Close releases the client's idle connections. A caller-supplied
+// Options.HTTPClient is left alone.
+func (c *Client) Close() error {
+	if c.owned {
+		c.http.CloseIdleConnections()
+	}
+
+	return nil
+}
+
+// call posts body as JSON to path and decodes the reply into out. A reply
+// other than 200 is an error carrying the coordinator's message.
+func (c *Client) call(ctx context.Context, path string, body, out any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	reader := io.LimitReader(resp.Body, maxBody)
+	if resp.StatusCode != http.StatusOK {
+		var reply errorReply
+		if json.NewDecoder(reader).Decode(&reply) != nil || reply.Error == "" {
+			reply.Error = http.StatusText(resp.StatusCode)
+		}
+
+		return fmt.Errorf("coordinator replied %d: %s", resp.StatusCode, reply.Error)
+	}
+
+	if err := json.NewDecoder(reader).Decode(out); err != nil {
+		return fmt.Errorf("reading reply: %w", err)
+	}
+
+	return nil
+}
+
+// parsePolicy reads a decision. Unlike a stray counter, a decision this
+// process cannot name is an error: the fleet has agreed on a policy and this
+// replica is unable to follow it.
+func parsePolicy(text string) (ascache.PolicyType, error) {
+	policy, ok := bandit.DecodePolicy(text)
+	if !ok {
+		return ascache.Undefined, fmt.Errorf("decision %q is not a policy known to this process", text)
+	}
+
+	return policy, nil
+}
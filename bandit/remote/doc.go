@@ -0,0 +1,43 @@
+// Package remote backs a distributed bandit with a coordinator service, so a
+// replica needs a URL rather than a Redis client, database credentials or a
+// shared volume.
+//
+//	store, err := remote.New(remote.Options{URL: "https://coordinator:8080", Token: token})
+//	if err != nil {
+//	    return err
+//	}
+//	defer store.Close()
+//
+//	b, err := bandit.NewDistributed(bandit.Config{
+//	    Store:             store,
+//	    Namespace:         "sessions",
+//	    CoordinationEpoch: time.Second,
+//	})
+//
+// The coordinator is github.com/sshaplygin/as-cache/cmd/coordinator, which
+// serves NewHandler over a store of its own - in memory, or any of the stores
+// in this repository. A service that would rather host it than run another
+// binary can mount NewHandler itself.
+//
+// # Wire
+//
+// HTTP and JSON, one POST per Store method, with the same three fields every
+// other store persists: per-policy hit and miss integers, a role, and a
+// policy named as bandit.EncodePolicy names it. A policy registered in the
+// replicas and not in the coordinator is registered there by name on first
+// sight; the coordinator runs no caches, and only ever needs the name.
+//
+// # When the coordinator is down
+//
+// A call that fails - refused, timed out, or answered with anything but 200
+// - returns an error, and the bandit treats it exactly as it treats any store
+// failing: it holds the fleet's last decision for Config.FallbackAfter, then
+// decides on local evidence until the coordinator answers again. Nothing
+// about fallback is specific to this store.
+//
+// A coordinator backed by memory loses the fleet's window when it restarts.
+// That costs the fleet its pooled history, which the window rebuilds within
+// Config.Window epochs; it costs nothing else, since every replica keeps its
+// own local evidence. A fleet that cannot afford the gap backs the
+// coordinator with a store that persists.
+package remote
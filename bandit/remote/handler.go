@@ -0,0 +1,412 @@
+package remote
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+const (
+	// maxBody bounds a request or reply body. A sync carries one entry per
+	// arm and a window one per arm per bucket, so a megabyte is room for
+	// thousands of both.
+	maxBody = 1 << 20
+
+	// maxWindowSpan bounds the buckets one window call may ask for. A leader
+	// asks for Config.Window+1 of them, ten by default; the bound is there so
+	// a single request cannot make the backend walk an arbitrary range.
+	maxWindowSpan = 1 << 12
+
+	// maxRegistered bounds how many policy names the coordinator registers on
+	// replicas' behalf. Registration is permanent for the life of the process,
+	// so without a bound a client could grow the registry without limit.
+	maxRegistered = 1 << 10
+)
+
+// HandlerOptions configures NewHandler.
+type HandlerOptions struct {
+	// Token, when set, must be presented by every call as a bearer token. A
+	// coordinator reachable by anything other than the fleet should have one:
+	// a caller who can sync can skew the fleet's evidence, and one who can
+	// decide can pick its policy.
+	Token string
+}
+
+// NewHandler serves store to remote clients. It is what cmd/coordinator runs,
+// and what a service that would rather host the coordinator itself can mount.
+//
+// It serves POST /v1/sync, /v1/window and /v1/decide, POST /v1/regimes,
+// /v1/node-window, and /v1/set-pin and /v1/pin when store is a
+// bandit.RegimeStore, a bandit.ContributorStore or a bandit.PinStore, and GET
+// /healthz, which answers without touching the store or asking for the token.
+func NewHandler(store bandit.Store, opts HandlerOptions) http.Handler {
+	h := &handler{store: store, token: opts.Token}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+syncPath, h.authorized(h.sync))
+	mux.HandleFunc("POST "+windowPath, h.authorized(h.window))
+	mux.HandleFunc("POST "+nodeWindowPath, h.authorized(h.nodeWindow))
+	mux.HandleFunc("POST "+decidePath, h.authorized(h.decide))
+	mux.HandleFunc("POST "+regimesPath, h.authorized(h.regimes))
+	mux.HandleFunc("POST "+setPinPath, h.authorized(h.setPin))
+	mux.HandleFunc("POST "+pinPath, h.authorized(h.pin))
+	mux.HandleFunc("GET "+healthPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return mux
+}
+
+type handler struct {
+	store bandit.Store
+	token string
+}
+
+// errBadRequest marks an error as the caller's, which is answered with 400
+// rather than the 502 a failing backend gets.
+var errBadRequest = errors.New("bad request")
+
+// errNotImplemented answers a call the backend cannot serve with 501.
+var errNotImplemented = errors.New("not implemented")
+
+func (h *handler) authorized(next func(r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+				writeJSON(w, http.StatusUnauthorized, errorReply{Error: "missing or wrong bearer token"})
+				return
+			}
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+
+		reply, err := next(r)
+		switch {
+		case errors.Is(err, errBadRequest):
+			writeJSON(w, http.StatusBadRequest, errorReply{Error: err.Error()})
+		case errors.Is(err, errNotImplemented):
+			writeJSON(w, http.StatusNotImplemented, errorReply{Error: err.Error()})
+		case err != nil:
+			writeJSON(w, http.StatusBadGateway, errorReply{Error: err.Error()})
+		default:
+			writeJSON(w, http.StatusOK, reply)
+		}
+	}
+}
+
+func (h *handler) sync(r *http.Request) (any, error) {
+	var body syncRequest
+	if err := decode(r, &body); err != nil {
+		return nil, err
+	}
+
+	req := bandit.SyncRequest{
+		Namespace:   body.Namespace,
+		NodeID:      body.NodeID,
+		Counts:      make([]bandit.ArmCounts, 0, len(body.Counts)),
+		EpochMillis: body.EpochMillis,
+		CounterTTL:  fromMillis(body.CounterTTLMS),
+		LeaderTTL:   fromMillis(body.LeaderTTLMS),
+		Lead:        body.Lead,
+	}
+	if req.Namespace == "" || req.EpochMillis <= 0 {
+		return nil, fmt.Errorf("%w: sync needs a namespace and a positive epoch", errBadRequest)
+	}
+
+	for _, counts := range body.Counts {
+		policy, err := resolvePolicy(counts.Policy)
+		if err != nil {
+			return nil, err
+		}
+		role, ok := parseRole(counts.Role)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown role %q", errBadRequest, counts.Role)
+		}
+
+		req.Counts = append(req.Counts, bandit.ArmCounts{
+			Policy: policy,
+			Role:   role,
+			Hits:   counts.Hits,
+			Misses: counts.Misses,
+		})
+	}
+
+	result, err := h.store.Sync(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := syncReply{Bucket: int64(result.Bucket), Leader: result.Leader}
+	if result.HasDecision {
+		reply.Decision = bandit.EncodePolicy(result.Decision)
+	}
+
+	return reply, nil
+}
+
+func (h *handler) window(r *http.Request) (any, error) {
+	body, empty, err := decodeWindow(r)
+	if err != nil || empty {
+		return windowReply{Buckets: []wireBucket{}}, err
+	}
+
+	window, err := h.store.Window(r.Context(), body.Namespace, bandit.Bucket(body.First), bandit.Bucket(body.Last))
+	if err != nil {
+		return nil, err
+	}
+
+	reply := windowReply{Buckets: make([]wireBucket, 0, len(window))}
+	for _, bucket := range window {
+		reply.Buckets = append(reply.Buckets, wireBucket{Bucket: int64(bucket.Bucket), Arms: toWire(bucket.Arms)})
+	}
+
+	return reply, nil
+}
+
+func (h *handler) nodeWindow(r *http.Request) (any, error) {
+	store, ok := h.store.(bandit.ContributorStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: the coordinator's backend does not keep counts per node", errNotImplemented)
+	}
+
+	body, empty, err := decodeWindow(r)
+	if err != nil || empty {
+		return nodeWindowReply{Buckets: []wireNodeBucket{}}, err
+	}
+
+	window, err := store.NodeWindow(r.Context(), body.Namespace, bandit.Bucket(body.First), bandit.Bucket(body.Last))
+	if err != nil {
+		return nil, err
+	}
+
+	reply := nodeWindowReply{Buckets: make([]wireNodeBucket, 0, len(window))}
+	for _, bucket := range window {
+		nodes := make([]wireNode, 0, len(bucket.Nodes))
+		for node, arms := range bucket.Nodes {
+			nodes = append(nodes, wireNode{NodeID: node, Arms: toWire(arms)})
+		}
+
+		reply.Buckets = append(reply.Buckets, wireNodeBucket{Bucket: int64(bucket.Bucket), Nodes: nodes})
+	}
+
+	return reply, nil
+}
+
+// decodeWindow reads and checks a window request. An inverted range is
+// reported as empty, as every store answers it, rather than as an error.
+func decodeWindow(r *http.Request) (body windowRequest, empty bool, err error) {
+	if err := decode(r, &body); err != nil {
+		return body, false, err
+	}
+	if body.Namespace == "" {
+		return body, false, fmt.Errorf("%w: window needs a namespace", errBadRequest)
+	}
+	if body.Last < body.First {
+		return body, true, nil
+	}
+	// Unsigned, so a span wider than int64 cannot wrap round to a small one.
+	if uint64(body.Last-body.First) >= maxWindowSpan {
+		return body, false, fmt.Errorf("%w: window spans more than %d buckets", errBadRequest, maxWindowSpan)
+	}
+
+	return body, false, nil
+}
+
+func (h *handler) decide(r *http.Request) (any, error) {
+	var body decideRequest
+	if err := decode(r, &body); err != nil {
+		return nil, err
+	}
+	if body.Namespace == "" {
+		return nil, fmt.Errorf("%w: decide needs a namespace", errBadRequest)
+	}
+
+	policy, err := resolvePolicy(body.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	decided, err := h.store.Decide(r.Context(), body.Namespace, bandit.Bucket(body.Bucket), policy,
+		fromMillis(body.TTLMS))
+	if err != nil {
+		return nil, err
+	}
+
+	return decideReply{Policy: bandit.EncodePolicy(decided)}, nil
+}
+
+func (h *handler) regimes(r *http.Request) (any, error) {
+	store, ok := h.store.(bandit.RegimeStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: the coordinator's backend does not record regimes", errNotImplemented)
+	}
+
+	var body regimesRequest
+	if err := decode(r, &body); err != nil {
+		return nil, err
+	}
+	if body.Namespace == "" || body.NodeID == "" {
+		return nil, fmt.Errorf("%w: regimes needs a namespace and a node", errBadRequest)
+	}
+
+	regimes, err := store.Regimes(r.Context(), bandit.RegimeRequest{
+		Namespace: body.Namespace,
+		NodeID:    body.NodeID,
+		Regime:    body.Regime,
+		TTL:       fromMillis(body.TTLMS),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return regimesReply{Regimes: regimes}, nil
+}
+
+func (h *handler) setPin(r *http.Request) (any, error) {
+	store, ok := h.store.(bandit.PinStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: the coordinator's backend does not hold pins", errNotImplemented)
+	}
+
+	var body pinRequest
+	if err := decode(r, &body); err != nil {
+		return nil, err
+	}
+	if body.Namespace == "" {
+		return nil, fmt.Errorf("%w: set pin needs a namespace", errBadRequest)
+	}
+
+	policy := ascache.Undefined
+	if body.Policy != "" {
+		var err error
+		if policy, err = resolvePolicy(body.Policy); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := store.SetPin(r.Context(), body.Namespace, policy); err != nil {
+		return nil, err
+	}
+
+	return struct{}{}, nil
+}
+
+func (h *handler) pin(r *http.Request) (any, error) {
+	store, ok := h.store.(bandit.PinStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: the coordinator's backend does not hold pins", errNotImplemented)
+	}
+
+	var body pinRequest
+	if err := decode(r, &body); err != nil {
+		return nil, err
+	}
+	if body.Namespace == "" {
+		return nil, fmt.Errorf("%w: pin needs a namespace", errBadRequest)
+	}
+
+	policy, err := store.Pin(r.Context(), body.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply pinReply
+	if policy != ascache.Undefined {
+		reply.Policy = bandit.EncodePolicy(policy)
+	}
+
+	return reply, nil
+}
+
+func decode(r *http.Request, into any) error {
+	if err := json.NewDecoder(r.Body).Decode(into); err != nil {
+		return fmt.Errorf("%w: %w", errBadRequest, err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+var (
+	registerMu sync.Mutex
+	registered int
+)
+
+// resolvePolicy turns a policy as a replica encoded it into a PolicyType in
+// this process.
+//
+// A built-in decodes as is. A registered policy or a variant is identified by
+// its name, and the coordinator has not registered the replicas' arms - it
+// runs no caches - so the first time it meets one it registers the name
+// itself. The number it gets is this process's own, which is fine: the
+// backend stores names, and the replica reading the reply decodes the name
+// back into its own number.
+func resolvePolicy(text string) (ascache.PolicyType, error) {
+	if policy, ok := bandit.DecodePolicy(text); ok {
+		return policy, nil
+	}
+
+	registerMu.Lock()
+	defer registerMu.Unlock()
+
+	if policy, ok := bandit.DecodePolicy(text); ok {
+		return policy, nil
+	}
+	if registered >= maxRegistered {
+		return ascache.Undefined, fmt.Errorf("%w: policy %q: coordinator has registered %d names already",
+			errBadRequest, text, maxRegistered)
+	}
+
+	policy, err := register(text)
+	if err != nil {
+		return ascache.Undefined, fmt.Errorf("%w: policy %q: %w", errBadRequest, text, err)
+	}
+	registered++
+
+	return policy, nil
+}
+
+// register registers a name, or a base/variant pair and the base under it if
+// that is new too. Called with registerMu held.
+func register(name string) (ascache.PolicyType, error) {
+	base, variant, isVariant := strings.Cut(name, "/")
+	if !isVariant {
+		return registerName(name)
+	}
+
+	policy, ok := ascache.ParsePolicyType(base)
+	if !ok {
+		var err error
+		if policy, err = registerName(base); err != nil {
+			return ascache.Undefined, err
+		}
+	}
+
+	return ascache.Variant(policy, variant)
+}
+
+// registerName is RegisterPolicyType with its panic - a malformed name, or
+// one that is taken - turned back into the error it is here: bad input from a
+// client, not a programming error in this one.
+func registerName(name string) (policy ascache.PolicyType, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return ascache.RegisterPolicyType(name), nil
+}
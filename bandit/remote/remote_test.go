@@ -0,0 +1,228 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+	"github.com/sshaplygin/as-cache/bandit/storetest"
+)
+
+// newCoordinator serves a fresh MemStore and returns a client for it.
+func newCoordinator(t *testing.T, opts HandlerOptions) (*Client, *bandit.MemStore, *httptest.Server) {
+	t.Helper()
+
+	mem := bandit.NewMemStore()
+	server := httptest.NewServer(NewHandler(mem, opts))
+	t.Cleanup(server.Close)
+
+	client, err := New(Options{URL: server.URL, Token: opts.Token})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client, mem, server
+}
+
+// clockedClient hands the suite's clock through to the store behind the
+// coordinator, which is the only clock a remote store has.
+type clockedClient struct {
+	*Client
+	mem *bandit.MemStore
+}
+
+func (c clockedClient) SetClock(now func() time.Time) { c.mem.SetClock(now) }
+
+func TestClient_Contract(t *testing.T) {
+	storetest.Run(t, func() bandit.Store {
+		client, mem, _ := newCoordinator(t, HandlerOptions{})
+		return clockedClient{Client: client, mem: mem}
+	})
+}
+
+func TestNew_ValidatesOptions(t *testing.T) {
+	_, err := New(Options{})
+	require.ErrorIs(t, err, ErrEmptyURL)
+
+	for _, raw := range []string{"coordinator:8080", "ftp://coordinator", "http://", "::"} {
+		_, err = New(Options{URL: raw})
+		require.ErrorIs(t, err, ErrInvalidURL, "%q", raw)
+	}
+}
+
+func TestHandler_RequiresTheToken(t *testing.T) {
+	client, _, server := newCoordinator(t, HandlerOptions{Token: "s3cret"})
+
+	_, err := client.Sync(t.Context(), syncRequestFor("ns"))
+	require.NoError(t, err)
+
+	anonymous, err := New(Options{URL: server.URL})
+	require.NoError(t, err)
+	_, err = anonymous.Sync(t.Context(), syncRequestFor("ns"))
+	assert.ErrorContains(t, err, "401")
+
+	wrong, err := New(Options{URL: server.URL, Token: "guess"})
+	require.NoError(t, err)
+	_, err = wrong.Sync(t.Context(), syncRequestFor("ns"))
+	assert.ErrorContains(t, err, "401")
+
+	resp, err := http.Get(server.URL + healthPath)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "health needs no token")
+}
+
+// TestHandler_RegistersNamesItHasNotSeen checks that a coordinator, which
+// registers no arms of its own, accepts a replica's registered policies and
+// variants by name and hands the same names back.
+func TestHandler_RegistersNamesItHasNotSeen(t *testing.T) {
+	_, _, server := newCoordinator(t, HandlerOptions{})
+
+	for i, name := range []string{"RemoteTestClock", "RemoteTestClock/fast", "LRU/remote-test"} {
+		var reply decideReply
+		request := decideRequest{Namespace: "ns", Bucket: int64(i), Policy: name, TTLMS: 60_000}
+		status := post(t, server.URL+decidePath, request, &reply)
+		require.Equal(t, http.StatusOK, status, name)
+		assert.Equal(t, name, reply.Policy)
+	}
+
+	for _, name := range []string{"LRU", "9999", "not a name", ""} {
+		request := decideRequest{Namespace: "ns", Bucket: 10, Policy: name, TTLMS: 60_000}
+		status := post(t, server.URL+decidePath, request, nil)
+		assert.Equal(t, http.StatusBadRequest, status, "%q", name)
+	}
+}
+
+func TestHandler_RejectsAnUnboundedWindow(t *testing.T) {
+	client, _, _ := newCoordinator(t, HandlerOptions{})
+
+	_, err := client.Window(t.Context(), "ns", 0, maxWindowSpan)
+	assert.ErrorContains(t, err, "400")
+
+	_, err = client.Window(t.Context(), "", 0, 1)
+	assert.ErrorContains(t, err, "400")
+}
+
+func TestClient_ReportsABackendFailure(t *testing.T) {
+	client, mem, _ := newCoordinator(t, HandlerOptions{})
+	mem.Fail(assert.AnError)
+
+	_, err := client.Sync(t.Context(), syncRequestFor("ns"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "502")
+	assert.Contains(t, err.Error(), assert.AnError.Error())
+}
+
+func TestHandler_RegimesNeedARegimeStore(t *testing.T) {
+	// Wrapping hides MemStore's Regimes method, as a third-party store would.
+	server := httptest.NewServer(NewHandler(struct{ bandit.Store }{bandit.NewMemStore()}, HandlerOptions{}))
+	t.Cleanup(server.Close)
+
+	client, err := New(Options{URL: server.URL})
+	require.NoError(t, err)
+
+	_, err = client.Regimes(t.Context(), bandit.RegimeRequest{Namespace: "ns", NodeID: "a", Regime: "r", TTL: time.Minute})
+	assert.ErrorContains(t, err, "501")
+}
+
+func TestHandler_NodeWindowNeedsAContributorStore(t *testing.T) {
+	server := httptest.NewServer(NewHandler(struct{ bandit.Store }{bandit.NewMemStore()}, HandlerOptions{}))
+	t.Cleanup(server.Close)
+
+	client, err := New(Options{URL: server.URL})
+	require.NoError(t, err)
+
+	_, err = client.NodeWindow(t.Context(), "ns", 0, 1)
+	assert.ErrorContains(t, err, "501")
+}
+
+func TestHandler_PinsNeedAPinStore(t *testing.T) {
+	server := httptest.NewServer(NewHandler(struct{ bandit.Store }{bandit.NewMemStore()}, HandlerOptions{}))
+	t.Cleanup(server.Close)
+
+	client, err := New(Options{URL: server.URL})
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, client.SetPin(t.Context(), "ns", ascache.LFU), "501")
+	_, err = client.Pin(t.Context(), "ns")
+	assert.ErrorContains(t, err, "501")
+}
+
+// TestDistributed_FallsBackWhenTheCoordinatorIsDown checks that a coordinator
+// going away looks to a replica like any other store going away.
+func TestDistributed_FallsBackWhenTheCoordinatorIsDown(t *testing.T) {
+	client, _, server := newCoordinator(t, HandlerOptions{})
+
+	d, err := bandit.NewDistributed(bandit.Config{
+		Store:             client,
+		Namespace:         "ns",
+		CoordinationEpoch: 10 * time.Millisecond,
+		FallbackAfter:     50 * time.Millisecond,
+		SyncTimeout:       50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = d.Close() })
+
+	// A replica syncs once it has something to report, so keep reporting.
+	report := func() {
+		d.RecordEpoch(ascache.EpochReport{
+			Active: ascache.LRU,
+			Stats: []ascache.ShadowStats{
+				{Policy: ascache.LRU, Hits: 60, Misses: 40},
+				{Policy: ascache.TinyLFU, Hits: 70, Misses: 30},
+			},
+			Capacity:   1000,
+			SampleRate: 1,
+		})
+	}
+
+	require.Eventually(t, func() bool {
+		report()
+		return d.Snapshot().Syncs > 0
+	}, 5*time.Second, 5*time.Millisecond)
+	require.False(t, d.Snapshot().Fallback)
+
+	server.Close()
+
+	require.Eventually(t, func() bool {
+		report()
+		return d.Snapshot().Fallback
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.NotEmpty(t, d.Snapshot().LastError)
+}
+
+func syncRequestFor(namespace string) bandit.SyncRequest {
+	return bandit.SyncRequest{
+		Namespace:   namespace,
+		NodeID:      "a",
+		Counts:      []bandit.ArmCounts{{Policy: ascache.LRU, Role: bandit.RoleShadow, Hits: 1}},
+		EpochMillis: time.Second.Milliseconds(),
+		CounterTTL:  time.Minute,
+		LeaderTTL:   time.Second,
+	}
+}
+
+// post sends body as JSON and decodes a 200 reply into out, if out is set.
+func post(t *testing.T, url string, body, out any) int {
+	t.Helper()
+
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(payload))
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusOK && out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+
+	return resp.StatusCode
+}
@@ -0,0 +1,169 @@
+package remote
+
+import (
+	"time"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+// The request and reply bodies. Policies travel as bandit.EncodePolicy writes
+// them and roles by name, exactly as every other store persists them, so the
+// coordinator and its replicas agree on the wire for the same reasons a fleet
+// agrees on the contents of a shared Redis. Durations travel as milliseconds,
+// which is the resolution every store keeps them at anyway.
+
+const (
+	syncPath       = "/v1/sync"
+	windowPath     = "/v1/window"
+	nodeWindowPath = "/v1/node-window"
+	decidePath     = "/v1/decide"
+	regimesPath    = "/v1/regimes"
+	setPinPath     = "/v1/set-pin"
+	pinPath        = "/v1/pin"
+	healthPath     = "/healthz"
+)
+
+type wireCounts struct {
+	Policy string `json:"policy"`
+	Role   string `json:"role"`
+	Hits   int64  `json:"hits"`
+	Misses int64  `json:"misses"`
+}
+
+type syncRequest struct {
+	Namespace    string       `json:"namespace"`
+	NodeID       string       `json:"node_id"`
+	Counts       []wireCounts `json:"counts,omitempty"`
+	EpochMillis  int64        `json:"epoch_ms"`
+	CounterTTLMS int64        `json:"counter_ttl_ms"`
+	LeaderTTLMS  int64        `json:"leader_ttl_ms"`
+	Lead         bool         `json:"lead,omitempty"`
+}
+
+type syncReply struct {
+	Bucket   int64  `json:"bucket"`
+	Leader   bool   `json:"leader,omitempty"`
+	Decision string `json:"decision,omitempty"`
+}
+
+type windowRequest struct {
+	Namespace string `json:"namespace"`
+	First     int64  `json:"first"`
+	Last      int64  `json:"last"`
+}
+
+type windowReply struct {
+	Buckets []wireBucket `json:"buckets"`
+}
+
+type wireBucket struct {
+	Bucket int64        `json:"bucket"`
+	Arms   []wireCounts `json:"arms"`
+}
+
+type nodeWindowReply struct {
+	Buckets []wireNodeBucket `json:"buckets"`
+}
+
+type wireNodeBucket struct {
+	Bucket int64      `json:"bucket"`
+	Nodes  []wireNode `json:"nodes"`
+}
+
+type wireNode struct {
+	NodeID string       `json:"node_id"`
+	Arms   []wireCounts `json:"arms"`
+}
+
+type decideRequest struct {
+	Namespace string `json:"namespace"`
+	Bucket    int64  `json:"bucket"`
+	Policy    string `json:"policy"`
+	TTLMS     int64  `json:"ttl_ms"`
+}
+
+type decideReply struct {
+	Policy string `json:"policy"`
+}
+
+type regimesRequest struct {
+	Namespace string `json:"namespace"`
+	NodeID    string `json:"node_id"`
+	Regime    string `json:"regime"`
+	TTLMS     int64  `json:"ttl_ms"`
+}
+
+type regimesReply struct {
+	Regimes []bandit.RegimeCount `json:"regimes"`
+}
+
+// pinRequest sets a pin, or lifts it when Policy is empty, and names the
+// namespace whose pin is read.
+type pinRequest struct {
+	Namespace string `json:"namespace"`
+	Policy    string `json:"policy,omitempty"`
+}
+
+// pinReply carries the pin in force, or no policy when there is none.
+type pinReply struct {
+	Policy string `json:"policy,omitempty"`
+}
+
+type errorReply struct {
+	Error string `json:"error"`
+}
+
+// toWire lists a bucket's arms as they travel.
+func toWire(arms map[bandit.ArmKey]ascache.PolicyStats) []wireCounts {
+	counts := make([]wireCounts, 0, len(arms))
+	for key, stats := range arms {
+		counts = append(counts, wireCounts{
+			Policy: bandit.EncodePolicy(key.Policy),
+			Role:   key.Role.String(),
+			Hits:   stats.Hits,
+			Misses: stats.Misses,
+		})
+	}
+
+	return counts
+}
+
+// fromWire reads a bucket's arms back. Arms this process cannot name are
+// skipped, as every store skips them.
+func fromWire(counts []wireCounts) map[bandit.ArmKey]ascache.PolicyStats {
+	arms := make(map[bandit.ArmKey]ascache.PolicyStats, len(counts))
+	for _, arm := range counts {
+		policy, ok := bandit.DecodePolicy(arm.Policy)
+		if !ok {
+			continue
+		}
+		role, ok := parseRole(arm.Role)
+		if !ok {
+			continue
+		}
+
+		key := bandit.ArmKey{Policy: policy, Role: role}
+		stats := arms[key]
+		stats.Hits += arm.Hits
+		stats.Misses += arm.Misses
+		arms[key] = stats
+	}
+
+	return arms
+}
+
+func parseRole(text string) (bandit.Role, bool) {
+	switch text {
+	case bandit.RoleActive.String():
+		return bandit.RoleActive, true
+	case bandit.RoleShadow.String():
+		return bandit.RoleShadow, true
+	default:
+		return 0, false
+	}
+}
+
+func fromMillis(ms int64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
@@ -2,6 +2,7 @@ package bandit
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -33,8 +34,15 @@ type ArmEvidence struct {
 type Snapshot struct {
 	// Selection is the policy this replica's bandit is currently returning.
 	Selection string `json:"selection"`
+	// Pinned is the policy the fleet is pinned to, as this replica last read
+	// it, and empty when there is no pin. A pinned cache serves it whatever
+	// Selection says.
+	Pinned string `json:"pinned,omitempty"`
 	// Mode is "leader" or "shared-posterior".
 	Mode string `json:"mode"`
+	// Aggregation is how replicas' evidence is combined: "sum", "capped",
+	// "equal" or "trimmed-mean".
+	Aggregation string `json:"aggregation"`
 	// NodeID identifies this replica, and Namespace is the fingerprinted
 	// namespace it coordinates under - the plain namespace plus a hash of the
 	// cache's arms, capacity and sample rate. Two replicas that should be
@@ -74,6 +82,34 @@ type Snapshot struct {
 	// decision, not making one - so read it from whichever replica has
 	// Leaderships climbing.
 	Fleet []ArmEvidence `json:"fleet"`
+
+	// SiblingRegimes lists the other regimes replicas are running under the
+	// same configured namespace, with how many replicas run each. Those
+	// replicas are not pooling with this one - their caches differ in arms,
+	// capacity or sample rate - and an operator who expected one fleet will
+	// want to know it is several. Empty when the fleet agrees, and always
+	// empty over a store that does not implement RegimeStore.
+	SiblingRegimes []RegimeCount `json:"sibling_regimes,omitempty"`
+
+	// Contributors breaks the evidence behind Fleet down by replica, largest
+	// share first, as the replicas measured it and before any Aggregation
+	// reweighted it, and flags replicas whose hit rate on an arm is unlike
+	// the rest of the fleet's. It is populated only when Config.Contributors is set, and
+	// then on the same replicas as Fleet.
+	Contributors []Contributor `json:"contributors,omitempty"`
+
+	// Canary is the latest staged rollout this replica has seen, nil until
+	// there has been one or when Config.Canary is off. Promotions and
+	// Rollbacks count the verdicts it has applied.
+	Canary     *CanaryStatus `json:"canary,omitempty"`
+	Promotions int64         `json:"promotions"`
+	Rollbacks  int64         `json:"rollbacks"`
+
+	// Subscribed reports that this replica is receiving decisions pushed by
+	// a PushStore, and Pushed counts the decisions that reached it that way
+	// before its own sync did.
+	Subscribed bool  `json:"subscribed"`
+	Pushed     int64 `json:"pushed"`
 }
 
 // Snapshot reads the bandit's current state. It is safe to call at any time
@@ -90,6 +126,7 @@ func (d *Distributed) Snapshot() Snapshot {
 	snapshot := Snapshot{
 		Selection:    ascache.PolicyType(d.selection.Load()).String(),
 		Mode:         mode,
+		Aggregation:  d.cfg.Aggregation.String(),
 		NodeID:       d.cfg.NodeID,
 		Namespace:    d.namespace,
 		Fallback:     d.state.fallback,
@@ -99,8 +136,28 @@ func (d *Distributed) Snapshot() Snapshot {
 		Leaderships:  d.state.leaderships,
 		Decisions:    d.state.decisions,
 		Rejected:     d.state.rejected,
+		Promotions:   d.state.promotions,
+		Rollbacks:    d.state.rollbacks,
+		Subscribed:   d.state.subscribed,
+		Pushed:       d.state.pushed,
 		Fleet:        make([]ArmEvidence, 0, len(d.state.fleet)),
 	}
+	if pinned := ascache.PolicyType(d.pinned.Load()); pinned != ascache.Undefined {
+		snapshot.Pinned = pinned.String()
+	}
+	if len(d.state.siblings) > 0 {
+		snapshot.SiblingRegimes = slices.Clone(d.state.siblings)
+	}
+	for _, contributor := range d.state.contributors {
+		contributor.Arms = slices.Clone(contributor.Arms)
+		contributor.Outliers = slices.Clone(contributor.Outliers)
+		snapshot.Contributors = append(snapshot.Contributors, contributor)
+	}
+
+	if d.state.canary != nil {
+		canary := *d.state.canary
+		snapshot.Canary = &canary
+	}
 
 	if d.haveShape {
 		snapshot.Regime = d.shape.String()
@@ -121,17 +178,22 @@ func (d *Distributed) Snapshot() Snapshot {
 		})
 	}
 
-	// Ties broken by name so an unchanged fleet renders identically on every
-	// scrape; ranging a map alone would reorder equal arms on each call.
-	sort.SliceStable(snapshot.Fleet, func(i, j int) bool {
-		if snapshot.Fleet[i].HitRate != snapshot.Fleet[j].HitRate {
-			return snapshot.Fleet[i].HitRate > snapshot.Fleet[j].HitRate
+	sortEvidence(snapshot.Fleet)
+
+	return snapshot
+}
+
+// sortEvidence orders arms best hit rate first. Ties are broken by name so an
+// unchanged fleet renders identically on every scrape; ranging a map alone
+// would reorder equal arms on each call.
+func sortEvidence(arms []ArmEvidence) {
+	sort.SliceStable(arms, func(i, j int) bool {
+		if arms[i].HitRate != arms[j].HitRate {
+			return arms[i].HitRate > arms[j].HitRate
 		}
 
-		return snapshot.Fleet[i].Policy < snapshot.Fleet[j].Policy
+		return arms[i].Policy < arms[j].Policy
 	})
-
-	return snapshot
 }
 
 // String renders the snapshot as a short human-readable summary.
@@ -139,14 +201,48 @@ func (s Snapshot) String() string {
 	var b strings.Builder
 
 	fmt.Fprintf(&b, "%s running %s, %s mode", s.NodeID, s.Selection, s.Mode)
+	if s.Pinned != "" {
+		fmt.Fprintf(&b, ", PINNED to %s fleet-wide", s.Pinned)
+	}
+	if s.Aggregation != "" && s.Aggregation != AggregationSum.String() {
+		fmt.Fprintf(&b, ", %s aggregation", s.Aggregation)
+	}
 	if s.Fallback {
 		fmt.Fprintf(&b, " (FALLBACK: store unreachable for %s)", s.LastSyncAge.Round(time.Millisecond))
 	}
 	b.WriteString("\n")
 
 	fmt.Fprintf(&b, "namespace %s [%s]\n", s.Namespace, s.Regime)
-	fmt.Fprintf(&b, "%d syncs, %d failures, %d led, %d decisions applied, %d rejected\n",
+	fmt.Fprintf(&b, "%d syncs, %d failures, %d led, %d decisions applied, %d rejected",
 		s.Syncs, s.SyncFailures, s.Leaderships, s.Decisions, s.Rejected)
+	if s.Subscribed || s.Pushed > 0 {
+		fmt.Fprintf(&b, ", %d pushed", s.Pushed)
+	}
+	b.WriteString("\n")
+
+	if c := s.Canary; c != nil {
+		role := "control"
+		if c.Member {
+			role = "canary"
+		}
+		fmt.Fprintf(&b, "rollout of %s over %s: %s (this replica: %s, buckets %d-%d)",
+			c.Candidate, c.Baseline, c.Phase, role, c.Since, c.Until)
+		if c.CanaryHitRate != 0 || c.ControlHitRate != 0 {
+			fmt.Fprintf(&b, ", canary %.2f%% vs control %.2f%%", c.CanaryHitRate*100, c.ControlHitRate*100)
+		}
+		fmt.Fprintf(&b, "; %d promoted, %d rolled back\n", s.Promotions, s.Rollbacks)
+	}
+
+	if len(s.SiblingRegimes) > 0 {
+		replicas := 0
+		for _, sibling := range s.SiblingRegimes {
+			replicas += sibling.Replicas
+		}
+		fmt.Fprintf(&b, "%d replicas in sibling regimes, not pooling with this one:\n", replicas)
+		for _, sibling := range s.SiblingRegimes {
+			fmt.Fprintf(&b, "  %d x [%s]\n", sibling.Replicas, sibling.Regime)
+		}
+	}
 
 	if len(s.Fleet) == 0 {
 		return b.String()
@@ -158,5 +254,18 @@ func (s Snapshot) String() string {
 			arm.Policy, arm.HitRate*100, arm.Hits, arm.Misses)
 	}
 
+	if len(s.Contributors) == 0 {
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "\n%-24s %7s\n", "contributor", "share")
+	for _, contributor := range s.Contributors {
+		fmt.Fprintf(&b, "%-24s %6.1f%%", contributor.NodeID, contributor.Share*100)
+		if len(contributor.Outliers) > 0 {
+			fmt.Fprintf(&b, "  OUTLIER on %s", strings.Join(contributor.Outliers, ", "))
+		}
+		b.WriteString("\n")
+	}
+
 	return b.String()
 }
@@ -0,0 +1,16 @@
+package sqlstore
+
+import (
+	"testing"
+
+	"github.com/sshaplygin/as-cache/bandit"
+	"github.com/sshaplygin/as-cache/bandit/storetest"
+)
+
+func TestStore_Contract(t *testing.T) {
+	storetest.Run(t, func() bandit.Store {
+		store, _ := newStore(t)
+
+		return store
+	})
+}
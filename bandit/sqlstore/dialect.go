@@ -0,0 +1,77 @@
+package sqlstore
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect selects the SQL this store speaks. The statements are the same in
+// both - each supports INSERT ... ON CONFLICT - and differ only in how
+// parameters are written and how the database is asked the time.
+type Dialect uint8
+
+const (
+	// Postgres is PostgreSQL 9.5 or later, or anything speaking its dialect.
+	Postgres Dialect = iota + 1
+	// SQLite is SQLite 3.24 or later.
+	SQLite
+)
+
+// String names the dialect, for errors.
+func (d Dialect) String() string {
+	switch d {
+	case Postgres:
+		return "postgres"
+	case SQLite:
+		return "sqlite"
+	default:
+		return "Dialect(" + strconv.Itoa(int(d)) + ")"
+	}
+}
+
+func (d Dialect) valid() bool {
+	return d == Postgres || d == SQLite
+}
+
+// nowQuery asks the database for its clock in Unix milliseconds. Every bucket
+// and every expiry is derived from this reading, never from a replica's clock.
+//
+// Postgres's now() is the start of the transaction, which is a moment inside
+// the call and all the accuracy a bucket needs. SQLite has no server; its
+// clock is the clock of whichever host holds the file, which is as shared as
+// an SQLite database gets.
+func (d Dialect) nowQuery() string {
+	if d == Postgres {
+		return "SELECT CAST(EXTRACT(EPOCH FROM now()) * 1000 AS BIGINT)"
+	}
+
+	return "SELECT CAST((julianday('now') - 2440587.5) * 86400000.0 AS INTEGER)"
+}
+
+// rebind rewrites the ? placeholders the statements are written with into
+// the dialect's own. SQLite takes them as they are; Postgres numbers them.
+// None of the statements here has a ? inside a literal, so a plain scan will
+// do.
+func (d Dialect) rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+
+			continue
+		}
+
+		n++
+		b.WriteByte('$')
+		b.WriteString(strconv.Itoa(n))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,61 @@
+// Package sqlstore backs a distributed bandit with PostgreSQL or SQLite.
+//
+// It implements github.com/sshaplygin/as-cache/bandit.Store over
+// database/sql, for fleets that have a database they trust and no Valkey. It
+// imports no driver: the application opens the *sql.DB with whichever one it
+// already uses and names the dialect.
+//
+//	db, err := sql.Open("pgx", dsn)
+//	if err != nil {
+//	    return err
+//	}
+//	store, err := sqlstore.New(sqlstore.Options{DB: db, Dialect: sqlstore.Postgres})
+//	if err != nil {
+//	    return err
+//	}
+//	defer store.Close()
+//	if err := store.CreateTables(ctx); err != nil {
+//	    return err
+//	}
+//
+// # What it stores
+//
+// Six tables. Counts holds per-policy hit and miss integers per bucket,
+// summed in place by INSERT ... ON CONFLICT, and node_counts the same again
+// per replica, for bandit.ContributorStore. Leaders holds one row per bucket,
+// claimed by the same statement with a conditional update, so exactly one
+// replica sees its insert land. Decisions is write-once: Decide inserts or
+// does nothing, and reads back whichever row is there. Regimes holds each
+// replica's latest regime announcement, one row per node. Pins holds the
+// fleet-wide pin for bandit.PinStore, one row per pinned namespace.
+//
+// Every row but a pin carries an expiry in Unix milliseconds. Reads ignore
+// expired rows, and a reaper inside the store deletes them every
+// Options.ReapInterval, so a fleet that stops running leaves nothing behind
+// but its pins once its last store has reaped. A pin stands until an operator
+// lifts it.
+//
+// # Clock
+//
+// Buckets and expiries come from the database's clock - now() on Postgres -
+// read once at the start of each call. No replica's clock is consulted, so a
+// fleet needs no clock agreement.
+//
+// # Load
+//
+// One transaction per replica per coordination epoch: a clock read, two
+// upserts per arm - summed and per node - and a decision read. Two more calls
+// for the replica leading the epoch. Every replica upserts its arms in the
+// same order and locks no row it does not write, so concurrent syncs into one
+// bucket queue on Postgres rather than deadlock. A Postgres that is comfortable with a few
+// thousand small writes a second is comfortable with a fleet of that many
+// replicas at a one-second epoch.
+//
+// # SQLite
+//
+// SQLite is supported for development, tests, and fleets of processes on one
+// machine. Every call writes, and SQLite takes one writer at a time, so open
+// it with a busy timeout and either one connection (db.SetMaxOpenConns(1)) or
+// immediate transactions, or concurrent replicas will see SQLITE_BUSY rather
+// than waiting their turn.
+package sqlstore
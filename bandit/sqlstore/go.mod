@@ -0,0 +1,27 @@
+module github.com/sshaplygin/as-cache/bandit/sqlstore
+
+go 1.25.2
+
+require (
+	github.com/jackc/pgx/v5 v5.7.5
+	github.com/mattn/go-sqlite3 v1.14.33
+	github.com/sshaplygin/as-cache v0.3.1
+	github.com/sshaplygin/as-cache/bandit v0.3.1
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/crypto v0.37.0 // indirect
+	golang.org/x/sync v0.13.0 // indirect
+	golang.org/x/text v0.24.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/sshaplygin/as-cache => ../..
+
+replace github.com/sshaplygin/as-cache/bandit => ..
@@ -0,0 +1,181 @@
+package sqlstore
+
+// queries holds every statement the store runs, with the table prefix filled
+// in and the placeholders rebound for the dialect, built once in New.
+type queries struct {
+	schema []string
+
+	now string
+
+	addCounts     string
+	addNodeCounts string
+	claimLeader   string
+
+	readDecision   string
+	dropDecision   string
+	insertDecision string
+	window         string
+	nodeWindow     string
+	announceRegime string
+	regimes        string
+	setPin         string
+	liftPin        string
+	readPin        string
+	reapCounts     string
+	reapNodeCounts string
+	reapLeaders    string
+	reapDecisions  string
+	reapRegimes    string
+}
+
+// newQueries builds the statements for a dialect and table prefix. The prefix
+// has been validated as an identifier, which is what makes splicing it in
+// safe.
+//
+// Every expiry is a Unix millisecond count in a BIGINT, compared against the
+// database's clock read at the start of the call, so neither dialect's
+// timestamp types - nor their time zones - are involved.
+func newQueries(d Dialect, prefix string) queries {
+	counts := prefix + "counts"
+	nodeCounts := prefix + "node_counts"
+	leaders := prefix + "leaders"
+	decisions := prefix + "decisions"
+	regimes := prefix + "regimes"
+	pins := prefix + "pins"
+
+	return queries{
+		schema: []string{
+			`CREATE TABLE IF NOT EXISTS ` + counts + ` (
+	namespace  TEXT   NOT NULL,
+	bucket     BIGINT NOT NULL,
+	policy     TEXT   NOT NULL,
+	role       TEXT   NOT NULL,
+	hits       BIGINT NOT NULL,
+	misses     BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL,
+	PRIMARY KEY (namespace, bucket, policy, role)
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + counts + `_expires_at ON ` + counts + ` (expires_at)`,
+			`CREATE TABLE IF NOT EXISTS ` + nodeCounts + ` (
+	namespace  TEXT   NOT NULL,
+	bucket     BIGINT NOT NULL,
+	node_id    TEXT   NOT NULL,
+	policy     TEXT   NOT NULL,
+	role       TEXT   NOT NULL,
+	hits       BIGINT NOT NULL,
+	misses     BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL,
+	PRIMARY KEY (namespace, bucket, node_id, policy, role)
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + nodeCounts + `_expires_at ON ` + nodeCounts + ` (expires_at)`,
+			`CREATE TABLE IF NOT EXISTS ` + leaders + ` (
+	namespace  TEXT   NOT NULL,
+	bucket     BIGINT NOT NULL,
+	node_id    TEXT   NOT NULL,
+	expires_at BIGINT NOT NULL,
+	PRIMARY KEY (namespace, bucket)
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + leaders + `_expires_at ON ` + leaders + ` (expires_at)`,
+			`CREATE TABLE IF NOT EXISTS ` + decisions + ` (
+	namespace  TEXT   NOT NULL,
+	bucket     BIGINT NOT NULL,
+	policy     TEXT   NOT NULL,
+	expires_at BIGINT NOT NULL,
+	PRIMARY KEY (namespace, bucket)
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + decisions + `_expires_at ON ` + decisions + ` (expires_at)`,
+			`CREATE TABLE IF NOT EXISTS ` + regimes + ` (
+	namespace  TEXT   NOT NULL,
+	node_id    TEXT   NOT NULL,
+	regime     TEXT   NOT NULL,
+	expires_at BIGINT NOT NULL,
+	PRIMARY KEY (namespace, node_id)
+)`,
+			`CREATE INDEX IF NOT EXISTS ` + regimes + `_expires_at ON ` + regimes + ` (expires_at)`,
+			`CREATE TABLE IF NOT EXISTS ` + pins + ` (
+	namespace TEXT NOT NULL,
+	policy    TEXT NOT NULL,
+	PRIMARY KEY (namespace)
+)`,
+		},
+
+		now: d.nowQuery(),
+
+		// Counters are summed in place by the conflict clause, so concurrent
+		// replicas never read-modify-write. The TTL is refreshed on the row
+		// written and no other: an arm outlives its last contribution, and
+		// a sync locks only the rows it adds to.
+		addCounts: d.rebind(`INSERT INTO ` + counts + ` (namespace, bucket, policy, role, hits, misses, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (namespace, bucket, policy, role) DO UPDATE SET
+	hits = ` + counts + `.hits + excluded.hits,
+	misses = ` + counts + `.misses + excluded.misses,
+	expires_at = excluded.expires_at`),
+
+		// The same counts again, per node, for NodeWindow. A node's rows
+		// only ever see its own writes, and take the same expiry as the
+		// summed row written beside them.
+		addNodeCounts: d.rebind(`INSERT INTO ` + nodeCounts + `
+	(namespace, bucket, node_id, policy, role, hits, misses, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (namespace, bucket, node_id, policy, role) DO UPDATE SET
+	hits = ` + nodeCounts + `.hits + excluded.hits,
+	misses = ` + nodeCounts + `.misses + excluded.misses,
+	expires_at = excluded.expires_at`),
+
+		// A claim inserts, or takes over a claim that has expired. The
+		// conflict clause's WHERE makes it one atomic statement, and the
+		// affected row count says whether it won.
+		claimLeader: d.rebind(`INSERT INTO ` + leaders + ` (namespace, bucket, node_id, expires_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (namespace, bucket) DO UPDATE SET
+	node_id = excluded.node_id,
+	expires_at = excluded.expires_at
+WHERE ` + leaders + `.expires_at < ?`),
+
+		readDecision: d.rebind(`SELECT policy FROM ` + decisions + `
+WHERE namespace = ? AND bucket = ? AND expires_at >= ?`),
+		dropDecision: d.rebind(`DELETE FROM ` + decisions + `
+WHERE namespace = ? AND bucket = ? AND expires_at < ?`),
+		// Write-once: a decision row is inserted or left alone, never
+		// updated.
+		insertDecision: d.rebind(`INSERT INTO ` + decisions + ` (namespace, bucket, policy, expires_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (namespace, bucket) DO NOTHING`),
+
+		window: d.rebind(`SELECT bucket, policy, role, hits, misses FROM ` + counts + `
+WHERE namespace = ? AND bucket BETWEEN ? AND ? AND expires_at >= ?
+ORDER BY bucket`),
+		nodeWindow: d.rebind(`SELECT bucket, node_id, policy, role, hits, misses FROM ` + nodeCounts + `
+WHERE namespace = ? AND bucket BETWEEN ? AND ? AND expires_at >= ?
+ORDER BY bucket`),
+
+		// One row per node, so announcing again replaces the last
+		// announcement rather than adding to the count.
+		announceRegime: d.rebind(`INSERT INTO ` + regimes + ` (namespace, node_id, regime, expires_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (namespace, node_id) DO UPDATE SET
+	regime = excluded.regime,
+	expires_at = excluded.expires_at`),
+		// Sorted by the caller rather than ORDER BY, which follows the
+		// database's collation and not the byte order every other store uses.
+		regimes: d.rebind(`SELECT regime, COUNT(*) FROM ` + regimes + `
+WHERE namespace = ? AND expires_at >= ?
+GROUP BY regime`),
+
+		// A pin is an operator's standing instruction, so it carries no
+		// expiry and the reaper never touches it: it lasts until lifted.
+		setPin: d.rebind(`INSERT INTO ` + pins + ` (namespace, policy)
+VALUES (?, ?)
+ON CONFLICT (namespace) DO UPDATE SET
+	policy = excluded.policy`),
+		liftPin: d.rebind(`DELETE FROM ` + pins + ` WHERE namespace = ?`),
+		readPin: d.rebind(`SELECT policy FROM ` + pins + ` WHERE namespace = ?`),
+
+		reapCounts:     d.rebind(`DELETE FROM ` + counts + ` WHERE expires_at < ?`),
+		reapNodeCounts: d.rebind(`DELETE FROM ` + nodeCounts + ` WHERE expires_at < ?`),
+		reapLeaders:    d.rebind(`DELETE FROM ` + leaders + ` WHERE expires_at < ?`),
+		reapDecisions:  d.rebind(`DELETE FROM ` + decisions + ` WHERE expires_at < ?`),
+		reapRegimes:    d.rebind(`DELETE FROM ` + regimes + ` WHERE expires_at < ?`),
+	}
+}
@@ -0,0 +1,688 @@
+package sqlstore
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+var (
+	_ bandit.RegimeStore      = (*Store)(nil)
+	_ bandit.ContributorStore = (*Store)(nil)
+	_ bandit.PinStore         = (*Store)(nil)
+)
+
+// DefaultTablePrefix begins the name of every table the store uses.
+const DefaultTablePrefix = "as_cache_bandit_"
+
+// DefaultReapInterval is how often expired rows are deleted when
+// Options.ReapInterval is zero.
+const DefaultReapInterval = time.Minute
+
+// ErrNilDB is returned by New when Options.DB is nil.
+var ErrNilDB = errors.New("sqlstore: db must not be nil")
+
+// ErrUnknownDialect is returned by New when Options.Dialect is neither
+// Postgres nor SQLite.
+var ErrUnknownDialect = errors.New("sqlstore: unknown dialect")
+
+// ErrInvalidTablePrefix is returned by New for a table prefix that is not a
+// plain SQL identifier. It is spliced into every statement, so anything else
+// would be an injection.
+var ErrInvalidTablePrefix = errors.New("sqlstore: table prefix must be a letter or underscore " +
+	"followed by at most 39 letters, digits or underscores")
+
+// tablePrefixPattern leaves room for the longest suffix, "decisions_expires_at",
+// inside Postgres's 63-byte identifier limit.
+var tablePrefixPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,39}$`)
+
+// Options configures a Store.
+type Options struct {
+	// DB is the database. Required. The store does not take ownership of it:
+	// Close leaves it open for the application to keep using.
+	DB *sql.DB
+
+	// Dialect is the SQL the database speaks. Required: database/sql cannot
+	// say which driver is behind a *sql.DB.
+	Dialect Dialect
+
+	// TablePrefix begins every table name. Defaults to DefaultTablePrefix.
+	TablePrefix string
+
+	// ReapInterval is how often the store deletes rows past their TTL.
+	// Defaults to DefaultReapInterval; negative disables the reaper, for an
+	// application that calls Reap on its own schedule. Expired rows are
+	// ignored on read either way, so the interval bounds the table's size and
+	// not the store's behaviour.
+	ReapInterval time.Duration
+}
+
+// Store implements bandit.Store over database/sql.
+type Store struct {
+	db *sql.DB
+	q  queries
+
+	mu sync.Mutex
+	// now, when set, replaces the database's clock. See SetClock.
+	now func() time.Time
+
+	// reaping is cancelled by Close, taking any reap in flight with it.
+	reaping context.Context
+	stop    context.CancelFunc
+	done    chan struct{}
+}
+
+// New returns a Store over opts.DB and starts its reaper. It does not create
+// the tables; see CreateTables.
+func New(opts Options) (*Store, error) {
+	if opts.DB == nil {
+		return nil, ErrNilDB
+	}
+	if !opts.Dialect.valid() {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownDialect, opts.Dialect)
+	}
+
+	prefix := opts.TablePrefix
+	if prefix == "" {
+		prefix = DefaultTablePrefix
+	}
+	if !tablePrefixPattern.MatchString(prefix) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTablePrefix, prefix)
+	}
+
+	interval := opts.ReapInterval
+	if interval == 0 {
+		interval = DefaultReapInterval
+	}
+
+	s := &Store{
+		db:   opts.DB,
+		q:    newQueries(opts.Dialect, prefix),
+		done: make(chan struct{}),
+	}
+	s.reaping, s.stop = context.WithCancel(context.Background())
+
+	if interval > 0 {
+		go s.reapEvery(interval)
+	} else {
+		close(s.done)
+	}
+
+	return s, nil
+}
+
+// CreateTables creates the store's tables and indexes if they do not already
+// exist. It is safe to call on every start; an application that manages its
+// schema through migrations can run the same statements there instead.
+func (s *Store) CreateTables(ctx context.Context) error {
+	for _, statement := range s.q.schema {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("sqlstore: create tables: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SetClock replaces the database's clock with now, or restores it when now is
+// nil. It exists for tests, which need to move bucket boundaries without
+// waiting for them; a fleet must use the database's clock, or its replicas are
+// back to trusting their own.
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+// querier is what a call needs from either a *sql.DB or a *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// clock returns the time by the store's clock in Unix milliseconds: the
+// database's, unless a test has replaced it.
+func (s *Store) clock(ctx context.Context, q querier) (int64, error) {
+	s.mu.Lock()
+	now := s.now
+	s.mu.Unlock()
+
+	if now != nil {
+		return now().UnixMilli(), nil
+	}
+
+	var millis int64
+	if err := q.QueryRowContext(ctx, s.q.now).Scan(&millis); err != nil {
+		return 0, fmt.Errorf("read clock: %w", err)
+	}
+
+	return millis, nil
+}
+
+// Sync publishes one replica's counts, claims the bucket if asked and if it is
+// unclaimed, and reports any decision published for it, in one transaction.
+func (s *Store) Sync(ctx context.Context, req bandit.SyncRequest) (bandit.SyncResult, error) {
+	if err := ctx.Err(); err != nil {
+		return bandit.SyncResult{}, err
+	}
+
+	result, err := s.sync(ctx, req)
+	if err != nil {
+		return bandit.SyncResult{}, fmt.Errorf("sqlstore: sync: %w", err)
+	}
+
+	return result, nil
+}
+
+func (s *Store) sync(ctx context.Context, req bandit.SyncRequest) (result bandit.SyncResult, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	now, err := s.clock(ctx, tx)
+	if err != nil {
+		return result, err
+	}
+
+	epochMillis := req.EpochMillis
+	if epochMillis <= 0 {
+		epochMillis = 1
+	}
+	bucket := now / epochMillis
+	result.Bucket = bandit.Bucket(bucket)
+
+	// Every replica upserts its arms in the same order, so two syncs into
+	// one bucket queue on the first row they share rather than each holding
+	// a row the other is waiting for: Postgres would break that cycle by
+	// failing one of them with a deadlock.
+	counts := slices.Clone(req.Counts)
+	slices.SortFunc(counts, func(a, b bandit.ArmCounts) int {
+		return cmp.Or(
+			cmp.Compare(bandit.EncodePolicy(a.Policy), bandit.EncodePolicy(b.Policy)),
+			cmp.Compare(a.Role, b.Role),
+		)
+	})
+
+	expires := now + req.CounterTTL.Milliseconds()
+	for _, count := range counts {
+		// Zero deltas are dropped rather than written. They would create a
+		// row for an arm that measured nothing, which reads back as evidence
+		// of a zero hit rate rather than as an absence of evidence.
+		if count.Hits == 0 && count.Misses == 0 {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, s.q.addCounts,
+			req.Namespace, bucket, bandit.EncodePolicy(count.Policy), count.Role.String(),
+			count.Hits, count.Misses, expires)
+		if err != nil {
+			return result, fmt.Errorf("add counts: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, s.q.addNodeCounts,
+			req.Namespace, bucket, req.NodeID, bandit.EncodePolicy(count.Policy), count.Role.String(),
+			count.Hits, count.Misses, expires)
+		if err != nil {
+			return result, fmt.Errorf("add node counts: %w", err)
+		}
+	}
+
+	if req.Lead {
+		claimed, err := tx.ExecContext(ctx, s.q.claimLeader,
+			req.Namespace, bucket, req.NodeID, now+req.LeaderTTL.Milliseconds(), now)
+		if err != nil {
+			return result, fmt.Errorf("claim leadership: %w", err)
+		}
+
+		rows, err := claimed.RowsAffected()
+		if err != nil {
+			return result, fmt.Errorf("claim leadership: %w", err)
+		}
+		result.Leader = rows == 1
+	}
+
+	result.Decision, result.HasDecision, err = s.readDecision(ctx, tx, req.Namespace, bucket, now)
+	if err != nil {
+		return result, err
+	}
+
+	return result, tx.Commit()
+}
+
+// readDecision returns the live decision for a bucket, if there is one.
+//
+// Unlike a stray counter, a decision this process cannot name is an error: the
+// fleet has agreed on a policy and this replica is unable to follow it.
+func (s *Store) readDecision(
+	ctx context.Context,
+	q querier,
+	namespace string,
+	bucket, now int64,
+) (ascache.PolicyType, bool, error) {
+	var text string
+
+	err := q.QueryRowContext(ctx, s.q.readDecision, namespace, bucket, now).Scan(&text)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ascache.Undefined, false, nil
+	}
+	if err != nil {
+		return ascache.Undefined, false, fmt.Errorf("read decision: %w", err)
+	}
+
+	policy, ok := bandit.DecodePolicy(text)
+	if !ok {
+		return ascache.Undefined, false, fmt.Errorf("decision %q is not a policy known to this process", text)
+	}
+
+	return policy, true, nil
+}
+
+// Window reads the aggregated counts for a range of buckets in one query.
+// Buckets that expired or were never written have no rows and are omitted.
+func (s *Store) Window(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.WindowCounts, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if first > last {
+		return nil, nil
+	}
+
+	window, err := s.window(ctx, namespace, first, last)
+	if err != nil {
+		return nil, fmt.Errorf("sqlstore: window: %w", err)
+	}
+
+	return window, nil
+}
+
+func (s *Store) window(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.WindowCounts, error) {
+	now, err := s.clock(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.q.window, namespace, int64(first), int64(last), now)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var window []bandit.WindowCounts
+	for rows.Next() {
+		var (
+			bucket           int64
+			policyText, role string
+			hits, misses     int64
+		)
+		if err := rows.Scan(&bucket, &policyText, &role, &hits, &misses); err != nil {
+			return nil, err
+		}
+
+		// Rows this process cannot read - a policy it has not registered, a
+		// role from a newer release - are skipped, as a Valkey store skips
+		// fields it does not recognise.
+		policy, ok := bandit.DecodePolicy(policyText)
+		if !ok {
+			continue
+		}
+		armRole, ok := parseRole(role)
+		if !ok {
+			continue
+		}
+
+		if len(window) == 0 || window[len(window)-1].Bucket != bandit.Bucket(bucket) {
+			window = append(window, bandit.WindowCounts{
+				Bucket: bandit.Bucket(bucket),
+				Arms:   make(map[bandit.ArmKey]ascache.PolicyStats),
+			})
+		}
+
+		arms := window[len(window)-1].Arms
+		key := bandit.ArmKey{Policy: policy, Role: armRole}
+		stats := arms[key]
+		stats.Hits += hits
+		stats.Misses += misses
+		arms[key] = stats
+	}
+
+	return window, rows.Err()
+}
+
+// NodeWindow reads the per-node counts for buckets first through last
+// inclusive, in bucket order.
+func (s *Store) NodeWindow(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.NodeWindowCounts, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if first > last {
+		return nil, nil
+	}
+
+	window, err := s.nodeWindow(ctx, namespace, first, last)
+	if err != nil {
+		return nil, fmt.Errorf("sqlstore: node window: %w", err)
+	}
+
+	return window, nil
+}
+
+func (s *Store) nodeWindow(
+	ctx context.Context,
+	namespace string,
+	first, last bandit.Bucket,
+) ([]bandit.NodeWindowCounts, error) {
+	now, err := s.clock(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.q.nodeWindow, namespace, int64(first), int64(last), now)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var window []bandit.NodeWindowCounts
+	for rows.Next() {
+		var (
+			bucket                 int64
+			node, policyText, role string
+			hits, misses           int64
+		)
+		if err := rows.Scan(&bucket, &node, &policyText, &role, &hits, &misses); err != nil {
+			return nil, err
+		}
+
+		policy, ok := bandit.DecodePolicy(policyText)
+		if !ok {
+			continue
+		}
+		armRole, ok := parseRole(role)
+		if !ok {
+			continue
+		}
+
+		if len(window) == 0 || window[len(window)-1].Bucket != bandit.Bucket(bucket) {
+			window = append(window, bandit.NodeWindowCounts{
+				Bucket: bandit.Bucket(bucket),
+				Nodes:  make(map[string]map[bandit.ArmKey]ascache.PolicyStats),
+			})
+		}
+
+		nodes := window[len(window)-1].Nodes
+		arms := nodes[node]
+		if arms == nil {
+			arms = make(map[bandit.ArmKey]ascache.PolicyStats)
+			nodes[node] = arms
+		}
+		key := bandit.ArmKey{Policy: policy, Role: armRole}
+		stats := arms[key]
+		stats.Hits += hits
+		stats.Misses += misses
+		arms[key] = stats
+	}
+
+	return window, rows.Err()
+}
+
+func parseRole(text string) (bandit.Role, bool) {
+	switch text {
+	case bandit.RoleActive.String():
+		return bandit.RoleActive, true
+	case bandit.RoleShadow.String():
+		return bandit.RoleShadow, true
+	default:
+		return 0, false
+	}
+}
+
+// Decide publishes the policy the fleet should run for a bucket and returns
+// the decision actually in force for it.
+//
+// The decisions table is write-once: a live row is never updated, and a
+// publish that finds one returns it. An expired row - one the reaper has not
+// reached yet - is deleted first, so it cannot outlive its TTL by blocking the
+// insert.
+func (s *Store) Decide(
+	ctx context.Context,
+	namespace string,
+	bucket bandit.Bucket,
+	policy ascache.PolicyType,
+	ttl time.Duration,
+) (ascache.PolicyType, error) {
+	if err := ctx.Err(); err != nil {
+		return ascache.Undefined, err
+	}
+
+	decided, err := s.decide(ctx, namespace, int64(bucket), policy, ttl)
+	if err != nil {
+		return ascache.Undefined, fmt.Errorf("sqlstore: decide: %w", err)
+	}
+
+	return decided, nil
+}
+
+func (s *Store) decide(
+	ctx context.Context,
+	namespace string,
+	bucket int64,
+	policy ascache.PolicyType,
+	ttl time.Duration,
+) (ascache.PolicyType, error) {
+	now, err := s.clock(ctx, s.db)
+	if err != nil {
+		return ascache.Undefined, err
+	}
+
+	if _, err := s.db.ExecContext(ctx, s.q.dropDecision, namespace, bucket, now); err != nil {
+		return ascache.Undefined, fmt.Errorf("drop expired decision: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.q.insertDecision,
+		namespace, bucket, bandit.EncodePolicy(policy), now+ttl.Milliseconds())
+	if err != nil {
+		return ascache.Undefined, fmt.Errorf("insert decision: %w", err)
+	}
+
+	// Read back rather than assume: if another leader's insert won, this
+	// one did nothing and the fleet is following theirs.
+	decided, ok, err := s.readDecision(ctx, s.db, namespace, bucket, now)
+	if err != nil {
+		return ascache.Undefined, err
+	}
+	if !ok {
+		return ascache.Undefined, errors.New("decision vanished after insert")
+	}
+
+	return decided, nil
+}
+
+// Regimes records a replica's regime announcement and counts the live ones
+// under the namespace, in one transaction.
+func (s *Store) Regimes(ctx context.Context, req bandit.RegimeRequest) ([]bandit.RegimeCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	regimes, err := s.regimes(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("sqlstore: regimes: %w", err)
+	}
+
+	return regimes, nil
+}
+
+func (s *Store) regimes(ctx context.Context, req bandit.RegimeRequest) (regimes []bandit.RegimeCount, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	now, err := s.clock(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, s.q.announceRegime, req.Namespace, req.NodeID, req.Regime, now+req.TTL.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("announce: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, s.q.regimes, req.Namespace, now)
+	if err != nil {
+		return nil, fmt.Errorf("count: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var count bandit.RegimeCount
+		if err = rows.Scan(&count.Regime, &count.Replicas); err != nil {
+			return nil, fmt.Errorf("count: %w", err)
+		}
+		regimes = append(regimes, count)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("count: %w", err)
+	}
+	slices.SortFunc(regimes, func(a, b bandit.RegimeCount) int { return cmp.Compare(a.Regime, b.Regime) })
+
+	return regimes, tx.Commit()
+}
+
+// SetPin pins the namespace to policy, or lifts its pin when policy is
+// ascache.Undefined. Pins have a table of their own with no expiry column: a
+// pin stands until it is lifted, and Reap leaves it alone.
+func (s *Store) SetPin(ctx context.Context, namespace string, policy ascache.PolicyType) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var err error
+	if policy == ascache.Undefined {
+		_, err = s.db.ExecContext(ctx, s.q.liftPin, namespace)
+	} else {
+		_, err = s.db.ExecContext(ctx, s.q.setPin, namespace, bandit.EncodePolicy(policy))
+	}
+	if err != nil {
+		return fmt.Errorf("sqlstore: set pin: %w", err)
+	}
+
+	return nil
+}
+
+// Pin returns the namespace's pin, or ascache.Undefined. A pin naming a policy
+// this process does not know is reported as no pin, which is what it is for
+// a replica without that arm.
+func (s *Store) Pin(ctx context.Context, namespace string) (ascache.PolicyType, error) {
+	if err := ctx.Err(); err != nil {
+		return ascache.Undefined, err
+	}
+
+	var text string
+	err := s.db.QueryRowContext(ctx, s.q.readPin, namespace).Scan(&text)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ascache.Undefined, nil
+	}
+	if err != nil {
+		return ascache.Undefined, fmt.Errorf("sqlstore: pin: %w", err)
+	}
+
+	policy, ok := bandit.DecodePolicy(text)
+	if !ok {
+		return ascache.Undefined, nil
+	}
+
+	return policy, nil
+}
+
+// Reap deletes every row past its TTL and reports how many it deleted. The
+// store's own reaper calls it every Options.ReapInterval; an application that
+// disabled that can call it on a schedule of its own.
+func (s *Store) Reap(ctx context.Context) (int64, error) {
+	now, err := s.clock(ctx, s.db)
+	if err != nil {
+		return 0, fmt.Errorf("sqlstore: reap: %w", err)
+	}
+
+	var total int64
+	statements := []string{
+		s.q.reapCounts, s.q.reapNodeCounts, s.q.reapLeaders, s.q.reapDecisions, s.q.reapRegimes,
+	}
+	for _, statement := range statements {
+		result, err := s.db.ExecContext(ctx, statement, now)
+		if err != nil {
+			return total, fmt.Errorf("sqlstore: reap: %w", err)
+		}
+
+		// A driver that cannot count is not a failure: the rows are gone.
+		if rows, err := result.RowsAffected(); err == nil {
+			total += rows
+		}
+	}
+
+	return total, nil
+}
+
+// reapEvery runs Reap until Close. A failed reap is left for the next tick:
+// expired rows are already invisible to every read, so a late reap costs
+// space and nothing else.
+func (s *Store) reapEvery(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.reaping.Done():
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(s.reaping, interval)
+			_, _ = s.Reap(ctx)
+			cancel()
+		}
+	}
+}
+
+// Close stops the reaper. The database belongs to the caller and is left
+// open.
+func (s *Store) Close() error {
+	s.stop()
+	<-s.done
+
+	return nil
+}
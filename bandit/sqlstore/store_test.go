@@ -0,0 +1,207 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+// runID keeps tables from one run apart from the next on a shared Postgres.
+var runID = strconv.FormatInt(time.Now().UnixNano()%1e9, 36)
+
+// newStore returns a store over a fresh SQLite database, or over Postgres
+// when AS_CACHE_POSTGRES_DSN is set.
+//
+// SQLite covers the logic and needs nothing installed; Postgres covers the
+// assumptions the logic rests on - now() inside a transaction, the conflict
+// clause's WHERE and the affected-row count it produces, and concurrent
+// upserts under READ COMMITTED - which is why the same tests run against both.
+func newStore(t *testing.T) (*Store, *sql.DB) {
+	t.Helper()
+
+	if dsn := os.Getenv("AS_CACHE_POSTGRES_DSN"); dsn != "" {
+		db, err := sql.Open("pgx", dsn)
+		require.NoError(t, err)
+		require.NoError(t, db.PingContext(t.Context()), "AS_CACHE_POSTGRES_DSN is set but unreachable")
+
+		// A real server is shared and outlives the run, so each test gets its
+		// own tables rather than truncating someone else's.
+		name := fnv.New32a()
+		_, _ = name.Write([]byte(t.Name()))
+		prefix := fmt.Sprintf("asctest_%s_%x_", runID, name.Sum32())
+
+		store := openStore(t, db, Postgres, prefix)
+		t.Cleanup(func() {
+			for _, table := range []string{"counts", "node_counts", "leaders", "decisions", "regimes"} {
+				_, _ = db.ExecContext(context.Background(), "DROP TABLE IF EXISTS "+prefix+table)
+			}
+			_ = db.Close()
+		})
+
+		return store, db
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "bandit.db")+"?_busy_timeout=5000")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return openStore(t, db, SQLite, ""), db
+}
+
+func openStore(t *testing.T, db *sql.DB, dialect Dialect, prefix string) *Store {
+	t.Helper()
+
+	store, err := New(Options{DB: db, Dialect: dialect, TablePrefix: prefix, ReapInterval: -1})
+	require.NoError(t, err)
+	require.NoError(t, store.CreateTables(t.Context()))
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+// testClock is a clock a test drives by hand, so bucket boundaries are exact
+// and nothing has to sleep.
+type testClock struct {
+	mu sync.Mutex
+	at time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{at: time.Date(2026, 7, 26, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.at
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.at = c.at.Add(d)
+}
+
+const testEpoch = time.Second
+
+func testSyncRequest(namespace, node string, counts ...bandit.ArmCounts) bandit.SyncRequest {
+	return bandit.SyncRequest{
+		Namespace:   namespace,
+		NodeID:      node,
+		Counts:      counts,
+		EpochMillis: testEpoch.Milliseconds(),
+		CounterTTL:  12 * testEpoch,
+		LeaderTTL:   2 * testEpoch,
+	}
+}
+
+func shadow(policy ascache.PolicyType, hits, misses int64) bandit.ArmCounts {
+	return bandit.ArmCounts{Policy: policy, Role: bandit.RoleShadow, Hits: hits, Misses: misses}
+}
+
+var lruShadow = bandit.ArmKey{Policy: ascache.LRU, Role: bandit.RoleShadow}
+
+func TestNew_ValidatesOptions(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = New(Options{Dialect: SQLite})
+	require.ErrorIs(t, err, ErrNilDB)
+
+	_, err = New(Options{DB: db})
+	require.ErrorIs(t, err, ErrUnknownDialect)
+
+	for _, prefix := range []string{"1abc", "a-b", "a; DROP TABLE x; --", "a2345678901234567890123456789012345678901"} {
+		_, err = New(Options{DB: db, Dialect: SQLite, TablePrefix: prefix})
+		require.ErrorIs(t, err, ErrInvalidTablePrefix, "%q", prefix)
+	}
+}
+
+func TestDialect_RebindNumbersPlaceholdersForPostgres(t *testing.T) {
+	query := "SELECT a FROM t WHERE b = ? AND c BETWEEN ? AND ?"
+
+	assert.Equal(t, query, SQLite.rebind(query))
+	assert.Equal(t, "SELECT a FROM t WHERE b = $1 AND c BETWEEN $2 AND $3", Postgres.rebind(query))
+}
+
+// TestStore_BucketComesFromTheDatabaseClock checks that with no clock set the
+// bucket is the database's time, which for SQLite is this machine's.
+func TestStore_BucketComesFromTheDatabaseClock(t *testing.T) {
+	store, _ := newStore(t)
+
+	before := time.Now().Add(-time.Minute).UnixMilli() / testEpoch.Milliseconds()
+	result, err := store.Sync(t.Context(), testSyncRequest("ns", "a"))
+	require.NoError(t, err)
+	after := time.Now().Add(time.Minute).UnixMilli() / testEpoch.Milliseconds()
+
+	assert.GreaterOrEqual(t, int64(result.Bucket), before)
+	assert.LessOrEqual(t, int64(result.Bucket), after)
+}
+
+func TestStore_UnknownDecisionIsAnError(t *testing.T) {
+	store, db := newStore(t)
+	store.SetClock(newTestClock().now)
+
+	result, err := store.Sync(t.Context(), testSyncRequest("ns", "a"))
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(t.Context(), store.q.insertDecision, "ns", int64(result.Bucket), "NoSuchPolicy",
+		newTestClock().now().Add(time.Minute).UnixMilli())
+	require.NoError(t, err)
+
+	_, err = store.Sync(t.Context(), testSyncRequest("ns", "a"))
+	assert.ErrorContains(t, err, "NoSuchPolicy")
+}
+
+func TestStore_ReapDeletesExpiredRows(t *testing.T) {
+	store, _ := newStore(t)
+	clock := newTestClock()
+	store.SetClock(clock.now)
+
+	req := testSyncRequest("ns", "a", shadow(ascache.LRU, 10, 0), shadow(ascache.LFU, 1, 0))
+	req.Lead = true
+	result, err := store.Sync(t.Context(), req)
+	require.NoError(t, err)
+	_, err = store.Decide(t.Context(), "ns", result.Bucket, ascache.LRU, testEpoch)
+	require.NoError(t, err)
+	_, err = store.Regimes(t.Context(), bandit.RegimeRequest{Namespace: "ns", NodeID: "a", Regime: "r", TTL: testEpoch})
+	require.NoError(t, err)
+
+	reaped, err := store.Reap(t.Context())
+	require.NoError(t, err)
+	assert.Zero(t, reaped, "nothing has expired yet")
+
+	clock.advance(time.Minute)
+	reaped, err = store.Reap(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), reaped, "two counter rows, two per-node ones, one leader, one decision and one regime")
+}
+
+func TestStore_CloseStopsTheReaper(t *testing.T) {
+	_, db := newStore(t)
+
+	store, err := New(Options{DB: db, Dialect: SQLite, ReapInterval: time.Millisecond})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Close())
+	require.NoError(t, store.Close(), "Close is idempotent")
+}
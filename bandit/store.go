@@ -97,7 +97,8 @@ type SyncResult struct {
 	// Decision is the policy published for Bucket, and HasDecision reports
 	// whether one had been published when the sync ran. A replica that syncs
 	// before its leader has decided sees no decision and keeps using the
-	// previous one, which is the mode's one epoch of built-in staleness.
+	// previous one, which is the mode's one epoch of built-in staleness - or
+	// less, over a PushStore that delivers the decision when it is made.
 	Decision    ascache.PolicyType
 	HasDecision bool
 }
@@ -126,6 +127,11 @@ type ArmKey struct {
 // Implementations must be safe for concurrent use, and must respect the
 // context: a replica whose store has stopped responding falls back to deciding
 // locally, and it can only do that if these calls actually return.
+//
+// The rules below are checked by github.com/sshaplygin/as-cache/bandit/storetest,
+// which every store in this repository runs and a store of your own should.
+// The one exception is bandit/gossip, which has no leader to elect and says
+// so with an error.
 type Store interface {
 	// Sync publishes one replica's counts and reports the bucket they landed
 	// in, whether this replica leads that bucket, and any decision already
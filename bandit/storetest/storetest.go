@@ -0,0 +1,888 @@
+// Package storetest checks a bandit.Store against the contract the bandit
+// relies on.
+//
+// The rules are written out on the bandit.Store interface; this package is
+// them, executable. Every store in this repository runs it, and a store of
+// your own - etcd, Spanner, whatever the fleet already has - should too:
+//
+//	func TestStore_Contract(t *testing.T) {
+//	    storetest.Run(t, func() bandit.Store {
+//	        return newMyStore(t)
+//	    })
+//	}
+//
+// newStore is called once per check, and each store it returns is closed when
+// that check ends. The stores may share a backend: every check writes under
+// namespaces of its own, made unique per run, so a real server left over from
+// a previous run, or shared with other tests, does not upset it.
+//
+// # Clock-driven checks
+//
+// Some rules are about time: a bucket boundary, a TTL running out. A store
+// that implements Clocked has its clock driven by the suite, and those checks
+// run exactly and instantly. A store that does not is still checked against
+// everything else, and the clock-driven checks are skipped - with a message,
+// so a store that meant to support them and does not is visible.
+//
+// The same goes for bandit.RegimeStore, bandit.ContributorStore and
+// bandit.PinStore: a store that implements one has what it adds checked, and
+// one that does not has those checks skipped.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+// Clocked is implemented by a store whose clock can be replaced. The store
+// must call now whenever it would read its own clock - to assign a bucket or
+// to judge an expiry - and nowhere else.
+type Clocked interface {
+	SetClock(now func() time.Time)
+}
+
+// epoch is the coordination epoch the checks run at. It is long enough that a
+// store on its real clock will not cross a bucket boundary in the middle of
+// most checks, and the checks that could be upset by one allow for it.
+const epoch = time.Minute
+
+// runID separates one run's namespaces from the last one's on a backend that
+// outlives the process.
+var runID = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// Run checks the stores newStore returns against the bandit.Store contract,
+// each rule in a subtest of its own.
+func Run(t *testing.T, newStore func() bandit.Store) {
+	t.Helper()
+
+	checks := []struct {
+		name string
+		run  func(t *testing.T, s *suite)
+	}{
+		{"SumsCountsAcrossReplicas", sumsCountsAcrossReplicas},
+		{"KeepsRolesApart", keepsRolesApart},
+		{"NamespacesDoNotPool", namespacesDoNotPool},
+		{"OneLeaderPerBucket", oneLeaderPerBucket},
+		{"LeadershipIsNotClaimedUnlessAsked", leadershipIsNotClaimedUnlessAsked},
+		{"DecisionIsWriteOnce", decisionIsWriteOnce},
+		{"SyncReportsTheDecision", syncReportsTheDecision},
+		{"WindowOmitsBucketsNeverWritten", windowOmitsBucketsNeverWritten},
+		{"WindowIsInBucketOrder", windowIsInBucketOrder},
+		{"WindowBelongsToTheCaller", windowBelongsToTheCaller},
+		{"RespectsContextCancellation", respectsContextCancellation},
+		{"ConcurrentReplicas", concurrentReplicas},
+		{"ConcurrentReplicasWithManyArms", concurrentReplicasWithManyArms},
+		{"BucketComesFromTheStoreClock", bucketComesFromTheStoreClock},
+		{"NextBucketHasANewLeader", nextBucketHasANewLeader},
+		{"ExpiredBucketsLeaveHoles", expiredBucketsLeaveHoles},
+		{"ExpiredDecisionMakesWay", expiredDecisionMakesWay},
+		{"RegimesAreCountedPerNamespace", regimesAreCountedPerNamespace},
+		{"RegimeAnnouncementsExpire", regimeAnnouncementsExpire},
+		{"KeepsCountsPerNode", keepsCountsPerNode},
+		{"ExpiredNodeBucketsLeaveHoles", expiredNodeBucketsLeaveHoles},
+		{"PinIsSetReadAndLifted", pinIsSetReadAndLifted},
+		{"PinDoesNotExpire", pinDoesNotExpire},
+	}
+
+	for _, check := range checks {
+		t.Run(check.name, func(t *testing.T) {
+			store := newStore()
+			if store == nil {
+				t.Fatal("newStore returned nil")
+			}
+			t.Cleanup(func() {
+				if err := store.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			})
+
+			check.run(t, &suite{store: store, prefix: "storetest:" + runID + ":" + check.name + ":"})
+		})
+	}
+}
+
+// suite is one check's store and the namespaces it writes under.
+type suite struct {
+	store  bandit.Store
+	prefix string
+}
+
+// namespace returns a namespace unique to this check and this run.
+func (s *suite) namespace(name string) string {
+	return s.prefix + name
+}
+
+// clocked hands the store a clock the check drives, or skips the check if the
+// store cannot take one.
+func (s *suite) clocked(t *testing.T) *clock {
+	t.Helper()
+
+	clocked, ok := s.store.(Clocked)
+	if !ok {
+		t.Skip("the store does not implement storetest.Clocked, so clock-driven rules are not checked")
+	}
+
+	c := &clock{at: time.Date(2026, 7, 26, 12, 0, 0, 0, time.UTC)}
+	clocked.SetClock(c.now)
+
+	return c
+}
+
+// regimes returns the store as a RegimeStore, or skips the check if it is
+// not one.
+func (s *suite) regimes(t *testing.T) bandit.RegimeStore {
+	t.Helper()
+
+	regimes, ok := s.store.(bandit.RegimeStore)
+	if !ok {
+		t.Skip("the store does not implement bandit.RegimeStore, so regime announcements are not checked")
+	}
+
+	return regimes
+}
+
+func (s *suite) announce(t *testing.T, store bandit.RegimeStore, namespace, node, regime string,
+	ttl time.Duration,
+) []bandit.RegimeCount {
+	t.Helper()
+
+	regimes, err := store.Regimes(t.Context(), bandit.RegimeRequest{
+		Namespace: namespace,
+		NodeID:    node,
+		Regime:    regime,
+		TTL:       ttl,
+	})
+	if err != nil {
+		t.Fatalf("Regimes: %v", err)
+	}
+
+	return regimes
+}
+
+// pins returns the store as a PinStore, or skips the check if it is not one.
+func (s *suite) pins(t *testing.T) bandit.PinStore {
+	t.Helper()
+
+	pins, ok := s.store.(bandit.PinStore)
+	if !ok {
+		t.Skip("the store does not implement bandit.PinStore, so fleet-wide pins are not checked")
+	}
+
+	return pins
+}
+
+func (s *suite) pin(t *testing.T, store bandit.PinStore, namespace string) ascache.PolicyType {
+	t.Helper()
+
+	policy, err := store.Pin(t.Context(), namespace)
+	if err != nil {
+		t.Fatalf("Pin: %v", err)
+	}
+
+	return policy
+}
+
+// contributors returns the store as a ContributorStore, or skips the check if
+// it is not one.
+func (s *suite) contributors(t *testing.T) bandit.ContributorStore {
+	t.Helper()
+
+	contributors, ok := s.store.(bandit.ContributorStore)
+	if !ok {
+		t.Skip("the store does not implement bandit.ContributorStore, so per-node counts are not checked")
+	}
+
+	return contributors
+}
+
+func (s *suite) nodeWindow(t *testing.T, store bandit.ContributorStore, namespace string,
+	first, last bandit.Bucket,
+) []bandit.NodeWindowCounts {
+	t.Helper()
+
+	window, err := store.NodeWindow(t.Context(), namespace, first, last)
+	if err != nil {
+		t.Fatalf("NodeWindow: %v", err)
+	}
+
+	return window
+}
+
+// clock is a clock a check drives by hand, so bucket boundaries are exact and
+// nothing has to sleep.
+type clock struct {
+	mu sync.Mutex
+	at time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.at
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.at = c.at.Add(d)
+}
+
+func request(namespace, node string, counts ...bandit.ArmCounts) bandit.SyncRequest {
+	return bandit.SyncRequest{
+		Namespace:   namespace,
+		NodeID:      node,
+		Counts:      counts,
+		EpochMillis: epoch.Milliseconds(),
+		CounterTTL:  12 * epoch,
+		LeaderTTL:   2 * epoch,
+	}
+}
+
+func shadow(policy ascache.PolicyType, hits, misses int64) bandit.ArmCounts {
+	return bandit.ArmCounts{Policy: policy, Role: bandit.RoleShadow, Hits: hits, Misses: misses}
+}
+
+func active(policy ascache.PolicyType, hits, misses int64) bandit.ArmCounts {
+	return bandit.ArmCounts{Policy: policy, Role: bandit.RoleActive, Hits: hits, Misses: misses}
+}
+
+var lruShadow = bandit.ArmKey{Policy: ascache.LRU, Role: bandit.RoleShadow}
+
+func (s *suite) sync(t *testing.T, req bandit.SyncRequest) bandit.SyncResult {
+	t.Helper()
+
+	result, err := s.store.Sync(t.Context(), req)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	return result
+}
+
+func (s *suite) window(t *testing.T, namespace string, first, last bandit.Bucket) []bandit.WindowCounts {
+	t.Helper()
+
+	window, err := s.store.Window(t.Context(), namespace, first, last)
+	if err != nil {
+		t.Fatalf("Window: %v", err)
+	}
+
+	return window
+}
+
+func (s *suite) decide(t *testing.T, namespace string, bucket bandit.Bucket, policy ascache.PolicyType,
+	ttl time.Duration,
+) ascache.PolicyType {
+	t.Helper()
+
+	decided, err := s.store.Decide(t.Context(), namespace, bucket, policy, ttl)
+	if err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+
+	return decided
+}
+
+// sum adds one arm's counts across a window. Checks that run on a store's own
+// clock read a window around their bucket rather than the bucket alone, in
+// case a boundary passed between two calls.
+func sum(window []bandit.WindowCounts, key bandit.ArmKey) ascache.PolicyStats {
+	var total ascache.PolicyStats
+	for _, counts := range window {
+		total.Hits += counts.Arms[key].Hits
+		total.Misses += counts.Arms[key].Misses
+	}
+
+	return total
+}
+
+func sumsCountsAcrossReplicas(t *testing.T, s *suite) {
+	ns := s.namespace("ns")
+
+	result := s.sync(t, request(ns, "a", shadow(ascache.LRU, 10, 5)))
+	s.sync(t, request(ns, "b", shadow(ascache.LRU, 20, 15)))
+
+	got := sum(s.window(t, ns, result.Bucket, result.Bucket+1), lruShadow)
+	if want := (ascache.PolicyStats{Hits: 30, Misses: 20}); got != want {
+		t.Errorf("summed counts = %+v, want %+v", got, want)
+	}
+}
+
+func keepsRolesApart(t *testing.T, s *suite) {
+	ns := s.namespace("ns")
+
+	result := s.sync(t, request(ns, "a", shadow(ascache.LRU, 10, 0), active(ascache.LRU, 7, 0)))
+	window := s.window(t, ns, result.Bucket, result.Bucket+1)
+
+	if got := sum(window, lruShadow).Hits; got != 10 {
+		t.Errorf("shadow hits = %d, want 10", got)
+	}
+	if got := sum(window, bandit.ArmKey{Policy: ascache.LRU, Role: bandit.RoleActive}).Hits; got != 7 {
+		t.Errorf("active hits = %d, want 7", got)
+	}
+}
+
+func namespacesDoNotPool(t *testing.T, s *suite) {
+	one, two := s.namespace("one"), s.namespace("two")
+
+	result := s.sync(t, request(one, "a", shadow(ascache.LRU, 10, 0)))
+	s.sync(t, request(two, "b", shadow(ascache.LRU, 999, 0)))
+
+	if got := sum(s.window(t, one, result.Bucket, result.Bucket+1), lruShadow).Hits; got != 10 {
+		t.Errorf("hits in namespace one = %d, want 10: namespaces pooled", got)
+	}
+}
+
+func oneLeaderPerBucket(t *testing.T, s *suite) {
+	ns := s.namespace("ns")
+
+	leaders := make(map[bandit.Bucket]int)
+	for _, node := range []string{"a", "b", "c", "d", "e"} {
+		req := request(ns, node)
+		req.Lead = true
+		result := s.sync(t, req)
+		if result.Leader {
+			leaders[result.Bucket]++
+		}
+	}
+
+	for bucket, count := range leaders {
+		if count > 1 {
+			t.Errorf("bucket %d has %d leaders, want at most one", bucket, count)
+		}
+	}
+	if len(leaders) == 0 {
+		t.Error("no replica became leader of an unclaimed bucket")
+	}
+}
+
+func leadershipIsNotClaimedUnlessAsked(t *testing.T, s *suite) {
+	ns := s.namespace("ns")
+
+	if s.sync(t, request(ns, "a")).Leader {
+		t.Error("a replica that did not ask to lead was made leader")
+	}
+
+	// And a replica that did not ask must not have taken the claim either.
+	req := request(ns, "b")
+	req.Lead = true
+	first := s.sync(t, request(ns, "a"))
+	if result := s.sync(t, req); result.Bucket == first.Bucket && !result.Leader {
+		t.Error("a bucket nobody asked to lead was already claimed")
+	}
+}
+
+func decisionIsWriteOnce(t *testing.T, s *suite) {
+	ns := s.namespace("ns")
+
+	if got := s.decide(t, ns, 100, ascache.TinyLFU, time.Hour); got != ascache.TinyLFU {
+		t.Fatalf("first Decide returned %v, want TinyLFU", got)
+	}
+
+	// Republishing is not an error, and reports what is actually in force.
+	if got := s.decide(t, ns, 100, ascache.LRU, time.Hour); got != ascache.TinyLFU {
+		t.Errorf("second Decide returned %v, want the decision already in force, TinyLFU", got)
+	}
+
+	if got := s.decide(t, ns, 101, ascache.LRU, time.Hour); got != ascache.LRU {
+		t.Errorf("Decide on another bucket returned %v, want LRU", got)
+	}
+}
+
+func syncReportsTheDecision(t *testing.T, s *suite) {
+	ns := s.namespace("ns")
+
+	result := s.sync(t, request(ns, "a"))
+	if result.HasDecision {
+		t.Fatalf("an undecided bucket reported decision %v", result.Decision)
+	}
+
+	s.decide(t, ns, result.Bucket, ascache.LFU, time.Hour)
+
+	again := s.sync(t, request(ns, "b"))
+	if again.Bucket != result.Bucket {
+		t.Skip("a bucket boundary passed mid-check")
+	}
+	if !again.HasDecision || again.Decision != ascache.LFU {
+		t.Errorf("Sync reported decision %v (%t), want LFU", again.Decision, again.HasDecision)
+	}
+}
+
+func windowOmitsBucketsNeverWritten(t *testing.T, s *suite) {
+	ns := s.namespace("ns")
+
+	result := s.sync(t, request(ns, "a", shadow(ascache.LRU, 1, 1)))
+
+	window := s.window(t, ns, result.Bucket-10, result.Bucket)
+	if len(window) != 1 || window[0].Bucket != result.Bucket {
+		t.Errorf("window = %+v, want only bucket %d", window, result.Bucket)
+	}
+
+	if window := s.window(t, s.namespace("never-written"), result.Bucket-10, result.Bucket); len(window) != 0 {
+		t.Errorf("window of a namespace nobody wrote = %+v, want empty", window)
+	}
+	if window := s.window(t, ns, result.Bucket, result.Bucket-1); len(window) != 0 {
+		t.Errorf("window of an inverted range = %+v, want empty", window)
+	}
+}
+
+func windowIsInBucketOrder(t *testing.T, s *suite) {
+	c := s.clocked(t)
+	ns := s.namespace("ns")
+
+	var buckets []bandit.Bucket
+	for range 3 {
+		buckets = append(buckets, s.sync(t, request(ns, "a", shadow(ascache.LRU, 1, 0))).Bucket)
+		c.advance(epoch)
+	}
+
+	window := s.window(t, ns, buckets[0], buckets[len(buckets)-1])
+	if len(window) != len(buckets) {
+		t.Fatalf("window has %d buckets, want %d", len(window), len(buckets))
+	}
+	for i, counts := range window {
+		if counts.Bucket != buckets[i] {
+			t.Errorf("window[%d] is bucket %d, want %d", i, counts.Bucket, buckets[i])
+		}
+	}
+}
+
+func windowBelongsToTheCaller(t *testing.T, s *suite) {
+	ns := s.namespace("ns")
+
+	result := s.sync(t, request(ns, "a", shadow(ascache.LRU, 10, 0)))
+
+	window := s.window(t, ns, result.Bucket, result.Bucket)
+	if len(window) != 1 {
+		t.Skip("a bucket boundary passed mid-check")
+	}
+
+	// A caller mutating what it read must not corrupt the store's own state.
+	window[0].Arms[lruShadow] = ascache.PolicyStats{Hits: 1 << 40}
+
+	if got := sum(s.window(t, ns, result.Bucket, result.Bucket), lruShadow).Hits; got != 10 {
+		t.Errorf("hits after the caller mutated its window = %d, want 10", got)
+	}
+}
+
+func respectsContextCancellation(t *testing.T, s *suite) {
+	ns := s.namespace("ns")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := s.store.Sync(ctx, request(ns, "a")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Sync with a cancelled context returned %v, want context.Canceled", err)
+	}
+	if _, err := s.store.Window(ctx, ns, 0, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Window with a cancelled context returned %v, want context.Canceled", err)
+	}
+	if _, err := s.store.Decide(ctx, ns, 1, ascache.LRU, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("Decide with a cancelled context returned %v, want context.Canceled", err)
+	}
+}
+
+func concurrentReplicas(t *testing.T, s *suite) {
+	ns := s.namespace("ns")
+
+	const replicas = 8
+	const rounds = 10
+
+	var (
+		mu      sync.Mutex
+		leaders = make(map[bandit.Bucket]int)
+		first   = bandit.Bucket(1<<63 - 1)
+		last    bandit.Bucket
+		wg      sync.WaitGroup
+	)
+	for node := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				req := request(ns, "node-"+strconv.Itoa(node), shadow(ascache.LRU, 1, 1))
+				req.Lead = true
+				result, err := s.store.Sync(t.Context(), req)
+				if err != nil {
+					t.Errorf("Sync: %v", err)
+
+					return
+				}
+
+				mu.Lock()
+				first, last = min(first, result.Bucket), max(last, result.Bucket)
+				if result.Leader {
+					leaders[result.Bucket]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	for bucket, count := range leaders {
+		if count > 1 {
+			t.Errorf("bucket %d has %d leaders under contention, want at most one", bucket, count)
+		}
+	}
+	if got := sum(s.window(t, ns, first, last), lruShadow).Hits; got != replicas*rounds {
+		t.Errorf("hits = %d, want %d: counts were lost under contention", got, replicas*rounds)
+	}
+}
+
+// concurrentReplicasWithManyArms is concurrentReplicas with every replica
+// writing several arms, each in an order of its own. A store that locks per
+// arm must take those locks in an order of its own, too, or two replicas that
+// meet on the same arms in opposite orders deadlock.
+func concurrentReplicasWithManyArms(t *testing.T, s *suite) {
+	ns := s.namespace("ns")
+
+	const replicas = 8
+	const rounds = 10
+
+	arms := []bandit.ArmCounts{
+		active(ascache.LRU, 1, 0),
+		shadow(ascache.LRU, 1, 1),
+		shadow(ascache.LFU, 2, 1),
+		shadow(ascache.ARC, 3, 1),
+		shadow(ascache.TinyLFU, 4, 1),
+	}
+
+	var (
+		mu      sync.Mutex
+		leaders = make(map[bandit.Bucket]int)
+		first   = bandit.Bucket(1<<63 - 1)
+		last    bandit.Bucket
+		wg      sync.WaitGroup
+	)
+	for node := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := range rounds {
+				counts := slices.Clone(arms)
+				slices.Reverse(counts[:(node+round)%len(counts)])
+				slices.Reverse(counts)
+
+				req := request(ns, "node-"+strconv.Itoa(node), counts...)
+				req.Lead = true
+				result, err := s.store.Sync(t.Context(), req)
+				if err != nil {
+					t.Errorf("Sync: %v", err)
+
+					return
+				}
+
+				mu.Lock()
+				first, last = min(first, result.Bucket), max(last, result.Bucket)
+				if result.Leader {
+					leaders[result.Bucket]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	for bucket, count := range leaders {
+		if count > 1 {
+			t.Errorf("bucket %d has %d leaders under contention, want at most one", bucket, count)
+		}
+	}
+
+	window := s.window(t, ns, first, last)
+	for _, arm := range arms {
+		key := bandit.ArmKey{Policy: arm.Policy, Role: arm.Role}
+		want := ascache.PolicyStats{Hits: replicas * rounds * arm.Hits, Misses: replicas * rounds * arm.Misses}
+		if got := sum(window, key); got != want {
+			t.Errorf("%v = %+v, want %+v: counts were lost under contention", key, got, want)
+		}
+	}
+
+	store, ok := s.store.(bandit.ContributorStore)
+	if !ok {
+		return
+	}
+	nodes := sumNodes(s.nodeWindow(t, store, ns, first, last))
+	if len(nodes) != replicas {
+		t.Errorf("NodeWindow has %d nodes, want %d", len(nodes), replicas)
+	}
+	for node, got := range nodes {
+		for _, arm := range arms {
+			key := bandit.ArmKey{Policy: arm.Policy, Role: arm.Role}
+			want := ascache.PolicyStats{Hits: rounds * arm.Hits, Misses: rounds * arm.Misses}
+			if got[key] != want {
+				t.Errorf("%s's %v = %+v, want %+v: counts were lost under contention", node, key, got[key], want)
+			}
+		}
+	}
+}
+
+func bucketComesFromTheStoreClock(t *testing.T, s *suite) {
+	c := s.clocked(t)
+	ns := s.namespace("ns")
+
+	first := s.sync(t, request(ns, "a"))
+	if want := bandit.Bucket(c.now().UnixMilli() / epoch.Milliseconds()); first.Bucket != want {
+		t.Errorf("bucket = %d, want %d: the store clock divided by the epoch", first.Bucket, want)
+	}
+
+	c.advance(epoch)
+	if second := s.sync(t, request(ns, "a")); second.Bucket != first.Bucket+1 {
+		t.Errorf("bucket after one epoch = %d, want %d", second.Bucket, first.Bucket+1)
+	}
+}
+
+func nextBucketHasANewLeader(t *testing.T, s *suite) {
+	c := s.clocked(t)
+	ns := s.namespace("ns")
+
+	req := request(ns, "a")
+	req.Lead = true
+	if !s.sync(t, req).Leader {
+		t.Fatal("the first claim on a bucket failed")
+	}
+
+	c.advance(epoch)
+	req.NodeID = "b"
+	if !s.sync(t, req).Leader {
+		t.Error("the next bucket was not up for grabs")
+	}
+}
+
+func expiredBucketsLeaveHoles(t *testing.T, s *suite) {
+	c := s.clocked(t)
+	ns := s.namespace("ns")
+
+	req := request(ns, "a", shadow(ascache.LRU, 10, 0))
+	req.CounterTTL = 2 * epoch
+	first := s.sync(t, req)
+
+	c.advance(5 * epoch)
+	latest := s.sync(t, req)
+
+	window := s.window(t, ns, first.Bucket, latest.Bucket)
+	if len(window) != 1 || window[0].Bucket != latest.Bucket {
+		t.Errorf("window = %+v, want only bucket %d: an expired bucket is absent, not present and empty",
+			window, latest.Bucket)
+	}
+}
+
+func expiredDecisionMakesWay(t *testing.T, s *suite) {
+	c := s.clocked(t)
+	ns := s.namespace("ns")
+
+	s.decide(t, ns, 7, ascache.LRU, epoch)
+
+	c.advance(2 * epoch)
+	if got := s.decide(t, ns, 7, ascache.LFU, epoch); got != ascache.LFU {
+		t.Errorf("Decide after the decision expired returned %v, want LFU", got)
+	}
+}
+
+func regimesAreCountedPerNamespace(t *testing.T, s *suite) {
+	store := s.regimes(t)
+	ns := s.namespace("ns")
+
+	s.announce(t, store, ns, "a", "arms=LRU,LFU;cap=1000;rate=1.0000", epoch)
+	s.announce(t, store, ns, "b", "arms=LRU,LFU;cap=1000;rate=1.0000", epoch)
+	s.announce(t, store, s.namespace("other"), "d", "arms=LRU;cap=10;rate=1.0000", epoch)
+	got := s.announce(t, store, ns, "c", "arms=LRU,LFU;cap=2000;rate=1.0000", epoch)
+
+	want := []bandit.RegimeCount{
+		{Regime: "arms=LRU,LFU;cap=1000;rate=1.0000", Replicas: 2},
+		{Regime: "arms=LRU,LFU;cap=2000;rate=1.0000", Replicas: 1},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Regimes = %+v, want %+v", got, want)
+	}
+
+	// A node announcing again replaces its announcement rather than adding one.
+	got = s.announce(t, store, ns, "a", "arms=LRU,LFU;cap=2000;rate=1.0000", epoch)
+	want = []bandit.RegimeCount{
+		{Regime: "arms=LRU,LFU;cap=1000;rate=1.0000", Replicas: 1},
+		{Regime: "arms=LRU,LFU;cap=2000;rate=1.0000", Replicas: 2},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Regimes after a re-announcement = %+v, want %+v", got, want)
+	}
+
+	// Announcements live apart from the counts synced under the same name.
+	result := s.sync(t, request(ns, "a", shadow(ascache.LRU, 1, 1)))
+	if window := s.window(t, ns, result.Bucket, result.Bucket); len(window) != 1 || len(window[0].Arms) != 1 {
+		t.Errorf("window after announcements = %+v, want the one synced arm", window)
+	}
+}
+
+func regimeAnnouncementsExpire(t *testing.T, s *suite) {
+	store := s.regimes(t)
+	c := s.clocked(t)
+	ns := s.namespace("ns")
+
+	s.announce(t, store, ns, "a", "arms=LRU;cap=1000;rate=1.0000", epoch)
+
+	c.advance(2 * epoch)
+	got := s.announce(t, store, ns, "b", "arms=LRU;cap=2000;rate=1.0000", epoch)
+	want := []bandit.RegimeCount{{Regime: "arms=LRU;cap=2000;rate=1.0000", Replicas: 1}}
+	if !slices.Equal(got, want) {
+		t.Errorf("Regimes after the first announcement expired = %+v, want %+v", got, want)
+	}
+}
+
+func pinIsSetReadAndLifted(t *testing.T, s *suite) {
+	store := s.pins(t)
+	ns := s.namespace("ns")
+
+	if got := s.pin(t, store, ns); got != ascache.Undefined {
+		t.Errorf("Pin before any was set = %v, want Undefined", got)
+	}
+
+	for _, policy := range []ascache.PolicyType{ascache.LFU, ascache.LRU} {
+		if err := store.SetPin(t.Context(), ns, policy); err != nil {
+			t.Fatalf("SetPin: %v", err)
+		}
+		if got := s.pin(t, store, ns); got != policy {
+			t.Errorf("Pin = %v, want %v", got, policy)
+		}
+	}
+	if got := s.pin(t, store, s.namespace("other")); got != ascache.Undefined {
+		t.Errorf("Pin under another namespace = %v, want Undefined", got)
+	}
+
+	// A pin lives apart from the counts synced under the same name.
+	result := s.sync(t, request(ns, "a", shadow(ascache.LFU, 1, 1)))
+	if window := s.window(t, ns, result.Bucket, result.Bucket); len(window) != 1 || len(window[0].Arms) != 1 {
+		t.Errorf("window beside a pin = %+v, want the one synced arm", window)
+	}
+
+	if err := store.SetPin(t.Context(), ns, ascache.Undefined); err != nil {
+		t.Fatalf("SetPin(Undefined): %v", err)
+	}
+	if got := s.pin(t, store, ns); got != ascache.Undefined {
+		t.Errorf("Pin after it was lifted = %v, want Undefined", got)
+	}
+}
+
+func pinDoesNotExpire(t *testing.T, s *suite) {
+	store := s.pins(t)
+	c := s.clocked(t)
+	ns := s.namespace("ns")
+
+	if err := store.SetPin(t.Context(), ns, ascache.LFU); err != nil {
+		t.Fatalf("SetPin: %v", err)
+	}
+
+	// Long past every TTL a sync sets, with syncs along the way to give a
+	// store that sweeps as it writes the chance to.
+	for range 3 {
+		c.advance(100 * epoch)
+		s.sync(t, request(ns, "a", shadow(ascache.LRU, 1, 0)))
+	}
+	if got := s.pin(t, store, ns); got != ascache.LFU {
+		t.Errorf("Pin long after it was set = %v, want LFU: a pin stands until it is lifted", got)
+	}
+}
+
+func keepsCountsPerNode(t *testing.T, s *suite) {
+	store := s.contributors(t)
+	ns := s.namespace("ns")
+
+	// A node ID is the caller's, and may hold anything a store uses to
+	// separate the parts of a key.
+	odd := "node:with spaces/and|separators"
+
+	result := s.sync(t, request(ns, "a", shadow(ascache.LRU, 10, 5)))
+	s.sync(t, request(ns, odd, shadow(ascache.LRU, 20, 15), shadow(ascache.LFU, 1, 0)))
+	s.sync(t, request(ns, "a", shadow(ascache.LRU, 1, 1)))
+	s.sync(t, request(s.namespace("other"), "c", shadow(ascache.LRU, 999, 0)))
+
+	nodes := s.nodeWindow(t, store, ns, result.Bucket, result.Bucket+1)
+	want := map[string]map[bandit.ArmKey]ascache.PolicyStats{
+		"a": {lruShadow: {Hits: 11, Misses: 6}},
+		odd: {lruShadow: {Hits: 20, Misses: 15}, {Policy: ascache.LFU, Role: bandit.RoleShadow}: {Hits: 1}},
+	}
+	if got := sumNodes(nodes); !equalNodes(got, want) {
+		t.Errorf("per-node counts = %+v, want %+v", got, want)
+	}
+
+	// Summing the replicas gives the fleet's window, bucket for bucket.
+	window := s.window(t, ns, result.Bucket, result.Bucket+1)
+	if len(nodes) != len(window) {
+		t.Fatalf("NodeWindow has %d buckets, Window %d", len(nodes), len(window))
+	}
+	for i, bucket := range nodes {
+		summed := make(map[bandit.ArmKey]ascache.PolicyStats)
+		for _, arms := range bucket.Nodes {
+			addArms(summed, arms)
+		}
+		if bucket.Bucket != window[i].Bucket || !maps.Equal(summed, window[i].Arms) {
+			t.Errorf("bucket %d summed over nodes = %+v, Window has bucket %d = %+v",
+				bucket.Bucket, summed, window[i].Bucket, window[i].Arms)
+		}
+	}
+}
+
+func expiredNodeBucketsLeaveHoles(t *testing.T, s *suite) {
+	store := s.contributors(t)
+	c := s.clocked(t)
+	ns := s.namespace("ns")
+
+	req := request(ns, "a", shadow(ascache.LRU, 10, 0))
+	req.CounterTTL = 2 * epoch
+	first := s.sync(t, req)
+
+	c.advance(5 * epoch)
+	latest := s.sync(t, req)
+
+	window := s.nodeWindow(t, store, ns, first.Bucket, latest.Bucket)
+	if len(window) != 1 || window[0].Bucket != latest.Bucket {
+		t.Errorf("node window = %+v, want only bucket %d", window, latest.Bucket)
+	}
+}
+
+// sumNodes adds each node's counts across every bucket of a window.
+func sumNodes(window []bandit.NodeWindowCounts) map[string]map[bandit.ArmKey]ascache.PolicyStats {
+	nodes := make(map[string]map[bandit.ArmKey]ascache.PolicyStats)
+	for _, bucket := range window {
+		for node, arms := range bucket.Nodes {
+			if nodes[node] == nil {
+				nodes[node] = make(map[bandit.ArmKey]ascache.PolicyStats)
+			}
+			addArms(nodes[node], arms)
+		}
+	}
+
+	return nodes
+}
+
+func addArms(into, arms map[bandit.ArmKey]ascache.PolicyStats) {
+	for key, stats := range arms {
+		sum := into[key]
+		sum.Hits += stats.Hits
+		sum.Misses += stats.Misses
+		into[key] = sum
+	}
+}
+
+func equalNodes(a, b map[string]map[bandit.ArmKey]ascache.PolicyStats) bool {
+	return maps.EqualFunc(a, b, func(x, y map[bandit.ArmKey]ascache.PolicyStats) bool {
+		return maps.Equal(x, y)
+	})
+}
@@ -44,32 +44,50 @@ func aggregate(
 	pooled := make(map[ascache.PolicyType]weighted)
 
 	for _, bucket := range window {
-		age := int64(newest - bucket.Bucket)
-		if age < 0 {
-			// A bucket newer than the reference is still being written by the
-			// rest of the fleet, so it is a partial count that would weight
-			// whichever replicas happen to have arrived already.
+		weight, ok := bucketWeight(bucket.Bucket, newest, decay)
+		if !ok {
 			continue
 		}
+		addWeighted(pooled, bucket.Arms, weight, mode)
+	}
 
-		weight := math.Pow(decay, float64(age))
-		if weight == 0 {
-			continue
-		}
+	return pooled
+}
+
+// bucketWeight is decay raised to a bucket's age relative to newest, and
+// reports false for a bucket that carries no weight at all.
+func bucketWeight(bucket, newest Bucket, decay float64) (float64, bool) {
+	age := int64(newest - bucket)
+	if age < 0 {
+		// A bucket newer than the reference is still being written by the
+		// rest of the fleet, so it is a partial count that would weight
+		// whichever replicas happen to have arrived already.
+		return 0, false
+	}
 
-		for key, stats := range bucket.Arms {
-			if mode == EvidenceShadowOnly && key.Role == RoleActive {
-				continue
-			}
+	weight := math.Pow(decay, float64(age))
 
-			arm := pooled[key.Policy]
-			arm.Hits += weight * float64(stats.Hits)
-			arm.Misses += weight * float64(stats.Misses)
-			pooled[key.Policy] = arm
+	return weight, weight != 0
+}
+
+// addWeighted adds one bucket's counts into pooled at the given weight,
+// dropping the roles mode excludes.
+func addWeighted(
+	pooled map[ascache.PolicyType]weighted,
+	arms map[ArmKey]ascache.PolicyStats,
+	weight float64,
+	mode EvidenceMode,
+) {
+	for key, stats := range arms {
+		if mode == EvidenceShadowOnly && key.Role == RoleActive {
+			continue
 		}
-	}
 
-	return pooled
+		arm := pooled[key.Policy]
+		arm.Hits += weight * float64(stats.Hits)
+		arm.Misses += weight * float64(stats.Misses)
+		pooled[key.Policy] = arm
+	}
 }
 
 // capEvidence scales each arm's counts down to at most maxEvidence
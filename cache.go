@@ -62,10 +62,13 @@ type AdaptiveCache[K comparable, V any] struct {
 	// rather than on every epoch, and its nil-ness is what selects between the
 	// two reporting shapes - a bandit never receives both.
 	epochBandit EpochBandit
+	// pinningBandit is bandit again when it implements PinningBandit, and nil
+	// otherwise, asserted once for the same reason epochBandit is.
+	pinningBandit PinningBandit
 
 	// epochStats holds the per-policy stats measured in the epoch the last
 	// report covered, keyed by policy. The switch-stability gates in
-	// allowSwitchLocked read it; it is empty on epochs that skipped reporting.
+	// heldByLocked read it; it is empty on epochs that skipped reporting.
 	epochStats map[PolicyType]PolicyStats
 
 	// tenureStats accumulates a policy's measurements for as long as it stays
@@ -91,6 +94,29 @@ type AdaptiveCache[K comparable, V any] struct {
 	// used by the SwitchCooldownEpochs gate.
 	lastSwitchEpoch int64
 
+	// switches counts the policy switches made since construction. A scraper
+	// polling ActivePolicy cannot count them itself: two switches between
+	// scrapes, there and back, look like none.
+	switches int64
+
+	// history is the ring History reports, oldest switch first. ledger is
+	// each policy's record as the active one, closed at every switch;
+	// activeSince and servedTenure are the current tenure's start and what it
+	// has served up to the last reporting epoch.
+	history      []SwitchRecord
+	ledger       map[PolicyType]PolicyTenure
+	activeSince  time.Time
+	servedTenure PolicyStats
+
+	// damping is oscillation damping's state; see Settings.Damping.
+	damping dampingState
+
+	// pin is the policy an operator pinned the cache to with Pin, and
+	// fleetPin the one the bandit last reported a pin for. Both are
+	// Undefined when there is none, and pin wins when both are set.
+	pin      PolicyType
+	fleetPin PolicyType
+
 	// --- Settings ---
 	epochID int64
 	// epochTicker is nil when the cache ends its epochs on request count
@@ -99,7 +125,18 @@ type AdaptiveCache[K comparable, V any] struct {
 	// epochRequests counts Get calls since the last request-driven epoch. It
 	// is mutated on the read path, so it must be atomic.
 	epochRequests atomic.Int64
-	settings      *Settings
+	// epochLimit is Settings.EpochRequests, read on every Get. It is kept
+	// apart from settings so Get need not take the lock to read it while
+	// UpdateSettings may be changing it.
+	epochLimit atomic.Int64
+	// settings is the cache's own copy of the settings in force, and pending
+	// the ones UpdateSettings accepted for the next epoch boundary, nil when
+	// there are none. Both are guarded by mu. updateMu serialises
+	// UpdateSettings calls, so two of them cannot build on the same base and
+	// lose one another's change.
+	settings *Settings
+	pending  *Settings
+	updateMu sync.Mutex
 
 	ctx       context.Context
 	cancel    context.CancelFunc
@@ -343,6 +380,17 @@ func (c *AdaptiveCache[K, V]) ActivePolicy() PolicyType {
 	return c.activePolicy
 }
 
+// Settings returns a copy of the settings the cache is running with. Zero
+// fields are returned as zero, not as the defaults they stand for. Changing
+// the copy changes nothing; UpdateSettings does, from the next epoch, and
+// until then this reports the settings still in force.
+func (c *AdaptiveCache[K, V]) Settings() Settings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return *c.settings
+}
+
 // Close stops the background epoch goroutine and waits for it to exit. It is
 // idempotent and safe to call concurrently; every call returns nil after the
 // goroutine has stopped.
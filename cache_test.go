@@ -172,7 +172,7 @@ func triggerSwitch(ac *AdaptiveCache[string, int], to PolicyType) {
 	if from == to {
 		return
 	}
-	ac.switchLocked(from, to)
+	ac.switchLocked(from, to, ac.settings.MigrationStrategy)
 }
 
 // --- MigrationCold ---
@@ -1265,7 +1265,10 @@ func TestPolicyType_String(t *testing.T) {
 		{Undefined, "Undefined"},
 		{LRU, "LRU"},
 		{LFU, "LFU"},
+		{TinyLFU, "TinyLFU"},
+		{LRFU, "LRFU"},
 		{PolicyType(99), "PolicyType(99)"},
+		{firstRegistered + 1<<20, "PolicyType(1114112)"},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,35 @@
+module github.com/sshaplygin/as-cache/cmd/coordinator
+
+go 1.25.2
+
+require (
+	github.com/jackc/pgx/v5 v5.7.5
+	github.com/redis/go-redis/v9 v9.21.0
+	github.com/sshaplygin/as-cache v0.3.1
+	github.com/sshaplygin/as-cache/bandit v0.3.1
+	github.com/sshaplygin/as-cache/bandit/redis v0.3.1
+	github.com/sshaplygin/as-cache/bandit/sqlstore v0.3.1
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.uber.org/atomic v1.11.0 // indirect
+	golang.org/x/crypto v0.37.0 // indirect
+	golang.org/x/sync v0.13.0 // indirect
+	golang.org/x/text v0.24.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/sshaplygin/as-cache => ../..
+
+replace github.com/sshaplygin/as-cache/bandit => ../../bandit
+
+replace github.com/sshaplygin/as-cache/bandit/redis => ../../bandit/redis
+
+replace github.com/sshaplygin/as-cache/bandit/sqlstore => ../../bandit/sqlstore
@@ -0,0 +1,237 @@
+// Command coordinator serves a distributed bandit's store over HTTP, so the
+// replicas of a fleet need only its URL.
+//
+// Replicas reach it through github.com/sshaplygin/as-cache/bandit/remote. The
+// coordinator holds no logic of its own - it is bandit/remote's handler in
+// front of one of the stores in this repository - so the fleet behaves
+// exactly as it would talking to that store directly, and falls back to local
+// decisions when the coordinator is down exactly as it would when the store
+// is.
+//
+//	coordinator -listen :8080 -backend memory
+//	coordinator -listen :8080 -backend redis    # AS_CACHE_REDIS_URL=redis://...
+//	coordinator -listen :8080 -backend postgres # AS_CACHE_POSTGRES_DSN=postgres://...
+//	coordinator -listen :8080 -backend file -dir /var/lib/as-cache
+//
+// A memory backend loses the fleet's pooled window on restart, which the fleet
+// rebuilds in Config.Window epochs; the others survive one, and let several
+// coordinators behind a load balancer share state.
+//
+// Connection strings and the token are read from the environment or a file
+// rather than from flags, which any user on the machine can read from the
+// process list. Set AS_CACHE_COORDINATOR_TOKEN, or -token-file, and give the
+// replicas the same token; a coordinator without one accepts anybody's counts.
+//
+// It is built from a checkout - go build ./cmd/coordinator from this
+// directory - since the replace directives that tie it to its sibling modules
+// stop go install from fetching it by version.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/sshaplygin/as-cache/bandit"
+	"github.com/sshaplygin/as-cache/bandit/filestore"
+	"github.com/sshaplygin/as-cache/bandit/redis"
+	"github.com/sshaplygin/as-cache/bandit/remote"
+	"github.com/sshaplygin/as-cache/bandit/sqlstore"
+)
+
+// config is everything the flags and the environment decide.
+type config struct {
+	listen  string
+	backend string
+	dir     string
+
+	redisURL    string
+	postgresDSN string
+	token       string
+
+	tlsCert string
+	tlsKey  string
+}
+
+func main() {
+	cfg, err := parseConfig(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parseConfig(args []string, getenv func(string) string, output io.Writer) (config, error) {
+	var cfg config
+	var tokenFile string
+
+	flags := flag.NewFlagSet("coordinator", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&cfg.listen, "listen", ":8080", "address to serve on")
+	flags.StringVar(&cfg.backend, "backend", "memory", "where state is kept: memory, file, redis or postgres")
+	flags.StringVar(&cfg.dir, "dir", "", "directory for the file backend")
+	flags.StringVar(&tokenFile, "token-file", "", "file holding the bearer token replicas must present "+
+		"(default $AS_CACHE_COORDINATOR_TOKEN)")
+	flags.StringVar(&cfg.tlsCert, "tls-cert", "", "certificate file, to serve HTTPS")
+	flags.StringVar(&cfg.tlsKey, "tls-key", "", "key file, to serve HTTPS")
+	if err := flags.Parse(args); err != nil {
+		return config{}, err
+	}
+	if flags.NArg() > 0 {
+		return config{}, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	cfg.redisURL = getenv("AS_CACHE_REDIS_URL")
+	cfg.postgresDSN = getenv("AS_CACHE_POSTGRES_DSN")
+	cfg.token = getenv("AS_CACHE_COORDINATOR_TOKEN")
+	if tokenFile != "" {
+		raw, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return config{}, fmt.Errorf("reading token: %w", err)
+		}
+		cfg.token = strings.TrimSpace(string(raw))
+		if cfg.token == "" {
+			return config{}, fmt.Errorf("token file %s is empty", tokenFile)
+		}
+	}
+
+	if (cfg.tlsCert == "") != (cfg.tlsKey == "") {
+		return config{}, errors.New("-tls-cert and -tls-key go together")
+	}
+
+	switch cfg.backend {
+	case "memory":
+	case "file":
+		if cfg.dir == "" {
+			return config{}, errors.New("the file backend needs -dir")
+		}
+	case "redis":
+		if cfg.redisURL == "" {
+			return config{}, errors.New("the redis backend needs AS_CACHE_REDIS_URL")
+		}
+	case "postgres":
+		if cfg.postgresDSN == "" {
+			return config{}, errors.New("the postgres backend needs AS_CACHE_POSTGRES_DSN")
+		}
+	default:
+		return config{}, fmt.Errorf("unknown backend %q: want memory, file, redis or postgres", cfg.backend)
+	}
+
+	return cfg, nil
+}
+
+// openStore opens the backend and returns it with whatever else has to be
+// closed when it is.
+func openStore(ctx context.Context, cfg config) (bandit.Store, func() error, error) {
+	switch cfg.backend {
+	case "file":
+		store, err := filestore.New(filestore.Options{Dir: cfg.dir})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return store, store.Close, nil
+
+	case "redis":
+		opts, err := goredis.ParseURL(cfg.redisURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("AS_CACHE_REDIS_URL: %w", err)
+		}
+		client := goredis.NewClient(opts)
+
+		store, err := redis.New(redis.Options{Client: client})
+		if err != nil {
+			_ = client.Close()
+			return nil, nil, err
+		}
+
+		return store, func() error { return errors.Join(store.Close(), client.Close()) }, nil
+
+	case "postgres":
+		db, err := sql.Open("pgx", cfg.postgresDSN)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		store, err := sqlstore.New(sqlstore.Options{DB: db, Dialect: sqlstore.Postgres})
+		if err == nil {
+			err = store.CreateTables(ctx)
+		}
+		if err != nil {
+			_ = db.Close()
+			return nil, nil, err
+		}
+
+		return store, func() error { return errors.Join(store.Close(), db.Close()) }, nil
+
+	default:
+		store := bandit.NewMemStore()
+		return store, store.Close, nil
+	}
+}
+
+func run(ctx context.Context, cfg config) error {
+	store, closeStore, err := openStore(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("opening %s backend: %w", cfg.backend, err)
+	}
+	defer func() { _ = closeStore() }()
+
+	if cfg.token == "" {
+		log.Print("no token set: any caller can publish counts and decisions")
+	}
+
+	server := &http.Server{
+		Addr:              cfg.listen,
+		Handler:           remote.NewHandler(store, remote.HandlerOptions{Token: cfg.token}),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("serving on %s, state in %s", cfg.listen, cfg.backend)
+		if cfg.tlsCert != "" {
+			serveErr <- server.ListenAndServeTLS(cfg.tlsCert, cfg.tlsKey)
+		} else {
+			serveErr <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// A replica mid-sync gets its answer; one that arrives after this gets a
+	// refused connection and, after its grace period, decides locally.
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return server.Shutdown(shutdown)
+}
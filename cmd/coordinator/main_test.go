@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+	"github.com/sshaplygin/as-cache/bandit/remote"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestParseConfig_Defaults(t *testing.T) {
+	cfg, err := parseConfig(nil, env(nil), io.Discard)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8080", cfg.listen)
+	assert.Equal(t, "memory", cfg.backend)
+	assert.Empty(t, cfg.token)
+}
+
+func TestParseConfig_ReadsSecretsOutsideTheFlags(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("from-file\n"), 0o600))
+
+	cfg, err := parseConfig([]string{"-backend", "postgres", "-token-file", tokenFile}, env(map[string]string{
+		"AS_CACHE_POSTGRES_DSN":      "postgres://localhost/db",
+		"AS_CACHE_COORDINATOR_TOKEN": "from-env",
+	}), io.Discard)
+	require.NoError(t, err)
+
+	assert.Equal(t, "postgres://localhost/db", cfg.postgresDSN)
+	assert.Equal(t, "from-file", cfg.token, "the file wins over the environment")
+}
+
+func TestParseConfig_Rejects(t *testing.T) {
+	for name, args := range map[string][]string{
+		"unknown backend":       {"-backend", "etcd"},
+		"file without a dir":    {"-backend", "file"},
+		"redis without a URL":   {"-backend", "redis"},
+		"postgres without DSN":  {"-backend", "postgres"},
+		"half of TLS":           {"-tls-cert", "cert.pem"},
+		"stray arguments":       {"memory"},
+		"missing token file":    {"-token-file", filepath.Join(t.TempDir(), "absent")},
+		"flag that is not ours": {"-verbose"},
+	} {
+		_, err := parseConfig(args, env(nil), io.Discard)
+		assert.Error(t, err, name)
+	}
+}
+
+// TestCoordinator_ServesTheStore runs a replica's client against the handler
+// the coordinator serves, over each backend that needs nothing installed.
+func TestCoordinator_ServesTheStore(t *testing.T) {
+	for _, cfg := range []config{
+		{backend: "memory"},
+		{backend: "file", dir: t.TempDir()},
+	} {
+		t.Run(cfg.backend, func(t *testing.T) {
+			store, closeStore, err := openStore(t.Context(), cfg)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = closeStore() })
+
+			server := httptest.NewServer(remote.NewHandler(store, remote.HandlerOptions{Token: "t"}))
+			t.Cleanup(server.Close)
+
+			client, err := remote.New(remote.Options{URL: server.URL, Token: "t"})
+			require.NoError(t, err)
+
+			result, err := client.Sync(t.Context(), bandit.SyncRequest{
+				Namespace:   "ns",
+				NodeID:      "a",
+				Counts:      []bandit.ArmCounts{{Policy: ascache.LRU, Role: bandit.RoleShadow, Hits: 3, Misses: 1}},
+				EpochMillis: time.Minute.Milliseconds(),
+				CounterTTL:  time.Hour,
+				LeaderTTL:   time.Minute,
+				Lead:        true,
+			})
+			require.NoError(t, err)
+			assert.True(t, result.Leader)
+
+			window, err := client.Window(t.Context(), "ns", result.Bucket, result.Bucket)
+			require.NoError(t, err)
+			require.Len(t, window, 1)
+			assert.Equal(t, ascache.PolicyStats{Hits: 3, Misses: 1},
+				window[0].Arms[bandit.ArmKey{Policy: ascache.LRU, Role: bandit.RoleShadow}])
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package ascache
+
+import "math"
+
+// probabilityBeats returns the posterior probability that the policy measured
+// as a has a higher true hit rate than the one measured as b.
+//
+// Each arm's hit rate gets a Beta(hits+1, misses+1) posterior - a uniform
+// prior updated by what the arm measured - and the probability that one
+// exceeds the other is taken from the normal approximation to their
+// difference. The approximation is poor only for a handful of requests, where
+// the answer is close to a coin toss either way; it costs one Erf, which
+// matters because it runs inside the epoch's write lock.
+//
+// With no evidence on either side it returns one half, which is the honest
+// answer, and which no sensible SwitchConfidence accepts.
+func probabilityBeats(a, b PolicyStats) float64 {
+	meanA, varA := betaMoments(a)
+	meanB, varB := betaMoments(b)
+
+	spread := math.Sqrt(varA + varB)
+
+	return 0.5 * (1 + math.Erf((meanA-meanB)/(spread*math.Sqrt2)))
+}
+
+// betaMoments returns the mean and variance of the Beta(hits+1, misses+1)
+// posterior on a hit rate.
+func betaMoments(s PolicyStats) (mean, variance float64) {
+	alpha := float64(s.Hits) + 1
+	beta := float64(s.Misses) + 1
+	total := alpha + beta
+
+	mean = alpha / total
+	variance = alpha * beta / (total * total * (total + 1))
+
+	return mean, variance
+}
+
+// IntervalLevel is the coverage of the interval PolicyReport.Interval returns.
+const IntervalLevel = 0.95
+
+// intervalZ is the standard normal quantile for IntervalLevel.
+const intervalZ = 1.959963984540054
+
+// wilson returns the Wilson score interval on a hit rate, at IntervalLevel.
+//
+// Wilson rather than the textbook rate plus or minus two standard errors,
+// which collapses to a single point at a hit rate of 0 or 1 and strays outside
+// [0,1] near them - exactly where a cache that is mostly hitting lives.
+// With no evidence it returns the whole of [0,1].
+func wilson(s PolicyStats) (low, high float64) {
+	n := float64(s.Hits + s.Misses)
+	if n == 0 {
+		return 0, 1
+	}
+
+	p := float64(s.Hits) / n
+	z2 := intervalZ * intervalZ
+	centre := (p + z2/(2*n)) / (1 + z2/n)
+	spread := intervalZ / (1 + z2/n) * math.Sqrt(p*(1-p)/n+z2/(4*n*n))
+
+	return max(centre-spread, 0), min(centre+spread, 1)
+}
+
+// confidentLocked reports whether the evidence gathered since the active
+// policy and candidate last changed role makes the candidate better with at
+// least Settings.SwitchConfidence probability. It must be called while the
+// write lock is held, after selectPolicyLocked has added the epoch to the
+// tenure statistics.
+//
+// The test runs on tenure statistics rather than the epoch's, so evidence
+// accumulates for as long as the two policies keep their roles and the gate
+// opens as soon as there is enough of it - after one epoch for a large win, or
+// after fifty for a narrow one - instead of asking every single epoch to prove
+// the case on its own. A switch resets both tenures, so the policy switched
+// away from has to win its way back from nothing.
+func (c *AdaptiveCache[K, V]) confidentLocked(candidate PolicyType) bool {
+	return probabilityBeats(c.tenureStats[candidate], c.tenureStats[c.activePolicy]) >=
+		c.settings.SwitchConfidence
+}
@@ -0,0 +1,126 @@
+package ascache
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbabilityBeats_WeighsTheEvidence(t *testing.T) {
+	assert.InDelta(t, 0.5, probabilityBeats(PolicyStats{}, PolicyStats{}), 1e-9,
+		"no evidence either way is a coin toss")
+	assert.InDelta(t, 0.5, probabilityBeats(PolicyStats{Hits: 50, Misses: 50}, PolicyStats{Hits: 50, Misses: 50}), 1e-9)
+
+	thin := probabilityBeats(PolicyStats{Hits: 12, Misses: 8}, PolicyStats{Hits: 10, Misses: 10})
+	thick := probabilityBeats(PolicyStats{Hits: 120_000, Misses: 80_000}, PolicyStats{Hits: 100_000, Misses: 100_000})
+	assert.Less(t, thin, 0.9, "0.6 against 0.5 from twenty requests proves little")
+	assert.Greater(t, thick, 0.999, "the same rates from 200k requests prove a lot")
+
+	a, b := PolicyStats{Hits: 30, Misses: 70}, PolicyStats{Hits: 25, Misses: 75}
+	assert.InDelta(t, 1, probabilityBeats(a, b)+probabilityBeats(b, a), 1e-9)
+}
+
+func TestSwitchConfidence_HoldsAThinlyMeasuredWin(t *testing.T) {
+	ac, _, lfu := makeStabilityCache(t, &Settings{SwitchConfidence: 0.95})
+
+	primeActiveStats(ac, 10, 10)
+	primeStats(lfu, 12, 8)
+	ac.runEpoch()
+
+	assert.Equal(t, LRU, ac.ActivePolicy(), "a 10-point win from 20 requests is not yet a win")
+	assert.InDelta(t, probabilityBeats(PolicyStats{Hits: 12, Misses: 8}, PolicyStats{Hits: 10, Misses: 10}),
+		ac.Advice().Confidence, 1e-9)
+}
+
+func TestSwitchConfidence_SwitchesOnceTheEvidenceAccumulates(t *testing.T) {
+	ac, _, lfu := makeStabilityCache(t, &Settings{SwitchConfidence: 0.95})
+
+	epochs := 0
+	for ac.ActivePolicy() == LRU && epochs < 100 {
+		primeActiveStats(ac, 10, 10)
+		primeStats(lfu, 12, 8)
+		ac.runEpoch()
+		epochs++
+	}
+
+	assert.Equal(t, LFU, ac.ActivePolicy())
+	assert.Greater(t, epochs, 1, "no single epoch was enough")
+	assert.Less(t, epochs, 30, "but the tenure evidence added up")
+
+	history := ac.History()
+	require.Len(t, history.Switches, 1)
+	assert.Contains(t, history.Switches[0].GatesPassed, GateSwitchConfidence)
+}
+
+func TestSwitchConfidence_SwitchesOnAClearWinAtOnce(t *testing.T) {
+	ac, _, lfu := makeStabilityCache(t, &Settings{SwitchConfidence: 0.99})
+
+	primeActiveStats(ac, 500, 500)
+	primeStats(lfu, 900, 100)
+	ac.runEpoch()
+
+	assert.Equal(t, LFU, ac.ActivePolicy())
+}
+
+func TestAdvice_ReportsConfidence(t *testing.T) {
+	ac, _, lfu := makeStabilityCache(t, &Settings{ObserveOnly: true})
+
+	primeActiveStats(ac, 100, 100)
+	primeStats(lfu, 130, 70)
+	ac.runEpoch()
+
+	advice := ac.Advice()
+	require.Equal(t, LFU, advice.Best)
+	assert.Greater(t, advice.Confidence, 0.99)
+	assert.Contains(t, advice.String(), "chance of truly beating LRU")
+
+	// Once the active policy is the best, there is nothing to be confident of.
+	primeActiveStats(ac, 200, 0)
+	ac.runEpoch()
+	assert.Zero(t, ac.Advice().Confidence)
+}
+
+func TestSwitchConfidence_RejectsUnreachableValues(t *testing.T) {
+	for _, confidence := range []float64{-0.1, 1, 1.5, math.NaN()} {
+		_, err := NewAdaptiveCache(
+			[]Policy[string, int]{newMockPolicy[string, int](LRU, 10)}, &mockBandit{next: LRU},
+			&Settings{EpochRequests: 10, SwitchConfidence: confidence},
+		)
+		assert.ErrorIs(t, err, ErrInvalidSwitchConfidence, "%v", confidence)
+	}
+}
+
+func TestPolicyReport_IntervalNarrowsWithEvidence(t *testing.T) {
+	low, high := PolicyReport{}.Interval()
+	assert.Equal(t, [2]float64{0, 1}, [2]float64{low, high}, "nothing measured, nothing ruled out")
+
+	thinLow, thinHigh := PolicyReport{Hits: 6, Misses: 4}.Interval()
+	thickLow, thickHigh := PolicyReport{Hits: 6000, Misses: 4000}.Interval()
+	for _, bounds := range [][2]float64{{thinLow, thinHigh}, {thickLow, thickHigh}} {
+		assert.Less(t, bounds[0], 0.6)
+		assert.Greater(t, bounds[1], 0.6)
+	}
+	assert.Greater(t, thinHigh-thinLow, 10*(thickHigh-thickLow))
+	assert.InDelta(t, 0.5904, thickLow, 1e-4)
+	assert.InDelta(t, 0.6095, thickHigh, 1e-4)
+
+	low, high = PolicyReport{Hits: 50}.Interval()
+	assert.Less(t, low, 1.0, "a perfect record from 50 requests is not a perfect policy")
+	assert.Equal(t, 1.0, high)
+}
+
+func TestAdvice_StringRendersIntervalsAndConfidence(t *testing.T) {
+	ac, _, lfu := makeObserver(t)
+
+	primeActiveStats(ac, 25, 75)
+	primeStats(lfu, 600, 400)
+	ac.runEpoch()
+
+	summary := ac.Advice().String()
+	assert.Contains(t, summary, "95% interval")
+	assert.Contains(t, summary, "56.93-62.99%")
+	assert.Contains(t, summary, "over a 99.9% chance of truly beating LRU",
+		"a near-certainty is never rounded up to a certainty")
+}
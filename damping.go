@@ -0,0 +1,234 @@
+package ascache
+
+import "slices"
+
+// Damping configures automatic oscillation damping: the cache notices when it
+// is flapping between two policies and lengthens its own switch cooldown until
+// it stops.
+//
+// SwitchCooldownEpochs and MinHitRateImprovement have to be tuned by hand, and
+// the right values depend on the epoch duration, the traffic and how far apart
+// the arms are - which is to say they are wrong as soon as any of those
+// changes. Flapping is the symptom they exist to prevent, and unlike the right
+// threshold it can be measured directly: Switches switches between the same two
+// policies within WindowEpochs epochs.
+//
+// Each time it is seen, the effective cooldown doubles, starting from
+// SwitchCooldownEpochs, or from one epoch when that is unset, and never beyond
+// MaxCooldownEpochs. Each time a window passes without it, the cooldown halves
+// again, so a cache whose workload has settled returns to the configured
+// behaviour on its own. The window is never shorter than the time Switches
+// switches take at the current cooldown, so a cache that flaps as fast as its
+// cooldown allows is still seen to flap.
+//
+// Only the cooldown is raised, never MinHitRateImprovement: a cooldown bounds
+// how often the cache can migrate whatever the arms measure, whereas no fixed
+// threshold separates noise from signal across every traffic level.
+type Damping struct {
+	// Switches is how many switches between the same two policies count as
+	// flapping. Zero, the default, disables damping; otherwise it must be at
+	// least 2.
+	Switches int
+
+	// WindowEpochs is how recent those switches must be. Defaults to
+	// DefaultDampingWindowEpochs.
+	WindowEpochs int64
+
+	// MaxCooldownEpochs caps the effective cooldown. Defaults to
+	// DefaultDampingMaxCooldownEpochs.
+	MaxCooldownEpochs int64
+}
+
+// Defaults applied to a zero-valued Damping field once Switches is set.
+const (
+	DefaultDampingWindowEpochs      = 20
+	DefaultDampingMaxCooldownEpochs = 128
+)
+
+// dampingHistorySize is how many adjustments DampingStatus.Recent keeps.
+const dampingHistorySize = 16
+
+func (d Damping) enabled() bool { return d.Switches > 0 }
+
+func (d Damping) window() int64 {
+	if d.WindowEpochs <= 0 {
+		return DefaultDampingWindowEpochs
+	}
+
+	return d.WindowEpochs
+}
+
+func (d Damping) maxCooldown() int64 {
+	if d.MaxCooldownEpochs <= 0 {
+		return DefaultDampingMaxCooldownEpochs
+	}
+
+	return d.MaxCooldownEpochs
+}
+
+// DampingStatus is what oscillation damping has done, as Advice reports it.
+type DampingStatus struct {
+	// Level is how many times the cooldown is currently doubled. Zero means
+	// the cache is not damping.
+	Level int
+	// CooldownEpochs is the switch cooldown in force now, damping included.
+	CooldownEpochs int64
+	// Raised and Relaxed count the adjustments made in each direction.
+	Raised  int64
+	Relaxed int64
+	// Recent holds the most recent adjustments, oldest first.
+	Recent []DampingAdjustment
+}
+
+// DampingAdjustment is one change of damping level.
+type DampingAdjustment struct {
+	// Epoch is the epoch the adjustment was made in.
+	Epoch int64
+	// Raised reports whether the cooldown was lengthened, after flapping, or
+	// shortened, after a stable window.
+	Raised bool
+	// Level and CooldownEpochs are the damping in force after it.
+	Level          int
+	CooldownEpochs int64
+	// Between names the two policies seen flapping, for a raise.
+	Between [2]PolicyType
+}
+
+// dampingState is the cache's side of oscillation damping. It is only touched
+// under the write lock.
+type dampingState struct {
+	level      int
+	lastChange int64
+	recent     []switchMark
+	raised     int64
+	relaxed    int64
+	history    []DampingAdjustment
+}
+
+// switchMark is one switch, as flapping detection remembers it.
+type switchMark struct {
+	epoch int64
+	pair  [2]PolicyType
+}
+
+// pairOf orders two policies so a switch and its reversal share a key.
+func pairOf(a, b PolicyType) [2]PolicyType {
+	if b < a {
+		a, b = b, a
+	}
+
+	return [2]PolicyType{a, b}
+}
+
+// cooldownLocked returns the switch cooldown in force, damping included.
+func (c *AdaptiveCache[K, V]) cooldownLocked() int64 {
+	base := c.settings.SwitchCooldownEpochs
+	if c.damping.level == 0 {
+		return base
+	}
+
+	base = max(base, 1)
+	limit := c.settings.Damping.maxCooldown()
+	cooldown := base
+	for range c.damping.level {
+		if cooldown >= limit {
+			break
+		}
+		cooldown *= 2
+	}
+
+	// A configured cooldown above the cap still applies: damping only ever
+	// lengthens it.
+	return max(min(cooldown, limit), c.settings.SwitchCooldownEpochs)
+}
+
+// dampingWindowLocked is how far back flapping is looked for, and how long a
+// level must hold without it before relaxing.
+func (c *AdaptiveCache[K, V]) dampingWindowLocked() int64 {
+	return max(c.settings.Damping.window(), c.cooldownLocked()*int64(c.settings.Damping.Switches))
+}
+
+// noteSwitchLocked records a switch for flapping detection, raising the
+// damping level if it completes a flap. It must be called while the write lock
+// is held, at the epoch the switch was made in.
+func (c *AdaptiveCache[K, V]) noteSwitchLocked(from, to PolicyType) {
+	if !c.settings.Damping.enabled() {
+		return
+	}
+
+	d := &c.damping
+	oldest := c.epochID - c.dampingWindowLocked()
+	d.recent = slices.DeleteFunc(d.recent, func(m switchMark) bool { return m.epoch <= oldest })
+
+	mark := switchMark{epoch: c.epochID, pair: pairOf(from, to)}
+	d.recent = append(d.recent, mark)
+
+	flaps := 0
+	for _, m := range d.recent {
+		if m.pair == mark.pair {
+			flaps++
+		}
+	}
+	if flaps < c.settings.Damping.Switches {
+		return
+	}
+
+	// Raising is pointless once the cap is reached, and would leave a level
+	// the cache then has to relax through for nothing.
+	if c.cooldownLocked() >= c.settings.Damping.maxCooldown() {
+		d.recent = d.recent[:0]
+		d.lastChange = c.epochID
+		return
+	}
+
+	d.level++
+	d.raised++
+	// The flap is dealt with. What counts from here is whether the cache
+	// flaps again at the new cooldown.
+	d.recent = d.recent[:0]
+	c.adjustDampingLocked(true, mark.pair)
+}
+
+// relaxDampingLocked lowers the damping level by one when a whole window has
+// passed without flapping. It runs once per epoch, under the write lock,
+// before the epoch's own switch is noted: the window is exactly as long as
+// Switches switches take at the current cooldown, and relaxing at its last
+// epoch would forgive the very switch that completes the next flap.
+func (c *AdaptiveCache[K, V]) relaxDampingLocked() {
+	d := &c.damping
+	if d.level == 0 || c.epochID-d.lastChange <= c.dampingWindowLocked() {
+		return
+	}
+
+	d.level--
+	d.relaxed++
+	c.adjustDampingLocked(false, [2]PolicyType{})
+}
+
+func (c *AdaptiveCache[K, V]) adjustDampingLocked(raised bool, between [2]PolicyType) {
+	d := &c.damping
+	d.lastChange = c.epochID
+
+	if len(d.history) >= dampingHistorySize {
+		d.history = slices.Delete(d.history, 0, len(d.history)-dampingHistorySize+1)
+	}
+	d.history = append(d.history, DampingAdjustment{
+		Epoch:          c.epochID,
+		Raised:         raised,
+		Level:          d.level,
+		CooldownEpochs: c.cooldownLocked(),
+		Between:        between,
+	})
+}
+
+// dampingStatusLocked reports damping for Advice. It must be called while at
+// least the read lock is held.
+func (c *AdaptiveCache[K, V]) dampingStatusLocked() DampingStatus {
+	return DampingStatus{
+		Level:          c.damping.level,
+		CooldownEpochs: c.cooldownLocked(),
+		Raised:         c.damping.raised,
+		Relaxed:        c.damping.relaxed,
+		Recent:         slices.Clone(c.damping.history),
+	}
+}
@@ -0,0 +1,112 @@
+package ascache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeFlappingCache builds a cache whose bandit is steered by the test, with
+// damping configured.
+func makeFlappingCache(t *testing.T, damping Damping) (*AdaptiveCache[string, int], *mockBandit) {
+	t.Helper()
+
+	bandit := &mockBandit{next: LRU}
+	ac, err := NewAdaptiveCache(
+		[]Policy[string, int]{newMockPolicy[string, int](LRU, 10), newMockPolicy[string, int](LFU, 10)},
+		bandit,
+		&Settings{EpochDuration: 24 * time.Hour, EvictPartialCapacityFilling: true, Damping: damping},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ac.Close() })
+
+	return ac, bandit
+}
+
+// flap runs epochs with a bandit that always wants the policy not serving.
+func flap(ac *AdaptiveCache[string, int], bandit *mockBandit, epochs int) {
+	for range epochs {
+		bandit.next = map[PolicyType]PolicyType{LRU: LFU, LFU: LRU}[ac.ActivePolicy()]
+		ac.runEpoch()
+	}
+}
+
+func TestDamping_LengthensTheCooldownWhileTheCacheFlaps(t *testing.T) {
+	ac, bandit := makeFlappingCache(t, Damping{Switches: 3, WindowEpochs: 10})
+
+	flap(ac, bandit, 3)
+	damping := ac.Advice().Damping
+	require.Equal(t, 1, damping.Level, "three switches between one pair is a flap")
+	assert.Equal(t, int64(2), damping.CooldownEpochs, "doubled from the implied one epoch")
+	require.Len(t, damping.Recent, 1)
+	assert.True(t, damping.Recent[0].Raised)
+	assert.Equal(t, [2]PolicyType{LRU, LFU}, damping.Recent[0].Between)
+
+	// Still flapping at the new cooldown: it doubles again, and the switch
+	// rate falls with it.
+	flap(ac, bandit, 6)
+	damping = ac.Advice().Damping
+	assert.Equal(t, 2, damping.Level)
+	assert.Equal(t, int64(4), damping.CooldownEpochs)
+	assert.Equal(t, int64(6), ac.Advice().Switches, "nine epochs, six switches: the cooldown held three back")
+
+	advice := Advice{Active: LRU, Best: LRU, Reports: []PolicyReport{{Policy: LRU, Active: true}}, Damping: damping}
+	assert.Contains(t, advice.String(), "switches at most every 4 epochs")
+
+	history := ac.History()
+	assert.Contains(t, history.Switches[len(history.Switches)-1].GatesPassed, GateSwitchCooldown,
+		"a damped switch passed a cooldown nobody configured")
+}
+
+func TestDamping_RelaxesOnceTheCacheIsStable(t *testing.T) {
+	ac, bandit := makeFlappingCache(t, Damping{Switches: 2, WindowEpochs: 5})
+
+	flap(ac, bandit, 2)
+	require.Equal(t, 1, ac.Advice().Damping.Level)
+
+	// The bandit settles on the serving policy.
+	bandit.next = ac.ActivePolicy()
+	for range 5 {
+		ac.runEpoch()
+	}
+	require.Equal(t, 1, ac.Advice().Damping.Level, "the window has not yet passed")
+	ac.runEpoch()
+
+	damping := ac.Advice().Damping
+	assert.Zero(t, damping.Level)
+	assert.Zero(t, damping.CooldownEpochs, "back to the configured cooldown, which is none")
+	assert.Equal(t, int64(1), damping.Relaxed)
+	require.Len(t, damping.Recent, 2)
+	assert.False(t, damping.Recent[1].Raised)
+}
+
+func TestDamping_NeverExceedsTheCap(t *testing.T) {
+	ac, bandit := makeFlappingCache(t, Damping{Switches: 2, WindowEpochs: 2, MaxCooldownEpochs: 8})
+
+	flap(ac, bandit, 200)
+
+	damping := ac.Advice().Damping
+	assert.Equal(t, int64(8), damping.CooldownEpochs)
+	assert.Equal(t, 3, damping.Level, "raising stops at the cap rather than piling up levels to relax through")
+}
+
+func TestDamping_IsOffByDefault(t *testing.T) {
+	ac, bandit := makeFlappingCache(t, Damping{})
+
+	flap(ac, bandit, 20)
+
+	assert.Equal(t, int64(20), ac.Advice().Switches)
+	assert.Zero(t, ac.Advice().Damping)
+}
+
+func TestDamping_RejectsAFlapOfOneSwitch(t *testing.T) {
+	for _, damping := range []Damping{{Switches: 1}, {Switches: -1}, {Switches: 2, WindowEpochs: -1}} {
+		_, err := NewAdaptiveCache(
+			[]Policy[string, int]{newMockPolicy[string, int](LRU, 10)}, &mockBandit{next: LRU},
+			&Settings{EpochDuration: time.Hour, Damping: damping},
+		)
+		assert.ErrorIs(t, err, ErrInvalidDamping, "%+v", damping)
+	}
+}
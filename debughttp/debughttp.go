@@ -0,0 +1,285 @@
+// Package debughttp serves an AdaptiveCache's adaptive layer over HTTP: what
+// it has measured, what it has done and how it is configured, and - for an
+// operator who is allowed to - a way to take it over. It is to the cache's
+// policy selection what net/http/pprof is to the runtime.
+//
+//	mux.Handle("/debug/ascache/", http.StripPrefix("/debug/ascache",
+//	    debughttp.NewHandler(cache, debughttp.Options{Bandit: distributed})))
+//
+// # Endpoints
+//
+// Every read is a GET and answers JSON, or the text a person would rather
+// read with ?format=text where one exists:
+//
+//	/          an index of the endpoints below
+//	/advice    metrics.Snapshot; as text, Advice.String
+//	/history   recent switches and each policy's served-time ledger
+//	/settings  the settings the cache is running with
+//	/bandit    bandit.Snapshot, when Options.Bandit is set; as text, its String
+//
+// The control endpoints are POSTs:
+//
+//	/pin?policy=LFU&migration=warm  serve one policy and ignore the bandit
+//	/unpin                          hand selection back to the bandit
+//	/observe?enabled=true           turn ObserveOnly on or off, from the next epoch
+//
+// They are refused with 403 unless Options.Authorize is set and accepts the
+// request, and with 501 when the cache cannot do what was asked - a cache is
+// only pinned through a Pin method and reconfigured through UpdateSettings,
+// which this package finds on the cache rather than requiring. A request the
+// cache rejects answers 400 when the request itself is wrong - a policy the
+// cache was not built with, settings that do not validate - and 409 when the
+// cache's state refuses it, as turning ObserveOnly off without a bandit
+// does. A successful control request answers /advice's snapshot, taken after
+// the change.
+//
+// Nothing here is cheap enough to scrape: /advice takes the same read lock a
+// Get does, for longer. Point monitoring at the metrics package, and people at
+// this one.
+package debughttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+	"github.com/sshaplygin/as-cache/metrics"
+)
+
+// Cache is the part of an AdaptiveCache the handler reads. Every
+// AdaptiveCache satisfies it, whatever its key and value types.
+type Cache interface {
+	metrics.Advisor
+	History() ascache.History
+	Settings() ascache.Settings
+}
+
+// Pinner is implemented by a cache that can be pinned to one policy.
+type Pinner interface {
+	Pin(policy ascache.PolicyType, migration ascache.MigrationStrategy) error
+	Unpin()
+}
+
+// SettingsUpdater is implemented by a cache whose settings can change while
+// it runs.
+type SettingsUpdater interface {
+	UpdateSettings(update func(*ascache.Settings)) error
+}
+
+// Snapshotter is implemented by *bandit.Distributed.
+type Snapshotter interface {
+	Snapshot() bandit.Snapshot
+}
+
+// Options configures a handler.
+type Options struct {
+	// Bandit, when set, is served at /bandit. Pass the *bandit.Distributed
+	// driving the cache; a local bandit has nothing to report.
+	Bandit Snapshotter
+
+	// Authorize decides whether a control request may proceed. Nil, the
+	// default, refuses every one, so a handler mounted without thinking about
+	// it can be looked at but not driven. It is called after the method has
+	// been checked, and before anything else.
+	Authorize func(*http.Request) bool
+}
+
+type handler struct {
+	cache Cache
+	opts  Options
+	mux   *http.ServeMux
+}
+
+// NewHandler returns a handler serving cache. Its paths are relative to
+// wherever it is mounted; strip the mount prefix, as in the package example.
+func NewHandler(cache Cache, opts Options) http.Handler {
+	h := &handler{cache: cache, opts: opts, mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("GET /{$}", h.index)
+	h.mux.HandleFunc("GET /advice", h.advice)
+	h.mux.HandleFunc("GET /history", h.history)
+	h.mux.HandleFunc("GET /settings", h.settings)
+	h.mux.HandleFunc("GET /bandit", h.bandit)
+	h.mux.HandleFunc("POST /pin", h.control(h.pin))
+	h.mux.HandleFunc("POST /unpin", h.control(h.unpin))
+	h.mux.HandleFunc("POST /observe", h.control(h.observe))
+
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *handler) index(w http.ResponseWriter, _ *http.Request) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "adaptive cache, serving %s\n\n", h.cache.ActivePolicy())
+	b.WriteString("GET  advice    measurements and recommendation (?format=text)\n")
+	b.WriteString("GET  history   recent switches and served time per policy\n")
+	b.WriteString("GET  settings  configuration in force\n")
+	if h.opts.Bandit != nil {
+		b.WriteString("GET  bandit    distributed bandit state (?format=text)\n")
+	}
+
+	control := "disabled"
+	if h.opts.Authorize != nil {
+		control = "authorized requests only"
+	}
+	fmt.Fprintf(&b, "\ncontrol, %s:\n", control)
+	b.WriteString("POST pin?policy=NAME&migration=cold|warm|gradual\n")
+	b.WriteString("POST unpin\n")
+	b.WriteString("POST observe?enabled=true|false\n")
+
+	writeText(w, b.String())
+}
+
+func (h *handler) advice(w http.ResponseWriter, r *http.Request) {
+	if wantsText(r) {
+		writeText(w, h.cache.Advice().String())
+		return
+	}
+
+	writeJSON(w, metrics.Take(h.cache))
+}
+
+func (h *handler) history(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, newHistoryView(h.cache.History(), time.Now()))
+}
+
+func (h *handler) settings(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, newSettingsView(h.cache.Settings()))
+}
+
+func (h *handler) bandit(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Bandit == nil {
+		http.Error(w, "no distributed bandit configured", http.StatusNotFound)
+		return
+	}
+
+	snapshot := h.opts.Bandit.Snapshot()
+	if wantsText(r) {
+		writeText(w, snapshot.String())
+		return
+	}
+
+	writeJSON(w, snapshot)
+}
+
+// control guards an endpoint that changes the cache. The handler it wraps
+// reports the status to answer with, and an error to answer it with unless
+// the status is http.StatusOK.
+func (h *handler) control(next func(*http.Request) (int, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.opts.Authorize == nil || !h.opts.Authorize(r) {
+			http.Error(w, "control endpoints are not authorized", http.StatusForbidden)
+			return
+		}
+
+		if status, err := next(r); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		writeJSON(w, metrics.Take(h.cache))
+	}
+}
+
+func (h *handler) pin(r *http.Request) (int, error) {
+	pinner, ok := h.cache.(Pinner)
+	if !ok {
+		return http.StatusNotImplemented, fmt.Errorf("this cache cannot be pinned")
+	}
+
+	name := r.URL.Query().Get("policy")
+	policy, ok := ascache.ParsePolicyType(name)
+	if !ok {
+		return http.StatusBadRequest, fmt.Errorf("unknown policy %q", name)
+	}
+
+	migration := h.cache.Settings().MigrationStrategy
+	if name := r.URL.Query().Get("migration"); name != "" {
+		if migration, ok = parseMigration(name); !ok {
+			return http.StatusBadRequest, fmt.Errorf("migration must be cold, warm or gradual, got %q", name)
+		}
+	}
+
+	if err := pinner.Pin(policy, migration); err != nil {
+		if errors.Is(err, ascache.ErrUnknownPolicy) {
+			return http.StatusBadRequest, err
+		}
+
+		return http.StatusConflict, err
+	}
+
+	return http.StatusOK, nil
+}
+
+func (h *handler) unpin(*http.Request) (int, error) {
+	pinner, ok := h.cache.(Pinner)
+	if !ok {
+		return http.StatusNotImplemented, fmt.Errorf("this cache cannot be pinned")
+	}
+
+	pinner.Unpin()
+
+	return http.StatusOK, nil
+}
+
+func (h *handler) observe(r *http.Request) (int, error) {
+	updater, ok := h.cache.(SettingsUpdater)
+	if !ok {
+		return http.StatusNotImplemented, fmt.Errorf("this cache cannot be reconfigured while it runs")
+	}
+
+	enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("enabled must be true or false")
+	}
+
+	if err := updater.UpdateSettings(func(s *ascache.Settings) { s.ObserveOnly = enabled }); err != nil {
+		if errors.Is(err, ascache.ErrNilBandit) {
+			return http.StatusConflict, err
+		}
+
+		return http.StatusBadRequest, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// parseMigration is the inverse of MigrationStrategy.String.
+func parseMigration(name string) (ascache.MigrationStrategy, bool) {
+	for _, strategy := range []ascache.MigrationStrategy{
+		ascache.MigrationCold, ascache.MigrationWarm, ascache.MigrationGradual,
+	} {
+		if strings.EqualFold(name, strategy.String()) {
+			return strategy, true
+		}
+	}
+
+	return ascache.MigrationCold, false
+}
+
+func wantsText(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "text"
+}
+
+func writeText(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(body))
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
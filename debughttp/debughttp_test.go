@@ -0,0 +1,227 @@
+package debughttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+	"github.com/sshaplygin/as-cache/metrics"
+	"github.com/sshaplygin/as-cache/policies"
+)
+
+// alwaysBandit selects one policy whatever it is shown.
+type alwaysBandit struct{ policy ascache.PolicyType }
+
+func (alwaysBandit) RecordStats(ascache.ShadowStats)    {}
+func (b alwaysBandit) SelectPolicy() ascache.PolicyType { return b.policy }
+
+// fixedBandit reports one snapshot.
+type fixedBandit struct{ snapshot bandit.Snapshot }
+
+func (b fixedBandit) Snapshot() bandit.Snapshot { return b.snapshot }
+
+// controllable is a cache that records the control calls made on it.
+type controllable struct {
+	*ascache.AdaptiveCache[string, int]
+
+	pinned    ascache.PolicyType
+	migration ascache.MigrationStrategy
+	unpinned  bool
+	updated   ascache.Settings
+	updateErr error
+}
+
+func (c *controllable) Pin(policy ascache.PolicyType, migration ascache.MigrationStrategy) error {
+	c.pinned, c.migration = policy, migration
+	return nil
+}
+
+func (c *controllable) Unpin() { c.unpinned = true }
+
+func (c *controllable) UpdateSettings(update func(*ascache.Settings)) error {
+	c.updated = c.Settings()
+	update(&c.updated)
+	return c.updateErr
+}
+
+// newCache builds a cache that switches from LRU to LFU on its first epoch,
+// ten Gets in, and measures LFU for one more.
+func newCache(t *testing.T) *ascache.AdaptiveCache[string, int] {
+	t.Helper()
+
+	lru, err := policies.NewLRU[string, int](100)
+	require.NoError(t, err)
+	lfu, err := policies.NewLFU[string, int](100)
+	require.NoError(t, err)
+
+	cache, err := ascache.NewAdaptiveCache(
+		[]ascache.Policy[string, int]{lru, lfu}, alwaysBandit{policy: ascache.LFU},
+		&ascache.Settings{
+			EpochRequests:               10,
+			EvictPartialCapacityFilling: true,
+			MigrationStrategy:           ascache.MigrationWarm,
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cache.Close() })
+
+	for i := range 20 {
+		cache.Add("key-"+strconv.Itoa(i%10), i)
+		cache.Get("key-" + strconv.Itoa(i%10))
+	}
+
+	return cache
+}
+
+func serve(t *testing.T, h http.Handler, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+
+	return recorder
+}
+
+func TestHandler_ServesAdviceAsJSONAndText(t *testing.T) {
+	h := NewHandler(newCache(t), Options{})
+
+	response := serve(t, h, http.MethodGet, "/advice")
+	require.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+	var snapshot metrics.Snapshot
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &snapshot))
+	assert.Equal(t, "LFU", snapshot.ActivePolicy)
+	assert.Equal(t, int64(1), snapshot.Switches)
+
+	response = serve(t, h, http.MethodGet, "/advice?format=text")
+	require.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), "currently active")
+}
+
+func TestHandler_ServesHistoryAndSettings(t *testing.T) {
+	h := NewHandler(newCache(t), Options{})
+
+	var history historyView
+	require.NoError(t, json.Unmarshal(serve(t, h, http.MethodGet, "/history").Body.Bytes(), &history))
+	require.Len(t, history.Switches, 1)
+	assert.Equal(t, "LRU", history.Switches[0].From)
+	assert.Equal(t, "LFU", history.Switches[0].To)
+	assert.Len(t, history.Policies, 2)
+
+	var settings map[string]any
+	require.NoError(t, json.Unmarshal(serve(t, h, http.MethodGet, "/settings").Body.Bytes(), &settings))
+	assert.Equal(t, "warm", settings["migration_strategy"])
+	assert.Equal(t, float64(10), settings["epoch_requests"])
+	assert.Equal(t, false, settings["on_switch"])
+	assert.Equal(t, false, settings["logger"])
+}
+
+func TestHandler_ServesTheBanditOnlyWhenThereIsOne(t *testing.T) {
+	cache := newCache(t)
+
+	assert.Equal(t, http.StatusNotFound, serve(t, NewHandler(cache, Options{}), http.MethodGet, "/bandit").Code)
+
+	h := NewHandler(cache, Options{Bandit: fixedBandit{bandit.Snapshot{Mode: "leader", Selection: "LFU", Fallback: true}}})
+
+	var snapshot bandit.Snapshot
+	require.NoError(t, json.Unmarshal(serve(t, h, http.MethodGet, "/bandit").Body.Bytes(), &snapshot))
+	assert.True(t, snapshot.Fallback)
+	assert.Contains(t, serve(t, h, http.MethodGet, "/bandit?format=text").Body.String(), "LFU")
+	assert.Contains(t, serve(t, h, http.MethodGet, "/").Body.String(), "bandit")
+}
+
+func TestHandler_RefusesControlUnlessAuthorized(t *testing.T) {
+	cache := &controllable{AdaptiveCache: newCache(t)}
+
+	for _, opts := range []Options{{}, {Authorize: func(*http.Request) bool { return false }}} {
+		response := serve(t, NewHandler(cache, opts), http.MethodPost, "/pin?policy=LRU")
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	}
+	assert.Equal(t, ascache.Undefined, cache.pinned, "a refused request changes nothing")
+
+	h := NewHandler(cache, Options{Authorize: func(*http.Request) bool { return true }})
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(t, h, http.MethodGet, "/pin?policy=LRU").Code,
+		"a link followed by a crawler must not pin anything")
+}
+
+func TestHandler_PinsUnpinsAndToggles(t *testing.T) {
+	cache := &controllable{AdaptiveCache: newCache(t)}
+	h := NewHandler(cache, Options{Authorize: func(*http.Request) bool { return true }})
+
+	response := serve(t, h, http.MethodPost, "/pin?policy=LRU&migration=gradual")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.Equal(t, ascache.LRU, cache.pinned)
+	assert.Equal(t, ascache.MigrationGradual, cache.migration)
+
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/pin?policy=LFU").Code)
+	assert.Equal(t, ascache.MigrationWarm, cache.migration, "the configured strategy unless one is asked for")
+
+	assert.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPost, "/pin?policy=nonsense").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPost, "/pin?policy=LRU&migration=hot").Code)
+
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/unpin").Code)
+	assert.True(t, cache.unpinned)
+
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/observe?enabled=true").Code)
+	assert.True(t, cache.updated.ObserveOnly)
+	assert.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPost, "/observe").Code)
+
+	cache.updateErr = ascache.ErrInvalidSwitchConfidence
+	assert.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPost, "/observe?enabled=true").Code,
+		"settings that do not validate are the request's fault")
+}
+
+func TestHandler_DrivesARealCache(t *testing.T) {
+	cache := newCache(t)
+	h := NewHandler(cache, Options{Authorize: func(*http.Request) bool { return true }})
+
+	response := serve(t, h, http.MethodPost, "/pin?policy=LRU")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	var snapshot metrics.Snapshot
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &snapshot))
+	assert.Equal(t, "LRU", snapshot.ActivePolicy)
+	assert.True(t, snapshot.Pinned)
+
+	assert.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPost, "/pin?policy=ARC").Code,
+		"a policy the cache was not built with")
+
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/unpin").Code)
+	assert.False(t, cache.Advice().Pin.Pinned())
+
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/observe?enabled=true").Code)
+	assert.False(t, cache.Settings().ObserveOnly, "not until the next epoch")
+	for i := range 10 {
+		cache.Get("key-" + strconv.Itoa(i))
+	}
+	assert.True(t, cache.Settings().ObserveOnly)
+}
+
+func TestHandler_RefusesToStopObservingWithoutABandit(t *testing.T) {
+	lru, err := policies.NewLRU[string, int](100)
+	require.NoError(t, err)
+	cache, err := ascache.NewAdaptiveCache([]ascache.Policy[string, int]{lru}, nil,
+		&ascache.Settings{EpochRequests: 10, ObserveOnly: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cache.Close() })
+
+	h := NewHandler(cache, Options{Authorize: func(*http.Request) bool { return true }})
+	assert.Equal(t, http.StatusConflict, serve(t, h, http.MethodPost, "/observe?enabled=false").Code)
+}
+
+func TestHandler_ReportsWhatTheCacheCannotDo(t *testing.T) {
+	// Embedding the interface hides every method the handler looks for.
+	h := NewHandler(struct{ Cache }{newCache(t)}, Options{Authorize: func(*http.Request) bool { return true }})
+
+	for _, target := range []string{"/pin?policy=LRU", "/unpin", "/observe?enabled=true"} {
+		assert.Equal(t, http.StatusNotImplemented, serve(t, h, http.MethodPost, target).Code, target)
+	}
+}
@@ -0,0 +1,107 @@
+package debughttp
+
+import (
+	"time"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// The types below are the JSON the handler writes. The cache's own types name
+// policies by number and hold hooks, neither of which is any use on the wire.
+
+type historyView struct {
+	Switches []switchView `json:"switches"`
+	Policies []tenureView `json:"policies"`
+}
+
+type switchView struct {
+	Epoch         int64     `json:"epoch"`
+	At            time.Time `json:"at"`
+	Ago           string    `json:"ago"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	Reason        string    `json:"reason"`
+	GatesPassed   []string  `json:"gates_passed"`
+	HitRateBefore float64   `json:"hit_rate_before"`
+	HitRateAfter  float64   `json:"hit_rate_after"`
+}
+
+type tenureView struct {
+	Policy        string  `json:"policy"`
+	ServedSeconds float64 `json:"served_seconds"`
+	Tenures       int64   `json:"tenures"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRate       float64 `json:"hit_rate"`
+}
+
+func newHistoryView(history ascache.History, now time.Time) historyView {
+	view := historyView{
+		Switches: make([]switchView, 0, len(history.Switches)),
+		Policies: make([]tenureView, 0, len(history.Policies)),
+	}
+
+	for _, record := range history.Switches {
+		view.Switches = append(view.Switches, switchView{
+			Epoch:         record.Epoch,
+			At:            record.At,
+			Ago:           now.Sub(record.At).Round(time.Second).String(),
+			From:          record.From.String(),
+			To:            record.To.String(),
+			Reason:        string(record.Reason),
+			GatesPassed:   record.GatesPassed,
+			HitRateBefore: record.HitRateBefore,
+			HitRateAfter:  record.HitRateAfter,
+		})
+	}
+	for _, tenure := range history.Policies {
+		view.Policies = append(view.Policies, tenureView{
+			Policy:        tenure.Policy.String(),
+			ServedSeconds: tenure.Served.Seconds(),
+			Tenures:       tenure.Tenures,
+			Hits:          tenure.Hits,
+			Misses:        tenure.Misses,
+			HitRate:       tenure.HitRate(),
+		})
+	}
+
+	return view
+}
+
+type settingsView struct {
+	EpochDuration               string          `json:"epoch_duration"`
+	EpochRequests               int64           `json:"epoch_requests"`
+	EvictPartialCapacityFilling bool            `json:"evict_partial_capacity_filling"`
+	MigrationStrategy           string          `json:"migration_strategy"`
+	MinHitRateImprovement       float64         `json:"min_hit_rate_improvement"`
+	SwitchConfidence            float64         `json:"switch_confidence"`
+	SwitchCooldownEpochs        int64           `json:"switch_cooldown_epochs"`
+	MinEpochRequests            int64           `json:"min_epoch_requests"`
+	Damping                     ascache.Damping `json:"damping"`
+	ShadowSampleRate            float64         `json:"shadow_sample_rate"`
+	ObserveOnly                 bool            `json:"observe_only"`
+	MinShadowCapacity           int             `json:"min_shadow_capacity"`
+	HistorySize                 int             `json:"history_size"`
+	OnSwitch                    bool            `json:"on_switch"`
+	Logger                      bool            `json:"logger"`
+}
+
+func newSettingsView(s ascache.Settings) settingsView {
+	return settingsView{
+		EpochDuration:               s.EpochDuration.String(),
+		EpochRequests:               s.EpochRequests,
+		EvictPartialCapacityFilling: s.EvictPartialCapacityFilling,
+		MigrationStrategy:           s.MigrationStrategy.String(),
+		MinHitRateImprovement:       s.MinHitRateImprovement,
+		SwitchConfidence:            s.SwitchConfidence,
+		SwitchCooldownEpochs:        s.SwitchCooldownEpochs,
+		MinEpochRequests:            s.MinEpochRequests,
+		Damping:                     s.Damping,
+		ShadowSampleRate:            s.ShadowSampleRate,
+		ObserveOnly:                 s.ObserveOnly,
+		MinShadowCapacity:           s.MinShadowCapacity,
+		HistorySize:                 s.HistorySize,
+		OnSwitch:                    s.OnSwitch != nil,
+		Logger:                      s.Logger != nil,
+	}
+}
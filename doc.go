@@ -27,8 +27,9 @@
 // [AdaptiveCache.Close] are the additions.
 //
 // Ready-made policies live in companion modules, so the core has no
-// dependencies: github.com/sshaplygin/as-cache/policies for LRU, 2Q, Random
-// and TTL, .../policies/arc for ARC, .../policies/tinylfu for W-TinyLFU.
+// dependencies: github.com/sshaplygin/as-cache/policies for LRU, LFU, 2Q,
+// LRU-K, LRFU, Random and TTL, .../policies/arc for ARC, .../policies/tinylfu
+// for W-TinyLFU.
 //
 // # Start by observing
 //
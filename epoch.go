@@ -35,7 +35,7 @@ func (c *AdaptiveCache[K, V]) runAdaptiveSelect() {
 // during the crossing are still counted towards the next epoch instead of
 // being dropped.
 func (c *AdaptiveCache[K, V]) countRequest() {
-	limit := c.settings.EpochRequests
+	limit := c.epochLimit.Load()
 	if limit <= 0 {
 		return
 	}
@@ -48,30 +48,65 @@ func (c *AdaptiveCache[K, V]) countRequest() {
 	c.runEpoch()
 }
 
-// runEpoch performs one epoch tick: it selects the next policy, migrates data
-// when the policy changes and the stability gates allow it, and advances the
-// epoch counter. The entire sequence runs under the write lock so concurrent
-// cache operations never observe a half-applied switch (a torn activePolicy or
-// partially migrated state).
+// runEpoch performs one epoch tick and then tells Settings.OnSwitch and
+// Settings.Logger what it did. Both are told after the write lock is
+// released: they are caller code, and anything slow in them would otherwise
+// stall every Get.
 func (c *AdaptiveCache[K, V]) runEpoch() {
+	c.epochLocked().report()
+}
+
+// epochLocked selects the next policy, migrates data when the policy changes
+// and the stability gates allow it, and advances the epoch counter. The entire
+// sequence runs under the write lock so concurrent cache operations never
+// observe a half-applied switch (a torn activePolicy or partially migrated
+// state). It returns what the epoch did, for runEpoch to report once the lock
+// is released.
+func (c *AdaptiveCache[K, V]) epochLocked() epochOutcome {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	// Settings change between epochs, never during one, so every decision
+	// below is made under one consistent set.
+	c.applyPendingLocked()
+	outcome := c.outcomeLocked()
+
 	// A gradual migration window lasts at most one epoch. Left open it would
 	// never close on a workload that stops touching the keys still pending:
 	// the source would hold real values at full capacity indefinitely, compete
 	// as an arm measured at a capacity no other shadow runs at, and keep every
 	// Get on the write-locked path. Closing here also demotes it, so it is a
 	// comparable miniature by the time stats are collected below.
+	if c.migrating {
+		outcome.abandoned = len(c.migrationRealKeys)
+	}
 	c.closeMigrationLocked()
+	c.relaxDampingLocked()
 
 	newPolicy := c.selectPolicyLocked()
+	c.refreshFleetPinLocked()
+	if pinned := c.pinnedLocked(); pinned != Undefined {
+		// The epoch was measured and the bandit learned from it, but while
+		// pinned it does not choose. The only switch a pinned cache makes is
+		// onto a fleet pin it has just heard of; an operator's Pin has
+		// switched already. A pin is checked before ObserveOnly because it is
+		// an operator's instruction rather than the bandit's, so it acts on
+		// an observing cache too, whether set on the cache or for the fleet.
+		if pinned != c.activePolicy {
+			outcome.event, outcome.switched = c.switchToLocked(pinned, c.settings.MigrationStrategy, ReasonPin), true
+		}
+		c.epochID++
+
+		return outcome
+	}
+
 	if c.settings.ObserveOnly {
-		// Measure, report, advise - but never act. The cache keeps behaving
-		// exactly like the policy it was built with.
+		// Measure, report, advise - but never act on the bandit. The cache
+		// keeps behaving exactly like the policy it was built with, or was
+		// pinned to.
 		c.epochID++
 
-		return
+		return outcome
 	}
 
 	// A Bandit is caller-supplied code, and nothing constrains what it returns.
@@ -80,12 +115,72 @@ func (c *AdaptiveCache[K, V]) runEpoch() {
 	// switchLocked, look the missing policy up in the map, and dereference a
 	// nil interface, panicking the epoch goroutine and taking the process with
 	// it. An unrecognised selection means no change.
-	if c.activePolicy != newPolicy && c.hasPolicy(newPolicy) && c.allowSwitchLocked(newPolicy) {
-		c.switchLocked(c.activePolicy, newPolicy)
-		c.lastSwitchEpoch = c.epochID
+	if c.activePolicy == newPolicy || !c.hasPolicy(newPolicy) {
+		c.epochID++
+
+		return outcome
+	}
+
+	if gate := c.heldByLocked(newPolicy); gate != "" {
+		outcome.candidate, outcome.heldBy = newPolicy, gate
+		c.epochID++
+
+		return outcome
 	}
 
+	event := c.switchToLocked(newPolicy, c.settings.MigrationStrategy, ReasonBandit)
+	c.noteSwitchLocked(event.From, event.To)
+	outcome.event, outcome.switched = event, true
 	c.epochID++
+
+	return outcome
+}
+
+// switchToLocked makes the active policy to, migrating with strategy, and
+// records the switch in the history and the switch count. It returns the
+// event Settings.OnSwitch is to receive once the lock is released. It must be
+// called while the write lock is held, with to a policy the cache holds and
+// not the active one.
+func (c *AdaptiveCache[K, V]) switchToLocked(to PolicyType, strategy MigrationStrategy, reason SwitchReason) SwitchEvent {
+	c.settleActiveLocked()
+
+	event := SwitchEvent{
+		Epoch:     c.epochID,
+		From:      c.activePolicy,
+		To:        to,
+		Reason:    reason,
+		Migration: strategy,
+		Start:     time.Now(),
+	}
+	c.recordSwitchLocked(c.epochID, event.Start, event.From, to, reason)
+	event.MigrationKeys = c.switchLocked(event.From, to, strategy)
+	event.Duration = time.Since(event.Start)
+	c.lastSwitchEpoch = c.epochID
+	c.switches++
+
+	return event
+}
+
+// settleActiveLocked credits what the active policy has served since its
+// counters were last collected to Stats and the ledger, and clears them. A
+// switch at an epoch boundary finds them just collected by
+// selectPolicyLocked; one made between epochs - a Pin - would otherwise have
+// demotion reset them, losing that traffic from Stats and History and
+// recording the tenure it ends as having served nothing. The sampled counters
+// are cleared with them, or the epoch's report would credit the outgoing
+// policy's evidence to the incoming one. It must be called while the write
+// lock is held, before the active policy changes.
+func (c *AdaptiveCache[K, V]) settleActiveLocked() {
+	policy := c.policies[c.activePolicy]
+	stats := policy.GetStats()
+	policy.ResetStats()
+
+	c.globalStats.Hits += stats.Hits
+	c.globalStats.Misses += stats.Misses
+	c.recordServedLocked(stats)
+
+	c.activeSampledHits.Store(0)
+	c.activeSampledMisses.Store(0)
 }
 
 // hasPolicy reports whether the cache holds the named policy as one of its
@@ -163,6 +258,7 @@ func (c *AdaptiveCache[K, V]) selectPolicyLocked() PolicyType {
 			// policy's full counters are what accumulate there.
 			c.globalStats.Hits += stats.Hits
 			c.globalStats.Misses += stats.Misses
+			c.recordServedLocked(stats)
 
 			// The bandit instead sees the active policy measured over the
 			// sampled substream, the same one the shadows are measured over,
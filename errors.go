@@ -30,3 +30,48 @@ var ErrInvalidEpochDuration = errors.New(
 // ErrInvalidEpochRequests is returned by NewAdaptiveCache when
 // Settings.EpochRequests is negative.
 var ErrInvalidEpochRequests = errors.New("epoch requests must not be negative")
+
+// ErrInvalidSwitchConfidence is returned by NewAdaptiveCache when
+// Settings.SwitchConfidence is outside [0,1). A confidence of 1 is never
+// reached, so it would silently disable switching.
+var ErrInvalidSwitchConfidence = errors.New("switch confidence must be in [0,1)")
+
+// ErrInvalidDamping is returned by NewAdaptiveCache when Settings.Damping
+// is malformed: a negative field, or a flap of a single switch, which would
+// damp every switch the cache made.
+var ErrInvalidDamping = errors.New("invalid damping settings")
+
+// ErrUnknownPolicy is returned by Pin when the cache was not built with the
+// policy asked for.
+var ErrUnknownPolicy = errors.New("policy is not one of the cache's arms")
+
+// ErrImmutableSetting is returned by UpdateSettings for a change to a setting
+// that is fixed when the cache is built, wrapped in an ImmutableSettingError
+// naming the field.
+var ErrImmutableSetting = errors.New("setting cannot change after construction")
+
+// ImmutableSettingError is the error UpdateSettings returns for a change to a
+// setting that is fixed when the cache is built. It matches
+// ErrImmutableSetting, and errors.As recovers the field that was refused.
+type ImmutableSettingError struct {
+	// Field is the name of the Settings field the update tried to change.
+	Field string
+}
+
+// Error implements error.
+func (e *ImmutableSettingError) Error() string {
+	return ErrImmutableSetting.Error() + ": " + e.Field
+}
+
+// Unwrap returns ErrImmutableSetting.
+func (e *ImmutableSettingError) Unwrap() error {
+	return ErrImmutableSetting
+}
+
+// ErrInvalidVariant is returned by Variant and AsVariant when the variant name
+// is unusable or the base policy cannot be parameterised.
+var ErrInvalidVariant = errors.New("invalid policy variant")
+
+// ErrInvalidPolicyName is what RegisterPolicyType panics with when the name is
+// malformed or already taken.
+var ErrInvalidPolicyName = errors.New("invalid policy name")
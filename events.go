@@ -0,0 +1,28 @@
+package ascache
+
+import "time"
+
+// SwitchEvent describes one change of active policy, as Settings.OnSwitch
+// receives it.
+type SwitchEvent struct {
+	// Epoch is the epoch that made the switch.
+	Epoch int64
+	// From is the policy that was serving, To the one serving now.
+	From PolicyType
+	To   PolicyType
+	// Reason is what made the switch: the bandit, or a pin.
+	Reason SwitchReason
+	// Migration is the strategy the switch migrated data with: the one Pin
+	// was given, or otherwise Settings.MigrationStrategy. It is the zero value
+	// when that was left unset, which migrates as MigrationCold does.
+	Migration MigrationStrategy
+	// MigrationKeys is how many keys the migration moves: those a warm
+	// migration copied, or those a gradual one queued to move on Get - some
+	// of which may never be touched, and are dropped when the window closes.
+	// A cold migration moves none.
+	MigrationKeys int
+	// Start is when the switch began, and Duration how long it held the
+	// cache's write lock for, migration included. Every Get waited that long.
+	Start    time.Time
+	Duration time.Duration
+}
@@ -0,0 +1,77 @@
+package ascache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnSwitch_DescribesTheSwitchAfterReleasingTheLock(t *testing.T) {
+	lru := newMockPolicy[string, int](LRU, 10)
+	lfu := newMockPolicy[string, int](LFU, 10)
+	bandit := &mockBandit{next: LRU}
+
+	var (
+		events []SwitchEvent
+		ac     *AdaptiveCache[string, int]
+	)
+	ac, err := NewAdaptiveCache(
+		[]Policy[string, int]{lru, lfu}, bandit,
+		&Settings{
+			EpochDuration:               24 * time.Hour,
+			EvictPartialCapacityFilling: true,
+			MigrationStrategy:           MigrationWarm,
+			OnSwitch: func(event SwitchEvent) {
+				// Reading the cache here would deadlock if the hook ran under
+				// the epoch's write lock.
+				assert.Equal(t, event.To, ac.ActivePolicy())
+				events = append(events, event)
+			},
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ac.Close() })
+
+	ac.Add("a", 1)
+	ac.Add("b", 2)
+	ac.runEpoch()
+	assert.Empty(t, events, "an epoch that keeps the policy is not a switch")
+
+	bandit.next = LFU
+	ac.runEpoch()
+
+	require.Len(t, events, 1)
+	event := events[0]
+	assert.Equal(t, int64(1), event.Epoch)
+	assert.Equal(t, LRU, event.From)
+	assert.Equal(t, LFU, event.To)
+	assert.Equal(t, ReasonBandit, event.Reason)
+	assert.Equal(t, MigrationWarm, event.Migration)
+	assert.Equal(t, 2, event.MigrationKeys, "a warm migration reports the keys it copied")
+	assert.False(t, event.Start.IsZero())
+}
+
+func TestOnSwitch_CountsTheKeysAGradualMigrationQueues(t *testing.T) {
+	ac, _, _, bandit := makeCache(t, MigrationGradual)
+
+	var event SwitchEvent
+	ac.settings.OnSwitch = func(e SwitchEvent) { event = e }
+
+	for i, key := range []string{"a", "b", "c"} {
+		ac.Add(key, i)
+	}
+	bandit.next = LFU
+	ac.runEpoch()
+
+	assert.Equal(t, MigrationGradual, event.Migration)
+	assert.Equal(t, 3, event.MigrationKeys)
+}
+
+func TestMigrationStrategy_StringNamesHowTheCacheMigrates(t *testing.T) {
+	assert.Equal(t, "warm", MigrationWarm.String())
+	assert.Equal(t, "gradual", MigrationGradual.String())
+	assert.Equal(t, "cold", MigrationCold.String())
+	assert.Equal(t, "cold", MigrationStrategy(0).String(), "an unset strategy migrates cold")
+}
@@ -14,6 +14,7 @@
 //	GET  /stats                active policy, key count, hit/miss stats
 //	POST /switch?to=lfu|lru    schedule a policy switch for the next epoch tick
 //	GET  /demo                 run the full migration demo and return a report
+//	GET  /debug/ascache/       advice, switch history and settings (debughttp)
 package main
 
 import (
@@ -32,6 +33,7 @@ import (
 	"github.com/stitchfix/mab"
 
 	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/debughttp"
 	slfu "github.com/sshaplygin/as-cache/lfu"
 )
 
@@ -473,6 +475,9 @@ func main() {
 	mux.HandleFunc("/stats", s.handleStats)
 	mux.HandleFunc("/switch", s.handleSwitch)
 	mux.HandleFunc("/demo", s.handleDemo)
+	// Read-only: with no Authorize, the debug handler's control endpoints
+	// are refused. /switch above is this demo's way to drive the cache.
+	mux.Handle("/debug/ascache/", http.StripPrefix("/debug/ascache", debughttp.NewHandler(cache, debughttp.Options{})))
 
 	// The /demo endpoint sleeps for up to epochDur, so timeouts must be larger.
 	timeout := epochDur*2 + 10*time.Second
@@ -1,3 +0,0 @@
-package ascache
-
-//go:generate stringer -type=PolicyType
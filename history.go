@@ -0,0 +1,221 @@
+package ascache
+
+import (
+	"slices"
+	"time"
+)
+
+// DefaultHistorySize is how many switches History keeps when
+// Settings.HistorySize is zero.
+const DefaultHistorySize = 64
+
+// SwitchReason says what made the cache change its active policy.
+type SwitchReason string
+
+const (
+	// ReasonBandit is a switch the bandit selected and every configured
+	// stability gate allowed.
+	ReasonBandit SwitchReason = "bandit"
+	// ReasonPin is a switch onto a pinned policy, made by Pin or by a fleet
+	// pin. It passes no gates.
+	ReasonPin SwitchReason = "pin"
+)
+
+// Stability gates, as SwitchRecord.GatesPassed names them.
+const (
+	GateSwitchCooldown        = "switch-cooldown"
+	GateMinEpochRequests      = "min-epoch-requests"
+	GateMinHitRateImprovement = "min-hit-rate-improvement"
+	GateSwitchConfidence      = "switch-confidence"
+)
+
+// SwitchRecord is one change of active policy.
+type SwitchRecord struct {
+	// Epoch is the epoch that made the switch, and At when it made it.
+	Epoch int64
+	At    time.Time
+	// From is the policy that was serving, To the one that served next.
+	From PolicyType
+	To   PolicyType
+	// Reason is what made the switch.
+	Reason SwitchReason
+	// GatesPassed names the stability gates that were configured and that the
+	// switch passed. It is empty when Settings configures none, in which case
+	// every selection the bandit makes is applied, and for a pin, which is
+	// never gated.
+	GatesPassed []string
+	// HitRateBefore is the hit rate From served over the tenure this switch
+	// ended, and HitRateAfter the one To served over the tenure it began -
+	// so far, for the latest switch. Both are measured over all traffic while
+	// serving, never over a shadow's sample, which makes them the one
+	// like-for-like comparison of two policies this cache can offer: were
+	// things better after the switch than before it?
+	//
+	// They compare different stretches of traffic, so a workload that changed
+	// at the moment of the switch moves them both. Read them as evidence about
+	// a switch, not as a measurement of a policy.
+	HitRateBefore float64
+	HitRateAfter  float64
+}
+
+// PolicyTenure is the ledger of one policy's time as the active policy.
+type PolicyTenure struct {
+	Policy PolicyType
+	// Served is the total time the policy has spent active, the current
+	// tenure included.
+	Served time.Duration
+	// Tenures is how many times it became active, counting the policy the
+	// cache was built with as once.
+	Tenures int64
+	// Hits and Misses are every request it served while active. They are
+	// never sampled.
+	Hits   int64
+	Misses int64
+}
+
+// HitRate returns the fraction of requests the policy served from the cache
+// while active, or 0 when it has served none.
+func (t PolicyTenure) HitRate() float64 {
+	return hitRate(PolicyStats{Hits: t.Hits, Misses: t.Misses})
+}
+
+// History is what a cache has done with its policies since it was built.
+type History struct {
+	// Switches holds the most recent switches, oldest first, at most
+	// Settings.HistorySize of them. Advice.Switches counts every one.
+	Switches []SwitchRecord
+	// Policies holds every arm's ledger, in PolicyType order, including arms
+	// that have never been active.
+	Policies []PolicyTenure
+}
+
+// History reports the cache's recent switches and how long, and how well,
+// each policy has served.
+//
+// Advice cannot answer either question: its tenure statistics restart for
+// both policies in every switch, deliberately, so that a policy's long record
+// in one role cannot outweigh its short record in another. That makes it the
+// right basis for a recommendation and no basis at all for asking how the
+// cache has behaved, which is what this is for.
+func (c *AdaptiveCache[K, V]) History() History {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := time.Now()
+	live := c.policies[c.activePolicy].GetStats()
+
+	history := History{
+		Switches: slices.Clone(c.history),
+		Policies: make([]PolicyTenure, 0, len(c.policyOrder)),
+	}
+	for i := range history.Switches {
+		history.Switches[i].GatesPassed = slices.Clone(history.Switches[i].GatesPassed)
+	}
+	if n := len(history.Switches); n > 0 {
+		history.Switches[n-1].HitRateAfter = hitRate(addStats(c.servedTenure, live))
+	}
+
+	for _, policyType := range c.policyOrder {
+		tenure := c.ledger[policyType]
+		tenure.Policy = policyType
+		if policyType == c.activePolicy {
+			tenure.Served += now.Sub(c.activeSince)
+			tenure.Hits += live.Hits
+			tenure.Misses += live.Misses
+		}
+		history.Policies = append(history.Policies, tenure)
+	}
+
+	return history
+}
+
+// addStats returns the sum of two measurements.
+func addStats(a, b PolicyStats) PolicyStats {
+	return PolicyStats{Hits: a.Hits + b.Hits, Misses: a.Misses + b.Misses}
+}
+
+// startLedgerLocked opens the ledger on the policy the cache was built with.
+// It runs once, during construction.
+func (c *AdaptiveCache[K, V]) startLedgerLocked(now time.Time) {
+	c.ledger = make(map[PolicyType]PolicyTenure, len(c.policies))
+	c.activeSince = now
+
+	tenure := c.ledger[c.activePolicy]
+	tenure.Tenures++
+	c.ledger[c.activePolicy] = tenure
+}
+
+// recordServedLocked credits the active policy with requests it served in a
+// reporting epoch. It must be called while the write lock is held.
+func (c *AdaptiveCache[K, V]) recordServedLocked(stats PolicyStats) {
+	c.servedTenure = addStats(c.servedTenure, stats)
+
+	tenure := c.ledger[c.activePolicy]
+	tenure.Hits += stats.Hits
+	tenure.Misses += stats.Misses
+	c.ledger[c.activePolicy] = tenure
+}
+
+// recordSwitchLocked closes the outgoing policy's tenure, opens the incoming
+// one's and appends the switch to the history ring. It must be called while
+// the write lock is held, before switchLocked changes the active policy.
+func (c *AdaptiveCache[K, V]) recordSwitchLocked(epoch int64, at time.Time, from, to PolicyType, reason SwitchReason) {
+	before := hitRate(c.servedTenure)
+	if n := len(c.history); n > 0 {
+		// The previous switch's "after" is the tenure this one ends.
+		c.history[n-1].HitRateAfter = before
+	}
+
+	outgoing := c.ledger[from]
+	outgoing.Served += at.Sub(c.activeSince)
+	c.ledger[from] = outgoing
+
+	incoming := c.ledger[to]
+	incoming.Tenures++
+	c.ledger[to] = incoming
+
+	c.activeSince = at
+	c.servedTenure = PolicyStats{}
+
+	record := SwitchRecord{
+		Epoch:         epoch,
+		At:            at,
+		From:          from,
+		To:            to,
+		Reason:        reason,
+		HitRateBefore: before,
+	}
+	if reason == ReasonBandit {
+		record.GatesPassed = c.gatesLocked()
+	}
+
+	size := c.settings.HistorySize
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	if len(c.history) >= size {
+		c.history = slices.Delete(c.history, 0, len(c.history)-size+1)
+	}
+	c.history = append(c.history, record)
+}
+
+// gatesLocked names the stability gates a switch must pass now, in the order
+// heldByLocked checks them. The cooldown counts when damping imposes
+// one, whether or not Settings does.
+func (c *AdaptiveCache[K, V]) gatesLocked() []string {
+	var gates []string
+	if c.cooldownLocked() > 0 {
+		gates = append(gates, GateSwitchCooldown)
+	}
+	if c.settings.MinEpochRequests > 0 {
+		gates = append(gates, GateMinEpochRequests)
+	}
+	if c.settings.MinHitRateImprovement > 0 {
+		gates = append(gates, GateMinHitRateImprovement)
+	}
+	if c.settings.SwitchConfidence > 0 {
+		gates = append(gates, GateSwitchConfidence)
+	}
+
+	return gates
+}
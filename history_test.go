@@ -0,0 +1,100 @@
+package ascache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory_RecordsEachSwitchWithTheTenuresEitherSide(t *testing.T) {
+	lru := newMockPolicy[string, int](LRU, 100)
+	lfu := newMockPolicy[string, int](LFU, 100)
+	bandit := &mockBandit{next: LRU}
+
+	ac, err := NewAdaptiveCache(
+		[]Policy[string, int]{lru, lfu}, bandit,
+		&Settings{
+			EpochDuration:               24 * time.Hour,
+			EvictPartialCapacityFilling: true,
+			MinHitRateImprovement:       0.01,
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ac.Close() })
+
+	// LRU serves two epochs at 40%, then loses to LFU.
+	primeStats(lru, 40, 60)
+	ac.runEpoch()
+	primeStats(lru, 40, 60)
+	primeActiveStats(ac, 4, 6)
+	primeStats(lfu, 8, 2)
+	bandit.next = LFU
+	ac.runEpoch()
+	require.Equal(t, LFU, ac.ActivePolicy())
+
+	// LFU serves at 70% so far.
+	primeStats(lfu, 70, 30)
+
+	history := ac.History()
+	require.Len(t, history.Switches, 1)
+	record := history.Switches[0]
+	assert.Equal(t, int64(1), record.Epoch)
+	assert.Equal(t, LRU, record.From)
+	assert.Equal(t, LFU, record.To)
+	assert.Equal(t, ReasonBandit, record.Reason)
+	assert.Equal(t, []string{GateMinHitRateImprovement}, record.GatesPassed)
+	assert.InDelta(t, 0.4, record.HitRateBefore, 1e-9)
+	assert.InDelta(t, 0.7, record.HitRateAfter, 1e-9, "the latest switch's after is the tenure still running")
+
+	require.Len(t, history.Policies, 2)
+	served := map[PolicyType]PolicyTenure{}
+	for _, tenure := range history.Policies {
+		served[tenure.Policy] = tenure
+	}
+	assert.Equal(t, int64(80), served[LRU].Hits)
+	assert.Equal(t, int64(1), served[LRU].Tenures)
+	assert.Equal(t, int64(70), served[LFU].Hits, "requests served since the last epoch count too")
+	assert.Equal(t, int64(1), served[LFU].Tenures)
+	assert.Positive(t, served[LFU].Served)
+}
+
+func TestHistory_KeepsOnlyTheMostRecentSwitches(t *testing.T) {
+	lru := newMockPolicy[string, int](LRU, 100)
+	lfu := newMockPolicy[string, int](LFU, 100)
+	bandit := &mockBandit{next: LRU}
+
+	ac, err := NewAdaptiveCache(
+		[]Policy[string, int]{lru, lfu}, bandit,
+		&Settings{EpochDuration: 24 * time.Hour, EvictPartialCapacityFilling: true, HistorySize: 3},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ac.Close() })
+
+	for i := range 5 {
+		primeStats(ac.policies[ac.ActivePolicy()].(*mockPolicy[string, int]), int64(i), 1)
+		bandit.next = map[PolicyType]PolicyType{LRU: LFU, LFU: LRU}[ac.ActivePolicy()]
+		ac.runEpoch()
+	}
+
+	history := ac.History()
+	require.Len(t, history.Switches, 3)
+	assert.Equal(t, []int64{2, 3, 4}, []int64{
+		history.Switches[0].Epoch, history.Switches[1].Epoch, history.Switches[2].Epoch,
+	})
+	assert.Equal(t, int64(5), ac.Advice().Switches, "the ring forgets switches, the count does not")
+
+	// Each record's after is the next one's before: one tenure, seen from
+	// both ends.
+	for i := 1; i < len(history.Switches); i++ {
+		assert.InDelta(t, history.Switches[i].HitRateBefore, history.Switches[i-1].HitRateAfter, 1e-9)
+	}
+	assert.Empty(t, history.Switches[0].GatesPassed, "no gates are configured")
+
+	tenures := int64(0)
+	for _, tenure := range history.Policies {
+		tenures += tenure.Tenures
+	}
+	assert.Equal(t, int64(6), tenures, "the initial policy and five switches")
+}
@@ -109,3 +109,23 @@ type EpochBandit interface {
 	// under the cache's write lock.
 	RecordEpoch(report EpochReport)
 }
+
+// PinningBandit is an optional extension of Bandit for implementations that
+// can pin the cache to one policy from outside it - a fleet-wide pin an
+// operator set once, for every cache sharing the bandit's state.
+//
+// The cache asks at every epoch boundary, after the epoch has been reported,
+// so a pinned cache keeps measuring every arm and the bandit keeps learning
+// from them. While a pin is reported the cache switches to the pinned policy,
+// if it holds it and is not serving it already, and does not act on
+// SelectPolicy. A cache in ObserveOnly mode follows it too, though it ignores
+// SelectPolicy: a pin is an operator's instruction, not the bandit's choice.
+// A pin set on the cache itself with Pin takes precedence.
+type PinningBandit interface {
+	Bandit
+
+	// Pinned returns the policy the cache should serve and true, or false
+	// when nothing is pinned. It runs under the cache's write lock, so it
+	// must answer from memory.
+	Pinned() (PolicyType, bool)
+}
@@ -30,13 +30,24 @@ type Entry[K comparable, V any] struct {
 	// The expiry bucket item was put in, optional
 	ExpireBucket uint8
 
-	// The current frequency counter
+	// The current frequency counter: the bucket the element sits in. Under
+	// dynamic aging this is the element's priority rather than its count.
 	Freq int
+
+	// The number of references, kept separately from Freq only under
+	// dynamic aging
+	Refs int
+
+	// The halving epoch Freq was counted in. A halving does not visit the
+	// entries, so Freq is behind by however many have happened since.
+	Epoch int
 }
 
-// PrevEntry returns the previous list element or nil.
+// PrevEntry returns the previous list element or nil. The sentinel is told
+// apart by never belonging to a list, so an element moved by PushFrontList
+// still finds the end of its new list.
 func (e *Entry[K, V]) PrevEntry() *Entry[K, V] {
-	if p := e.prev; e.list != nil && p != &e.list.root {
+	if p := e.prev; e.list != nil && p.list != nil {
 		return p
 	}
 	return nil
@@ -143,6 +154,26 @@ func (l *LfuList[K, V]) PushFrontFreqExpirable(k K, v V, expiresAt time.Time, fr
 	return l.insertValue(k, v, expiresAt, &l.root, freq)
 }
 
+// PushFrontList moves every element of other, in order, to the front of l and
+// leaves other empty. The complexity is O(1): the moved elements are not
+// visited, and keep naming other as their list, so only Back, PrevEntry and
+// Remove through l should be used on them afterwards.
+func (l *LfuList[K, V]) PushFrontList(other *LfuList[K, V]) {
+	l.lazyInit()
+	if other.len == 0 {
+		return
+	}
+
+	first, last := other.root.next, other.root.prev
+	last.next = l.root.next
+	l.root.next.prev = last
+	l.root.next = first
+	first.prev = &l.root
+	l.len += other.len
+
+	other.Init()
+}
+
 // MoveToFront moves element e to the front of list l.
 // If e is not an element of l, the list is not modified.
 // The element must not be nil.
@@ -19,11 +19,27 @@ type Cache[K comparable, V any] struct {
 	lock        sync.RWMutex
 }
 
-func New[K comparable, V any](size int) (*Cache[K, V], error) {
-	return NewWithEvict[K, V](size, nil)
+// Option configures how the cache ages its counts. See simplelfu.Options.
+type Option = simplelfu.Option
+
+// Options is what a set of Option values configures.
+type Options = simplelfu.Options
+
+// WithHalving halves every count after each period references, so a key that
+// was popular once does not stay resident forever. Halving is lazy, and costs
+// O(1) amortised per reference for any period past the square root of the
+// cache's size; see simplelfu.Options.HalvingPeriod.
+func WithHalving(period int) Option { return simplelfu.WithHalving(period) }
+
+// WithDynamicAging selects LFU with dynamic aging (LFU-DA): a newly
+// referenced key competes from the cache's current age rather than from zero.
+func WithDynamicAging() Option { return simplelfu.WithDynamicAging() }
+
+func New[K comparable, V any](size int, opts ...Option) (*Cache[K, V], error) {
+	return NewWithEvict[K, V](size, nil, opts...)
 }
 
-func NewWithEvict[K comparable, V any](size int, onEvicted func(key K, value V)) (c *Cache[K, V], err error) {
+func NewWithEvict[K comparable, V any](size int, onEvicted func(key K, value V), opts ...Option) (c *Cache[K, V], err error) {
 	c = &Cache[K, V]{
 		onEvictedCB: onEvicted,
 	}
@@ -31,7 +47,7 @@ func NewWithEvict[K comparable, V any](size int, onEvicted func(key K, value V))
 		c.initEvictBuffers()
 		onEvicted = c.onEvicted
 	}
-	c.lfu, err = simplelfu.NewLFU(size, onEvicted)
+	c.lfu, err = simplelfu.NewLFU(size, onEvicted, opts...)
 	return
 }
 
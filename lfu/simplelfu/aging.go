@@ -0,0 +1,137 @@
+package simplelfu
+
+import (
+	"errors"
+
+	"github.com/sshaplygin/as-cache/lfu/internal"
+)
+
+// Options configures how an LFU forgets. The zero value is classic LFU, which
+// never does: a key that was popular once keeps its count, and so its place,
+// long after the traffic has moved on.
+type Options struct {
+	// HalvingPeriod, when positive, halves every entry's count after each
+	// HalvingPeriod references, so old popularity decays geometrically and a
+	// newly popular key can overtake it. A reference is an Add or a Get that
+	// hits.
+	//
+	// Halving is lazy. It advances a global epoch and relabels each count
+	// bucket once, merging its entries into the halved bucket in O(1), but
+	// never visits an entry: each records the epoch its count was taken in,
+	// and is brought up to date when it is next referenced or removed. A
+	// halving therefore costs one step per distinct count, of which a cache
+	// holding n entries has at most O(sqrt(n + HalvingPeriod)), and O(1)
+	// amortised per reference once the period is past sqrt(n).
+	HalvingPeriod int
+
+	// DynamicAging selects LFU with dynamic aging (LFU-DA, Arlitt et al.): an
+	// entry's priority is its reference count plus the cache's age, and the
+	// age rises to the priority of each entry evicted. A key referenced now
+	// starts from where the cache is, not from zero, so it competes with
+	// counts built up long ago without those counts ever being rewritten.
+	DynamicAging bool
+}
+
+// Option sets a field of Options.
+type Option func(*Options)
+
+// WithHalving halves every count after each period references.
+func WithHalving(period int) Option {
+	return func(o *Options) { o.HalvingPeriod = period }
+}
+
+// WithDynamicAging selects LFU-DA.
+func WithDynamicAging() Option {
+	return func(o *Options) { o.DynamicAging = true }
+}
+
+// validate reports an Options that cannot be built into a cache.
+func (o Options) validate() error {
+	if o.HalvingPeriod < 0 {
+		return errors.New("halving period must not be negative")
+	}
+	if o.HalvingPeriod > 0 && o.DynamicAging {
+		return errors.New("halving and dynamic aging are alternatives, not a combination")
+	}
+
+	return nil
+}
+
+// priority is the bucket an entry with refs references belongs in. Without
+// dynamic aging it is the count itself.
+func (c *LFU[K, V]) priority(refs int) int {
+	if c.opts.DynamicAging {
+		return c.age + refs
+	}
+
+	return refs
+}
+
+// referenced counts a reference towards the halving period, halving once the
+// period is up.
+func (c *LFU[K, V]) referenced() {
+	if c.opts.HalvingPeriod <= 0 {
+		return
+	}
+
+	c.references++
+	if c.references >= c.opts.HalvingPeriod {
+		c.references = 0
+		c.halve()
+	}
+}
+
+// freq returns ent's count, first bringing it up to the current epoch: one
+// halving is max(f/2, 1), so d of them are max(f>>d, 1). Every read of an
+// entry's bucket goes through here.
+func (c *LFU[K, V]) freq(ent *internal.Entry[K, V]) int {
+	if behind := c.epoch - ent.Epoch; behind > 0 {
+		ent.Freq = max(ent.Freq>>behind, 1)
+		ent.Epoch = c.epoch
+	}
+
+	return ent.Freq
+}
+
+// halve halves every entry's count, never below one, by relabelling the
+// buckets and advancing the epoch; the entries catch up in freq.
+//
+// Buckets 2k and 2k+1 merge into bucket k, and 1, 2 and 3 into 1. Each keeps
+// its own recency order, and the entries from the higher count go in front,
+// as more recently used: the buckets do not record which of two entries in
+// different buckets was referenced last, and the one referenced more often is
+// the better guess.
+func (c *LFU[K, V]) halve() {
+	halved := make(map[int]*internal.LfuList[K, V], len(c.evictList))
+	for freq := range c.evictList {
+		newFreq := max(freq/2, 1)
+		if _, done := halved[newFreq]; done {
+			continue
+		}
+
+		from := 2 * newFreq
+		if newFreq == 1 {
+			from = 1
+		}
+
+		var merged *internal.LfuList[K, V]
+		for source := from; source <= 2*newFreq+1; source++ {
+			bucket, ok := c.evictList[source]
+			switch {
+			case !ok:
+			case merged == nil:
+				merged = bucket
+			default:
+				merged.PushFrontList(bucket)
+			}
+		}
+		halved[newFreq] = merged
+	}
+
+	c.evictList = halved
+	c.epoch++
+	if len(c.evictList) > 0 {
+		// Halving keeps order, so the smallest bucket is still the smallest.
+		c.minFreq = max(c.minFreq/2, 1)
+	}
+}
@@ -0,0 +1,256 @@
+package simplelfu
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLFU_RejectsConflictingAging(t *testing.T) {
+	_, err := NewLFU[string, int](10, nil, WithHalving(100), WithDynamicAging())
+	require.Error(t, err)
+
+	_, err = NewLFU[string, int](10, nil, WithHalving(-1))
+	require.Error(t, err)
+}
+
+func TestLFU_HalvingVisitsNoEntry(t *testing.T) {
+	c, err := NewLFU[string, int](4, nil, WithHalving(1000))
+	require.NoError(t, err)
+
+	c.Add("hot", 1)
+	for range 11 {
+		c.Get("hot")
+	}
+	c.Add("cold", 2)
+
+	c.halve()
+	c.halve()
+
+	assert.Equal(t, 12, c.items["hot"].Freq, "a halving leaves the entries alone")
+	assert.Equal(t, 3, c.freq(c.items["hot"]), "and the count catches up when read")
+	assert.Equal(t, 1, c.freq(c.items["cold"]), "never below one")
+
+	c.Get("hot")
+	assert.Equal(t, 4, c.items["hot"].Freq)
+	assert.Equal(t, []string{"cold", "hot"}, c.Keys())
+	assertBucketsConsistent(t, c)
+}
+
+func TestLFU_ShortHalvingPeriodKeepsOrder(t *testing.T) {
+	c, err := NewLFU[string, int](100, nil, WithHalving(1))
+	require.NoError(t, err, "a period shorter than the size costs no more than one step per count")
+
+	for i := range 100 {
+		key := "key-" + strconv.Itoa(i)
+		c.Add(key, i)
+		for range i % 7 {
+			c.Get(key)
+		}
+	}
+	assertBucketsConsistent(t, c)
+	require.Equal(t, 100, c.Len())
+}
+
+// forgetsOldPopularity runs the access pattern plain LFU gets wrong: "old" is
+// referenced heavily and then never again, while a stream of keys each
+// referenced a few times competes for the remaining room. It reports whether
+// "old" is still resident at the end.
+func forgetsOldPopularity(t *testing.T, opts ...Option) bool {
+	t.Helper()
+
+	c, err := NewLFU[string, int](4, nil, opts...)
+	require.NoError(t, err)
+
+	c.Add("old", 0)
+	for i := 0; i < 50; i++ {
+		c.Get("old")
+	}
+
+	for i := 0; i < 200; i++ {
+		key := "new-" + strconv.Itoa(i)
+		c.Add(key, i)
+		for j := 0; j < 5; j++ {
+			c.Get(key)
+		}
+	}
+
+	return c.Contains("old")
+}
+
+func TestLFU_PlainLFUNeverForgets(t *testing.T) {
+	assert.True(t, forgetsOldPopularity(t),
+		"without aging a once-popular key is resident forever; the aging tests depend on it")
+}
+
+func TestLFU_HalvingForgetsOldPopularity(t *testing.T) {
+	assert.False(t, forgetsOldPopularity(t, WithHalving(20)))
+}
+
+func TestLFU_DynamicAgingForgetsOldPopularity(t *testing.T) {
+	assert.False(t, forgetsOldPopularity(t, WithDynamicAging()))
+}
+
+func TestLFU_HalvingHalvesCountsButKeepsOrder(t *testing.T) {
+	c, err := NewLFU[string, int](4, nil, WithHalving(1000))
+	require.NoError(t, err)
+
+	c.Add("a", 1)
+	c.Add("d", 4)
+	for i := 0; i < 4; i++ {
+		c.Get("d")
+	}
+	c.Add("b", 2)
+	for i := 0; i < 3; i++ {
+		c.Get("b")
+	}
+	c.Add("c", 3)
+	for i := 0; i < 7; i++ {
+		c.Get("c")
+	}
+
+	c.halve()
+
+	assert.Equal(t, 1, c.freq(c.items["a"]))
+	assert.Equal(t, 2, c.freq(c.items["b"]))
+	assert.Equal(t, 2, c.freq(c.items["d"]))
+	assert.Equal(t, 4, c.freq(c.items["c"]))
+	assert.Equal(t, []string{"a", "b", "d", "c"}, c.Keys(),
+		"b and d share a bucket now, and d, counted higher, is evicted after b")
+	assertBucketsConsistent(t, c)
+}
+
+func TestLFU_DynamicAgingStartsNewKeysAtTheAge(t *testing.T) {
+	c, err := NewLFU[string, int](1, nil, WithDynamicAging())
+	require.NoError(t, err)
+
+	c.Add("a", 1)
+	c.Get("a")
+	c.Get("a")
+	c.Add("b", 2) // evicts a, at priority 3
+
+	assert.Equal(t, 3, c.age)
+	assert.Equal(t, 4, c.items["b"].Freq, "a new key starts one above the age")
+}
+
+// TestLFU_AgingKeepsBucketsConsistent drives random operations under each kind
+// of aging and checks the structure after every one: aging rewrites the bucket
+// keys, which is exactly where a stale minFreq would come from.
+func TestLFU_AgingKeepsBucketsConsistent(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"none":                           nil,
+		"halving":                        {WithHalving(8)},
+		"halving, shorter than the size": {WithHalving(3)},
+		"da":                             {WithDynamicAging()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := NewLFU[int, int](8, nil, opts...)
+			require.NoError(t, err)
+
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 5000; i++ {
+				key := rng.Intn(24)
+				switch rng.Intn(10) {
+				case 0:
+					c.Remove(key)
+				case 1, 2, 3:
+					c.Add(key, i)
+				default:
+					c.Get(key)
+				}
+				assertBucketsConsistent(t, c)
+			}
+		})
+	}
+}
+
+// TestLFU_LazyHalvingCountsLikeEagerHalving keeps every count the obvious way
+// alongside the cache - halving them all at each period - and checks the lazy
+// counts agree, and that what the cache evicts had the lowest count.
+func TestLFU_LazyHalvingCountsLikeEagerHalving(t *testing.T) {
+	const period = 5
+
+	c, err := NewLFU[int, int](8, nil, WithHalving(period))
+	require.NoError(t, err)
+
+	counts := make(map[int]int)
+	references := 0
+	referenced := func() {
+		references++
+		if references == period {
+			references = 0
+			for key, count := range counts {
+				counts[key] = max(count/2, 1)
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		key := rng.Intn(24)
+		switch {
+		case rng.Intn(3) == 0:
+			if _, ok := counts[key]; ok {
+				counts[key]++
+				c.Add(key, i)
+				referenced()
+				break
+			}
+
+			lowest := 0
+			for _, count := range counts {
+				if lowest == 0 || count < lowest {
+					lowest = count
+				}
+			}
+			before := c.Keys()
+			c.Add(key, i)
+			for _, held := range before {
+				if !c.Contains(held) {
+					require.Equal(t, lowest, counts[held], "evicted %d", held)
+					delete(counts, held)
+				}
+			}
+			counts[key] = 1
+			referenced()
+		default:
+			if _, ok := c.Get(key); ok {
+				counts[key]++
+				referenced()
+			}
+		}
+
+		require.Len(t, c.items, len(counts))
+		for key, count := range counts {
+			require.Equal(t, count, c.freq(c.items[key]), "count of %d", key)
+		}
+	}
+}
+
+// assertBucketsConsistent checks every entry sits in the bucket its count names,
+// no bucket is empty, and minFreq addresses the smallest bucket.
+func assertBucketsConsistent[K comparable, V any](t *testing.T, c *LFU[K, V]) {
+	t.Helper()
+
+	count := 0
+	smallest := 0
+	for freq, bucket := range c.evictList {
+		require.Positive(t, bucket.Length(), "bucket %d is empty", freq)
+		for ent := bucket.Back(); ent != nil; ent = ent.PrevEntry() {
+			require.Equal(t, freq, c.freq(ent))
+			require.Same(t, ent, c.items[ent.Key])
+			count++
+		}
+		if smallest == 0 || freq < smallest {
+			smallest = freq
+		}
+	}
+
+	require.Equal(t, len(c.items), count)
+	if count > 0 {
+		require.Equal(t, smallest, c.minFreq)
+	}
+}
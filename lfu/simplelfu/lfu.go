@@ -19,18 +19,38 @@ type LFU[K comparable, V any] struct {
 	items     map[K]*internal.Entry[K, V]
 	evictList map[int]*internal.LfuList[K, V]
 	onEvict   EvictCallback[K, V]
+
+	opts Options
+	// references counts towards the next halving.
+	references int
+	// epoch counts halvings; see freq.
+	epoch int
+	// age is the LFU-DA inflation value: the priority of the last entry
+	// evicted.
+	age int
 }
 
-func NewLFU[K comparable, V any](size int, onEvict EvictCallback[K, V]) (*LFU[K, V], error) {
+// NewLFU returns an LFU of the given size. Without options it never forgets;
+// see Options for the ways it can.
+func NewLFU[K comparable, V any](size int, onEvict EvictCallback[K, V], opts ...Option) (*LFU[K, V], error) {
 	if size <= 0 {
 		return nil, errors.New("must provide a positive size")
 	}
 
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
 	c := &LFU[K, V]{
 		size:      size,
 		evictList: make(map[int]*internal.LfuList[K, V]),
 		items:     make(map[K]*internal.Entry[K, V]),
 		onEvict:   onEvict,
+		opts:      options,
 	}
 
 	return c, nil
@@ -49,6 +69,7 @@ func (c *LFU[K, V]) Add(key K, value V) (evicted bool) {
 	if ok {
 		ent.Value = value
 		c.updateFreq(ent)
+		c.referenced()
 		return
 	}
 
@@ -59,15 +80,24 @@ func (c *LFU[K, V]) Add(key K, value V) (evicted bool) {
 		_, _, evicted = c.evictOldest()
 	}
 
-	newFreq := 1
+	newFreq := c.priority(1)
 	if _, ok := c.evictList[newFreq]; !ok {
 		c.evictList[newFreq] = internal.NewList[K, V]()
 	}
 
-	ent = c.evictList[newFreq].PushFront(key, value)
+	ent = c.evictList[newFreq].PushFrontFreq(key, value, newFreq)
+	ent.Refs = 1
+	ent.Epoch = c.epoch
 	c.items[key] = ent
 
-	c.minFreq = newFreq
+	// Without aging a new entry always has the lowest count. Under dynamic
+	// aging it starts at the age plus one, which can sit above an entry left
+	// at exactly the age.
+	if len(c.items) == 1 || newFreq < c.minFreq {
+		c.minFreq = newFreq
+	}
+
+	c.referenced()
 
 	return
 }
@@ -77,8 +107,10 @@ func (c *LFU[K, V]) Get(key K) (value V, ok bool) {
 	if !ok {
 		return
 	}
+	value = ent.Value
 	c.updateFreq(ent)
-	return ent.Value, true
+	c.referenced()
+	return value, true
 }
 
 func (c *LFU[K, V]) Contains(key K) (ok bool) {
@@ -205,6 +237,10 @@ func (c *LFU[K, V]) evictOldest() (key K, value V, ok bool) {
 
 	key, value, ok = ent.Key, ent.Value, true
 
+	if c.opts.DynamicAging {
+		c.age = ent.Freq
+	}
+
 	c.detach(ent)
 	delete(c.items, ent.Key)
 
@@ -225,27 +261,49 @@ func (c *LFU[K, V]) Purge() {
 
 	c.evictList = make(map[int]*internal.LfuList[K, V])
 	c.minFreq = 0
+	c.references = 0
+	c.epoch = 0
+	c.age = 0
 }
 
 func (c *LFU[K, V]) updateFreq(ent *internal.Entry[K, V]) {
-	oldFreq := ent.Freq
-	c.evictList[oldFreq].Remove(ent)
+	oldFreq := c.freq(ent)
+	refs := ent.Refs + 1
+	if !c.opts.DynamicAging {
+		refs = oldFreq + 1
+	}
+	newFreq := c.priority(refs)
 
-	if c.evictList[oldFreq].Length() == 0 {
+	c.evictList[oldFreq].Remove(ent)
+	emptied := c.evictList[oldFreq].Length() == 0
+	if emptied {
 		delete(c.evictList, oldFreq)
-		if c.minFreq == oldFreq {
-			c.minFreq = oldFreq + 1
-		}
 	}
 
-	newFreq := oldFreq + 1
-
 	if _, ok := c.evictList[newFreq]; !ok {
 		c.evictList[newFreq] = internal.NewList[K, V]()
 	}
 
 	ent = c.evictList[newFreq].PushFrontFreq(ent.Key, ent.Value, newFreq)
+	ent.Refs = refs
+	ent.Epoch = c.epoch
 	c.items[ent.Key] = ent
+
+	// The minimum moves up to the next live bucket, which is at most newFreq.
+	// Without aging that is exactly one step. Under dynamic aging the gap is
+	// as far as the age has risen since the entry was last referenced, and
+	// past the number of buckets it is cheaper to look at each of them.
+	if emptied && c.minFreq == oldFreq {
+		if newFreq-oldFreq > len(c.evictList) {
+			c.recomputeMinFreq()
+			return
+		}
+		for c.minFreq = oldFreq + 1; c.minFreq < newFreq; c.minFreq++ {
+			if _, ok := c.evictList[c.minFreq]; ok {
+				break
+			}
+		}
+	}
 }
 
 // removeElement is used to remove a given list element from the cache
@@ -262,7 +320,8 @@ func (c *LFU[K, V]) removeElement(e *internal.Entry[K, V]) {
 // the invariant that every bucket in evictList holds at least one entry and
 // that minFreq addresses a live bucket whenever the cache is non-empty.
 func (c *LFU[K, V]) detach(ent *internal.Entry[K, V]) {
-	bucket, found := c.evictList[ent.Freq]
+	freq := c.freq(ent)
+	bucket, found := c.evictList[freq]
 	if !found {
 		return
 	}
@@ -272,8 +331,8 @@ func (c *LFU[K, V]) detach(ent *internal.Entry[K, V]) {
 		return
 	}
 
-	delete(c.evictList, ent.Freq)
-	if c.minFreq == ent.Freq {
+	delete(c.evictList, freq)
+	if c.minFreq == freq {
 		c.recomputeMinFreq()
 	}
 }
@@ -5,9 +5,9 @@
 // snapshot suitable for scraping, and publishes it through expvar.
 //
 // It depends on nothing outside the standard library. A Prometheus collector
-// is a few lines on top of Snapshot; see the package example rather than a
-// dependency here, because how metrics are named and labelled is a decision
-// that belongs to the application, not to a cache library.
+// over Snapshot lives in the separate github.com/sshaplygin/as-cache/metrics/prometheus
+// module, and OpenTelemetry instruments in .../metrics/otel, so that an
+// application which exports only through expvar pulls in neither client.
 package metrics
 
 import (
@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	ascache "github.com/sshaplygin/as-cache"
 )
@@ -34,6 +35,13 @@ type Advisor interface {
 	Len() int
 }
 
+// Historian is implemented by a cache that keeps a switch history. Every
+// AdaptiveCache does; it is separate from Advisor so that an Advisor written
+// before History existed still satisfies it.
+type Historian interface {
+	History() ascache.History
+}
+
 // PolicySnapshot is one policy's measurements at a point in time.
 type PolicySnapshot struct {
 	Policy  string  `json:"policy"`
@@ -41,6 +49,33 @@ type PolicySnapshot struct {
 	Misses  int64   `json:"misses"`
 	HitRate float64 `json:"hit_rate"`
 	Active  bool    `json:"active"`
+
+	// HitRateLow and HitRateHigh bound the true hit rate at
+	// ascache.IntervalLevel confidence, from the same requests HitRate is.
+	// Arms whose intervals overlap are within noise of each other.
+	HitRateLow  float64 `json:"hit_rate_low"`
+	HitRateHigh float64 `json:"hit_rate_high"`
+
+	// ServedSeconds, ServedHits and ServedMisses are the policy's whole
+	// record as the active policy: how long it has served and what it served
+	// in that time, unsampled. Hits, Misses and HitRate above restart at every
+	// switch; these never do. They are zero for a cache that is not a
+	// Historian.
+	ServedSeconds float64 `json:"served_seconds"`
+	ServedHits    int64   `json:"served_hits"`
+	ServedMisses  int64   `json:"served_misses"`
+}
+
+// SwitchSnapshot is one recent change of active policy.
+type SwitchSnapshot struct {
+	Epoch         int64     `json:"epoch"`
+	At            time.Time `json:"at"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	Reason        string    `json:"reason"`
+	GatesPassed   []string  `json:"gates_passed,omitempty"`
+	HitRateBefore float64   `json:"hit_rate_before"`
+	HitRateAfter  float64   `json:"hit_rate_after"`
 }
 
 // Snapshot is everything worth exporting about a cache at a point in time.
@@ -55,6 +90,27 @@ type Snapshot struct {
 	ActivePolicy string `json:"active_policy"`
 	// Epochs is how many measurement rounds have completed.
 	Epochs int64 `json:"epochs"`
+	// Switches is how many times the active policy has changed. It counts
+	// switches a scrape interval would hide, so alert on its rate rather
+	// than on changes in ActivePolicy.
+	Switches int64 `json:"switches"`
+	// DampingLevel is how many times oscillation damping has doubled the
+	// switch cooldown, and CooldownEpochs the cooldown in force because of
+	// it. A level above zero means the cache caught itself flapping and is
+	// holding back switches it would otherwise make.
+	DampingLevel   int   `json:"damping_level"`
+	CooldownEpochs int64 `json:"cooldown_epochs"`
+	// DampingRaised and DampingRelaxed count damping adjustments in each
+	// direction. A rising DampingRaised is a workload the arms cannot tell
+	// apart, or a threshold set too low.
+	DampingRaised  int64 `json:"damping_raised"`
+	DampingRelaxed int64 `json:"damping_relaxed"`
+	// Pinned reports whether the cache is pinned to PinnedPolicy, which it
+	// then serves whatever BestPolicy says. PinSource is "operator" for a
+	// pin set on the cache and "fleet" for one the bandit reported.
+	Pinned       bool   `json:"pinned"`
+	PinnedPolicy string `json:"pinned_policy,omitempty"`
+	PinSource    string `json:"pin_source,omitempty"`
 	// Entries is how many entries the active policy currently holds.
 	Entries int `json:"entries"`
 
@@ -71,6 +127,10 @@ type Snapshot struct {
 	// mode and waiting for a human.
 	BestPolicy  string  `json:"best_policy"`
 	Improvement float64 `json:"improvement"`
+	// Confidence is the probability that BestPolicy truly beats the active
+	// one, zero when they are the same. An Improvement is worth acting on
+	// only when this is close to one.
+	Confidence float64 `json:"confidence"`
 
 	// Sampled reports whether the per-policy numbers are estimates from a
 	// sampled substream. Hits, Misses and HitRate above are never sampled.
@@ -79,6 +139,10 @@ type Snapshot struct {
 
 	// Policies holds every arm, best hit rate first.
 	Policies []PolicySnapshot `json:"policies"`
+
+	// History holds the cache's most recent switches, oldest first, for a
+	// cache that is a Historian.
+	History []SwitchSnapshot `json:"history,omitempty"`
 }
 
 // Take reads a cache's current measurements.
@@ -87,29 +151,73 @@ func Take(cache Advisor) Snapshot {
 	stats := cache.Stats()
 
 	snapshot := Snapshot{
-		ActivePolicy: advice.Active.String(),
-		Epochs:       advice.Epochs,
-		Entries:      cache.Len(),
-		Hits:         stats.Hits,
-		Misses:       stats.Misses,
-		BestPolicy:   advice.Best.String(),
-		Improvement:  advice.Improvement,
-		Sampled:      advice.Sampled,
-		SampleRate:   advice.SampleRate,
-		Policies:     make([]PolicySnapshot, 0, len(advice.Reports)),
+		ActivePolicy:   advice.Active.String(),
+		Epochs:         advice.Epochs,
+		Switches:       advice.Switches,
+		DampingLevel:   advice.Damping.Level,
+		CooldownEpochs: advice.Damping.CooldownEpochs,
+		DampingRaised:  advice.Damping.Raised,
+		DampingRelaxed: advice.Damping.Relaxed,
+		Entries:        cache.Len(),
+		Hits:           stats.Hits,
+		Misses:         stats.Misses,
+		BestPolicy:     advice.Best.String(),
+		Improvement:    advice.Improvement,
+		Confidence:     advice.Confidence,
+		Sampled:        advice.Sampled,
+		SampleRate:     advice.SampleRate,
+		Policies:       make([]PolicySnapshot, 0, len(advice.Reports)),
 	}
 
 	if total := stats.Hits + stats.Misses; total > 0 {
 		snapshot.HitRate = float64(stats.Hits) / float64(total)
 	}
 
+	if advice.Pin.Pinned() {
+		snapshot.Pinned = true
+		snapshot.PinnedPolicy = advice.Pin.Policy.String()
+		snapshot.PinSource = "operator"
+		if advice.Pin.Fleet {
+			snapshot.PinSource = "fleet"
+		}
+	}
+
+	var served map[ascache.PolicyType]ascache.PolicyTenure
+	if historian, ok := cache.(Historian); ok {
+		history := historian.History()
+
+		served = make(map[ascache.PolicyType]ascache.PolicyTenure, len(history.Policies))
+		for _, tenure := range history.Policies {
+			served[tenure.Policy] = tenure
+		}
+		for _, record := range history.Switches {
+			snapshot.History = append(snapshot.History, SwitchSnapshot{
+				Epoch:         record.Epoch,
+				At:            record.At,
+				From:          record.From.String(),
+				To:            record.To.String(),
+				Reason:        string(record.Reason),
+				GatesPassed:   record.GatesPassed,
+				HitRateBefore: record.HitRateBefore,
+				HitRateAfter:  record.HitRateAfter,
+			})
+		}
+	}
+
 	for _, report := range advice.Reports {
+		tenure := served[report.Policy]
+		low, high := report.Interval()
 		snapshot.Policies = append(snapshot.Policies, PolicySnapshot{
-			Policy:  report.Policy.String(),
-			Hits:    report.Hits,
-			Misses:  report.Misses,
-			HitRate: report.HitRate(),
-			Active:  report.Active,
+			Policy:        report.Policy.String(),
+			Hits:          report.Hits,
+			Misses:        report.Misses,
+			HitRate:       report.HitRate(),
+			Active:        report.Active,
+			HitRateLow:    low,
+			HitRateHigh:   high,
+			ServedSeconds: tenure.Served.Seconds(),
+			ServedHits:    tenure.Hits,
+			ServedMisses:  tenure.Misses,
 		})
 	}
 
@@ -67,6 +67,23 @@ func TestTake_ReportsTheCacheState(t *testing.T) {
 	assert.InDelta(t, float64(snapshot.Hits)/float64(snapshot.Hits+snapshot.Misses),
 		snapshot.HitRate, 1e-9)
 	assert.Len(t, snapshot.Policies, 2, "every arm should be reported")
+	for _, policy := range snapshot.Policies {
+		assert.LessOrEqual(t, policy.HitRateLow, policy.HitRate, policy.Policy)
+		assert.GreaterOrEqual(t, policy.HitRateHigh, policy.HitRate, policy.Policy)
+	}
+}
+
+func TestTake_ReportsThePin(t *testing.T) {
+	cache := newCache(t)
+
+	assert.False(t, metrics.Take(cache).Pinned)
+
+	require.NoError(t, cache.Pin(ascache.TwoQueue, ascache.MigrationWarm))
+	snapshot := metrics.Take(cache)
+	assert.True(t, snapshot.Pinned)
+	assert.Equal(t, "TwoQueue", snapshot.PinnedPolicy)
+	assert.Equal(t, "operator", snapshot.PinSource)
+	assert.Equal(t, snapshot.PinnedPolicy, snapshot.ActivePolicy)
 }
 
 func TestTake_UnsampledTotalsAreRealTraffic(t *testing.T) {
@@ -81,6 +98,24 @@ func TestTake_UnsampledTotalsAreRealTraffic(t *testing.T) {
 	assert.Equal(t, stats.Misses, snapshot.Misses)
 }
 
+func TestTake_ReportsTheServedLedger(t *testing.T) {
+	cache := newCache(t)
+	drive(t, cache)
+
+	snapshot := metrics.Take(cache)
+
+	for _, policy := range snapshot.Policies {
+		if policy.Policy != "LRU" {
+			assert.Zero(t, policy.ServedSeconds, "%s has never served", policy.Policy)
+			continue
+		}
+		assert.Positive(t, policy.ServedSeconds)
+		assert.Equal(t, snapshot.Hits+snapshot.Misses, policy.ServedHits+policy.ServedMisses,
+			"the only policy ever active served everything the cache did")
+	}
+	assert.Empty(t, snapshot.History, "observe-only never switches")
+}
+
 func TestTake_PoliciesAreOrderedBestFirst(t *testing.T) {
 	cache := newCache(t)
 	drive(t, cache)
@@ -0,0 +1,37 @@
+module github.com/sshaplygin/as-cache/metrics/otel
+
+go 1.25.2
+
+require (
+	github.com/sshaplygin/as-cache v0.3.1
+	github.com/sshaplygin/as-cache/metrics v0.3.1
+	github.com/sshaplygin/as-cache/policies v0.3.1
+	github.com/stretchr/testify v1.11.1
+	go.opentelemetry.io/otel v1.44.0
+	go.opentelemetry.io/otel/metric v1.44.0
+	go.opentelemetry.io/otel/sdk v1.44.0
+	go.opentelemetry.io/otel/sdk/metric v1.44.0
+	go.opentelemetry.io/otel/trace v1.44.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.6 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/sshaplygin/as-cache/lfu v0.3.1 // indirect
+	go.opentelemetry.io/auto/sdk v1.2.1 // indirect
+	golang.org/x/sys v0.45.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/sshaplygin/as-cache => ../..
+
+replace github.com/sshaplygin/as-cache/metrics => ..
+
+replace github.com/sshaplygin/as-cache/policies => ../../policies
+
+replace github.com/sshaplygin/as-cache/lfu => ../../lfu
@@ -0,0 +1,299 @@
+// Package otel exports an AdaptiveCache through OpenTelemetry: its snapshot as
+// observable instruments, and each change of active policy as a span.
+//
+// It lives in a module of its own for the reason the Prometheus collector
+// does: the metrics package imports nothing outside the standard library, and
+// an application that does not use OpenTelemetry should not pull it in.
+//
+//	tracer := otel.NewTracer(provider.Tracer("ascache"), otel.Options{})
+//	cache, err := ascache.NewAdaptiveCache(arms, b, &ascache.Settings{
+//	    EpochDuration: time.Second,
+//	    OnSwitch:      tracer.OnSwitch,
+//	})
+//	registration, err := otel.RegisterMetrics(meter, cache, otel.Options{})
+//	defer registration.Unregister()
+//
+// # Instruments
+//
+// Every instrument is observable and read from one metrics.Take per
+// collection, so nothing is recorded between collections and the cache's hot
+// path is untouched. Names are stable and prefixed with Options.Namespace,
+// "ascache" unless set:
+//
+//	ascache.hits                  counter  requests served from the cache
+//	ascache.misses                counter  requests the cache missed
+//	ascache.epochs                counter  epochs that measured something
+//	ascache.switches              counter  changes of active policy
+//	ascache.damping.level         gauge    times damping has doubled the switch cooldown
+//	ascache.damping.cooldown      gauge    the switch cooldown in force, damping included
+//	ascache.damping.adjustments   counter  damping raised or relaxed, by direction
+//	ascache.pinned                gauge    1 while pinned to one policy, 0 otherwise
+//	ascache.entries               gauge    entries the active policy holds
+//	ascache.improvement           gauge    hit rate the best policy beats the active one by
+//	ascache.confidence            gauge    probability the best policy truly beats the active one
+//	ascache.sample_rate           gauge    fraction of the keyspace shadows measure
+//	ascache.policy.hit_rate       gauge    each arm's measured hit rate
+//	ascache.policy.hit_rate_bound gauge    lower and upper ends of its 95% interval
+//	ascache.policy.active         gauge    1 for the active arm and 0 for the rest
+//	ascache.policy.served         counter  seconds each arm has spent active
+//	ascache.policy.served_hits    counter  requests each arm served while active
+//	ascache.policy.served_misses  counter  requests each arm missed while active
+//
+// The per-policy instruments carry the arm's name as ascache.policy,
+// ascache.policy.hit_rate_bound which end of the interval it is, "lower" or
+// "upper", as ascache.bound, and
+// ascache.damping.adjustments its direction, "raised" or "relaxed", as
+// ascache.damping.direction.
+//
+// # Spans
+//
+// A switch is reported as one span, "ascache.switch", covering the time the
+// cache held its write lock for it, migration included: the time every Get
+// waited. It carries the epoch, the old and new policy, what made the switch,
+// the migration strategy and how many keys the migration moved. Spans come only from switches - there
+// is no span per Get, which at cache rates would cost more than the lookup it
+// described.
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/metrics"
+)
+
+// DefaultNamespace prefixes every instrument and span name when
+// Options.Namespace is empty.
+const DefaultNamespace = "ascache"
+
+// Attribute keys on observations and spans.
+const (
+	// PolicyKey names the arm a per-policy observation describes.
+	PolicyKey = attribute.Key("ascache.policy")
+	// EpochKey is the epoch that switched.
+	EpochKey = attribute.Key("ascache.epoch")
+	// FromKey and ToKey are the policies either side of a switch.
+	FromKey = attribute.Key("ascache.policy.from")
+	ToKey   = attribute.Key("ascache.policy.to")
+	// ReasonKey is what made a switch: "bandit" or "pin".
+	ReasonKey = attribute.Key("ascache.switch.reason")
+	// StrategyKey is the migration strategy: "cold", "warm" or "gradual".
+	StrategyKey = attribute.Key("ascache.migration.strategy")
+	// KeysKey is how many keys the migration copied or queued.
+	KeysKey = attribute.Key("ascache.migration.keys")
+	// BoundKey is which end of a hit-rate interval an observation is.
+	BoundKey = attribute.Key("ascache.bound")
+	// DirectionKey is which way a damping adjustment went.
+	DirectionKey = attribute.Key("ascache.damping.direction")
+)
+
+// Options configures how instruments and spans are named and attributed.
+type Options struct {
+	// Namespace prefixes every instrument and span name. Defaults to
+	// DefaultNamespace.
+	Namespace string
+
+	// Attributes are attached to every observation and span, so that several
+	// caches reporting through one meter can be told apart - typically
+	// attribute.String("cache", "sessions").
+	Attributes []attribute.KeyValue
+}
+
+func (o Options) name(suffix string) string {
+	namespace := o.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
+	return namespace + "." + suffix
+}
+
+// RegisterMetrics registers cache's observable instruments with meter. Every
+// AdaptiveCache satisfies metrics.Advisor. Unregister the returned
+// registration when the cache is closed, or the meter keeps reading it.
+func RegisterMetrics(meter metric.Meter, cache metrics.Advisor, opts Options) (metric.Registration, error) {
+	hits, err := meter.Int64ObservableCounter(opts.name("hits"),
+		metric.WithDescription("Requests served from the cache."), metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, err
+	}
+	misses, err := meter.Int64ObservableCounter(opts.name("misses"),
+		metric.WithDescription("Requests the cache missed."), metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, err
+	}
+	epochs, err := meter.Int64ObservableCounter(opts.name("epochs"),
+		metric.WithDescription("Epochs that measured something."), metric.WithUnit("{epoch}"))
+	if err != nil {
+		return nil, err
+	}
+	switches, err := meter.Int64ObservableCounter(opts.name("switches"),
+		metric.WithDescription("Changes of active policy."), metric.WithUnit("{switch}"))
+	if err != nil {
+		return nil, err
+	}
+	dampingLevel, err := meter.Int64ObservableGauge(opts.name("damping.level"),
+		metric.WithDescription("How many times oscillation damping has doubled the switch cooldown."))
+	if err != nil {
+		return nil, err
+	}
+	cooldown, err := meter.Int64ObservableGauge(opts.name("damping.cooldown"),
+		metric.WithDescription("The switch cooldown in force, damping included."), metric.WithUnit("{epoch}"))
+	if err != nil {
+		return nil, err
+	}
+	adjustments, err := meter.Int64ObservableCounter(opts.name("damping.adjustments"),
+		metric.WithDescription("Changes of damping level, by direction."), metric.WithUnit("{adjustment}"))
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := meter.Int64ObservableGauge(opts.name("pinned"),
+		metric.WithDescription("1 while the cache is pinned to one policy and not consulting the bandit."))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := meter.Int64ObservableGauge(opts.name("entries"),
+		metric.WithDescription("Entries held by the active policy."), metric.WithUnit("{entry}"))
+	if err != nil {
+		return nil, err
+	}
+	improvement, err := meter.Float64ObservableGauge(opts.name("improvement"),
+		metric.WithDescription("Hit rate by which the best policy beats the active one."), metric.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+	confidence, err := meter.Float64ObservableGauge(opts.name("confidence"),
+		metric.WithDescription("Probability that the best policy truly beats the active one."), metric.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+	sampleRate, err := meter.Float64ObservableGauge(opts.name("sample_rate"),
+		metric.WithDescription("Fraction of the keyspace the shadow policies measure."), metric.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+	policyRate, err := meter.Float64ObservableGauge(opts.name("policy.hit_rate"),
+		metric.WithDescription("Each policy's hit rate, measured since it last changed role."), metric.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+	policyBound, err := meter.Float64ObservableGauge(opts.name("policy.hit_rate_bound"),
+		metric.WithDescription("Bounds of the 95% interval on each policy's true hit rate."), metric.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+	policyActive, err := meter.Int64ObservableGauge(opts.name("policy.active"),
+		metric.WithDescription("1 for the policy serving requests, 0 for the rest."))
+	if err != nil {
+		return nil, err
+	}
+	served, err := meter.Float64ObservableCounter(opts.name("policy.served"),
+		metric.WithDescription("Time each policy has spent as the active policy."), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	servedHits, err := meter.Int64ObservableCounter(opts.name("policy.served_hits"),
+		metric.WithDescription("Requests each policy served from the cache while active."), metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, err
+	}
+	servedMisses, err := meter.Int64ObservableCounter(opts.name("policy.served_misses"),
+		metric.WithDescription("Requests each policy missed while active."), metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, err
+	}
+
+	common := metric.WithAttributes(opts.Attributes...)
+	raised := metric.WithAttributes(append(opts.Attributes[:len(opts.Attributes):len(opts.Attributes)],
+		DirectionKey.String("raised"))...)
+	relaxed := metric.WithAttributes(append(opts.Attributes[:len(opts.Attributes):len(opts.Attributes)],
+		DirectionKey.String("relaxed"))...)
+
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		snapshot := metrics.Take(cache)
+
+		o.ObserveInt64(hits, snapshot.Hits, common)
+		o.ObserveInt64(misses, snapshot.Misses, common)
+		o.ObserveInt64(epochs, snapshot.Epochs, common)
+		o.ObserveInt64(switches, snapshot.Switches, common)
+		o.ObserveInt64(dampingLevel, int64(snapshot.DampingLevel), common)
+		o.ObserveInt64(cooldown, snapshot.CooldownEpochs, common)
+		o.ObserveInt64(adjustments, snapshot.DampingRaised, raised)
+		o.ObserveInt64(adjustments, snapshot.DampingRelaxed, relaxed)
+		isPinned := int64(0)
+		if snapshot.Pinned {
+			isPinned = 1
+		}
+		o.ObserveInt64(pinned, isPinned, common)
+		o.ObserveInt64(entries, int64(snapshot.Entries), common)
+		o.ObserveFloat64(improvement, snapshot.Improvement, common)
+		o.ObserveFloat64(confidence, snapshot.Confidence, common)
+		o.ObserveFloat64(sampleRate, snapshot.SampleRate, common)
+
+		for _, policy := range snapshot.Policies {
+			base := append(opts.Attributes[:len(opts.Attributes):len(opts.Attributes)], PolicyKey.String(policy.Policy))
+			attrs := metric.WithAttributes(base...)
+			lower := metric.WithAttributes(append(base[:len(base):len(base)], BoundKey.String("lower"))...)
+			upper := metric.WithAttributes(append(base[:len(base):len(base)], BoundKey.String("upper"))...)
+
+			active := int64(0)
+			if policy.Active {
+				active = 1
+			}
+			o.ObserveFloat64(policyRate, policy.HitRate, attrs)
+			o.ObserveFloat64(policyBound, policy.HitRateLow, lower)
+			o.ObserveFloat64(policyBound, policy.HitRateHigh, upper)
+			o.ObserveInt64(policyActive, active, attrs)
+			o.ObserveFloat64(served, policy.ServedSeconds, attrs)
+			o.ObserveInt64(servedHits, policy.ServedHits, attrs)
+			o.ObserveInt64(servedMisses, policy.ServedMisses, attrs)
+		}
+
+		return nil
+	}, hits, misses, epochs, switches, dampingLevel, cooldown, adjustments, pinned, entries, improvement, confidence, sampleRate,
+		policyRate, policyBound, policyActive, served, servedHits, servedMisses)
+}
+
+// Tracer reports a cache's policy switches as spans.
+type Tracer struct {
+	tracer trace.Tracer
+	name   string
+	attrs  []attribute.KeyValue
+}
+
+// NewTracer returns a Tracer that starts its spans on tracer.
+func NewTracer(tracer trace.Tracer, opts Options) *Tracer {
+	return &Tracer{
+		tracer: tracer,
+		name:   opts.name("switch"),
+		attrs:  opts.Attributes,
+	}
+}
+
+// OnSwitch records event as a span. Assign it to Settings.OnSwitch, or call
+// it from a hook of your own.
+//
+// The span has no parent: a switch is made by the cache's epoch, not by any
+// request, and attaching it to whichever request happened to end the epoch
+// would make that request look slow for a reason it had nothing to do with.
+func (t *Tracer) OnSwitch(event ascache.SwitchEvent) {
+	attrs := append(t.attrs[:len(t.attrs):len(t.attrs)],
+		EpochKey.Int64(event.Epoch),
+		FromKey.String(event.From.String()),
+		ToKey.String(event.To.String()),
+		ReasonKey.String(string(event.Reason)),
+		StrategyKey.String(event.Migration.String()),
+		KeysKey.Int(event.MigrationKeys),
+	)
+
+	_, span := t.tracer.Start(context.Background(), t.name,
+		trace.WithNewRoot(),
+		trace.WithTimestamp(event.Start),
+		trace.WithAttributes(attrs...),
+	)
+	span.End(trace.WithTimestamp(event.Start.Add(event.Duration)))
+}
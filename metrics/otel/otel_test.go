@@ -0,0 +1,182 @@
+package otel
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/policies"
+)
+
+// fixedAdvisor is a cache frozen at one set of measurements.
+type fixedAdvisor struct{ advice ascache.Advice }
+
+func (a fixedAdvisor) Advice() ascache.Advice           { return a.advice }
+func (a fixedAdvisor) Stats() ascache.GlobalStats       { return ascache.GlobalStats{Hits: 40, Misses: 60} }
+func (a fixedAdvisor) ActivePolicy() ascache.PolicyType { return a.advice.Active }
+func (a fixedAdvisor) Len() int                         { return 7 }
+
+// alwaysBandit selects one policy whatever it is shown.
+type alwaysBandit struct{ policy ascache.PolicyType }
+
+func (alwaysBandit) RecordStats(ascache.ShadowStats)    {}
+func (b alwaysBandit) SelectPolicy() ascache.PolicyType { return b.policy }
+
+// collect reads every metric the reader has, keyed by name.
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	out := make(map[string]metricdata.Aggregation)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			out[m.Name] = m.Data
+		}
+	}
+
+	return out
+}
+
+func TestRegisterMetrics_ObservesTheSnapshot(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	cache := fixedAdvisor{advice: ascache.Advice{
+		Epochs:     12,
+		Active:     ascache.LRU,
+		Switches:   3,
+		Damping:    ascache.DampingStatus{Level: 2, CooldownEpochs: 8, Raised: 3, Relaxed: 1},
+		Best:       ascache.TinyLFU,
+		Confidence: 0.875,
+		Reports: []ascache.PolicyReport{
+			{Policy: ascache.TinyLFU, Hits: 3, Misses: 1},
+			{Policy: ascache.LRU, Hits: 1, Misses: 1, Active: true},
+		},
+	}}
+	cacheName := attribute.String("cache", "sessions")
+	registration, err := RegisterMetrics(meter, cache, Options{Attributes: []attribute.KeyValue{cacheName}})
+	require.NoError(t, err)
+
+	got := collect(t, reader)
+
+	switches := got["ascache.switches"].(metricdata.Sum[int64])
+	require.Len(t, switches.DataPoints, 1)
+	assert.True(t, switches.IsMonotonic)
+	assert.Equal(t, int64(3), switches.DataPoints[0].Value)
+	assert.Equal(t, attribute.NewSet(cacheName), switches.DataPoints[0].Attributes)
+
+	assert.Equal(t, 0.875, got["ascache.confidence"].(metricdata.Gauge[float64]).DataPoints[0].Value)
+	assert.Equal(t, int64(8), got["ascache.damping.cooldown"].(metricdata.Gauge[int64]).DataPoints[0].Value)
+	adjustments := map[string]int64{}
+	for _, point := range got["ascache.damping.adjustments"].(metricdata.Sum[int64]).DataPoints {
+		direction, ok := point.Attributes.Value(DirectionKey)
+		require.True(t, ok)
+		adjustments[direction.AsString()] = point.Value
+	}
+	assert.Equal(t, map[string]int64{"raised": 3, "relaxed": 1}, adjustments)
+
+	rates := map[string]float64{}
+	for _, point := range got["ascache.policy.hit_rate"].(metricdata.Gauge[float64]).DataPoints {
+		policy, ok := point.Attributes.Value(PolicyKey)
+		require.True(t, ok)
+		rates[policy.AsString()] = point.Value
+	}
+	assert.Equal(t, map[string]float64{"TinyLFU": 0.75, "LRU": 0.5}, rates)
+
+	bounds := map[string]float64{}
+	for _, point := range got["ascache.policy.hit_rate_bound"].(metricdata.Gauge[float64]).DataPoints {
+		policy, _ := point.Attributes.Value(PolicyKey)
+		bound, ok := point.Attributes.Value(BoundKey)
+		require.True(t, ok)
+		bounds[policy.AsString()+"/"+bound.AsString()] = point.Value
+	}
+	require.Len(t, bounds, 4)
+	assert.Less(t, bounds["LRU/lower"], 0.5)
+	assert.Greater(t, bounds["LRU/upper"], 0.5)
+
+	for _, point := range got["ascache.policy.active"].(metricdata.Gauge[int64]).DataPoints {
+		policy, _ := point.Attributes.Value(PolicyKey)
+		assert.Equal(t, policy.AsString() == "LRU", point.Value == 1, policy.AsString())
+	}
+
+	require.NoError(t, registration.Unregister())
+	assert.Empty(t, collect(t, reader), "an unregistered cache is no longer read")
+}
+
+func TestTracer_RecordsEachSwitchAsASpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test"), Options{})
+
+	lru, err := policies.NewLRU[string, int](100)
+	require.NoError(t, err)
+	lfu, err := policies.NewLFU[string, int](100)
+	require.NoError(t, err)
+
+	cache, err := ascache.NewAdaptiveCache(
+		[]ascache.Policy[string, int]{lru, lfu}, alwaysBandit{policy: ascache.LFU},
+		&ascache.Settings{
+			EpochRequests:               10,
+			EvictPartialCapacityFilling: true,
+			MigrationStrategy:           ascache.MigrationWarm,
+			OnSwitch:                    tracer.OnSwitch,
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cache.Close() })
+
+	for i := range 4 {
+		cache.Add("key-"+strconv.Itoa(i), i)
+	}
+	for i := range 10 {
+		cache.Get("key-" + strconv.Itoa(i))
+	}
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1, "one switch, one span, and none for the Gets")
+
+	span := spans[0]
+	assert.Equal(t, "ascache.switch", span.Name())
+	assert.False(t, span.Parent().IsValid(), "a switch belongs to no request")
+	assert.False(t, span.EndTime().Before(span.StartTime()))
+
+	attrs := attribute.NewSet(span.Attributes()...)
+	for key, want := range map[attribute.Key]attribute.Value{
+		EpochKey:    attribute.Int64Value(0),
+		FromKey:     attribute.StringValue("LRU"),
+		ToKey:       attribute.StringValue("LFU"),
+		ReasonKey:   attribute.StringValue("bandit"),
+		StrategyKey: attribute.StringValue("warm"),
+		KeysKey:     attribute.IntValue(4),
+	} {
+		got, ok := attrs.Value(key)
+		require.True(t, ok, key)
+		assert.Equal(t, want, got, key)
+	}
+}
+
+func TestTracer_PlacesTheSpanAtTheSwitch(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test"),
+		Options{Namespace: "svc"})
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tracer.OnSwitch(ascache.SwitchEvent{From: ascache.LRU, To: ascache.LFU, Start: start, Duration: time.Millisecond})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "svc.switch", spans[0].Name())
+	assert.Equal(t, start, spans[0].StartTime())
+	assert.Equal(t, start.Add(time.Millisecond), spans[0].EndTime())
+}
@@ -0,0 +1,289 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sshaplygin/as-cache/bandit"
+	"github.com/sshaplygin/as-cache/metrics"
+)
+
+// DefaultNamespace prefixes every metric name when Options.Namespace is empty.
+const DefaultNamespace = "ascache"
+
+// Options configures how a collector names and labels what it exports.
+type Options struct {
+	// Namespace is prepended to every metric name. Defaults to
+	// DefaultNamespace.
+	Namespace string
+
+	// ConstLabels are attached to every series the collector exports. Two
+	// caches registered with one registry export the same names, so they must
+	// differ here - typically {"cache": "sessions"} and {"cache": "users"}.
+	ConstLabels prometheus.Labels
+}
+
+func (o Options) namespace() string {
+	if o.Namespace == "" {
+		return DefaultNamespace
+	}
+
+	return o.Namespace
+}
+
+// desc builds one metric's description under the options' namespace and
+// constant labels.
+func (o Options) desc(subsystem, name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(o.namespace(), subsystem, name),
+		help, labels, o.ConstLabels,
+	)
+}
+
+// CacheCollector exports a cache's metrics.Snapshot.
+type CacheCollector struct {
+	cache metrics.Advisor
+
+	activePolicy *prometheus.Desc
+	bestPolicy   *prometheus.Desc
+	improvement  *prometheus.Desc
+	confidence   *prometheus.Desc
+	policyRate   *prometheus.Desc
+	policyBound  *prometheus.Desc
+	servedTime   *prometheus.Desc
+	servedHits   *prometheus.Desc
+	servedMisses *prometheus.Desc
+	epochs       *prometheus.Desc
+	switches     *prometheus.Desc
+	damping      *prometheus.Desc
+	cooldown     *prometheus.Desc
+	adjustments  *prometheus.Desc
+	pinned       *prometheus.Desc
+	hits         *prometheus.Desc
+	misses       *prometheus.Desc
+	entries      *prometheus.Desc
+	sampleRate   *prometheus.Desc
+}
+
+// NewCacheCollector returns a collector for cache. Every AdaptiveCache
+// satisfies metrics.Advisor, whatever its key and value types.
+func NewCacheCollector(cache metrics.Advisor, opts Options) *CacheCollector {
+	return &CacheCollector{
+		cache: cache,
+		activePolicy: opts.desc("", "active_policy_info",
+			"The policy serving requests, as a label on a constant 1.", "policy"),
+		bestPolicy: opts.desc("", "best_policy_info",
+			"The policy with the best measured hit rate, as a label on a constant 1.", "policy"),
+		improvement: opts.desc("", "improvement_ratio",
+			"Hit rate by which the best policy beats the active one."),
+		confidence: opts.desc("", "improvement_confidence",
+			"Probability that the best policy truly beats the active one."),
+		policyRate: opts.desc("", "policy_hit_rate",
+			"Each policy's hit rate, measured since it last changed role.", "policy"),
+		policyBound: opts.desc("", "policy_hit_rate_bound",
+			"Bounds of the 95% interval on each policy's true hit rate.", "policy", "bound"),
+		servedTime: opts.desc("", "policy_served_seconds_total",
+			"Time each policy has spent as the active policy.", "policy"),
+		servedHits: opts.desc("", "policy_served_hits_total",
+			"Requests each policy served from the cache while active.", "policy"),
+		servedMisses: opts.desc("", "policy_served_misses_total",
+			"Requests each policy missed while active.", "policy"),
+		epochs: opts.desc("", "epochs_total",
+			"Epochs that measured something."),
+		switches: opts.desc("", "switches_total",
+			"Changes of active policy."),
+		damping: opts.desc("", "damping_level",
+			"How many times oscillation damping has doubled the switch cooldown."),
+		cooldown: opts.desc("", "switch_cooldown_epochs",
+			"The switch cooldown in force, damping included."),
+		adjustments: opts.desc("", "damping_adjustments_total",
+			"Changes of damping level, by direction.", "direction"),
+		pinned: opts.desc("", "pinned",
+			"1 while the cache is pinned to one policy and not consulting the bandit."),
+		hits: opts.desc("", "hits_total",
+			"Requests served from the cache."),
+		misses: opts.desc("", "misses_total",
+			"Requests the cache missed."),
+		entries: opts.desc("", "entries",
+			"Entries held by the active policy."),
+		sampleRate: opts.desc("", "sample_rate",
+			"Fraction of the keyspace the shadow policies measure."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *CacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.activePolicy
+	ch <- c.bestPolicy
+	ch <- c.improvement
+	ch <- c.confidence
+	ch <- c.policyRate
+	ch <- c.policyBound
+	ch <- c.servedTime
+	ch <- c.servedHits
+	ch <- c.servedMisses
+	ch <- c.epochs
+	ch <- c.switches
+	ch <- c.damping
+	ch <- c.cooldown
+	ch <- c.adjustments
+	ch <- c.pinned
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.entries
+	ch <- c.sampleRate
+}
+
+// Collect implements prometheus.Collector.
+func (c *CacheCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := metrics.Take(c.cache)
+
+	ch <- prometheus.MustNewConstMetric(c.activePolicy, prometheus.GaugeValue, 1, snapshot.ActivePolicy)
+	ch <- prometheus.MustNewConstMetric(c.bestPolicy, prometheus.GaugeValue, 1, snapshot.BestPolicy)
+	ch <- prometheus.MustNewConstMetric(c.improvement, prometheus.GaugeValue, snapshot.Improvement)
+	ch <- prometheus.MustNewConstMetric(c.confidence, prometheus.GaugeValue, snapshot.Confidence)
+	for _, policy := range snapshot.Policies {
+		ch <- prometheus.MustNewConstMetric(c.policyRate, prometheus.GaugeValue, policy.HitRate, policy.Policy)
+		ch <- prometheus.MustNewConstMetric(c.policyBound, prometheus.GaugeValue, policy.HitRateLow, policy.Policy, "lower")
+		ch <- prometheus.MustNewConstMetric(c.policyBound, prometheus.GaugeValue, policy.HitRateHigh, policy.Policy, "upper")
+		ch <- prometheus.MustNewConstMetric(c.servedTime, prometheus.CounterValue, policy.ServedSeconds, policy.Policy)
+		ch <- prometheus.MustNewConstMetric(c.servedHits, prometheus.CounterValue, float64(policy.ServedHits), policy.Policy)
+		ch <- prometheus.MustNewConstMetric(c.servedMisses, prometheus.CounterValue, float64(policy.ServedMisses), policy.Policy)
+	}
+	ch <- prometheus.MustNewConstMetric(c.epochs, prometheus.CounterValue, float64(snapshot.Epochs))
+	ch <- prometheus.MustNewConstMetric(c.switches, prometheus.CounterValue, float64(snapshot.Switches))
+	ch <- prometheus.MustNewConstMetric(c.damping, prometheus.GaugeValue, float64(snapshot.DampingLevel))
+	ch <- prometheus.MustNewConstMetric(c.cooldown, prometheus.GaugeValue, float64(snapshot.CooldownEpochs))
+	ch <- prometheus.MustNewConstMetric(c.adjustments, prometheus.CounterValue, float64(snapshot.DampingRaised), "raised")
+	ch <- prometheus.MustNewConstMetric(c.adjustments, prometheus.CounterValue, float64(snapshot.DampingRelaxed), "relaxed")
+	ch <- prometheus.MustNewConstMetric(c.pinned, prometheus.GaugeValue, flag(snapshot.Pinned))
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(snapshot.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(snapshot.Misses))
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(snapshot.Entries))
+	ch <- prometheus.MustNewConstMetric(c.sampleRate, prometheus.GaugeValue, snapshot.SampleRate)
+}
+
+// DistributedCollector exports a distributed bandit's bandit.Snapshot.
+type DistributedCollector struct {
+	snapshot func() bandit.Snapshot
+
+	info         *prometheus.Desc
+	selection    *prometheus.Desc
+	fallback     *prometheus.Desc
+	subscribed   *prometheus.Desc
+	lastSyncAge  *prometheus.Desc
+	fleetRate    *prometheus.Desc
+	syncs        *prometheus.Desc
+	syncFailures *prometheus.Desc
+	leaderships  *prometheus.Desc
+	decisions    *prometheus.Desc
+	rejected     *prometheus.Desc
+	pushed       *prometheus.Desc
+	promotions   *prometheus.Desc
+	rollbacks    *prometheus.Desc
+}
+
+// NewDistributedCollector returns a collector for a distributed bandit. It
+// complements a CacheCollector for the cache the bandit drives rather than
+// replacing it: the cache reports what it measured and what it is running,
+// the bandit whether it is hearing from the fleet.
+func NewDistributedCollector(b *bandit.Distributed, opts Options) *DistributedCollector {
+	return newDistributedCollector(b.Snapshot, opts)
+}
+
+func newDistributedCollector(snapshot func() bandit.Snapshot, opts Options) *DistributedCollector {
+	const subsystem = "bandit"
+
+	return &DistributedCollector{
+		snapshot: snapshot,
+		info: opts.desc(subsystem, "info",
+			"How this replica coordinates, as labels on a constant 1.",
+			"mode", "aggregation", "node", "namespace"),
+		selection: opts.desc(subsystem, "selection_info",
+			"The policy the bandit is returning, as a label on a constant 1.", "policy"),
+		fallback: opts.desc(subsystem, "fallback",
+			"1 while the store is unreachable and this replica selects locally."),
+		subscribed: opts.desc(subsystem, "subscribed",
+			"1 while this replica receives decisions pushed by the leader."),
+		lastSyncAge: opts.desc(subsystem, "last_sync_age_seconds",
+			"Time since the last successful sync."),
+		fleetRate: opts.desc(subsystem, "fleet_hit_rate",
+			"Each policy's pooled hit rate, as the last fleet decision read it.", "policy"),
+		syncs: opts.desc(subsystem, "syncs_total",
+			"Successful round trips to the store."),
+		syncFailures: opts.desc(subsystem, "sync_failures_total",
+			"Failed round trips to the store."),
+		leaderships: opts.desc(subsystem, "leaderships_total",
+			"Coordination buckets this replica led."),
+		decisions: opts.desc(subsystem, "decisions_total",
+			"Fleet decisions this replica applied."),
+		rejected: opts.desc(subsystem, "rejected_decisions_total",
+			"Fleet decisions refused for naming a policy this cache does not have."),
+		pushed: opts.desc(subsystem, "pushed_decisions_total",
+			"Fleet decisions applied from a push rather than a sync."),
+		promotions: opts.desc(subsystem, "promotions_total",
+			"Canaried decisions promoted to the whole fleet."),
+		rollbacks: opts.desc(subsystem, "rollbacks_total",
+			"Canaried decisions rolled back."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *DistributedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info
+	ch <- c.selection
+	ch <- c.fallback
+	ch <- c.subscribed
+	ch <- c.lastSyncAge
+	ch <- c.fleetRate
+	ch <- c.syncs
+	ch <- c.syncFailures
+	ch <- c.leaderships
+	ch <- c.decisions
+	ch <- c.rejected
+	ch <- c.pushed
+	ch <- c.promotions
+	ch <- c.rollbacks
+}
+
+// Collect implements prometheus.Collector.
+func (c *DistributedCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.snapshot()
+
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, s.Mode, s.Aggregation, s.NodeID, s.Namespace)
+	ch <- prometheus.MustNewConstMetric(c.selection, prometheus.GaugeValue, 1, s.Selection)
+	ch <- prometheus.MustNewConstMetric(c.fallback, prometheus.GaugeValue, flag(s.Fallback))
+	ch <- prometheus.MustNewConstMetric(c.subscribed, prometheus.GaugeValue, flag(s.Subscribed))
+	if s.Syncs > 0 {
+		// Before the first sync there is no age to report, and a zero would
+		// read as perfectly fresh.
+		ch <- prometheus.MustNewConstMetric(c.lastSyncAge, prometheus.GaugeValue, s.LastSyncAge.Seconds())
+	}
+	for _, arm := range s.Fleet {
+		ch <- prometheus.MustNewConstMetric(c.fleetRate, prometheus.GaugeValue, arm.HitRate, arm.Policy)
+	}
+
+	counters := []struct {
+		desc  *prometheus.Desc
+		value int64
+	}{
+		{c.syncs, s.Syncs},
+		{c.syncFailures, s.SyncFailures},
+		{c.leaderships, s.Leaderships},
+		{c.decisions, s.Decisions},
+		{c.rejected, s.Rejected},
+		{c.pushed, s.Pushed},
+		{c.promotions, s.Promotions},
+		{c.rollbacks, s.Rollbacks},
+	}
+	for _, counter := range counters {
+		ch <- prometheus.MustNewConstMetric(counter.desc, prometheus.CounterValue, float64(counter.value))
+	}
+}
+
+func flag(set bool) float64 {
+	if set {
+		return 1
+	}
+
+	return 0
+}
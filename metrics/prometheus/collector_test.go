@@ -0,0 +1,231 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/bandit"
+)
+
+// fixedAdvisor is a cache frozen at one set of measurements, so the exported
+// values can be compared exactly.
+type fixedAdvisor struct {
+	advice  ascache.Advice
+	stats   ascache.GlobalStats
+	history ascache.History
+	len     int
+}
+
+func (a fixedAdvisor) Advice() ascache.Advice           { return a.advice }
+func (a fixedAdvisor) Stats() ascache.GlobalStats       { return a.stats }
+func (a fixedAdvisor) ActivePolicy() ascache.PolicyType { return a.advice.Active }
+func (a fixedAdvisor) Len() int                         { return a.len }
+func (a fixedAdvisor) History() ascache.History         { return a.history }
+
+func newAdvisor() fixedAdvisor {
+	return fixedAdvisor{
+		advice: ascache.Advice{
+			Epochs:      12,
+			Active:      ascache.LRU,
+			Switches:    3,
+			Damping:     ascache.DampingStatus{Level: 1, CooldownEpochs: 4, Raised: 2, Relaxed: 1},
+			Pin:         ascache.PinStatus{Policy: ascache.LRU},
+			Best:        ascache.TinyLFU,
+			Improvement: 0.25,
+			Confidence:  0.875,
+			SampleRate:  1,
+			Reports: []ascache.PolicyReport{
+				{Policy: ascache.TinyLFU, Hits: 3, Misses: 1},
+				{Policy: ascache.LRU, Hits: 1, Misses: 1, Active: true},
+			},
+		},
+		stats: ascache.GlobalStats{Hits: 40, Misses: 60},
+		history: ascache.History{Policies: []ascache.PolicyTenure{
+			{Policy: ascache.LRU, Served: 90 * time.Second, Hits: 30, Misses: 50},
+			{Policy: ascache.TinyLFU, Served: 30 * time.Second, Hits: 10, Misses: 10},
+		}},
+		len: 7,
+	}
+}
+
+func TestCacheCollector_ExportsTheSnapshot(t *testing.T) {
+	collector := NewCacheCollector(newAdvisor(), Options{})
+
+	expected := `
+# HELP ascache_active_policy_info The policy serving requests, as a label on a constant 1.
+# TYPE ascache_active_policy_info gauge
+ascache_active_policy_info{policy="LRU"} 1
+# HELP ascache_best_policy_info The policy with the best measured hit rate, as a label on a constant 1.
+# TYPE ascache_best_policy_info gauge
+ascache_best_policy_info{policy="TinyLFU"} 1
+# HELP ascache_damping_adjustments_total Changes of damping level, by direction.
+# TYPE ascache_damping_adjustments_total counter
+ascache_damping_adjustments_total{direction="raised"} 2
+ascache_damping_adjustments_total{direction="relaxed"} 1
+# HELP ascache_damping_level How many times oscillation damping has doubled the switch cooldown.
+# TYPE ascache_damping_level gauge
+ascache_damping_level 1
+# HELP ascache_entries Entries held by the active policy.
+# TYPE ascache_entries gauge
+ascache_entries 7
+# HELP ascache_epochs_total Epochs that measured something.
+# TYPE ascache_epochs_total counter
+ascache_epochs_total 12
+# HELP ascache_hits_total Requests served from the cache.
+# TYPE ascache_hits_total counter
+ascache_hits_total 40
+# HELP ascache_improvement_confidence Probability that the best policy truly beats the active one.
+# TYPE ascache_improvement_confidence gauge
+ascache_improvement_confidence 0.875
+# HELP ascache_improvement_ratio Hit rate by which the best policy beats the active one.
+# TYPE ascache_improvement_ratio gauge
+ascache_improvement_ratio 0.25
+# HELP ascache_misses_total Requests the cache missed.
+# TYPE ascache_misses_total counter
+ascache_misses_total 60
+# HELP ascache_pinned 1 while the cache is pinned to one policy and not consulting the bandit.
+# TYPE ascache_pinned gauge
+ascache_pinned 1
+# HELP ascache_policy_hit_rate Each policy's hit rate, measured since it last changed role.
+# TYPE ascache_policy_hit_rate gauge
+ascache_policy_hit_rate{policy="LRU"} 0.5
+ascache_policy_hit_rate{policy="TinyLFU"} 0.75
+# HELP ascache_policy_hit_rate_bound Bounds of the 95% interval on each policy's true hit rate.
+# TYPE ascache_policy_hit_rate_bound gauge
+ascache_policy_hit_rate_bound{bound="lower",policy="LRU"} 0.09453120573423074
+ascache_policy_hit_rate_bound{bound="lower",policy="TinyLFU"} 0.30064184258240184
+ascache_policy_hit_rate_bound{bound="upper",policy="LRU"} 0.9054687942657693
+ascache_policy_hit_rate_bound{bound="upper",policy="TinyLFU"} 0.9544127391902995
+# HELP ascache_policy_served_hits_total Requests each policy served from the cache while active.
+# TYPE ascache_policy_served_hits_total counter
+ascache_policy_served_hits_total{policy="LRU"} 30
+ascache_policy_served_hits_total{policy="TinyLFU"} 10
+# HELP ascache_policy_served_misses_total Requests each policy missed while active.
+# TYPE ascache_policy_served_misses_total counter
+ascache_policy_served_misses_total{policy="LRU"} 50
+ascache_policy_served_misses_total{policy="TinyLFU"} 10
+# HELP ascache_policy_served_seconds_total Time each policy has spent as the active policy.
+# TYPE ascache_policy_served_seconds_total counter
+ascache_policy_served_seconds_total{policy="LRU"} 90
+ascache_policy_served_seconds_total{policy="TinyLFU"} 30
+# HELP ascache_sample_rate Fraction of the keyspace the shadow policies measure.
+# TYPE ascache_sample_rate gauge
+ascache_sample_rate 1
+# HELP ascache_switch_cooldown_epochs The switch cooldown in force, damping included.
+# TYPE ascache_switch_cooldown_epochs gauge
+ascache_switch_cooldown_epochs 4
+# HELP ascache_switches_total Changes of active policy.
+# TYPE ascache_switches_total counter
+ascache_switches_total 3
+`
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	problems, err := testutil.CollectAndLint(collector)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+// TestCacheCollector_TellsCachesApartByConstLabels guards the case the option
+// exists for: two caches in one registry export the same names, and only the
+// constant labels keep them from colliding.
+func TestCacheCollector_TellsCachesApartByConstLabels(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	for _, name := range []string{"sessions", "users"} {
+		require.NoError(t, registry.Register(NewCacheCollector(newAdvisor(), Options{
+			Namespace:   "svc",
+			ConstLabels: prometheus.Labels{"cache": name},
+		})))
+	}
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		assert.True(t, strings.HasPrefix(family.GetName(), "svc_"), family.GetName())
+	}
+	assert.Equal(t, 2, testutil.CollectAndCount(registry, "svc_switches_total"))
+
+	err = registry.Register(NewCacheCollector(newAdvisor(), Options{
+		Namespace:   "svc",
+		ConstLabels: prometheus.Labels{"cache": "users"},
+	}))
+	assert.Error(t, err, "a second collector with the same labels would export duplicate series")
+}
+
+func TestDistributedCollector_ExportsTheSnapshot(t *testing.T) {
+	collector := newDistributedCollector(func() bandit.Snapshot {
+		return bandit.Snapshot{
+			Selection:    "TinyLFU",
+			Mode:         "leader",
+			Aggregation:  "sum",
+			NodeID:       "node-1",
+			Namespace:    "sessions:abc",
+			Fallback:     true,
+			LastSyncAge:  1500 * time.Millisecond,
+			Syncs:        9,
+			SyncFailures: 2,
+			Leaderships:  4,
+			Decisions:    5,
+			Rejected:     1,
+			Pushed:       2,
+			Promotions:   1,
+			Fleet:        []bandit.ArmEvidence{{Policy: "TinyLFU", HitRate: 0.6}, {Policy: "LRU", HitRate: 0.4}},
+		}
+	}, Options{})
+
+	expected := `
+# HELP ascache_bandit_fallback 1 while the store is unreachable and this replica selects locally.
+# TYPE ascache_bandit_fallback gauge
+ascache_bandit_fallback 1
+# HELP ascache_bandit_fleet_hit_rate Each policy's pooled hit rate, as the last fleet decision read it.
+# TYPE ascache_bandit_fleet_hit_rate gauge
+ascache_bandit_fleet_hit_rate{policy="LRU"} 0.4
+ascache_bandit_fleet_hit_rate{policy="TinyLFU"} 0.6
+# HELP ascache_bandit_info How this replica coordinates, as labels on a constant 1.
+# TYPE ascache_bandit_info gauge
+ascache_bandit_info{aggregation="sum",mode="leader",namespace="sessions:abc",node="node-1"} 1
+# HELP ascache_bandit_last_sync_age_seconds Time since the last successful sync.
+# TYPE ascache_bandit_last_sync_age_seconds gauge
+ascache_bandit_last_sync_age_seconds 1.5
+# HELP ascache_bandit_rollbacks_total Canaried decisions rolled back.
+# TYPE ascache_bandit_rollbacks_total counter
+ascache_bandit_rollbacks_total 0
+# HELP ascache_bandit_selection_info The policy the bandit is returning, as a label on a constant 1.
+# TYPE ascache_bandit_selection_info gauge
+ascache_bandit_selection_info{policy="TinyLFU"} 1
+# HELP ascache_bandit_sync_failures_total Failed round trips to the store.
+# TYPE ascache_bandit_sync_failures_total counter
+ascache_bandit_sync_failures_total 2
+`
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"ascache_bandit_fallback", "ascache_bandit_fleet_hit_rate", "ascache_bandit_info",
+		"ascache_bandit_last_sync_age_seconds", "ascache_bandit_rollbacks_total",
+		"ascache_bandit_selection_info", "ascache_bandit_sync_failures_total"))
+	assert.Equal(t, 15, testutil.CollectAndCount(collector), "one series per metric, and one per pooled arm")
+}
+
+func TestDistributedCollector_ReportsNoAgeBeforeTheFirstSync(t *testing.T) {
+	b, err := bandit.NewDistributed(bandit.Config{
+		Store:             bandit.NewMemStore(),
+		Namespace:         "sessions",
+		CoordinationEpoch: time.Hour,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = b.Close() })
+
+	collector := NewDistributedCollector(b, Options{})
+
+	assert.Zero(t, testutil.CollectAndCount(collector, "ascache_bandit_last_sync_age_seconds"),
+		"a zero age would read as a replica that has just synced")
+
+	problems, err := testutil.CollectAndLint(collector)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
@@ -0,0 +1,77 @@
+// Package prometheus exports an AdaptiveCache, and the distributed bandit
+// behind a fleet of them, as Prometheus metrics.
+//
+// The metrics package deliberately depends on nothing outside the standard
+// library, so this collector lives in a module of its own: an application that
+// exports only through expvar never pulls in the Prometheus client.
+//
+//	registry.MustRegister(prometheus.NewCacheCollector(cache, prometheus.Options{
+//	    ConstLabels: map[string]string{"cache": "sessions"},
+//	}))
+//	registry.MustRegister(prometheus.NewDistributedCollector(b, prometheus.Options{
+//	    ConstLabels: map[string]string{"cache": "sessions"},
+//	}))
+//
+// Both collectors read a snapshot when scraped and keep no state of their own,
+// so a registered collector costs nothing between scrapes, and two scrapes in
+// a row take two snapshots rather than one stale one.
+//
+// # Metric names
+//
+// The names below are stable: a release that changes one is a breaking
+// change. Each is prefixed with [Options.Namespace], "ascache" unless set.
+// Every series carries [Options.ConstLabels] as well; a process registering
+// more than one cache in one registry must tell them apart there.
+//
+// [CacheCollector], for any [metrics.Advisor]:
+//
+//	ascache_active_policy_info{policy}           gauge    1 for the policy serving requests
+//	ascache_best_policy_info{policy}             gauge    1 for the best-measured policy
+//	ascache_improvement_ratio                    gauge    hit rate the best policy beats the active one by
+//	ascache_improvement_confidence               gauge    probability the best policy truly beats the active one
+//	ascache_policy_hit_rate{policy}              gauge    each arm's measured hit rate
+//	ascache_policy_hit_rate_bound{policy,bound}  gauge    lower and upper ends of its 95% interval
+//	ascache_policy_served_seconds_total{policy}  counter  time each arm has spent active
+//	ascache_policy_served_hits_total{policy}     counter  requests each arm served while active
+//	ascache_policy_served_misses_total{policy}   counter  requests each arm missed while active
+//	ascache_epochs_total                         counter  epochs that measured something
+//	ascache_switches_total                       counter  changes of active policy
+//	ascache_damping_level                        gauge    times damping has doubled the switch cooldown
+//	ascache_switch_cooldown_epochs               gauge    the switch cooldown in force, damping included
+//	ascache_damping_adjustments_total{direction} counter  damping raised or relaxed
+//	ascache_pinned                               gauge    1 while pinned to one policy, 0 otherwise
+//	ascache_hits_total                           counter  requests served from the cache
+//	ascache_misses_total                         counter  requests the cache missed
+//	ascache_entries                              gauge    entries the active policy holds
+//	ascache_sample_rate                          gauge    fraction of the keyspace shadows measure
+//
+// The per-policy hit rates are measured since each arm last changed role, and
+// from a sampled substream when sample_rate is below 1, exactly as
+// [ascache.Advice] reports them. hits_total and misses_total are never
+// sampled, and neither are the served totals, which come from
+// [ascache.AdaptiveCache.History] and never restart.
+//
+// A damping level above zero means the cache caught itself flapping between
+// two policies and is holding switches back; see [ascache.Damping].
+//
+// [DistributedCollector], for a [*bandit.Distributed]:
+//
+//	ascache_bandit_info{mode,aggregation,node,namespace}  gauge    always 1
+//	ascache_bandit_selection_info{policy}                 gauge    1 for the policy the bandit returns
+//	ascache_bandit_fallback                               gauge    1 while selecting locally
+//	ascache_bandit_subscribed                             gauge    1 while receiving pushed decisions
+//	ascache_bandit_last_sync_age_seconds                  gauge    time since the last successful sync, absent before one
+//	ascache_bandit_fleet_hit_rate{policy}                 gauge    the pooled rate the last decision used
+//	ascache_bandit_syncs_total                            counter  successful syncs
+//	ascache_bandit_sync_failures_total                    counter  failed syncs
+//	ascache_bandit_leaderships_total                      counter  buckets this replica led
+//	ascache_bandit_decisions_total                        counter  fleet decisions applied
+//	ascache_bandit_rejected_decisions_total               counter  decisions refused for an unknown policy
+//	ascache_bandit_pushed_decisions_total                 counter  decisions applied from a push
+//	ascache_bandit_promotions_total                       counter  canaries promoted
+//	ascache_bandit_rollbacks_total                        counter  canaries rolled back
+//
+// ascache_bandit_fallback is the one to alert on. A replica that has fallen
+// back keeps serving, deciding locally, and nothing else it exports looks any
+// different.
+package prometheus
@@ -0,0 +1,36 @@
+module github.com/sshaplygin/as-cache/metrics/prometheus
+
+go 1.25.2
+
+require (
+	github.com/prometheus/client_golang v1.24.1
+	github.com/sshaplygin/as-cache v0.3.1
+	github.com/sshaplygin/as-cache/bandit v0.3.1
+	github.com/sshaplygin/as-cache/metrics v0.3.1
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/prometheus/client_model v0.6.2 // indirect
+	github.com/prometheus/common v0.70.1 // indirect
+	github.com/prometheus/procfs v0.21.1 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/sshaplygin/as-cache => ../..
+
+replace github.com/sshaplygin/as-cache/bandit => ../../bandit
+
+replace github.com/sshaplygin/as-cache/metrics => ..
+
+replace github.com/sshaplygin/as-cache/policies => ../../policies
+
+replace github.com/sshaplygin/as-cache/lfu => ../../lfu
@@ -1,7 +1,7 @@
 package ascache
 
 // migrateData transfers key/value pairs from the old active policy to the new
-// one according to the configured MigrationStrategy. It always abandons any
+// one according to strategy. It always abandons any
 // in-progress gradual window first. It must be called while the write lock is
 // held.
 //
@@ -9,11 +9,14 @@ package ascache
 // MigrationWarm: purge stale shadow entries from target, copy all key/value pairs.
 // MigrationGradual: purge stale shadow entries from target, snapshot key list,
 // and open the gradual migration window (unless the source is empty).
-func (c *AdaptiveCache[K, V]) migrateData(from, to PolicyType) {
+//
+// It returns how many keys the migration moves: those copied by a warm
+// migration, or those queued by a gradual one, and none for a cold one.
+func (c *AdaptiveCache[K, V]) migrateData(from, to PolicyType, strategy MigrationStrategy) int {
 	// Abandon any incomplete gradual migration from the previous epoch.
 	c.clearMigrationState()
 
-	switch c.settings.MigrationStrategy {
+	switch strategy {
 	// Cold is the default, so it is the default arm rather than a named case.
 	// Every strategy has to purge the target's zero-value shadow entries, and
 	// a strategy value this switch did not recognise would otherwise fall
@@ -22,7 +25,7 @@ func (c *AdaptiveCache[K, V]) migrateData(from, to PolicyType) {
 	// invariant this library is built on.
 	default:
 		c.policies[to].Purge()
-		return
+		return 0
 
 	case MigrationWarm:
 		fromPolicy := c.policies[from]
@@ -33,14 +36,18 @@ func (c *AdaptiveCache[K, V]) migrateData(from, to PolicyType) {
 		toPolicy.Purge()
 
 		keys := fromPolicy.Keys()
+		copied := 0
 		for _, key := range keys {
 			val, ok := fromPolicy.Peek(key)
 			if !ok {
 				continue
 			}
 			toPolicy.Add(key, val)
+			copied++
 		}
 
+		return copied
+
 	case MigrationGradual:
 		// Remove stale zero-value shadow entries from the new active policy.
 		c.policies[to].Purge()
@@ -49,7 +56,7 @@ func (c *AdaptiveCache[K, V]) migrateData(from, to PolicyType) {
 		if len(keys) == 0 {
 			// Nothing to migrate: opening an empty window would only force
 			// Gets through the write lock until something closed it.
-			return
+			return 0
 		}
 		realKeys := make(map[K]struct{}, len(keys))
 		for _, k := range keys {
@@ -60,6 +67,8 @@ func (c *AdaptiveCache[K, V]) migrateData(from, to PolicyType) {
 		c.migrateFrom = from
 		c.migrationKeys = keys
 		c.migrationRealKeys = realKeys
+
+		return len(keys)
 	}
 }
 
@@ -28,6 +28,14 @@ const (
 	// would displace. It is the strongest general-purpose baseline in wide
 	// use, and the one an adaptive cache has to beat to justify itself.
 	TinyLFU
+	// LRUK evicts the entry whose K-th most recent access is the oldest, so a
+	// key touched once ranks below every key touched K times. It is the
+	// classic database page-cache policy, where LRU-2 tells a one-off table
+	// scan apart from the index pages that are genuinely hot.
+	LRUK
+	// LRFU evicts by a combined recency-frequency score whose decay parameter
+	// interpolates between LFU at one end and LRU at the other.
+	LRFU
 )
 
 // MigrationStrategy controls how key/value pairs are transferred when the
@@ -60,6 +68,20 @@ const (
 	MigrationGradual
 )
 
+// String returns "cold", "warm" or "gradual". The zero value, and any value
+// this package does not define, reports "cold", because that is how the cache
+// migrates under it.
+func (m MigrationStrategy) String() string {
+	switch m {
+	case MigrationWarm:
+		return "warm"
+	case MigrationGradual:
+		return "gradual"
+	default:
+		return "cold"
+	}
+}
+
 // GlobalStats holds aggregate hit/miss statistics exposed to callers.
 type GlobalStats struct {
 	Hits   int64
@@ -0,0 +1,90 @@
+package ascache
+
+import (
+	"context"
+	"log/slog"
+)
+
+// epochOutcome is what an epoch, or a Pin, did that the caller is told about:
+// through Settings.OnSwitch and Settings.Logger. It is gathered under the
+// write lock and reported once the lock is released, so neither hook can
+// stall a Get. The hooks themselves are read under the lock with everything
+// else, because UpdateSettings may replace them the moment it is released.
+type epochOutcome struct {
+	epoch    int64
+	active   PolicyType
+	onSwitch func(SwitchEvent)
+	logger   *slog.Logger
+
+	// switched reports a change of active policy, described by event.
+	switched bool
+	event    SwitchEvent
+
+	// heldBy names the stability gate that held back the bandit's selection
+	// of candidate, and is empty when none did.
+	heldBy    string
+	candidate PolicyType
+
+	// abandoned counts the keys a gradual migration had yet to promote when
+	// the epoch closed its window.
+	abandoned int
+}
+
+// outcomeLocked starts the outcome of the epoch about to run. It must be
+// called while the write lock is held.
+func (c *AdaptiveCache[K, V]) outcomeLocked() epochOutcome {
+	return epochOutcome{
+		epoch:    c.epochID,
+		active:   c.activePolicy,
+		onSwitch: c.settings.OnSwitch,
+		logger:   c.settings.Logger,
+	}
+}
+
+// report tells OnSwitch and the logger what happened. It must be called with
+// no lock held.
+func (o epochOutcome) report() {
+	if o.logger != nil {
+		o.log()
+	}
+	if o.switched && o.onSwitch != nil {
+		o.onSwitch(o.event)
+	}
+}
+
+// log writes the outcome's records. A switch and a migration summary are what
+// an operator reading the log after the fact needs, so they go out at Info;
+// a held selection can recur every epoch for as long as a gate holds, so it
+// goes out at Debug.
+func (o epochOutcome) log() {
+	ctx := context.Background()
+
+	if o.abandoned > 0 {
+		o.logger.LogAttrs(ctx, slog.LevelInfo, "ascache: gradual migration closed with keys unpromoted",
+			slog.Int64("epoch", o.epoch),
+			slog.String("policy", o.active.String()),
+			slog.Int("keys", o.abandoned),
+		)
+	}
+
+	if o.heldBy != "" {
+		o.logger.LogAttrs(ctx, slog.LevelDebug, "ascache: switch held by a stability gate",
+			slog.Int64("epoch", o.epoch),
+			slog.String("from", o.active.String()),
+			slog.String("to", o.candidate.String()),
+			slog.String("gate", o.heldBy),
+		)
+	}
+
+	if o.switched {
+		o.logger.LogAttrs(ctx, slog.LevelInfo, "ascache: switched policy",
+			slog.Int64("epoch", o.event.Epoch),
+			slog.String("from", o.event.From.String()),
+			slog.String("to", o.event.To.String()),
+			slog.String("reason", string(o.event.Reason)),
+			slog.String("migration", o.event.Migration.String()),
+			slog.Int("migration_keys", o.event.MigrationKeys),
+			slog.Duration("migration_duration", o.event.Duration),
+		)
+	}
+}
@@ -0,0 +1,112 @@
+package ascache
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHandler keeps every record it is given, calling onRecord first.
+type recordingHandler struct {
+	mu       sync.Mutex
+	records  []slog.Record
+	onRecord func()
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *recordingHandler) Handle(_ context.Context, record slog.Record) error {
+	if h.onRecord != nil {
+		h.onRecord()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, record)
+
+	return nil
+}
+
+// take returns the records so far, as message, level and attributes, and
+// forgets them.
+func (h *recordingHandler) take() []loggedRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	taken := make([]loggedRecord, 0, len(h.records))
+	for _, record := range h.records {
+		attrs := make(map[string]string)
+		record.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value.String()
+			return true
+		})
+		taken = append(taken, loggedRecord{Message: record.Message, Level: record.Level, Attrs: attrs})
+	}
+	h.records = nil
+
+	return taken
+}
+
+type loggedRecord struct {
+	Message string
+	Level   slog.Level
+	Attrs   map[string]string
+}
+
+func TestLogger_RecordsWhatEachEpochDecided(t *testing.T) {
+	ac, _, _, bandit := makeCache(t, MigrationGradual)
+	handler := &recordingHandler{}
+	ac.settings.Logger = slog.New(handler)
+
+	ac.Add("a", 1)
+	ac.Add("b", 2)
+	bandit.next = LFU
+	ac.runEpoch()
+
+	records := handler.take()
+	require.Len(t, records, 1)
+	assert.Equal(t, "ascache: switched policy", records[0].Message)
+	assert.Equal(t, slog.LevelInfo, records[0].Level)
+	assert.Equal(t, "LRU", records[0].Attrs["from"])
+	assert.Equal(t, "LFU", records[0].Attrs["to"])
+	assert.Equal(t, "bandit", records[0].Attrs["reason"])
+	assert.Equal(t, "gradual", records[0].Attrs["migration"])
+	assert.Equal(t, "2", records[0].Attrs["migration_keys"])
+
+	ac.settings.SwitchCooldownEpochs = 5
+	bandit.next = LRU
+	ac.runEpoch()
+
+	records = handler.take()
+	require.Len(t, records, 2)
+	assert.Equal(t, "ascache: gradual migration closed with keys unpromoted", records[0].Message)
+	assert.Equal(t, "2", records[0].Attrs["keys"], "nothing touched the keys queued")
+	assert.Equal(t, "ascache: switch held by a stability gate", records[1].Message)
+	assert.Equal(t, slog.LevelDebug, records[1].Level)
+	assert.Equal(t, "LRU", records[1].Attrs["to"])
+	assert.Equal(t, GateSwitchCooldown, records[1].Attrs["gate"])
+
+	require.NoError(t, ac.Pin(LRU, MigrationCold))
+	records = handler.take()
+	require.Len(t, records, 1)
+	assert.Equal(t, "pin", records[0].Attrs["reason"])
+}
+
+func TestLogger_IsWrittenToOutsideTheLock(t *testing.T) {
+	ac, _, _, bandit := makeCache(t, MigrationCold)
+	// A handler that reads the cache would deadlock on a record written under
+	// the write lock.
+	handler := &recordingHandler{onRecord: func() { ac.ActivePolicy() }}
+	ac.settings.Logger = slog.New(handler)
+
+	bandit.next = LFU
+	ac.runEpoch()
+
+	assert.Len(t, handler.take(), 1)
+}
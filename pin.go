@@ -0,0 +1,106 @@
+package ascache
+
+import "fmt"
+
+// PinStatus is the pin in force, as Advice reports it.
+type PinStatus struct {
+	// Policy is the pinned policy, or Undefined when nothing is pinned.
+	Policy PolicyType
+	// Fleet reports whether the pin came from the bandit - a pin set for
+	// every cache sharing its state - rather than from Pin on this cache.
+	Fleet bool
+}
+
+// Pinned reports whether a pin is in force.
+func (s PinStatus) Pinned() bool { return s.Policy != Undefined }
+
+// Pin makes policy the active policy now, migrating to it with migration, and
+// keeps it active until Unpin. It is the operator's override: for an incident,
+// or to hold a cache still while something else is measured.
+//
+// A pinned cache keeps measuring every arm and reporting them to the bandit,
+// so Advice stays current and the bandit has learned the traffic by the time
+// the pin is lifted; it just stops acting on what the bandit selects. No
+// stability gate applies to the switch Pin makes, which is recorded in History
+// with ReasonPin, and it is not counted towards damping: an operator changing
+// their mind is not the cache flapping.
+//
+// Pin acts in ObserveOnly mode as well, which only stops the bandit acting.
+// Pinning the policy already active switches nothing and only holds it there.
+// It returns ErrUnknownPolicy when the cache was not built with policy.
+func (c *AdaptiveCache[K, V]) Pin(policy PolicyType, migration MigrationStrategy) error {
+	outcome, err := c.pinLocked(policy, migration)
+	if err != nil {
+		return err
+	}
+	outcome.report()
+
+	return nil
+}
+
+// pinLocked sets the pin and makes its switch, returning it as epochLocked
+// does.
+func (c *AdaptiveCache[K, V]) pinLocked(policy PolicyType, migration MigrationStrategy) (epochOutcome, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if policy == Undefined || !c.hasPolicy(policy) {
+		return epochOutcome{}, fmt.Errorf("%w: %s", ErrUnknownPolicy, policy)
+	}
+
+	c.pin = policy
+	outcome := c.outcomeLocked()
+	if policy != c.activePolicy {
+		outcome.event, outcome.switched = c.switchToLocked(policy, migration, ReasonPin), true
+	}
+
+	return outcome, nil
+}
+
+// Unpin lifts a pin set with Pin. Nothing switches until the next epoch
+// boundary, when the bandit's selection is acted on again - through every
+// stability gate, and measured from the evidence gathered while pinned. A
+// fleet pin the bandit reports is unaffected, and is only lifted where it was
+// set. Unpin on a cache that is not pinned does nothing.
+func (c *AdaptiveCache[K, V]) Unpin() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pin = Undefined
+}
+
+// refreshFleetPinLocked asks the bandit for the fleet pin, if it can report
+// one. It must be called while the write lock is held.
+func (c *AdaptiveCache[K, V]) refreshFleetPinLocked() {
+	if c.pinningBandit == nil {
+		return
+	}
+
+	// A pin naming an arm this cache does not hold is no pin here: holding
+	// still on the wrong policy would be worse than letting the bandit
+	// choose.
+	c.fleetPin = Undefined
+	if policy, ok := c.pinningBandit.Pinned(); ok && c.hasPolicy(policy) {
+		c.fleetPin = policy
+	}
+}
+
+// pinnedLocked returns the policy the cache is pinned to, Pin's before the
+// fleet's, or Undefined. It must be called while at least the read lock is
+// held.
+func (c *AdaptiveCache[K, V]) pinnedLocked() PolicyType {
+	if c.pin != Undefined {
+		return c.pin
+	}
+
+	return c.fleetPin
+}
+
+// pinStatusLocked returns the pin in force for Advice. It must be called while
+// at least the read lock is held.
+func (c *AdaptiveCache[K, V]) pinStatusLocked() PinStatus {
+	return PinStatus{
+		Policy: c.pinnedLocked(),
+		Fleet:  c.pin == Undefined && c.fleetPin != Undefined,
+	}
+}
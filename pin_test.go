@@ -0,0 +1,156 @@
+package ascache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pinningBandit is a mockBandit that also reports a fleet pin.
+type pinningBandit struct {
+	mockBandit
+	pinned PolicyType
+}
+
+func (b *pinningBandit) Pinned() (PolicyType, bool) { return b.pinned, b.pinned != Undefined }
+
+func TestPin_SwitchesAtOnceAndHoldsAgainstTheBandit(t *testing.T) {
+	ac, _, lfu, bandit := makeCache(t, MigrationCold)
+
+	var events []SwitchEvent
+	ac.settings.OnSwitch = func(event SwitchEvent) { events = append(events, event) }
+
+	ac.Add("a", 1)
+	ac.Add("b", 2)
+	require.NoError(t, ac.Pin(LFU, MigrationWarm))
+
+	assert.Equal(t, LFU, ac.ActivePolicy(), "a pin does not wait for the epoch")
+	assert.Equal(t, 2, lfu.Len(), "the pin's own strategy, not the configured one")
+	require.Len(t, events, 1)
+	assert.Equal(t, ReasonPin, events[0].Reason)
+	assert.Equal(t, MigrationWarm, events[0].Migration)
+
+	bandit.next = LRU
+	ac.runEpoch()
+	assert.Equal(t, LFU, ac.ActivePolicy(), "the bandit is not consulted while pinned")
+
+	advice := ac.Advice()
+	assert.Equal(t, PinStatus{Policy: LFU}, advice.Pin)
+	assert.Equal(t, int64(1), advice.Epochs, "but measurement goes on")
+	assert.Contains(t, advice.String(), "Pinned to LFU by an operator")
+
+	ac.Unpin()
+	assert.Equal(t, LFU, ac.ActivePolicy(), "unpinning switches nothing by itself")
+	ac.runEpoch()
+	assert.Equal(t, LRU, ac.ActivePolicy())
+	assert.False(t, ac.Advice().Pin.Pinned())
+
+	history := ac.History()
+	require.Len(t, history.Switches, 2)
+	assert.Equal(t, ReasonPin, history.Switches[0].Reason)
+	assert.Equal(t, ReasonBandit, history.Switches[1].Reason)
+}
+
+func TestPin_RejectsAPolicyTheCacheDoesNotHold(t *testing.T) {
+	ac, _, _, _ := makeCache(t, MigrationCold)
+
+	assert.ErrorIs(t, ac.Pin(ARC, MigrationCold), ErrUnknownPolicy)
+	assert.ErrorIs(t, ac.Pin(Undefined, MigrationCold), ErrUnknownPolicy)
+	assert.False(t, ac.Advice().Pin.Pinned())
+}
+
+func TestPin_PassesNoGatesAndIsNotFlapping(t *testing.T) {
+	ac, bandit := makeFlappingCache(t, Damping{Switches: 2, WindowEpochs: 10})
+	ac.settings.SwitchCooldownEpochs = 100
+
+	for range 4 {
+		require.NoError(t, ac.Pin(LFU, MigrationCold))
+		require.NoError(t, ac.Pin(LRU, MigrationCold))
+	}
+	ac.Unpin()
+	bandit.next = LRU
+	ac.runEpoch()
+
+	assert.Zero(t, ac.Advice().Damping.Level, "an operator changing their mind is not the cache flapping")
+	for _, record := range ac.History().Switches {
+		assert.Empty(t, record.GatesPassed)
+	}
+}
+
+func TestPin_FollowsTheFleetUnlessPinnedLocally(t *testing.T) {
+	lru := newMockPolicy[string, int](LRU, 10)
+	lfu := newMockPolicy[string, int](LFU, 10)
+	bandit := &pinningBandit{mockBandit: mockBandit{next: LRU}}
+
+	ac, err := NewAdaptiveCache([]Policy[string, int]{lru, lfu}, bandit, defaultEpochSettings())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ac.Close() })
+
+	bandit.pinned = LFU
+	ac.runEpoch()
+	assert.Equal(t, LFU, ac.ActivePolicy())
+	ac.Get("a")
+	ac.runEpoch()
+	assert.Equal(t, PinStatus{Policy: LFU, Fleet: true}, ac.Advice().Pin)
+	assert.Contains(t, ac.Advice().String(), "Pinned to LFU by the fleet")
+
+	require.NoError(t, ac.Pin(LRU, MigrationCold))
+	ac.runEpoch()
+	assert.Equal(t, LRU, ac.ActivePolicy(), "the operator's pin wins")
+
+	ac.Unpin()
+	ac.runEpoch()
+	assert.Equal(t, LFU, ac.ActivePolicy())
+
+	bandit.pinned = Undefined
+	ac.runEpoch()
+	assert.Equal(t, LRU, ac.ActivePolicy(), "a lifted fleet pin hands back to the bandit")
+}
+
+func TestPin_CreditsWhatTheOutgoingPolicyServed(t *testing.T) {
+	ac, _, _, _ := makeCache(t, MigrationWarm)
+
+	ac.Add("a", 1)
+	for range 10 {
+		ac.Get("a")
+	}
+	require.NoError(t, ac.Pin(LFU, MigrationWarm))
+
+	history := ac.History()
+	require.Len(t, history.Switches, 1)
+	assert.Equal(t, 1.0, history.Switches[0].HitRateBefore, "the tenure the pin ended served ten hits")
+	assert.Zero(t, history.Switches[0].HitRateAfter, "and the incoming policy has served nothing yet")
+
+	ledger := map[PolicyType]PolicyTenure{}
+	for _, tenure := range history.Policies {
+		ledger[tenure.Policy] = tenure
+	}
+	assert.Equal(t, int64(10), ledger[LRU].Hits)
+	assert.Zero(t, ledger[LFU].Hits+ledger[LFU].Misses, "its shadow counts are not traffic it served")
+	assert.Equal(t, int64(10), ac.Stats().Hits)
+}
+
+func TestPin_ObserveOnlyFollowsTheFleetPin(t *testing.T) {
+	lru := newMockPolicy[string, int](LRU, 10)
+	lfu := newMockPolicy[string, int](LFU, 10)
+	bandit := &pinningBandit{mockBandit: mockBandit{next: LFU}}
+
+	settings := defaultEpochSettings()
+	settings.ObserveOnly = true
+	ac, err := NewAdaptiveCache([]Policy[string, int]{lru, lfu}, bandit, settings)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ac.Close() })
+
+	ac.runEpoch()
+	assert.Equal(t, LRU, ac.ActivePolicy(), "the bandit's selection is not acted on")
+
+	bandit.pinned = LFU
+	ac.runEpoch()
+	assert.Equal(t, LFU, ac.ActivePolicy(), "but a fleet pin is, as an operator's Pin would be")
+
+	bandit.pinned = Undefined
+	bandit.next = LRU
+	ac.runEpoch()
+	assert.Equal(t, LFU, ac.ActivePolicy(), "and lifting it hands back to observing, not to the bandit")
+}
@@ -1,7 +1,9 @@
 package policies
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	lru "github.com/hashicorp/golang-lru/v2"
@@ -37,24 +39,53 @@ func NewLFU[K comparable, V any](size int) (ascache.Policy[K, V], error) {
 	return ascache.NewCache[K, V](cache, ascache.LFU, size), nil
 }
 
-// NewTwoQueue returns a 2Q policy of the given size, backed by
-// hashicorp/golang-lru/v2.
+// NewLFUWithAging returns an LFU policy that forgets, configured by
+// lfu.WithHalving or lfu.WithDynamicAging, as an arm separate from NewLFU.
+//
+// Plain LFU's weakness is the one aging addresses: a count built up once keeps
+// an entry resident after the traffic has moved on. Racing the two settles
+// whether that matters for a workload, so the aged policy reports as a variant
+// of LFU - LFU/halving=N or LFU/da - and can sit alongside NewLFU in the same
+// cache. Without an aging option it is an error: that is NewLFU.
+func NewLFUWithAging[K comparable, V any](size int, opts ...lfu.Option) (ascache.Policy[K, V], error) {
+	var options lfu.Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var variant string
+	switch {
+	case options.HalvingPeriod > 0 && !options.DynamicAging:
+		variant = "halving=" + strconv.Itoa(options.HalvingPeriod)
+	case options.DynamicAging && options.HalvingPeriod == 0:
+		variant = "da"
+	case options.HalvingPeriod == 0:
+		return nil, errors.New("build lfu cache: no aging option given; use NewLFU")
+	}
+
+	cache, err := lfu.New[K, V](size, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("build lfu cache: %w", err)
+	}
+
+	policyType, err := ascache.Variant(ascache.LFU, variant)
+	if err != nil {
+		return nil, fmt.Errorf("build lfu cache: %w", err)
+	}
+
+	return ascache.NewCache[K, V](cache, policyType, size), nil
+}
+
+// NewTwoQueue returns a 2Q policy of the given size, with the usual split:
+// a quarter of the capacity for keys seen once, and half a capacity's worth of
+// evicted keys remembered. See TwoQueueCache.
 //
 // 2Q puts a small recent-access queue in front of a frequently-accessed queue,
 // so a one-off scan passes through the recent queue without flushing the
 // working set. That makes it a useful arm to hold alongside LRU, which a scan
 // defeats completely.
-// 2Q reports neither evictions nor removals and cannot be resized, so it is
-// adapted rather than used directly.
 func NewTwoQueue[K comparable, V any](size int) (ascache.Policy[K, V], error) {
-	cache, err := Adapt[K, V](size, func(size int) (PartialCacher[K, V], error) {
-		built, err := lru.New2Q[K, V](size)
-		if err != nil {
-			return nil, fmt.Errorf("build 2q cache: %w", err)
-		}
-
-		return built, nil
-	})
+	cache, err := NewTwoQueueCache[K, V](size, DefaultTwoQueueRecentRatio, DefaultTwoQueueGhostRatio)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +106,38 @@ func NewTTL[K comparable, V any](size int, ttl time.Duration) ascache.Policy[K,
 	return ascache.NewCache[K, V](NewTTLCache[K, V](size, ttl), ascache.TTL, size)
 }
 
+// NewLRUK returns an LRU-K policy of the given size, ranking each key by its
+// k-th most recent reference. See LRUKCache.
+//
+// k=2 is the setting the algorithm is known for: it separates keys referenced
+// repeatedly from keys touched once by a scan, which is where database page
+// caches spend most of their misses. Larger k reacts more slowly to a key
+// becoming hot. k=1 is plain LRU.
+func NewLRUK[K comparable, V any](size, k int) (ascache.Policy[K, V], error) {
+	cache, err := NewLRUKCache[K, V](size, k)
+	if err != nil {
+		return nil, err
+	}
+
+	return ascache.NewCache[K, V](cache, ascache.LRUK, size), nil
+}
+
+// NewLRFU returns an LRFU policy of the given size. lambda, in [0,1], sets how
+// quickly past references decay: 0 is LFU, 1 is LRU, and the values between
+// are LFU that forgets at a chosen rate. See LRFUCache.
+//
+// Small values are where it earns its place. Something around 0.001 behaves
+// like LFU over a horizon of a few thousand requests, which keeps what LFU is
+// good at while no longer pinning a key that was popular long ago.
+func NewLRFU[K comparable, V any](size int, lambda float64) (ascache.Policy[K, V], error) {
+	cache, err := NewLRFUCache[K, V](size, lambda)
+	if err != nil {
+		return nil, err
+	}
+
+	return ascache.NewCache[K, V](cache, ascache.LRFU, size), nil
+}
+
 // NewRandomPolicy returns a random-eviction policy of the given size, ready to
 // be used as a bandit arm.
 func NewRandomPolicy[K comparable, V any](size int) ascache.Policy[K, V] {
@@ -1,4 +1,4 @@
-// Package arc adapts the Adaptive Replacement Cache to ascache.Policy.
+// Package arc implements the Adaptive Replacement Cache as an ascache.Policy.
 //
 // It is a module of its own, separate from the other policy adapters, for one
 // reason: ARC is patented by IBM (US 6,996,676, filed 2002). Upstream
@@ -13,29 +13,67 @@
 package arc
 
 import (
-	"fmt"
-
-	arclru "github.com/hashicorp/golang-lru/arc/v2"
+	"container/list"
+	"sync"
 
 	ascache "github.com/sshaplygin/as-cache"
-	"github.com/sshaplygin/as-cache/policies"
 )
 
-// New returns an ARC cache holding up to size entries, satisfying
-// ascache.Cacher.
+// entry is a resident key.
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	// frequent records whether the entry is in T2, so a hit knows whether it
+	// promotes.
+	frequent bool
+}
+
+// ghost is an evicted key, remembered in B1 or B2.
+type ghost[K comparable] struct {
+	key K
+	// frequent records whether the key is in B2, having been evicted from T2.
+	frequent bool
+}
+
+// Cache is the Adaptive Replacement Cache of Megiddo and Modha.
 //
-// Upstream ARCCache reports neither evictions nor removals and has no Resize,
-// so it is wrapped by policies.Adapt, which supplies all three. See
-// policies.AdaptedCache.Resize for what resizing costs an adaptive algorithm.
-func New[K comparable, V any](size int) (*policies.AdaptedCache[K, V], error) {
-	return policies.Adapt[K, V](size, func(size int) (policies.PartialCacher[K, V], error) {
-		cache, err := arclru.NewARC[K, V](size)
-		if err != nil {
-			return nil, fmt.Errorf("build arc cache: %w", err)
-		}
+// It keeps two LRU lists of resident entries - T1 for keys seen once recently,
+// T2 for keys seen at least twice - and two ghost lists, B1 and B2, of keys
+// recently evicted from each, without their values. A miss that hits a ghost
+// is evidence about the workload: a key returning from B1 means T1 was evicted
+// too soon, so the target size p for T1 grows; one returning from B2 means the
+// same of T2, so p shrinks. Eviction then takes from whichever list is over
+// its share. That balance between recency and frequency is the algorithm's
+// whole value, and it is learned from traffic.
+//
+// Which is why this is native rather than hashicorp's ARC through an adapter:
+// that cache cannot be resized, and rebuilding it discards the ghosts and p,
+// while the adaptive layer resizes an arm every time it demotes or promotes
+// one. Here Resize trims in place and keeps both, and Add and Resize report
+// exactly what they evict.
+//
+// It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*list.Element
+	ghosts  map[K]*list.Element
+	// t1 and t2 hold *entry values, b1 and b2 *ghost values; each is most
+	// recently used at the front.
+	t1, t2, b1, b2 *list.List
 
-		return cache, nil
-	})
+	size int
+	// p is the target size of t1.
+	p int
+}
+
+// New returns an ARC cache holding up to size entries, satisfying
+// ascache.Cacher. A size of zero or less means the cache holds nothing.
+func New[K comparable, V any](size int) (*Cache[K, V], error) {
+	c := &Cache[K, V]{}
+	c.resetLocked()
+	c.size = max(size, 0)
+
+	return c, nil
 }
 
 // NewPolicy returns an ARC policy of the given size, ready to be used as a
@@ -48,3 +86,298 @@ func NewPolicy[K comparable, V any](size int) (ascache.Policy[K, V], error) {
 
 	return ascache.NewCache[K, V](cache, ascache.ARC, size), nil
 }
+
+func (c *Cache[K, V]) resetLocked() {
+	c.entries = make(map[K]*list.Element)
+	c.ghosts = make(map[K]*list.Element)
+	c.t1, c.t2, c.b1, c.b2 = list.New(), list.New(), list.New(), list.New()
+	c.p = 0
+}
+
+func entryOf[K comparable, V any](element *list.Element) *entry[K, V] {
+	e, _ := element.Value.(*entry[K, V])
+
+	return e
+}
+
+// replaceLocked evicts one resident entry into its ghost list: from T1 when T1
+// is over its target p, or at it and the key being admitted came from B2;
+// otherwise from T2.
+func (c *Cache[K, V]) replaceLocked(fromB2 bool) {
+	t1Len := c.t1.Len()
+	if t1Len > 0 && (t1Len > c.p || (t1Len == c.p && fromB2) || c.t2.Len() == 0) {
+		c.evictLocked(c.t1, c.b1)
+
+		return
+	}
+
+	c.evictLocked(c.t2, c.b2)
+}
+
+// evictLocked moves the least recently used entry of resident into ghosts.
+func (c *Cache[K, V]) evictLocked(resident, ghosts *list.List) {
+	victim := resident.Back()
+	resident.Remove(victim)
+
+	key := entryOf[K, V](victim).key
+	delete(c.entries, key)
+	c.ghosts[key] = ghosts.PushFront(&ghost[K]{key: key, frequent: ghosts == c.b2})
+}
+
+func ghostOf[K comparable](element *list.Element) *ghost[K] {
+	g, _ := element.Value.(*ghost[K])
+
+	return g
+}
+
+// forgetLocked drops the oldest key of a ghost list.
+func (c *Cache[K, V]) forgetLocked(ghosts *list.List) {
+	oldest := ghosts.Back()
+	ghosts.Remove(oldest)
+	delete(c.ghosts, ghostOf[K](oldest).key)
+}
+
+// Add stores a value, reporting whether storing it evicted another entry.
+func (c *Cache[K, V]) Add(key K, value V) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		e := entryOf[K, V](element)
+		e.value = value
+		c.promoteLocked(element, e)
+
+		return false
+	}
+
+	if c.size <= 0 {
+		return false
+	}
+
+	evicted := false
+
+	if element, ok := c.ghosts[key]; ok {
+		inB1 := !ghostOf[K](element).frequent
+		if inB1 {
+			// T1 was evicted too soon: give it more room.
+			c.p = min(c.p+max(1, c.b2.Len()/max(c.b1.Len(), 1)), c.size)
+			c.b1.Remove(element)
+		} else {
+			// T2 was.
+			c.p = max(c.p-max(1, c.b1.Len()/max(c.b2.Len(), 1)), 0)
+			c.b2.Remove(element)
+		}
+		delete(c.ghosts, key)
+
+		if len(c.entries) >= c.size {
+			c.replaceLocked(!inB1)
+			evicted = true
+		}
+		// Moving p narrowed one ghost list's share, and the eviction may have
+		// grown the other.
+		c.trimGhostsLocked()
+
+		c.entries[key] = c.t2.PushFront(&entry[K, V]{key: key, value: value, frequent: true})
+
+		return evicted
+	}
+
+	if len(c.entries) >= c.size {
+		c.replaceLocked(false)
+		evicted = true
+	}
+	c.trimGhostsLocked()
+
+	c.entries[key] = c.t1.PushFront(&entry[K, V]{key: key, value: value})
+
+	return evicted
+}
+
+// trimGhostsLocked keeps each ghost list within the share of the capacity its
+// resident list is not targeted to use - B1 within size-p, B2 within p - as
+// hashicorp's ARC does. So the cache never remembers more evicted keys than
+// it can hold.
+func (c *Cache[K, V]) trimGhostsLocked() {
+	for c.b1.Len() > c.size-c.p {
+		c.forgetLocked(c.b1)
+	}
+	for c.b2.Len() > c.p {
+		c.forgetLocked(c.b2)
+	}
+}
+
+// promoteLocked records a hit: an entry in T1 moves to T2, one in T2 to its
+// front.
+func (c *Cache[K, V]) promoteLocked(element *list.Element, e *entry[K, V]) {
+	if e.frequent {
+		c.t2.MoveToFront(element)
+
+		return
+	}
+
+	c.t1.Remove(element)
+	e.frequent = true
+	c.entries[e.key] = c.t2.PushFront(e)
+}
+
+// Get returns the value for key, if present, and records the access.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	e := entryOf[K, V](element)
+	c.promoteLocked(element, e)
+
+	return e.value, true
+}
+
+// Peek returns the value for key without recording an access.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	return entryOf[K, V](element).value, true
+}
+
+// Contains reports whether key is cached, without recording an access.
+func (c *Cache[K, V]) Contains(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.entries[key]
+
+	return ok
+}
+
+// Remove deletes key, reporting whether it was present. The key is not
+// remembered as a ghost: it was removed, not evicted, so its return would say
+// nothing about p.
+func (c *Cache[K, V]) Remove(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+
+	if entryOf[K, V](element).frequent {
+		c.t2.Remove(element)
+	} else {
+		c.t1.Remove(element)
+	}
+	delete(c.entries, key)
+
+	return true
+}
+
+// Purge empties the cache, forgets its ghosts and resets p.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resetLocked()
+}
+
+// Keys returns the cached keys: T1 least recently used first, then T2 least
+// recently used first.
+func (c *Cache[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]K, 0, len(c.entries))
+	for _, resident := range []*list.List{c.t1, c.t2} {
+		for element := resident.Back(); element != nil; element = element.Prev() {
+			keys = append(keys, entryOf[K, V](element).key)
+		}
+	}
+
+	return keys
+}
+
+// Values returns the cached values, in the same order as Keys.
+func (c *Cache[K, V]) Values() []V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	values := make([]V, 0, len(c.entries))
+	for _, resident := range []*list.List{c.t1, c.t2} {
+		for element := resident.Back(); element != nil; element = element.Prev() {
+			values = append(values, entryOf[K, V](element).value)
+		}
+	}
+
+	return values
+}
+
+// Len returns the number of cached entries.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// Resize changes the capacity in place and returns how many entries it
+// evicted. Entries are evicted by ARC's own rule, so they become ghosts like
+// any other; p is clamped to the new capacity rather than reset, and the ghost
+// lists are trimmed to their bounds at the new size.
+func (c *Cache[K, V]) Resize(size int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.size = max(size, 0)
+	c.p = min(c.p, c.size)
+
+	evicted := 0
+	for len(c.entries) > c.size {
+		c.replaceLocked(false)
+		evicted++
+	}
+	c.trimGhostsLocked()
+
+	return evicted
+}
+
+// Cap returns the capacity.
+func (c *Cache[K, V]) Cap() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+// P returns the current target size of T1, the recency half of the cache. It
+// is what ARC has learned about the workload: near zero, it favours frequency;
+// near the capacity, recency.
+func (c *Cache[K, V]) P() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.p
+}
+
+// Ghosts returns how many evicted keys the cache remembers across B1 and B2.
+// It is never more than the capacity.
+func (c *Cache[K, V]) Ghosts() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.ghosts)
+}
+
+var _ ascache.Cacher[string, int] = (*Cache[string, int])(nil)
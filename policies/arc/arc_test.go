@@ -112,10 +112,9 @@ func TestARCConformance(t *testing.T) {
 
 		p.Resize(3)
 
-		// Which entries survive is not guaranteed - ARC's Keys() is recent
-		// then frequent, not one recency order, so the rebuilt cache chooses.
-		// What must hold is that the shrink retains what it can and that no
-		// survivor comes back corrupted.
+		// Which entries survive is ARC's choice, made by the same rule it
+		// evicts by when full. What must hold is that the shrink retains what
+		// it can and that no survivor comes back corrupted.
 		assert.Equal(t, 3, p.Len(), "a shrink from 10 to 3 must retain 3 entries, not fewer")
 
 		survivors := 0
@@ -187,7 +186,7 @@ func TestARCConformance(t *testing.T) {
 			}(g)
 		}
 
-		// Resize concurrently: it swaps the underlying cache wholesale, which
+		// Resize concurrently: it evicts across all four lists at once, which
 		// is exactly the operation most likely to race with the workers.
 		wg.Add(1)
 		go func() {
@@ -262,3 +261,86 @@ type fixedBandit struct{ pick ascache.PolicyType }
 
 func (b *fixedBandit) RecordStats(_ ascache.ShadowStats) {}
 func (b *fixedBandit) SelectPolicy() ascache.PolicyType  { return b.pick }
+
+func newCache(t *testing.T, size int) *arc.Cache[string, int] {
+	t.Helper()
+
+	c, err := arc.New[string, int](size)
+	require.NoError(t, err)
+
+	return c
+}
+
+// TestARC_LearnsFromGhosts checks the adaptation itself: a key returning
+// from B1 - evicted from the recency list too soon - grows the recency
+// list's target, and one returning from B2 shrinks it again.
+func TestARC_LearnsFromGhosts(t *testing.T) {
+	c := newCache(t, 4)
+
+	for i := 0; i < 4; i++ {
+		c.Add("once-"+strconv.Itoa(i), i)
+	}
+	c.Add("pusher", 0) // evicts once-0 into B1
+	require.False(t, c.Contains("once-0"))
+	require.Zero(t, c.P())
+
+	c.Add("once-0", 0)
+	assert.Positive(t, c.P(), "a B1 hit must grow the recency target")
+	assert.True(t, c.Contains("once-0"))
+}
+
+func TestARC_ResizeKeepsWhatItLearned(t *testing.T) {
+	c := newCache(t, 8)
+
+	for i := 0; i < 8; i++ {
+		c.Add("once-"+strconv.Itoa(i), i)
+	}
+	for i := 0; i < 4; i++ {
+		c.Add("pusher-"+strconv.Itoa(i), i)
+	}
+	c.Add("once-0", 0)
+	c.Add("once-1", 1)
+	learned := c.P()
+	require.Positive(t, learned)
+
+	c.Resize(16)
+	assert.Equal(t, learned, c.P(), "growing must not reset the target")
+
+	c.Resize(learned)
+	assert.Equal(t, learned, c.P(), "shrinking to the target must keep it")
+}
+
+func TestARC_ReportsEvictionsExactly(t *testing.T) {
+	c := newCache(t, 3)
+
+	for i := 0; i < 3; i++ {
+		assert.False(t, c.Add("key-"+strconv.Itoa(i), i))
+	}
+	assert.True(t, c.Add("key-3", 3))
+	c.Get("key-3")
+
+	assert.Equal(t, 2, c.Resize(1))
+	assert.Equal(t, 1, c.Len())
+	assert.True(t, c.Contains("key-3"), "the shrink evicts from the recency list first")
+}
+
+// TestARC_GhostListsStayBounded drives a long scan through the cache: the
+// ghost lists remember evicted keys, and must forget them again.
+func TestARC_GhostListsStayBounded(t *testing.T) {
+	c := newCache(t, 10)
+
+	for i := 0; i < 10_000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		c.Add(key, i)
+		if i%3 == 0 {
+			c.Get(key)
+		}
+		if i%7 == 0 {
+			c.Add("key-"+strconv.Itoa(i/2), i)
+		}
+	}
+
+	assert.LessOrEqual(t, c.Len(), 10)
+	assert.LessOrEqual(t, c.P(), 10)
+	assert.LessOrEqual(t, c.Ghosts(), 10, "ARC remembers at most a capacity's worth of evicted keys")
+}
@@ -0,0 +1,41 @@
+package arc
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestARC_GhostHitsKeepGhostListsBounded returns evicted keys to the cache
+// one after another. Each hit moves p, which narrows the other ghost list's
+// share, and may evict into a ghost list, so both must be trimmed on the
+// ghost-hit path too rather than on the next ordinary Add.
+func TestARC_GhostHitsKeepGhostListsBounded(t *testing.T) {
+	c, err := New[string, int](4)
+	require.NoError(t, err)
+
+	for i := 0; i < 8; i++ {
+		c.Add("key-"+strconv.Itoa(i), i)
+	}
+	for i := 4; i < 8; i++ {
+		c.Get("key-" + strconv.Itoa(i))
+	}
+	for i := 8; i < 12; i++ {
+		c.Add("key-"+strconv.Itoa(i), i)
+	}
+
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 12; i++ {
+			key := "key-" + strconv.Itoa(i)
+			if _, ghost := c.ghosts[key]; !ghost {
+				continue
+			}
+
+			c.Add(key, i)
+			assert.LessOrEqual(t, c.b1.Len(), c.size-c.p, "B1 after a ghost hit on %s", key)
+			assert.LessOrEqual(t, c.b2.Len(), c.p, "B2 after a ghost hit on %s", key)
+		}
+	}
+}
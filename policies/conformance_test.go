@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/lfu"
 	"github.com/sshaplygin/as-cache/policies"
 )
 
@@ -33,6 +34,20 @@ var policiesUnderTest = map[string]newPolicy{
 
 		return p
 	},
+	"lfu-halving": func(t *testing.T, size int) ascache.Policy[string, int] {
+		t.Helper()
+		p, err := policies.NewLFUWithAging[string, int](size, lfu.WithHalving(4*size))
+		require.NoError(t, err)
+
+		return p
+	},
+	"lfu-da": func(t *testing.T, size int) ascache.Policy[string, int] {
+		t.Helper()
+		p, err := policies.NewLFUWithAging[string, int](size, lfu.WithDynamicAging())
+		require.NoError(t, err)
+
+		return p
+	},
 	"2q": func(t *testing.T, size int) ascache.Policy[string, int] {
 		t.Helper()
 		p, err := policies.NewTwoQueue[string, int](size)
@@ -47,6 +62,20 @@ var policiesUnderTest = map[string]newPolicy{
 		// Cacher contract, not about expiry behaviour.
 		return policies.NewTTL[string, int](size, time.Hour)
 	},
+	"lru-2": func(t *testing.T, size int) ascache.Policy[string, int] {
+		t.Helper()
+		p, err := policies.NewLRUK[string, int](size, 2)
+		require.NoError(t, err)
+
+		return p
+	},
+	"lrfu": func(t *testing.T, size int) ascache.Policy[string, int] {
+		t.Helper()
+		p, err := policies.NewLRFU[string, int](size, 0.01)
+		require.NoError(t, err)
+
+		return p
+	},
 	"random": func(t *testing.T, size int) ascache.Policy[string, int] {
 		t.Helper()
 
@@ -0,0 +1,44 @@
+package policies_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ascache "github.com/sshaplygin/as-cache"
+	"github.com/sshaplygin/as-cache/lfu"
+	"github.com/sshaplygin/as-cache/policies"
+)
+
+func TestNewLFUWithAging_RequiresAnAgingOption(t *testing.T) {
+	_, err := policies.NewLFUWithAging[string, int](10)
+	require.Error(t, err)
+
+	_, err = policies.NewLFUWithAging[string, int](10, lfu.WithHalving(100), lfu.WithDynamicAging())
+	require.Error(t, err)
+}
+
+// TestNewLFUWithAging_IsAnArmOfItsOwn checks the aged policies can race plain
+// LFU in one cache, which is the reason they are variants rather than LFU.
+func TestNewLFUWithAging_IsAnArmOfItsOwn(t *testing.T) {
+	plain, err := policies.NewLFU[string, int](10)
+	require.NoError(t, err)
+	halving, err := policies.NewLFUWithAging[string, int](10, lfu.WithHalving(100))
+	require.NoError(t, err)
+	aged, err := policies.NewLFUWithAging[string, int](10, lfu.WithDynamicAging())
+	require.NoError(t, err)
+
+	assert.Equal(t, "LFU/halving=100", halving.GetType().String())
+	assert.Equal(t, "LFU/da", aged.GetType().String())
+	assert.Equal(t, ascache.LFU, aged.GetType().Base())
+
+	cache, err := ascache.NewAdaptiveCache(
+		[]ascache.Policy[string, int]{plain, halving, aged},
+		nil,
+		&ascache.Settings{EpochDuration: time.Hour, ObserveOnly: true},
+	)
+	require.NoError(t, err)
+	require.NoError(t, cache.Close())
+}
@@ -0,0 +1,275 @@
+package policies
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// lrfuEntry is one resident key with its combined recency-frequency value as
+// of its last reference.
+type lrfuEntry[K comparable, V any] struct {
+	key   K
+	value V
+	// crf is the entry's combined recency-frequency value at time last.
+	crf  float64
+	last int64
+	pos  int
+}
+
+func (e *lrfuEntry[K, V]) setSlot(slot int) { e.pos = slot }
+func (e *lrfuEntry[K, V]) slot() int        { return e.pos }
+
+// LRFUCache evicts the entry with the lowest combined recency-frequency value,
+// the LRFU algorithm of Lee et al.
+//
+// Every past reference to a key contributes (1/2)^(lambda*age) to its value,
+// so lambda is the one knob: at 0 nothing decays and the value is a plain
+// reference count, which is LFU; at 1 the most recent reference outweighs all
+// earlier ones put together, which is LRU. Everything in between trades the
+// two off, and it is the in-between settings - LFU that forgets - that make
+// this worth carrying as an arm next to both of them.
+//
+// An entry's value is only recomputed when it is referenced. Two entries are
+// compared at any common time by log2(crf) + lambda*last, in which the current
+// time cancels out, so the eviction order never has to be rebuilt as time
+// passes.
+//
+// Time here is a logical clock advanced by every reference, not the wall
+// clock, so the order is a function of the access sequence alone.
+//
+// It is safe for concurrent use.
+type LRFUCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*lrfuEntry[K, V]
+	queue   rankHeap[*lrfuEntry[K, V]]
+	size    int
+	lambda  float64
+	clock   int64
+}
+
+// NewLRFUCache returns an LRFU cache holding up to size entries. lambda must
+// lie in [0,1]: 0 behaves as LFU, 1 as LRU. A size of zero or less means the
+// cache holds nothing.
+func NewLRFUCache[K comparable, V any](size int, lambda float64) (*LRFUCache[K, V], error) {
+	if math.IsNaN(lambda) || lambda < 0 || lambda > 1 {
+		return nil, fmt.Errorf("build lrfu cache: lambda must be in [0,1], got %v", lambda)
+	}
+	if size < 0 {
+		size = 0
+	}
+
+	c := &LRFUCache[K, V]{
+		entries: make(map[K]*lrfuEntry[K, V], size),
+		size:    size,
+		lambda:  lambda,
+	}
+	c.queue.less = c.evictsBefore
+	c.queue.reset(size)
+
+	return c, nil
+}
+
+// score is an entry's value on a log scale, offset so that entries referenced
+// at different times can be compared without decaying either to a common time.
+func (c *LRFUCache[K, V]) score(e *lrfuEntry[K, V]) float64 {
+	return math.Log2(e.crf) + c.lambda*float64(e.last)
+}
+
+// evictsBefore reports whether a is a better eviction victim than b. Equal
+// scores are broken towards the least recently referenced.
+func (c *LRFUCache[K, V]) evictsBefore(a, b *lrfuEntry[K, V]) bool {
+	if left, right := c.score(a), c.score(b); left != right {
+		return left < right
+	}
+
+	return a.last < b.last
+}
+
+// referenceLocked folds a reference at the next tick of the clock into e's
+// value: the old value decays by the time since e was last referenced, and the
+// new reference contributes one.
+func (c *LRFUCache[K, V]) referenceLocked(e *lrfuEntry[K, V]) {
+	c.clock++
+	e.crf = 1 + math.Exp2(-c.lambda*float64(c.clock-e.last))*e.crf
+	e.last = c.clock
+}
+
+func (c *LRFUCache[K, V]) evictLocked() {
+	victim := c.queue.min()
+	c.queue.remove(victim)
+	delete(c.entries, victim.key)
+}
+
+// Add stores a value, reporting whether storing it evicted another entry.
+// Storing counts as a reference.
+func (c *LRFUCache[K, V]) Add(key K, value V) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.value = value
+		c.referenceLocked(e)
+		c.queue.fix(e.pos)
+
+		return false
+	}
+
+	if c.size <= 0 {
+		return false
+	}
+
+	evicted := false
+	for len(c.entries) >= c.size {
+		c.evictLocked()
+		evicted = true
+	}
+
+	c.clock++
+	e := &lrfuEntry[K, V]{key: key, value: value, crf: 1, last: c.clock}
+	c.entries[key] = e
+	c.queue.push(e)
+
+	return evicted
+}
+
+// Get returns the value for key, if present, and records the reference.
+func (c *LRFUCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	c.referenceLocked(e)
+	c.queue.fix(e.pos)
+
+	return e.value, true
+}
+
+// Peek returns the value for key without recording a reference.
+func (c *LRFUCache[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+// Contains reports whether key is cached, without recording a reference.
+func (c *LRFUCache[K, V]) Contains(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.entries[key]
+
+	return ok
+}
+
+// Remove deletes key, reporting whether it was present.
+func (c *LRFUCache[K, V]) Remove(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+
+	c.queue.remove(e)
+	delete(c.entries, key)
+
+	return true
+}
+
+// Purge empties the cache.
+func (c *LRFUCache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[K]*lrfuEntry[K, V], c.size)
+	c.queue.reset(c.size)
+}
+
+// Keys returns the cached keys, next eviction victim first.
+func (c *LRFUCache[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ordered := c.queue.ordered()
+	keys := make([]K, 0, len(ordered))
+	for _, e := range ordered {
+		keys = append(keys, e.key)
+	}
+
+	return keys
+}
+
+// Values returns the cached values, in the same order as Keys.
+func (c *LRFUCache[K, V]) Values() []V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ordered := c.queue.ordered()
+	values := make([]V, 0, len(ordered))
+	for _, e := range ordered {
+		values = append(values, e.value)
+	}
+
+	return values
+}
+
+// Len returns the number of cached entries.
+func (c *LRFUCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// Resize changes the capacity, evicting the lowest-valued entries down to the
+// new size, and returns how many entries it evicted.
+func (c *LRFUCache[K, V]) Resize(size int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if size < 0 {
+		size = 0
+	}
+	c.size = size
+
+	evicted := 0
+	for len(c.entries) > c.size {
+		c.evictLocked()
+		evicted++
+	}
+
+	return evicted
+}
+
+// Cap returns the capacity.
+func (c *LRFUCache[K, V]) Cap() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+// Lambda returns the decay parameter the cache was built with.
+func (c *LRFUCache[K, V]) Lambda() float64 {
+	return c.lambda
+}
+
+var _ ascache.Cacher[string, int] = (*LRFUCache[string, int])(nil)
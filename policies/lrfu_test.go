@@ -0,0 +1,92 @@
+package policies_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sshaplygin/as-cache/policies"
+)
+
+func TestNewLRFU_RejectsLambdaOutsideTheUnitInterval(t *testing.T) {
+	for _, lambda := range []float64{-0.1, 1.1, math.NaN()} {
+		_, err := policies.NewLRFU[string, int](10, lambda)
+		assert.Error(t, err, "lambda %v", lambda)
+	}
+}
+
+// fillFrequencyThenRecency builds the one access pattern that LFU and LRU
+// disagree on: "old" is referenced often and then left alone, "new" is
+// referenced once, last.
+func fillFrequencyThenRecency(t *testing.T, lambda float64) *policies.LRFUCache[string, int] {
+	t.Helper()
+
+	c, err := policies.NewLRFUCache[string, int](2, lambda)
+	require.NoError(t, err)
+
+	c.Add("old", 1)
+	for i := 0; i < 10; i++ {
+		c.Get("old")
+	}
+	c.Add("new", 2)
+
+	return c
+}
+
+func TestLRFU_LambdaZeroBehavesAsLFU(t *testing.T) {
+	c := fillFrequencyThenRecency(t, 0)
+	c.Add("third", 3)
+
+	assert.True(t, c.Contains("old"), "at lambda 0 frequency wins")
+	assert.False(t, c.Contains("new"))
+}
+
+func TestLRFU_LambdaOneBehavesAsLRU(t *testing.T) {
+	c := fillFrequencyThenRecency(t, 1)
+
+	// Ten more references elsewhere age "old" well past its frequency.
+	c.Get("new")
+	for i := 0; i < 10; i++ {
+		c.Get("new")
+	}
+	c.Add("third", 3)
+
+	assert.True(t, c.Contains("new"), "at lambda 1 recency wins")
+	assert.False(t, c.Contains("old"))
+}
+
+// TestLRFU_SmallLambdaForgets is the reason to carry the policy at all: with a
+// small decay a once-popular key eventually loses to a key that is popular
+// now, where LFU would keep it forever.
+func TestLRFU_SmallLambdaForgets(t *testing.T) {
+	for _, tc := range []struct {
+		lambda    float64
+		keepsOld  bool
+		rationale string
+	}{
+		{lambda: 0, keepsOld: true, rationale: "LFU never forgets"},
+		{lambda: 0.1, keepsOld: false, rationale: "a decaying count does"},
+	} {
+		c, err := policies.NewLRFUCache[string, int](3, tc.lambda)
+		require.NoError(t, err)
+
+		c.Add("old", 1)
+		for i := 0; i < 20; i++ {
+			c.Get("old")
+		}
+		c.Add("new", 2)
+		for i := 0; i < 200; i++ {
+			c.Get("new")
+		}
+		// new is well ahead now by either measure, so the contest for the
+		// last slot is between old and a key that arrives after it.
+		c.Add("third", 3)
+		c.Get("third")
+		c.Get("third")
+		c.Add("fourth", 4)
+
+		assert.Equal(t, tc.keepsOld, c.Contains("old"), "lambda %v: %s", tc.lambda, tc.rationale)
+	}
+}
@@ -0,0 +1,322 @@
+package policies
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+// lrukEntry is one resident key with the times of its last K references.
+type lrukEntry[K comparable, V any] struct {
+	key   K
+	value V
+	// refs holds the logical times of the most recent references, oldest
+	// first, and never more than K of them.
+	refs []int64
+	pos  int
+}
+
+func (e *lrukEntry[K, V]) setSlot(slot int) { e.pos = slot }
+func (e *lrukEntry[K, V]) slot() int        { return e.pos }
+
+// LRUKCache evicts the entry whose K-th most recent reference is the oldest,
+// the LRU-K algorithm of O'Neil, O'Neil and Weikum.
+//
+// Plain LRU ranks a key by its last reference alone, so one pass over a large
+// table pushes every hot page out. LRU-K ranks by the K-th last reference
+// instead: a key seen once has no K-th reference at all and ranks below every
+// key that has one, so a scan evicts its own pages first. Entries that have
+// not yet been referenced K times are evicted among themselves in LRU order.
+//
+// An evicted key's reference history is retained for up to size further keys,
+// which is the paper's retained information period: a key that comes back
+// soon after eviction resumes with its history instead of starting over as a
+// one-off. The correlated reference period is not implemented - every access
+// counts as a reference - because the cache sees Get and Add, not the
+// transactions that would tell correlated references apart.
+//
+// Time here is a logical clock advanced by every reference, not the wall
+// clock, so the order is a function of the access sequence alone.
+//
+// It is safe for concurrent use.
+type LRUKCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*lrukEntry[K, V]
+	queue   rankHeap[*lrukEntry[K, V]]
+	// history holds the reference times of recently evicted keys, and
+	// historyOrder the order they were evicted in, so the oldest can be
+	// forgotten once more than size keys are retained.
+	history      map[K]*list.Element
+	historyOrder *list.List
+	size         int
+	k            int
+	clock        int64
+}
+
+// lrukGhost is an evicted key's retained history.
+type lrukGhost[K comparable] struct {
+	key  K
+	refs []int64
+}
+
+// NewLRUKCache returns an LRU-K cache holding up to size entries. k must be at
+// least 1; LRU-1 is plain LRU, and 2 is the setting the algorithm is known for.
+// A size of zero or less means the cache holds nothing.
+func NewLRUKCache[K comparable, V any](size, k int) (*LRUKCache[K, V], error) {
+	if k < 1 {
+		return nil, fmt.Errorf("build lru-k cache: k must be at least 1, got %d", k)
+	}
+	if size < 0 {
+		size = 0
+	}
+
+	c := &LRUKCache[K, V]{
+		entries:      make(map[K]*lrukEntry[K, V], size),
+		history:      make(map[K]*list.Element),
+		historyOrder: list.New(),
+		size:         size,
+		k:            k,
+	}
+	c.queue.less = c.evictsBefore
+	c.queue.reset(size)
+
+	return c, nil
+}
+
+// evictsBefore reports whether a is a better eviction victim than b.
+func (c *LRUKCache[K, V]) evictsBefore(a, b *lrukEntry[K, V]) bool {
+	aFull, bFull := len(a.refs) >= c.k, len(b.refs) >= c.k
+	if aFull != bFull {
+		// A key without K references has an infinite backward K-distance.
+		return !aFull
+	}
+
+	if aFull {
+		// The oldest retained reference is the K-th most recent one.
+		if a.refs[0] != b.refs[0] {
+			return a.refs[0] < b.refs[0]
+		}
+	}
+
+	// Among keys with too little history, and to break ties among the rest,
+	// the least recently used goes first.
+	return a.refs[len(a.refs)-1] < b.refs[len(b.refs)-1]
+}
+
+// referenceLocked records an access to e at the next tick of the clock.
+func (c *LRUKCache[K, V]) referenceLocked(e *lrukEntry[K, V]) {
+	c.clock++
+	if len(e.refs) == c.k {
+		copy(e.refs, e.refs[1:])
+		e.refs[len(e.refs)-1] = c.clock
+
+		return
+	}
+	e.refs = append(e.refs, c.clock)
+}
+
+// evictLocked removes the entry ranked first for eviction and retains its
+// history.
+func (c *LRUKCache[K, V]) evictLocked() {
+	victim := c.queue.min()
+	c.queue.remove(victim)
+	delete(c.entries, victim.key)
+
+	c.historyOrder.PushBack(&lrukGhost[K]{key: victim.key, refs: victim.refs})
+	c.history[victim.key] = c.historyOrder.Back()
+	c.trimHistoryLocked()
+}
+
+// trimHistoryLocked forgets the oldest retained histories beyond size.
+func (c *LRUKCache[K, V]) trimHistoryLocked() {
+	for c.historyOrder.Len() > c.size {
+		oldest := c.historyOrder.Front()
+		ghost, _ := c.historyOrder.Remove(oldest).(*lrukGhost[K])
+		delete(c.history, ghost.key)
+	}
+}
+
+// Add stores a value, reporting whether storing it evicted another entry.
+// Storing counts as a reference, as it does for LRU.
+func (c *LRUKCache[K, V]) Add(key K, value V) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.value = value
+		c.referenceLocked(e)
+		c.queue.fix(e.pos)
+
+		return false
+	}
+
+	if c.size <= 0 {
+		return false
+	}
+
+	evicted := false
+	for len(c.entries) >= c.size {
+		c.evictLocked()
+		evicted = true
+	}
+
+	e := &lrukEntry[K, V]{key: key, value: value, refs: make([]int64, 0, c.k)}
+	if element, ok := c.history[key]; ok {
+		ghost, _ := c.historyOrder.Remove(element).(*lrukGhost[K])
+		delete(c.history, key)
+		e.refs = append(e.refs, ghost.refs...)
+	}
+	c.referenceLocked(e)
+
+	c.entries[key] = e
+	c.queue.push(e)
+
+	return evicted
+}
+
+// Get returns the value for key, if present, and records the reference.
+func (c *LRUKCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	c.referenceLocked(e)
+	c.queue.fix(e.pos)
+
+	return e.value, true
+}
+
+// Peek returns the value for key without recording a reference.
+func (c *LRUKCache[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+// Contains reports whether key is cached, without recording a reference.
+func (c *LRUKCache[K, V]) Contains(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.entries[key]
+
+	return ok
+}
+
+// Remove deletes key, reporting whether it was present. A removed key's
+// history is discarded rather than retained: the caller asked for it gone,
+// which is not the same as the cache running out of room for it.
+func (c *LRUKCache[K, V]) Remove(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+
+	c.queue.remove(e)
+	delete(c.entries, key)
+
+	return true
+}
+
+// Purge empties the cache and forgets every retained history.
+func (c *LRUKCache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[K]*lrukEntry[K, V], c.size)
+	c.queue.reset(c.size)
+	c.history = make(map[K]*list.Element)
+	c.historyOrder.Init()
+}
+
+// Keys returns the cached keys, next eviction victim first.
+func (c *LRUKCache[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ordered := c.queue.ordered()
+	keys := make([]K, 0, len(ordered))
+	for _, e := range ordered {
+		keys = append(keys, e.key)
+	}
+
+	return keys
+}
+
+// Values returns the cached values, in the same order as Keys.
+func (c *LRUKCache[K, V]) Values() []V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ordered := c.queue.ordered()
+	values := make([]V, 0, len(ordered))
+	for _, e := range ordered {
+		values = append(values, e.value)
+	}
+
+	return values
+}
+
+// Len returns the number of cached entries.
+func (c *LRUKCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// Resize changes the capacity, evicting in LRU-K order down to the new size,
+// and returns how many entries it evicted. The retained history is bounded by
+// the capacity too, so it shrinks with it.
+func (c *LRUKCache[K, V]) Resize(size int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if size < 0 {
+		size = 0
+	}
+	c.size = size
+
+	evicted := 0
+	for len(c.entries) > c.size {
+		c.evictLocked()
+		evicted++
+	}
+	c.trimHistoryLocked()
+
+	return evicted
+}
+
+// Cap returns the capacity.
+func (c *LRUKCache[K, V]) Cap() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+// K returns how many references the cache ranks a key by.
+func (c *LRUKCache[K, V]) K() int {
+	return c.k
+}
+
+var _ ascache.Cacher[string, int] = (*LRUKCache[string, int])(nil)
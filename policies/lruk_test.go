@@ -0,0 +1,82 @@
+package policies_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sshaplygin/as-cache/policies"
+)
+
+func TestNewLRUK_RejectsKBelowOne(t *testing.T) {
+	_, err := policies.NewLRUK[string, int](10, 0)
+	require.Error(t, err)
+}
+
+// TestLRUK_ScanDoesNotFlushTheWorkingSet is the property LRU-2 exists for: a
+// one-pass scan over cold keys evicts the scan's own keys, not the ones that
+// have been referenced twice.
+func TestLRUK_ScanDoesNotFlushTheWorkingSet(t *testing.T) {
+	c, err := policies.NewLRUKCache[string, int](4, 2)
+	require.NoError(t, err)
+
+	for _, key := range []string{"a", "b"} {
+		c.Add(key, 1)
+		c.Get(key)
+	}
+
+	for i := 0; i < 20; i++ {
+		c.Add("scan-"+strconv.Itoa(i), i)
+	}
+
+	assert.True(t, c.Contains("a"), "a key referenced twice must survive a scan")
+	assert.True(t, c.Contains("b"), "a key referenced twice must survive a scan")
+
+	lru, err := policies.NewLRUKCache[string, int](4, 1)
+	require.NoError(t, err)
+	for _, key := range []string{"a", "b"} {
+		lru.Add(key, 1)
+		lru.Get(key)
+	}
+	for i := 0; i < 20; i++ {
+		lru.Add("scan-"+strconv.Itoa(i), i)
+	}
+
+	assert.False(t, lru.Contains("a"), "k=1 is plain LRU, which a scan defeats")
+}
+
+// TestLRUK_RetainsHistoryAcrossEviction checks the retained information period:
+// a key evicted and soon re-admitted resumes with its reference history, so
+// its second reference after re-admission already makes it a K-referenced key.
+func TestLRUK_RetainsHistoryAcrossEviction(t *testing.T) {
+	c, err := policies.NewLRUKCache[string, int](2, 2)
+	require.NoError(t, err)
+
+	c.Add("hot", 1)
+	c.Add("x", 1)
+	c.Add("y", 1) // evicts hot, the least recently used of two one-off keys
+	require.False(t, c.Contains("hot"))
+
+	// Re-admitted: with its retained reference it now has two, and outranks
+	// y, which has one.
+	c.Add("hot", 1)
+	c.Add("z", 1)
+
+	assert.True(t, c.Contains("hot"), "a re-admitted key must resume its history")
+	assert.False(t, c.Contains("y"))
+}
+
+func TestLRUK_KeysListTheNextVictimFirst(t *testing.T) {
+	c, err := policies.NewLRUKCache[string, int](3, 2)
+	require.NoError(t, err)
+
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Add("c", 3)
+	c.Get("a")
+
+	assert.Equal(t, []string{"b", "c", "a"}, c.Keys())
+	assert.Equal(t, []int{2, 3, 1}, c.Values())
+}
@@ -0,0 +1,124 @@
+package policies
+
+import "slices"
+
+// ranked is an entry that knows its own position in a rankHeap, so it can be
+// re-ranked or removed in place rather than searched for.
+type ranked interface {
+	setSlot(slot int)
+	slot() int
+}
+
+// rankHeap is a binary min-heap of entries ordered by less: the root is the
+// next entry to evict. It backs the policies whose eviction order is a score
+// rather than a list position, where an access changes one entry's score and
+// the heap has to be repaired around it in O(log n).
+//
+// It is written out rather than built on container/heap because every
+// operation here goes through the entry's recorded slot, and container/heap's
+// interface{} round trip would allocate on each Push for nothing.
+type rankHeap[E ranked] struct {
+	items []E
+	less  func(a, b E) bool
+}
+
+func (h *rankHeap[E]) len() int { return len(h.items) }
+
+// push adds an entry.
+func (h *rankHeap[E]) push(e E) {
+	e.setSlot(len(h.items))
+	h.items = append(h.items, e)
+	h.up(len(h.items) - 1)
+}
+
+// min returns the entry that would be evicted next. The heap must not be
+// empty.
+func (h *rankHeap[E]) min() E { return h.items[0] }
+
+// remove takes an entry out of the heap wherever it is.
+func (h *rankHeap[E]) remove(e E) {
+	slot := e.slot()
+	last := len(h.items) - 1
+	if slot != last {
+		h.swap(slot, last)
+	}
+
+	var zero E
+	h.items[last] = zero
+	h.items = h.items[:last]
+
+	if slot != last {
+		h.fix(slot)
+	}
+}
+
+// fix restores the ordering after the entry at slot changed its rank.
+func (h *rankHeap[E]) fix(slot int) {
+	if !h.down(slot) {
+		h.up(slot)
+	}
+}
+
+// reset empties the heap. A new slice rather than a truncation, for the same
+// reason RandomCache.Purge gives: a truncated backing array keeps every entry
+// reachable.
+func (h *rankHeap[E]) reset(capacity int) {
+	h.items = make([]E, 0, capacity)
+}
+
+// ordered returns every entry, next victim first. It sorts a copy, so it costs
+// O(n log n) and is meant for Keys and Values, not for the hot path.
+func (h *rankHeap[E]) ordered() []E {
+	entries := slices.Clone(h.items)
+	slices.SortFunc(entries, func(a, b E) int {
+		switch {
+		case h.less(a, b):
+			return -1
+		case h.less(b, a):
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return entries
+}
+
+func (h *rankHeap[E]) swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].setSlot(i)
+	h.items[j].setSlot(j)
+}
+
+func (h *rankHeap[E]) up(slot int) {
+	for slot > 0 {
+		parent := (slot - 1) / 2
+		if !h.less(h.items[slot], h.items[parent]) {
+			return
+		}
+		h.swap(slot, parent)
+		slot = parent
+	}
+}
+
+// down sifts the entry at slot towards the leaves and reports whether it
+// moved.
+func (h *rankHeap[E]) down(slot int) bool {
+	start := slot
+	for {
+		child := 2*slot + 1
+		if child >= len(h.items) {
+			break
+		}
+		if right := child + 1; right < len(h.items) && h.less(h.items[right], h.items[child]) {
+			child = right
+		}
+		if !h.less(h.items[child], h.items[slot]) {
+			break
+		}
+		h.swap(slot, child)
+		slot = child
+	}
+
+	return slot > start
+}
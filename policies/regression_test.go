@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -147,6 +148,20 @@ func TestTTLDoesNotLeakGoroutines(t *testing.T) {
 		"constructing 50 TTL policies must not leave goroutines behind (before %d, after %d)", before, after)
 }
 
+// adaptedTwoQueue is hashicorp's 2Q through Adapt. NewTwoQueue no longer
+// needs the adapter, but hashicorp's 2Q is still the most awkward cache it
+// serves, which makes it the one to test the adapter with.
+func adaptedTwoQueue(t *testing.T, size int) *policies.AdaptedCache[string, int] {
+	t.Helper()
+
+	p, err := policies.Adapt[string, int](size, func(size int) (policies.PartialCacher[string, int], error) {
+		return lru.New2Q[string, int](size)
+	})
+	require.NoError(t, err)
+
+	return p
+}
+
 // TestAdaptedResizeSurvivorsAreIntact pins down what a shrinking Resize does
 // guarantee. It cannot guarantee WHICH entries survive: Keys() means different
 // things per implementation - 2Q returns frequent-then-recent, ARC returns
@@ -155,8 +170,7 @@ func TestTTLDoesNotLeakGoroutines(t *testing.T) {
 // up within capacity and every survivor carries the value it was stored with,
 // never a zero or another key's value.
 func TestAdaptedResizeSurvivorsAreIntact(t *testing.T) {
-	p, err := policies.NewTwoQueue[string, int](200)
-	require.NoError(t, err)
+	p := adaptedTwoQueue(t, 200)
 
 	// Promote a small set into 2Q's frequent queue by touching it repeatedly.
 	hot := make([]string, 10)
@@ -200,8 +214,7 @@ func TestAdaptedResizeSurvivorsAreIntact(t *testing.T) {
 // reported the new one. hashicorp's 2Q rejects a size of 1, so this is
 // reachable rather than hypothetical.
 func TestAdaptedResizeEnforcesCapacityWhenRebuildFails(t *testing.T) {
-	p, err := policies.NewTwoQueue[string, int](50)
-	require.NoError(t, err)
+	p := adaptedTwoQueue(t, 50)
 
 	for i := 0; i < 50; i++ {
 		p.Add("key-"+strconv.Itoa(i), i)
@@ -221,8 +234,8 @@ func TestAdaptedResizeEnforcesCapacityWhenRebuildFails(t *testing.T) {
 	assert.LessOrEqual(t, p.Len(), 1, "capacity must stay enforced after further Adds")
 }
 
-// TestPolicyTypeNamesRoundTrip checks the regenerated stringer output covers
-// every policy this repository ships.
+// TestPolicyTypeNamesRoundTrip checks every policy this repository ships has a
+// name, and that the name parses back to the same PolicyType.
 func TestPolicyTypeNamesRoundTrip(t *testing.T) {
 	for policyType, want := range map[ascache.PolicyType]string{
 		ascache.LRU:      "LRU",
@@ -232,7 +245,13 @@ func TestPolicyTypeNamesRoundTrip(t *testing.T) {
 		ascache.Random:   "Random",
 		ascache.TTL:      "TTL",
 		ascache.TinyLFU:  "TinyLFU",
+		ascache.LRUK:     "LRUK",
+		ascache.LRFU:     "LRFU",
 	} {
 		assert.Equal(t, want, policyType.String())
+
+		parsed, ok := ascache.ParsePolicyType(want)
+		assert.True(t, ok, want)
+		assert.Equal(t, policyType, parsed)
 	}
 }
@@ -0,0 +1,359 @@
+package policies
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"sync"
+
+	ascache "github.com/sshaplygin/as-cache"
+)
+
+const (
+	// DefaultTwoQueueRecentRatio is the share of the capacity the recent queue
+	// may hold before it gives way to the frequent one. It matches
+	// hashicorp/golang-lru's 2Q, which this implementation replaces.
+	DefaultTwoQueueRecentRatio = 0.25
+
+	// DefaultTwoQueueGhostRatio is the number of evicted keys remembered, as
+	// a share of the capacity.
+	DefaultTwoQueueGhostRatio = 0.50
+)
+
+// twoQueueEntry is a resident key. Which queue it is in is recorded so a hit
+// knows whether it promotes.
+type twoQueueEntry[K comparable, V any] struct {
+	key      K
+	value    V
+	frequent bool
+}
+
+// TwoQueueCache is the 2Q algorithm of Johnson and Shasha, in the full
+// version with a ghost queue.
+//
+// A key seen for the first time goes into a small recent queue, and is evicted
+// from there in FIFO order unless it is seen again while resident. Seen again,
+// it moves to the frequent queue, which is LRU. A one-pass scan therefore
+// churns through the recent queue and never touches the working set in the
+// frequent one, which is the property that makes 2Q worth holding next to LRU.
+// Keys evicted from the recent queue are remembered, without their values, in
+// a ghost queue: one that comes back while still remembered was evidently not
+// a one-off, and goes straight to the frequent queue.
+//
+// Unlike the hashicorp cache it replaces, it resizes in place - the queues are
+// trimmed, and the ghost queue and the split between recent and frequent
+// survive - and it reports exactly what it evicts. The adaptive layer resizes
+// an arm every time it is demoted or promoted, so a rebuild there would throw
+// away what the ghost queue had learned on every switch.
+//
+// It is safe for concurrent use.
+type TwoQueueCache[K comparable, V any] struct {
+	mu       sync.Mutex
+	entries  map[K]*list.Element
+	recent   *list.List
+	frequent *list.List
+	// ghosts holds the keys most recently evicted from the recent queue,
+	// newest at the front, and ghostIndex finds them by key.
+	ghosts     *list.List
+	ghostIndex map[K]*list.Element
+
+	size        int
+	recentRatio float64
+	ghostRatio  float64
+	recentSize  int
+	ghostSize   int
+}
+
+// NewTwoQueueCache returns a 2Q cache holding up to size entries.
+// recentRatio is the share of the capacity the recent queue may hold before
+// the frequent queue is asked to give way, and ghostRatio the number of
+// evicted keys remembered as a share of the capacity; both must lie in [0,1].
+// DefaultTwoQueueRecentRatio and DefaultTwoQueueGhostRatio are the usual
+// settings. A size of zero or less means the cache holds nothing.
+func NewTwoQueueCache[K comparable, V any](
+	size int,
+	recentRatio, ghostRatio float64,
+) (*TwoQueueCache[K, V], error) {
+	if !unitInterval(recentRatio) {
+		return nil, fmt.Errorf("build 2q cache: recent ratio must be in [0,1], got %v", recentRatio)
+	}
+	if !unitInterval(ghostRatio) {
+		return nil, fmt.Errorf("build 2q cache: ghost ratio must be in [0,1], got %v", ghostRatio)
+	}
+
+	c := &TwoQueueCache[K, V]{
+		recentRatio: recentRatio,
+		ghostRatio:  ghostRatio,
+	}
+	c.resetLocked()
+	c.setSizeLocked(size)
+
+	return c, nil
+}
+
+func unitInterval(ratio float64) bool {
+	return !math.IsNaN(ratio) && ratio >= 0 && ratio <= 1
+}
+
+// setSizeLocked records a capacity and the queue sizes derived from it.
+func (c *TwoQueueCache[K, V]) setSizeLocked(size int) {
+	if size < 0 {
+		size = 0
+	}
+	c.size = size
+	c.recentSize = int(float64(size) * c.recentRatio)
+	c.ghostSize = int(float64(size) * c.ghostRatio)
+}
+
+func (c *TwoQueueCache[K, V]) resetLocked() {
+	c.entries = make(map[K]*list.Element)
+	c.recent = list.New()
+	c.frequent = list.New()
+	c.ghosts = list.New()
+	c.ghostIndex = make(map[K]*list.Element)
+}
+
+func entryOf[K comparable, V any](element *list.Element) *twoQueueEntry[K, V] {
+	entry, _ := element.Value.(*twoQueueEntry[K, V])
+
+	return entry
+}
+
+// evictLocked removes one resident entry to make room.
+//
+// The recent queue gives way while it holds more than its share. At exactly
+// its share it gives way too, unless the entry being made room for is a ghost
+// returning to the frequent queue: that entry is evidence the frequent queue
+// deserves to grow, so it is the frequent queue that shrinks. Only keys
+// evicted from the recent queue become ghosts; a key evicted from the
+// frequent queue has already had its second chance.
+func (c *TwoQueueCache[K, V]) evictLocked(forGhost bool) {
+	recentLen := c.recent.Len()
+	if recentLen > 0 &&
+		(recentLen > c.recentSize || (recentLen == c.recentSize && !forGhost) || c.frequent.Len() == 0) {
+		victim := c.recent.Back()
+		entry := entryOf[K, V](victim)
+		c.recent.Remove(victim)
+		delete(c.entries, entry.key)
+		c.rememberLocked(entry.key)
+
+		return
+	}
+
+	victim := c.frequent.Back()
+	c.frequent.Remove(victim)
+	delete(c.entries, entryOf[K, V](victim).key)
+}
+
+// rememberLocked adds a key to the ghost queue, forgetting the oldest ghost
+// once the queue is over its size.
+func (c *TwoQueueCache[K, V]) rememberLocked(key K) {
+	if c.ghostSize <= 0 {
+		return
+	}
+
+	c.ghostIndex[key] = c.ghosts.PushFront(key)
+	c.trimGhostsLocked()
+}
+
+func (c *TwoQueueCache[K, V]) trimGhostsLocked() {
+	for c.ghosts.Len() > c.ghostSize {
+		key, _ := c.ghosts.Remove(c.ghosts.Back()).(K)
+		delete(c.ghostIndex, key)
+	}
+}
+
+// Add stores a value, reporting whether storing it evicted another entry.
+func (c *TwoQueueCache[K, V]) Add(key K, value V) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := entryOf[K, V](element)
+		entry.value = value
+		c.promoteLocked(element, entry)
+
+		return false
+	}
+
+	if c.size <= 0 {
+		return false
+	}
+
+	ghost, wasGhost := c.ghostIndex[key]
+	if wasGhost {
+		c.ghosts.Remove(ghost)
+		delete(c.ghostIndex, key)
+	}
+
+	evicted := false
+	for len(c.entries) >= c.size {
+		c.evictLocked(wasGhost)
+		evicted = true
+	}
+
+	entry := &twoQueueEntry[K, V]{key: key, value: value, frequent: wasGhost}
+	if wasGhost {
+		c.entries[key] = c.frequent.PushFront(entry)
+	} else {
+		c.entries[key] = c.recent.PushFront(entry)
+	}
+
+	return evicted
+}
+
+// promoteLocked records a hit: a recent entry moves to the frequent queue, a
+// frequent one to its front.
+func (c *TwoQueueCache[K, V]) promoteLocked(element *list.Element, entry *twoQueueEntry[K, V]) {
+	if entry.frequent {
+		c.frequent.MoveToFront(element)
+
+		return
+	}
+
+	c.recent.Remove(element)
+	entry.frequent = true
+	c.entries[entry.key] = c.frequent.PushFront(entry)
+}
+
+// Get returns the value for key, if present, and records the access.
+func (c *TwoQueueCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	entry := entryOf[K, V](element)
+	c.promoteLocked(element, entry)
+
+	return entry.value, true
+}
+
+// Peek returns the value for key without recording an access.
+func (c *TwoQueueCache[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	return entryOf[K, V](element).value, true
+}
+
+// Contains reports whether key is cached, without recording an access.
+func (c *TwoQueueCache[K, V]) Contains(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.entries[key]
+
+	return ok
+}
+
+// Remove deletes key, reporting whether it was present. A removed key is not
+// remembered as a ghost: it was not evicted, so its return says nothing about
+// the queues.
+func (c *TwoQueueCache[K, V]) Remove(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+
+	if entryOf[K, V](element).frequent {
+		c.frequent.Remove(element)
+	} else {
+		c.recent.Remove(element)
+	}
+	delete(c.entries, key)
+
+	return true
+}
+
+// Purge empties the cache and forgets its ghosts.
+func (c *TwoQueueCache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resetLocked()
+}
+
+// Keys returns the cached keys: the recent queue oldest first, then the
+// frequent queue least recently used first.
+func (c *TwoQueueCache[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]K, 0, len(c.entries))
+	for _, queue := range []*list.List{c.recent, c.frequent} {
+		for element := queue.Back(); element != nil; element = element.Prev() {
+			keys = append(keys, entryOf[K, V](element).key)
+		}
+	}
+
+	return keys
+}
+
+// Values returns the cached values, in the same order as Keys.
+func (c *TwoQueueCache[K, V]) Values() []V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	values := make([]V, 0, len(c.entries))
+	for _, queue := range []*list.List{c.recent, c.frequent} {
+		for element := queue.Back(); element != nil; element = element.Prev() {
+			values = append(values, entryOf[K, V](element).value)
+		}
+	}
+
+	return values
+}
+
+// Len returns the number of cached entries.
+func (c *TwoQueueCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// Resize changes the capacity in place and returns how many entries it
+// evicted. Entries are evicted by the same rule as when making room, so the
+// evicted recent keys are remembered, and the ghost queue is trimmed to its
+// new share of the capacity.
+func (c *TwoQueueCache[K, V]) Resize(size int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setSizeLocked(size)
+
+	evicted := 0
+	for len(c.entries) > c.size {
+		c.evictLocked(false)
+		evicted++
+	}
+	c.trimGhostsLocked()
+
+	return evicted
+}
+
+// Cap returns the capacity.
+func (c *TwoQueueCache[K, V]) Cap() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+var _ ascache.Cacher[string, int] = (*TwoQueueCache[string, int])(nil)
@@ -0,0 +1,119 @@
+package policies_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sshaplygin/as-cache/policies"
+)
+
+func newTwoQueue(t *testing.T, size int) *policies.TwoQueueCache[string, int] {
+	t.Helper()
+
+	c, err := policies.NewTwoQueueCache[string, int](size,
+		policies.DefaultTwoQueueRecentRatio, policies.DefaultTwoQueueGhostRatio)
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestNewTwoQueue_RejectsRatiosOutsideTheUnitInterval(t *testing.T) {
+	for _, ratio := range []float64{-0.1, 1.1, math.NaN()} {
+		_, err := policies.NewTwoQueueCache[string, int](10, ratio, 0.5)
+		assert.Error(t, err, "recent ratio %v", ratio)
+
+		_, err = policies.NewTwoQueueCache[string, int](10, 0.25, ratio)
+		assert.Error(t, err, "ghost ratio %v", ratio)
+	}
+}
+
+func TestTwoQueue_ScanDoesNotFlushTheFrequentQueue(t *testing.T) {
+	c := newTwoQueue(t, 8)
+
+	for _, key := range []string{"a", "b", "c"} {
+		c.Add(key, 1)
+		c.Get(key)
+	}
+	for i := 0; i < 100; i++ {
+		c.Add("scan-"+strconv.Itoa(i), i)
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		assert.True(t, c.Contains(key), "%s was referenced twice and must survive a scan", key)
+	}
+}
+
+// TestTwoQueue_GhostReturnsToTheFrequentQueue checks the ghost queue is doing
+// its job: a key evicted from the recent queue and soon added again has shown
+// it is not a one-off, and must outlast a scan that follows.
+func TestTwoQueue_GhostReturnsToTheFrequentQueue(t *testing.T) {
+	c := newTwoQueue(t, 4)
+
+	c.Add("returning", 1)
+	for i := 0; i < 4; i++ {
+		c.Add("filler-"+strconv.Itoa(i), i)
+	}
+	require.False(t, c.Contains("returning"))
+
+	c.Add("returning", 2)
+	for i := 0; i < 20; i++ {
+		c.Add("scan-"+strconv.Itoa(i), i)
+	}
+
+	got, ok := c.Get("returning")
+	require.True(t, ok, "a returning ghost goes to the frequent queue, out of the scan's way")
+	assert.Equal(t, 2, got)
+}
+
+func TestTwoQueue_ReportsEvictionsExactly(t *testing.T) {
+	c := newTwoQueue(t, 2)
+
+	assert.False(t, c.Add("a", 1))
+	assert.False(t, c.Add("b", 2))
+	assert.False(t, c.Add("a", 3), "overwriting must not report an eviction")
+	assert.True(t, c.Add("c", 4))
+	assert.Equal(t, 2, c.Len())
+
+	assert.Equal(t, 1, c.Resize(1), "Resize must report what it evicted")
+	assert.Equal(t, 1, c.Len())
+	assert.Zero(t, c.Resize(10), "growing evicts nothing")
+}
+
+// TestTwoQueue_ResizeKeepsTheGhostQueue is the reason for the native
+// implementation: demotion and promotion resize an arm, and a rebuild would
+// forget every ghost.
+func TestTwoQueue_ResizeKeepsTheGhostQueue(t *testing.T) {
+	c := newTwoQueue(t, 8)
+
+	c.Add("returning", 1)
+	for i := 0; i < 8; i++ {
+		c.Add("filler-"+strconv.Itoa(i), i)
+	}
+	require.False(t, c.Contains("returning"))
+
+	// Make room first, so the shrink itself evicts nothing: evicted keys
+	// become ghosts too, and would push this one out of the smaller queue.
+	for i := 0; i < 4; i++ {
+		c.Remove("filler-" + strconv.Itoa(i))
+	}
+	require.Zero(t, c.Resize(4))
+	c.Resize(8)
+
+	c.Add("returning", 2)
+	for i := 0; i < 40; i++ {
+		c.Add("scan-"+strconv.Itoa(i), i)
+	}
+
+	assert.True(t, c.Contains("returning"), "the ghost must survive a shrink and a regrow")
+}
+
+func TestTwoQueue_ZeroCapacityHoldsNothing(t *testing.T) {
+	c := newTwoQueue(t, 0)
+
+	assert.False(t, c.Add("a", 1))
+	assert.Zero(t, c.Len())
+}
@@ -0,0 +1,107 @@
+package ascache
+
+import "fmt"
+
+// UpdateSettings changes the settings of a running cache. update is given a
+// copy of the settings the cache will run with next - those in force, or the
+// ones an earlier call left waiting - and changes it; the result is validated
+// as NewAdaptiveCache validates settings, and takes effect at the start of
+// the next epoch, all at once. Until then Settings reports the old values.
+//
+// Applying at the boundary is what makes an update safe to make at any time:
+// every decision an epoch makes - the gates it applies, the strategy it
+// migrates with, whether it acts at all - is made under one set of settings,
+// never half of one and half of the next. It also means a retune is judged on
+// the traffic that follows it, not on an epoch measured under the old values.
+//
+// Most fields can change. These cannot, and a change to them is rejected with
+// an *ImmutableSettingError naming the field, which matches
+// ErrImmutableSetting:
+//
+//   - ShadowSampleRate and MinShadowCapacity, which fixed the size of every
+//     shadow and the substream they measure. Changing them would compare
+//     evidence gathered at one scale with evidence gathered at another.
+//   - EpochDuration, between zero and non-zero: a cache built without an
+//     epoch clock has no ticker to retime, and one built with a clock keeps
+//     it. Any positive duration may replace another.
+//
+// Turning ObserveOnly off on a cache built without a bandit is rejected with
+// ErrNilBandit, since there is nothing to select a policy. update runs with no
+// lock held and may read the cache; calls are serialised, so one never
+// overwrites another's change. A rejected update leaves everything as it was.
+func (c *AdaptiveCache[K, V]) UpdateSettings(update func(*Settings)) error {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+
+	c.mu.RLock()
+	current := *c.settings
+	next := current
+	if c.pending != nil {
+		next = *c.pending
+	}
+	c.mu.RUnlock()
+
+	update(&next)
+
+	if err := next.validate(); err != nil {
+		return err
+	}
+	if err := c.checkImmutable(current, next); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending = &next
+
+	return nil
+}
+
+// checkImmutable rejects next when it changes what the cache fixed at
+// construction.
+func (c *AdaptiveCache[K, V]) checkImmutable(current, next Settings) error {
+	if next.ShadowSampleRate != current.ShadowSampleRate {
+		return &ImmutableSettingError{Field: "ShadowSampleRate"}
+	}
+	if next.MinShadowCapacity != current.MinShadowCapacity {
+		return &ImmutableSettingError{Field: "MinShadowCapacity"}
+	}
+	if (next.EpochDuration > 0) != (current.EpochDuration > 0) {
+		// Only turning the clock on or off is refused; any positive duration
+		// may replace another.
+		return &ImmutableSettingError{Field: "EpochDuration"}
+	}
+	if _, observer := c.bandit.(observerBandit); observer && !next.ObserveOnly {
+		return fmt.Errorf("%w: ObserveOnly cannot be turned off on a cache built without one", ErrNilBandit)
+	}
+
+	return nil
+}
+
+// applyPendingLocked puts the settings UpdateSettings accepted into force. It
+// must be called while the write lock is held, at the start of an epoch.
+func (c *AdaptiveCache[K, V]) applyPendingLocked() {
+	if c.pending == nil {
+		return
+	}
+
+	previous := c.settings
+	c.settings, c.pending = c.pending, nil
+
+	if c.epochTicker != nil && c.settings.EpochDuration != previous.EpochDuration {
+		c.epochTicker.Reset(c.settings.EpochDuration)
+	}
+	if c.settings.EpochRequests != previous.EpochRequests {
+		// The count restarts, or one already past a lowered limit would never
+		// equal it again and request-driven epochs would stop.
+		c.epochLimit.Store(c.settings.EpochRequests)
+		c.epochRequests.Store(0)
+	}
+	if !c.settings.Damping.enabled() {
+		// A level left standing would go on lengthening the cooldown with
+		// nothing left to relax it.
+		c.damping.level = 0
+		c.damping.recent = nil
+	}
+}
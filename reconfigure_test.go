@@ -0,0 +1,119 @@
+package ascache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateSettings_AppliesAtTheNextEpochBoundary(t *testing.T) {
+	ac, _, lfu, bandit := makeCache(t, MigrationCold)
+
+	require.NoError(t, ac.UpdateSettings(func(s *Settings) { s.MigrationStrategy = MigrationWarm }))
+	require.NoError(t, ac.UpdateSettings(func(s *Settings) { s.HistorySize = 3 }))
+	assert.Equal(t, MigrationCold, ac.Settings().MigrationStrategy, "nothing changes mid-epoch")
+
+	ac.Add("a", 1)
+	bandit.next = LFU
+	ac.runEpoch()
+
+	settings := ac.Settings()
+	assert.Equal(t, MigrationWarm, settings.MigrationStrategy)
+	assert.Equal(t, 3, settings.HistorySize, "a second update builds on the first")
+	assert.Equal(t, LFU, ac.ActivePolicy())
+	assert.Equal(t, 1, lfu.Len(), "the epoch that applied the update migrated under it")
+}
+
+func TestUpdateSettings_RejectsWhatNewAdaptiveCacheWould(t *testing.T) {
+	ac, _, _, _ := makeCache(t, MigrationCold)
+
+	assert.ErrorIs(t, ac.UpdateSettings(func(s *Settings) { s.SwitchConfidence = 1.5 }), ErrInvalidSwitchConfidence)
+	assert.ErrorIs(t, ac.UpdateSettings(func(s *Settings) { s.Damping.Switches = 1 }), ErrInvalidDamping)
+
+	ac.runEpoch()
+	assert.Zero(t, ac.Settings().SwitchConfidence, "a rejected update leaves nothing pending")
+}
+
+func TestUpdateSettings_RejectsImmutableFields(t *testing.T) {
+	ac, _, _, _ := makeCache(t, MigrationCold)
+
+	for field, update := range map[string]func(*Settings){
+		"ShadowSampleRate":  func(s *Settings) { s.ShadowSampleRate = 0.1 },
+		"MinShadowCapacity": func(s *Settings) { s.MinShadowCapacity = 8 },
+		"EpochDuration":     func(s *Settings) { s.EpochDuration, s.EpochRequests = 0, 100 },
+	} {
+		err := ac.UpdateSettings(update)
+		assert.ErrorIs(t, err, ErrImmutableSetting, field)
+
+		var immutable *ImmutableSettingError
+		require.ErrorAs(t, err, &immutable, field)
+		assert.Equal(t, field, immutable.Field)
+	}
+
+	require.NoError(t, ac.UpdateSettings(func(s *Settings) { s.EpochDuration = time.Hour }))
+	ac.runEpoch()
+	assert.Equal(t, time.Hour, ac.Settings().EpochDuration)
+}
+
+func TestUpdateSettings_TogglesObserveOnly(t *testing.T) {
+	ac, _, _, bandit := makeCache(t, MigrationCold)
+	bandit.next = LFU
+
+	require.NoError(t, ac.UpdateSettings(func(s *Settings) { s.ObserveOnly = true }))
+	ac.runEpoch()
+	assert.Equal(t, LRU, ac.ActivePolicy(), "observing from the epoch the update applied at")
+
+	require.NoError(t, ac.UpdateSettings(func(s *Settings) { s.ObserveOnly = false }))
+	ac.runEpoch()
+	assert.Equal(t, LFU, ac.ActivePolicy())
+
+	observer, _, _ := makeObserver(t)
+	assert.ErrorIs(t, observer.UpdateSettings(func(s *Settings) { s.ObserveOnly = false }), ErrNilBandit,
+		"a cache built without a bandit has nothing to act on")
+}
+
+func TestUpdateSettings_RetimesRequestDrivenEpochs(t *testing.T) {
+	bandit := &countingBandit{next: LRU}
+	cache := newRequestDrivenCache(t, 10, bandit)
+
+	for range 3 {
+		cache.Get("k")
+	}
+	require.NoError(t, cache.UpdateSettings(func(s *Settings) { s.EpochRequests = 2 }))
+	for range 7 {
+		cache.Get("k")
+	}
+	require.Equal(t, 1, bandit.count(), "the old limit ends the epoch that applies the new one")
+
+	for range 6 {
+		cache.Get("k")
+	}
+	assert.Equal(t, 4, bandit.count(), "then an epoch every 2 Gets")
+}
+
+func TestUpdateSettings_IsSafeAlongsideTraffic(t *testing.T) {
+	bandit := &countingBandit{next: LFU}
+	cache := newRequestDrivenCache(t, 5, bandit)
+
+	var wg sync.WaitGroup
+	for g := range 4 {
+		wg.Go(func() {
+			for i := range 500 {
+				cache.Add("k", g*i)
+				cache.Get("k")
+			}
+		})
+	}
+	for i := range 50 {
+		require.NoError(t, cache.UpdateSettings(func(s *Settings) {
+			s.EpochRequests = int64(3 + i%5)
+			s.MigrationStrategy = MigrationStrategy(i%3 + 1)
+		}))
+	}
+	wg.Wait()
+
+	assert.Positive(t, bandit.count())
+}
@@ -0,0 +1,182 @@
+package ascache
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// firstRegistered is the first PolicyType the registry hands out. Every value
+// below it is reserved for the policies this module defines, so a built-in
+// added in a later release can never land on a value a running program has
+// already allocated to something else.
+const firstRegistered PolicyType = 1 << 16
+
+// builtinNames names the policies this module defines, indexed by PolicyType.
+//
+// The names are part of the contract rather than a presentation detail: they
+// appear in Advice, in metrics, and in the fingerprint a distributed bandit
+// pools by. The keyed literal keeps each name next to its constant, so adding
+// a policy to models.go without naming it here leaves a gap the tests catch
+// instead of shifting every name after it by one.
+var builtinNames = [...]string{
+	Undefined: "Undefined",
+	LRU:       "LRU",
+	LFU:       "LFU",
+	TwoQueue:  "TwoQueue",
+	ARC:       "ARC",
+	Random:    "Random",
+	TTL:       "TTL",
+	TinyLFU:   "TinyLFU",
+	LRUK:      "LRUK",
+	LRFU:      "LRFU",
+}
+
+// registration is one PolicyType allocated at run time.
+type registration struct {
+	name string
+	// base is the algorithm a variant parameterises, and variant the name of
+	// the parameterisation. Both are zero for a type that is not a variant.
+	base    PolicyType
+	variant string
+}
+
+// registry holds every PolicyType allocated at run time. Entries are never
+// removed, so a PolicyType stays valid, and keeps its name, for the life of the
+// process.
+var registry struct {
+	mu      sync.RWMutex
+	byName  map[string]PolicyType
+	entries []registration
+}
+
+// String returns the policy's name: the constant's name for a built-in, the
+// registered name otherwise, and PolicyType(n) for a value nothing defines.
+func (p PolicyType) String() string {
+	if p.Builtin() {
+		return builtinNames[p]
+	}
+
+	if r, ok := lookup(p); ok {
+		return r.name
+	}
+
+	return "PolicyType(" + strconv.FormatUint(uint64(p), 10) + ")"
+}
+
+// Builtin reports whether the policy is one this module defines, rather than
+// one allocated at run time.
+func (p PolicyType) Builtin() bool {
+	return p < PolicyType(len(builtinNames))
+}
+
+// ParsePolicyType returns the PolicyType with the given name - a built-in's
+// constant name, or a name registered in this process - and reports whether
+// there is one.
+func ParsePolicyType(name string) (PolicyType, bool) {
+	for p, builtin := range builtinNames {
+		if builtin == name {
+			return PolicyType(p), true
+		}
+	}
+
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	p, ok := registry.byName[name]
+
+	return p, ok
+}
+
+// RegisterPolicyType allocates a PolicyType for a policy this module does not
+// define, so a cache of your own wrapped with NewCache reports under its own
+// name rather than borrowing a built-in's or showing up as PolicyType(42):
+//
+//	var Clock = ascache.RegisterPolicyType("Clock")
+//
+//	policy := ascache.NewCache[string, int](myclock.New(n), Clock, n)
+//
+// The name is what String returns, and it is what the distributed bandit's
+// fingerprint and the Valkey and Redis store identify the policy by, so every
+// replica of a fleet must register it under the same name. The number behind
+// it is allocated in this process and need not match across processes.
+//
+// It is meant for package-level variables and init functions, and like
+// regexp.MustCompile it panics rather than returning an error: a name that is
+// malformed or already taken - by a built-in, or by an earlier registration -
+// is a programming error that should stop the program at start-up, not an
+// arm that silently shares another's counters. Names must start with a
+// letter, and be at most 32 characters of letters, digits and _-.=+.
+//
+// It is safe for concurrent use.
+func RegisterPolicyType(name string) PolicyType {
+	if err := validPolicyName(name); err != nil {
+		panic(err)
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, taken := registry.byName[name]; taken || isBuiltinName(name) {
+		panic(fmt.Errorf("%w: %q is already registered", ErrInvalidPolicyName, name))
+	}
+
+	return registerLocked(registration{name: name})
+}
+
+// validPolicyName reports why a name is unusable for RegisterPolicyType. The
+// rules are a variant's, for the same reasons, plus a leading letter: that
+// keeps a name from ever being read as the number a built-in is stored as.
+func validPolicyName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: name must not be empty", ErrInvalidPolicyName)
+	}
+
+	first := name[0]
+	if (first < 'a' || first > 'z') && (first < 'A' || first > 'Z') {
+		return fmt.Errorf("%w: name %q must start with a letter", ErrInvalidPolicyName, name)
+	}
+
+	return validName(ErrInvalidPolicyName, name)
+}
+
+func isBuiltinName(name string) bool {
+	for _, builtin := range builtinNames {
+		if builtin == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookup returns a run-time allocated type's registration.
+func lookup(p PolicyType) (registration, bool) {
+	if p < firstRegistered {
+		return registration{}, false
+	}
+
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	index := int(p - firstRegistered)
+	if index >= len(registry.entries) {
+		return registration{}, false
+	}
+
+	return registry.entries[index], true
+}
+
+// registerLocked allocates the next PolicyType for r. The caller holds the
+// registry's write lock and has checked the name is free.
+func registerLocked(r registration) PolicyType {
+	if registry.byName == nil {
+		registry.byName = make(map[string]PolicyType)
+	}
+
+	p := firstRegistered + PolicyType(len(registry.entries))
+	registry.entries = append(registry.entries, r)
+	registry.byName[r.name] = p
+
+	return p
+}
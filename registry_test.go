@@ -0,0 +1,62 @@
+package ascache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Registrations last for the life of the process, so the tests register at
+// package level, the way callers are meant to, and stay repeatable under
+// -count.
+var testClock = RegisterPolicyType("TestClock")
+
+func TestRegisterPolicyType_NamesTheType(t *testing.T) {
+	assert.Equal(t, "TestClock", testClock.String())
+	assert.False(t, testClock.Builtin())
+	assert.Equal(t, testClock, testClock.Base(), "a registered type is not a variant")
+	assert.Empty(t, testClock.VariantName())
+
+	parsed, ok := ParsePolicyType("TestClock")
+	require.True(t, ok)
+	assert.Equal(t, testClock, parsed)
+}
+
+func TestRegisterPolicyType_NamesAWrappedCache(t *testing.T) {
+	policy := NewCache[string, int](newMockPolicy[string, int](Undefined, 10), testClock, 10)
+
+	assert.Equal(t, testClock, policy.GetType())
+	assert.Equal(t, "TestClock", policy.GetType().String())
+}
+
+func TestRegisterPolicyType_CanBeVaried(t *testing.T) {
+	variant, err := Variant(testClock, "hand=2")
+	require.NoError(t, err)
+
+	assert.Equal(t, "TestClock/hand=2", variant.String())
+	assert.Equal(t, testClock, variant.Base())
+}
+
+func TestRegisterPolicyType_PanicsOnACollisionOrABadName(t *testing.T) {
+	for _, name := range []string{
+		"TestClock", // registered above
+		"LRU",       // a built-in
+		"",
+		"2Q",
+		"Clock/2",
+		"has space",
+		"abcdefghijklmnopqrstuvwxyz0123456",
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				err, ok := recover().(error)
+				require.True(t, ok, "must panic with an error")
+				assert.True(t, errors.Is(err, ErrInvalidPolicyName), err)
+			}()
+
+			RegisterPolicyType(name)
+		})
+	}
+}
@@ -33,11 +33,11 @@ VERSION="${1:-vX.Y.Z}"
 # Modules intended for publication. bench and examples/* are deliberately
 # excluded: they are internal, nothing imports them, and their placeholder
 # requires are harmless.
-PUBLISHABLE=(. lfu policies policies/arc policies/tinylfu metrics bandit bandit/redis benchclient)
+PUBLISHABLE=(. lfu policies policies/arc policies/tinylfu metrics bandit bandit/redis bandit/sqlstore metrics/prometheus metrics/otel debughttp benchclient cmd/coordinator)
 
 # Tagging order. A module cannot require a real version of a sibling until that
 # sibling is tagged, so releases go bottom-up through the dependency graph.
-TAG_ORDER=(. lfu policies policies/arc policies/tinylfu metrics bandit bandit/redis benchclient)
+TAG_ORDER=(. lfu policies policies/arc policies/tinylfu metrics bandit bandit/redis bandit/sqlstore metrics/prometheus metrics/otel debughttp benchclient cmd/coordinator)
 
 fail=0
 
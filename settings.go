@@ -3,11 +3,14 @@ package ascache
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"math"
 	"slices"
 	"time"
 )
 
-// Settings configures the behaviour of AdaptiveCache.
+// Settings configures the behaviour of AdaptiveCache. NewAdaptiveCache keeps
+// a copy; change a running cache's settings with UpdateSettings.
 type Settings struct {
 	// EpochDuration is how often the cache re-evaluates its policies on a
 	// wall clock. Either this or EpochRequests must be set; setting both
@@ -46,8 +49,23 @@ type Settings struct {
 	// active policy in the epoch just measured before the switch is applied.
 	// It damps oscillation between policies that perform almost identically.
 	// Zero (the default) applies every selection the bandit makes.
+	//
+	// Deprecated: Use SwitchConfidence, which asks the same question of the
+	// evidence instead of treating two measured rates as exact. It is still
+	// applied when set, alongside any other gate.
 	MinHitRateImprovement float64
 
+	// SwitchConfidence is the probability, in [0,1), with which the evidence
+	// must show the bandit's selection beating the active policy before the
+	// switch is applied. Unlike MinHitRateImprovement it weighs how much
+	// evidence backs each rate: 0.6 against 0.5 from twenty requests is a coin
+	// toss, from two million a certainty. Evidence accumulates for as long as
+	// the two policies keep their roles, so a large win switches quickly and a
+	// narrow one once it is well measured; see Advice.Confidence for the
+	// value the gate compares. 0.95 is a sensible start. Zero (the default)
+	// applies every selection the bandit makes.
+	SwitchConfidence float64
+
 	// SwitchCooldownEpochs is the number of epochs that must elapse after a
 	// policy switch before another switch is allowed, counted from the last
 	// switch or from cache creation if none has happened yet. Zero (the
@@ -64,6 +82,11 @@ type Settings struct {
 	// of 100 is reached after roughly 2000 real requests.
 	MinEpochRequests int64
 
+	// Damping lengthens the switch cooldown automatically while the cache is
+	// flapping between two policies, and shortens it again once it stops.
+	// The zero value disables it.
+	Damping Damping
+
 	// ShadowSampleRate is the fraction of the keyspace, in (0,1], that shadow
 	// policies track. Shadows exist only to estimate a hit rate, and a hit
 	// rate can be estimated from a sample: at 0.05 a shadow skips 95% of the
@@ -81,8 +104,9 @@ type Settings struct {
 	ShadowSampleRate float64
 
 	// ObserveOnly runs the cache as a measurement instrument: every policy is
-	// still measured each epoch and reported to the bandit, but the active
-	// policy never changes and no migration ever happens.
+	// still measured each epoch and reported to the bandit, but the bandit's
+	// selection is never acted on. Only a pin, set with Pin or by a
+	// PinningBandit, switches an observing cache.
 	//
 	// This is the zero-risk way to adopt the library. The cache behaves
 	// exactly like the single policy you gave it first, while Advice() answers
@@ -99,6 +123,51 @@ type Settings struct {
 	// disables itself entirely. Zero (the default) applies
 	// DefaultMinShadowCapacity.
 	MinShadowCapacity int
+
+	// HistorySize is how many of the most recent switches History keeps.
+	// Zero (the default) applies DefaultHistorySize.
+	HistorySize int
+
+	// OnSwitch, when set, is called after every change of active policy,
+	// from whichever goroutine ran the epoch and after the cache's lock is
+	// released, so it may read the cache. It is how tracing and logging
+	// integrations learn what the cache did without touching Get.
+	//
+	// It runs on the epoch's goroutine, which is a caller's own Get under
+	// EpochRequests, so it should return promptly: the next epoch does not
+	// start until it has.
+	OnSwitch func(SwitchEvent)
+
+	// Logger, when set, receives a structured record of what each epoch
+	// decided: switches with their migration at Info, a gradual migration
+	// its epoch cut short at Info, and a selection a stability gate held back
+	// at Debug, naming the gate. Nil logs nothing.
+	//
+	// Like OnSwitch it is written to after the cache's lock is released, so a
+	// slow handler delays the next epoch but never a Get.
+	Logger *slog.Logger
+}
+
+// validate checks the fields NewAdaptiveCache and UpdateSettings both
+// require to be in range.
+func (s *Settings) validate() error {
+	if s.EpochRequests < 0 {
+		return fmt.Errorf("%w: got %d", ErrInvalidEpochRequests, s.EpochRequests)
+	}
+	if c := s.SwitchConfidence; c < 0 || c >= 1 || math.IsNaN(c) {
+		return fmt.Errorf("%w: got %v", ErrInvalidSwitchConfidence, c)
+	}
+	if d := s.Damping; d.Switches < 0 || d.Switches == 1 || d.WindowEpochs < 0 || d.MaxCooldownEpochs < 0 {
+		return fmt.Errorf("%w: %+v", ErrInvalidDamping, d)
+	}
+	// An epoch has to be ended by something. Either clock is acceptable and
+	// both together are fine; neither leaves a cache that measures every
+	// policy forever and never acts on any of it.
+	if s.EpochDuration <= 0 && s.EpochRequests == 0 {
+		return fmt.Errorf("%w: got %s", ErrInvalidEpochDuration, s.EpochDuration)
+	}
+
+	return nil
 }
 
 // DefaultMinShadowCapacity is the miniature capacity floor applied when
@@ -128,14 +197,8 @@ func NewAdaptiveCache[K comparable, V any](
 		}
 		bandit = observerBandit{}
 	}
-	if settings.EpochRequests < 0 {
-		return nil, fmt.Errorf("%w: got %d", ErrInvalidEpochRequests, settings.EpochRequests)
-	}
-	// An epoch has to be ended by something. Either clock is acceptable and
-	// both together are fine; neither leaves a cache that measures every
-	// policy forever and never acts on any of it.
-	if settings.EpochDuration <= 0 && settings.EpochRequests == 0 {
-		return nil, fmt.Errorf("%w: got %s", ErrInvalidEpochDuration, settings.EpochDuration)
+	if err := settings.validate(); err != nil {
+		return nil, err
 	}
 
 	availablePolicies := make(map[PolicyType]Policy[K, V], len(policies))
@@ -154,6 +217,13 @@ func NewAdaptiveCache[K comparable, V any](
 
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// The cache keeps a copy, so the caller's struct is theirs again once
+	// this returns. Changes go through UpdateSettings, which can validate
+	// them and apply them at an epoch boundary; a write to a struct the epoch
+	// goroutine was reading would be neither.
+	own := *settings
+	settings = &own
+
 	ac := &AdaptiveCache[K, V]{
 		policies:     availablePolicies,
 		policyOrder:  policyOrder,
@@ -170,6 +240,7 @@ func NewAdaptiveCache[K comparable, V any](
 	if settings.EpochDuration > 0 {
 		ac.epochTicker = time.NewTicker(settings.EpochDuration)
 	}
+	ac.epochLimit.Store(settings.EpochRequests)
 
 	// A bandit that wants whole epochs gets them instead of the per-arm
 	// stream, never as well as: RecordEpoch carries the same counts, so
@@ -177,6 +248,9 @@ func NewAdaptiveCache[K comparable, V any](
 	if epochBandit, ok := bandit.(EpochBandit); ok {
 		ac.epochBandit = epochBandit
 	}
+	if pinningBandit, ok := bandit.(PinningBandit); ok {
+		ac.pinningBandit = pinningBandit
+	}
 
 	sampleRate := settings.ShadowSampleRate
 	if sampleRate <= 0 {
@@ -188,6 +262,7 @@ func NewAdaptiveCache[K comparable, V any](
 	}
 	ac.minShadowCap = minShadowCap
 	ac.initShadowDutyLocked(sampleRate, minShadowCap)
+	ac.startLedgerLocked(time.Now())
 
 	ac.wg.Add(1)
 	go ac.runAdaptiveSelect()
@@ -85,14 +85,18 @@ func (c *AdaptiveCache[K, V]) promoteLockedCapacity(policyType PolicyType) {
 // because that window serves promotions out of the outgoing policy's real
 // values; closeMigrationLocked performs it once the window closes.
 //
-// It must be called while the write lock is held.
-func (c *AdaptiveCache[K, V]) switchLocked(from, to PolicyType) {
+// It returns the number of keys the migration copies or queues, as
+// migrateData reports it. It must be called while the write lock is held.
+func (c *AdaptiveCache[K, V]) switchLocked(from, to PolicyType, strategy MigrationStrategy) int {
 	// Abandon any window still open from a previous switch, demoting its
 	// source now that nothing will promote out of it again.
 	c.closeMigrationLocked()
 
 	c.promoteLockedCapacity(to)
-	c.migrateData(from, to)
+	// What the incoming policy counted as a shadow was counted over the
+	// sample; left in place, it would be credited as traffic it served.
+	c.policies[to].ResetStats()
+	moved := c.migrateData(from, to, strategy)
 	c.activePolicy = to
 
 	// Both policies just changed role, so what they measured in the previous
@@ -103,6 +107,8 @@ func (c *AdaptiveCache[K, V]) switchLocked(from, to PolicyType) {
 	if !c.migrating {
 		c.demoteLocked(from)
 	}
+
+	return moved
 }
 
 // closeMigrationLocked ends a gradual migration window and puts the source
@@ -15,25 +15,35 @@ func hitRate(s PolicyStats) float64 {
 // switches at all. When none are set, AdaptiveCache applies every bandit
 // selection, which is the behaviour of a zero-valued Settings.
 func (s *Settings) switchGated() bool {
-	return s.MinHitRateImprovement > 0 || s.SwitchCooldownEpochs > 0 || s.MinEpochRequests > 0
+	return s.MinHitRateImprovement > 0 || s.SwitchConfidence > 0 ||
+		s.SwitchCooldownEpochs > 0 || s.MinEpochRequests > 0
 }
 
-// allowSwitchLocked reports whether the bandit's selection of candidate should
-// actually be applied, given the stability settings and the stats measured in
-// the epoch that just ended. A rejected switch leaves the active policy in
-// place; the bandit still keeps the posterior it learned this epoch, so a
-// genuinely better policy wins again on a later epoch.
+// noEvidence is what heldByLocked names when the epoch measured nothing to
+// compare: not a gate a caller configures, but the reason every gate holds.
+const noEvidence = "no-evidence"
+
+// heldByLocked reports which stability gate, if any, holds back the bandit's
+// selection of candidate, given the stability settings and the stats measured
+// in the epoch that just ended. It returns the gate's name as GatesPassed
+// would record it, or "" when the switch should go ahead. A held switch
+// leaves the active policy in place; the bandit still keeps the posterior it
+// learned this epoch, so a genuinely better policy wins again on a later
+// epoch.
 //
 // It must be called while the write lock is held, immediately after
 // selectPolicyLocked, which populates epochStats.
-func (c *AdaptiveCache[K, V]) allowSwitchLocked(candidate PolicyType) bool {
-	if !c.settings.switchGated() {
-		return true
+func (c *AdaptiveCache[K, V]) heldByLocked(candidate PolicyType) string {
+	// The cooldown in force includes whatever oscillation damping has added
+	// to the configured one, so a damped cache is gated even when Settings
+	// configures no gate at all.
+	cooldown := c.cooldownLocked()
+	if !c.settings.switchGated() && cooldown == 0 {
+		return ""
 	}
 
-	if c.settings.SwitchCooldownEpochs > 0 &&
-		c.epochID-c.lastSwitchEpoch < c.settings.SwitchCooldownEpochs {
-		return false
+	if cooldown > 0 && c.epochID-c.lastSwitchEpoch < cooldown {
+		return GateSwitchCooldown
 	}
 
 	active, okActive := c.epochStats[c.activePolicy]
@@ -42,19 +52,23 @@ func (c *AdaptiveCache[K, V]) allowSwitchLocked(candidate PolicyType) bool {
 		// The epoch produced no comparable measurement (see the
 		// EvictPartialCapacityFilling gate in selectPolicyLocked). Hold the
 		// current policy rather than switch on no evidence.
-		return false
+		return noEvidence
 	}
 
 	if c.settings.MinEpochRequests > 0 &&
 		(active.Hits+active.Misses < c.settings.MinEpochRequests ||
 			cand.Hits+cand.Misses < c.settings.MinEpochRequests) {
-		return false
+		return GateMinEpochRequests
 	}
 
 	if c.settings.MinHitRateImprovement > 0 &&
 		hitRate(cand)-hitRate(active) < c.settings.MinHitRateImprovement {
-		return false
+		return GateMinHitRateImprovement
+	}
+
+	if c.settings.SwitchConfidence > 0 && !c.confidentLocked(candidate) {
+		return GateSwitchConfidence
 	}
 
-	return true
+	return ""
 }
@@ -0,0 +1,143 @@
+package ascache
+
+import (
+	"fmt"
+	"strings"
+)
+
+// variantSeparator joins a variant's base name to its own: TTL/30s.
+const variantSeparator = "/"
+
+// maxNameLength bounds a variant or registered name. It ends up in metric labels and in
+// keys a distributed store writes on every sync, so it is meant to be a short
+// tag like 30s or k=2, not a description.
+const maxNameLength = 32
+
+// Variant returns the PolicyType naming one parameterisation of base, such as
+// TTL at 30 seconds or LRU-K at k=3.
+//
+// A cache can hold each PolicyType once, and the built-in types name
+// algorithms, not settings, so racing TTL(30s) against TTL(5m) needs two
+// identities for one algorithm. A variant is that second identity: it is a
+// PolicyType of its own, distinct from base and from every other variant, and
+// it is named base/variant everywhere a policy is named - in Advice, in
+// metrics, in the fingerprint a distributed bandit pools by, and in what the
+// Valkey and Redis store writes.
+//
+// Calling it again with the same arguments returns the same PolicyType, so it
+// is safe to call wherever an arm is built. It is safe for concurrent use.
+//
+// The name must be 1 to 32 characters of letters, digits and _-.=+, and base
+// must name an algorithm rather than another variant or Undefined.
+//
+// The value is allocated in this process and is not stable across processes:
+// two programs registering variants in a different order give them different
+// numbers. Anything that leaves the process must identify a variant by its
+// name, which is what the packages in this repository do.
+func Variant(base PolicyType, variant string) (PolicyType, error) {
+	if err := validName(ErrInvalidVariant, variant); err != nil {
+		return Undefined, err
+	}
+
+	if base == Undefined {
+		return Undefined, fmt.Errorf("%w: base policy must not be Undefined", ErrInvalidVariant)
+	}
+	if !base.Builtin() {
+		r, ok := lookup(base)
+		if !ok {
+			return Undefined, fmt.Errorf("%w: base %s is not a known policy", ErrInvalidVariant, base)
+		}
+		if r.variant != "" {
+			return Undefined, fmt.Errorf("%w: base %s is itself a variant", ErrInvalidVariant, base)
+		}
+	}
+
+	name := base.String() + variantSeparator + variant
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if existing, ok := registry.byName[name]; ok {
+		return existing, nil
+	}
+
+	return registerLocked(registration{name: name, base: base, variant: variant}), nil
+}
+
+// Base returns the algorithm a variant parameterises, or the policy itself
+// when it is not a variant.
+func (p PolicyType) Base() PolicyType {
+	if r, ok := lookup(p); ok && r.variant != "" {
+		return r.base
+	}
+
+	return p
+}
+
+// VariantName returns the name a variant was registered with, without its
+// base: 30s for TTL/30s. It is empty for a policy that is not a variant.
+func (p PolicyType) VariantName() string {
+	r, _ := lookup(p)
+
+	return r.variant
+}
+
+// validName reports why a variant or registered name is unusable, if it is,
+// wrapping sentinel.
+//
+// The character set is narrow on purpose. A variant's name travels into places
+// that each reserve some punctuation - the bandit fingerprint joins arms with
+// commas and fields with semicolons, the Redis store separates the parts of a
+// counter field with colons - and a name that is safe everywhere is simpler
+// than one escaped differently in each.
+func validName(sentinel error, name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: name must not be empty", sentinel)
+	}
+	if len(name) > maxNameLength {
+		return fmt.Errorf("%w: name %q is longer than %d characters",
+			sentinel, name, maxNameLength)
+	}
+
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("_-.=+", r):
+		default:
+			return fmt.Errorf("%w: name %q contains %q", sentinel, name, r)
+		}
+	}
+
+	return nil
+}
+
+// variantPolicy is a policy reporting a variant's PolicyType in place of its
+// own. Everything else is the wrapped policy's.
+type variantPolicy[K comparable, V any] struct {
+	Policy[K, V]
+
+	policyType PolicyType
+}
+
+func (p *variantPolicy[K, V]) GetType() PolicyType { return p.policyType }
+
+// AsVariant returns policy under the identity of a variant of its own type, so
+// several instances of one algorithm can be arms of the same cache:
+//
+//	short, _ := ascache.AsVariant(policies.NewTTL[string, int](n, 30*time.Second), "30s")
+//	long, _ := ascache.AsVariant(policies.NewTTL[string, int](n, 5*time.Minute), "5m")
+//
+// The returned policy is policy in every respect except GetType, which reports
+// Variant(policy.GetType(), variant).
+func AsVariant[K comparable, V any](policy Policy[K, V], variant string) (Policy[K, V], error) {
+	if policy == nil {
+		return nil, ErrNilPolicy
+	}
+
+	policyType, err := Variant(policy.GetType(), variant)
+	if err != nil {
+		return nil, err
+	}
+
+	return &variantPolicy[K, V]{Policy: policy, policyType: policyType}, nil
+}
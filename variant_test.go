@@ -0,0 +1,116 @@
+package ascache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariant_IsItsOwnIdentity(t *testing.T) {
+	short, err := Variant(TTL, "30s")
+	require.NoError(t, err)
+	long, err := Variant(TTL, "5m")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, short, long)
+	assert.NotEqual(t, TTL, short)
+	assert.False(t, short.Builtin())
+
+	assert.Equal(t, "TTL/30s", short.String())
+	assert.Equal(t, TTL, short.Base())
+	assert.Equal(t, "30s", short.VariantName())
+
+	assert.Equal(t, TTL, TTL.Base(), "a built-in is its own base")
+	assert.Empty(t, TTL.VariantName())
+}
+
+func TestVariant_IsIdempotent(t *testing.T) {
+	first, err := Variant(LRUK, "k=3")
+	require.NoError(t, err)
+	second, err := Variant(LRUK, "k=3")
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+
+	parsed, ok := ParsePolicyType("LRUK/k=3")
+	require.True(t, ok)
+	assert.Equal(t, first, parsed)
+}
+
+func TestVariant_RejectsWhatCannotBeNamed(t *testing.T) {
+	nested, err := Variant(LRU, "nested-base")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		base    PolicyType
+		variant string
+	}{
+		{name: "empty name", base: LRU, variant: ""},
+		{name: "separator in name", base: LRU, variant: "a/b"},
+		{name: "colon in name", base: LRU, variant: "a:b"},
+		{name: "comma in name", base: LRU, variant: "a,b"},
+		{name: "overlong name", base: LRU, variant: "abcdefghijklmnopqrstuvwxyz0123456"},
+		{name: "undefined base", base: Undefined, variant: "x"},
+		{name: "unknown base", base: PolicyType(99), variant: "x"},
+		{name: "variant of a variant", base: nested, variant: "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Variant(tt.base, tt.variant)
+			assert.ErrorIs(t, err, ErrInvalidVariant)
+		})
+	}
+}
+
+func TestAsVariant_RejectsANilPolicy(t *testing.T) {
+	_, err := AsVariant[string, int](nil, "x")
+	assert.ErrorIs(t, err, ErrNilPolicy)
+}
+
+// TestAsVariant_RacesTwoInstancesOfOneAlgorithm is what variants are for: two
+// settings of one policy as arms of one cache, each measured and reported under
+// its own name.
+func TestAsVariant_RacesTwoInstancesOfOneAlgorithm(t *testing.T) {
+	shortInner := newMockPolicy[string, int](TTL, 100)
+	longInner := newMockPolicy[string, int](TTL, 100)
+
+	short, err := AsVariant[string, int](shortInner, "30s")
+	require.NoError(t, err)
+	long, err := AsVariant[string, int](longInner, "5m")
+	require.NoError(t, err)
+
+	ac, err := NewAdaptiveCache(
+		[]Policy[string, int]{short, long},
+		nil,
+		&Settings{EpochDuration: 24 * time.Hour, ObserveOnly: true},
+	)
+	require.NoError(t, err, "variants of one algorithm must not count as duplicates")
+	t.Cleanup(func() { _ = ac.Close() })
+
+	primeActiveStats(ac, 30, 70)
+	primeStats(longInner, 80, 20)
+	ac.runEpoch()
+
+	advice := ac.Advice()
+	assert.Equal(t, short.GetType(), advice.Active)
+	assert.Equal(t, long.GetType(), advice.Best)
+	assert.Equal(t, "TTL/5m", advice.Best.String())
+}
+
+func TestAsVariant_SameVariantTwiceIsStillADuplicate(t *testing.T) {
+	first, err := AsVariant[string, int](newMockPolicy[string, int](TTL, 10), "dup")
+	require.NoError(t, err)
+	second, err := AsVariant[string, int](newMockPolicy[string, int](TTL, 10), "dup")
+	require.NoError(t, err)
+
+	_, err = NewAdaptiveCache(
+		[]Policy[string, int]{first, second},
+		nil,
+		&Settings{EpochDuration: time.Hour, ObserveOnly: true},
+	)
+	assert.ErrorIs(t, err, ErrDuplicatePolicy)
+}
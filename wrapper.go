@@ -5,6 +5,9 @@ import (
 	"sync/atomic"
 )
 
+// NewCache wraps any Cacher as a Policy of the given type and capacity,
+// counting its hits and misses. For a cache this module has no PolicyType for,
+// allocate one with RegisterPolicyType.
 func NewCache[K comparable, V any](
 	cache Cacher[K, V],
 	policy PolicyType,